@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/audit"
+)
+
+// swagger:response
+//nolint:deadcode,unused
+type auditResponse struct {
+	// in: body
+	Records []apitypes.AuditRecord
+}
+
+// swagger:parameters retrieveAudit
+//nolint:deadcode,unused
+type auditParams struct {
+	// a glob that's matched against the record's path
+	//
+	// in: query
+	Path string
+	// only include records of this action
+	//
+	// in: query
+	Action string
+	// only include records of this journal
+	//
+	// in: query
+	Journal string
+	// only include records that started at or after this RFC3339 timestamp
+	//
+	// in: query
+	Since string
+}
+
+// swagger:route GET /audit audit retrieveAudit
+//
+// Get the audit log
+//
+// Get the records of the mutating actions (like delete and signal) that
+// were invoked on entries, optionally filtered by the query parameters.
+// The records are ordered by when they were logged.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: auditResponse
+//       400: errorResp
+//       500: errorResp
+var auditHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	query := r.URL.Query()
+	filter := audit.Filter{
+		Action:  query.Get("action"),
+		Journal: query.Get("journal"),
+	}
+	if query.Get("path") != "" {
+		washPath, errResp := getWashPathFromRequest(r)
+		if errResp != nil {
+			return errResp
+		}
+		filter.Path = washPath
+	}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339Nano, since)
+		if err != nil {
+			return badRequestResponse(fmt.Sprintf("invalid since parameter %v: %v", since, err))
+		}
+		filter.Since = t
+	}
+
+	records, err := audit.Query(filter)
+	if err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not query the audit log: %v", err))
+	}
+	activity.Record(r.Context(), "API: Audit %+v %v records", filter, len(records))
+
+	jsonEncoder := json.NewEncoder(w)
+	if err := jsonEncoder.Encode(records); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal the audit records: %v", err))
+	}
+	return nil
+}}
@@ -6,9 +6,17 @@ import (
 	"net/http"
 
 	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
 	"github.com/puppetlabs/wash/plugin"
 )
 
+// swagger:response
+//nolint:deadcode,unused
+type cacheStats struct {
+	// in: body
+	Stats apitypes.CacheStats
+}
+
 // swagger:route DELETE /cache cache cacheDelete
 //
 // Remove items from the cache
@@ -38,3 +46,43 @@ var cacheHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *err
 	}
 	return nil
 }}
+
+// swagger:route GET /cache cache cacheStats
+//
+// Describe the cache's contents
+//
+// Returns statistics about the cached results of the specified entry and its
+// children, including each result's age and approximate size. If no path is
+// specified, then the entire cache is described. Each op's hits and misses are
+// counted across all entries.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: cacheStats
+//       400: errorResp
+//       500: errorResp
+var cacheStatsHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	path := "/"
+	if r.URL.Query().Get("path") != "" {
+		washPath, errResp := getWashPathFromRequest(r)
+		if errResp != nil {
+			return errResp
+		}
+		if washPath != "" {
+			path = washPath
+		}
+	}
+
+	stats := plugin.CacheStatsFor(path)
+	activity.Record(r.Context(), "API: Cache GET %v %v results", path, len(stats.Results))
+
+	jsonEncoder := json.NewEncoder(w)
+	if err := jsonEncoder.Encode(stats); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal cache stats for %v: %v", path, err))
+	}
+	return nil
+}}
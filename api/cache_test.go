@@ -12,6 +12,7 @@ import (
 
 	"github.com/gorilla/mux"
 	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/datastore"
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -48,6 +49,25 @@ func (m *mockCache) GetOrUpdate(cat, key string, ttl time.Duration, resetTTLOnHi
 	return val, nil
 }
 
+func (m *mockCache) GetOrRefresh(cat, key string, ttl time.Duration, staleTTL time.Duration, generateValue func() (interface{}, error), refreshValue func() (interface{}, error)) (interface{}, error) {
+	return m.GetOrUpdate(cat, key, ttl, false, generateValue)
+}
+
+func (m *mockCache) Items(matcher *regexp.Regexp) []datastore.Item {
+	var items []datastore.Item
+	for k, v := range m.items {
+		if matcher.MatchString(k) {
+			segments := strings.SplitN(k, "::", 2)
+			items = append(items, datastore.Item{Category: segments[0], Key: segments[1], Value: v})
+		}
+	}
+	return items
+}
+
+func (m *mockCache) Stats() map[string]datastore.CategoryStats {
+	return map[string]datastore.CategoryStats{}
+}
+
 func (m *mockCache) Flush() {
 	m.items = make(map[string]interface{})
 }
@@ -73,14 +93,15 @@ func (suite *CacheHandlerTestSuite) SetupSuite() {
 	plugin.SetTestCache(newMockCache())
 	suite.router = mux.NewRouter()
 	suite.router.Handle("/cache", cacheHandler).Methods(http.MethodDelete)
+	suite.router.Handle("/cache", cacheStatsHandler).Methods(http.MethodGet)
 }
 
 func (suite *CacheHandlerTestSuite) TearDownSuite() {
 	plugin.UnsetTestCache()
 }
 
-func (suite *CacheHandlerTestSuite) TestRejectsGet() {
-	req := httptest.NewRequest(http.MethodGet, "http://example.com/cache", nil)
+func (suite *CacheHandlerTestSuite) TestRejectsPost() {
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/cache", nil)
 	w := httptest.NewRecorder()
 	suite.router.ServeHTTP(w, req)
 	suite.Equal(http.StatusMethodNotAllowed, w.Code)
@@ -145,6 +166,47 @@ func (suite *CacheHandlerTestSuite) TestClearCacheErrors() {
 	suite.Equal(apitypes.NonWashPath, errResp.Kind)
 }
 
+func (suite *CacheHandlerTestSuite) TestCacheStats() {
+	parent := newMockedParent()
+	parent.SetTestID("/dir")
+	parent.On("List", mock.Anything).Return([]plugin.Entry{}, nil)
+
+	reqCtx := context.WithValue(context.Background(), mountpointKey, "/mnt")
+	_, err := plugin.List(reqCtx, parent)
+	suite.NoError(err)
+	defer plugin.ClearCacheFor("/dir", false)
+
+	getStats := func(url string) apitypes.CacheStats {
+		req := httptest.NewRequest(http.MethodGet, url, nil).WithContext(reqCtx)
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		suite.Equal(http.StatusOK, w.Code)
+		var stats apitypes.CacheStats
+		suite.NoError(json.Unmarshal(w.Body.Bytes(), &stats))
+		return stats
+	}
+
+	// Test describing the entire cache
+	stats := getStats("http://example.com/cache")
+	if suite.Len(stats.Results, 1) {
+		suite.Equal("List", stats.Results[0].Op)
+		suite.Equal("/dir", stats.Results[0].Path)
+	}
+	suite.Equal(apitypes.CachePluginStats{Results: 1}, stats.Plugins["dir"])
+
+	// Test describing a path's cached results
+	stats = getStats("http://example.com/cache?path=/mnt/dir")
+	suite.Len(stats.Results, 1)
+	stats = getStats("http://example.com/cache?path=/mnt/other")
+	suite.Empty(stats.Results)
+
+	// Test describing a path outside the mountpoint
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/cache?path=/a/file", nil).WithContext(reqCtx)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Equal(http.StatusBadRequest, w.Code)
+}
+
 func TestCacheHandler(t *testing.T) {
 	suite.Run(t, new(CacheHandlerTestSuite))
 }
@@ -8,6 +8,8 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -19,11 +21,15 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/Benchkram/errz"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/analytics"
 	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
 )
 
 // Client represents a Wash API client.
@@ -32,20 +38,34 @@ type Client interface {
 	List(path string) ([]apitypes.Entry, error)
 	Metadata(path string) (map[string]interface{}, error)
 	Stream(path string) (io.ReadCloser, error)
-	Exec(path string, command string, args []string, opts apitypes.ExecOptions) (<-chan apitypes.ExecPacket, error)
+	Exec(path string, command string, args []string, opts apitypes.ExecOptions, stdin io.Reader) (<-chan apitypes.ExecPacket, error)
 	History(bool) (chan apitypes.Activity, error)
 	ActivityJournal(index int, follow bool) (io.ReadCloser, error)
 	Clear(path string) ([]string, error)
+	// An empty path describes the entire cache.
+	CacheStats(path string) (apitypes.CacheStats, error)
+	Audit(filter apitypes.AuditFilter) ([]apitypes.AuditRecord, error)
+	// An empty name reloads all of the plugins.
+	ReloadPlugins(name string) ([]apitypes.PluginReloadResult, error)
 	// A "nil" schema means that the schema's unknown.
 	Schema(path string) (*apitypes.EntrySchema, error)
 	Screenview(name string, params analytics.Params) error
 	Delete(path string) (bool, error)
 	Signal(path string, signal string) error
+	PlanDelete(path string) ([]string, error)
+	PlanSignal(path string, signal string) ([]string, error)
+	Create(path string, name string, isParent bool) (apitypes.Entry, error)
+	Rename(path string, newPath string) (apitypes.Entry, error)
 }
 
-// A domainSocketClient is a wash API client.
-type domainSocketClient struct {
+// An httpClient is a wash API client.
+type httpClient struct {
 	*http.Client
+	baseURL string
+	token   string
+	// remote is true if the client's talking to a remote Wash server. A
+	// remote server's paths are relative to its mountpoint.
+	remote bool
 }
 
 var domainSocketBaseURL = "http://localhost"
@@ -53,14 +73,94 @@ var domainSocketBaseURL = "http://localhost"
 // ForUNIXSocket returns a client suitable for making wash API calls over a UNIX
 // domain socket.
 func ForUNIXSocket(pathToSocket string) Client {
-	return &domainSocketClient{
-		&http.Client{
+	return &httpClient{
+		Client: &http.Client{
 			Transport: &http.Transport{
 				DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
 					return net.Dial("unix", pathToSocket)
 				},
 			},
-		}}
+		},
+		baseURL: domainSocketBaseURL,
+	}
+}
+
+// URLOptions configures a client that's returned by ForURL.
+type URLOptions struct {
+	// Token is the bearer token that's sent with each request.
+	Token string
+	// CAFile is a PEM-encoded certificate file that's used to verify the
+	// server's certificate instead of the system's certificate pool. Use it
+	// to trust a server's self-signed certificate.
+	CAFile string
+}
+
+// ForURL returns a client suitable for making wash API calls to a remote wash
+// server at baseURL, e.g. "https://example.com:8443". baseURL must use https
+// unless it's a loopback address, since the bearer token would otherwise be
+// sent in plaintext. The client's paths are relative to the remote server's
+// mountpoint.
+func ForURL(baseURL string, opts URLOptions) (Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %v: %v", baseURL, err)
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !isLoopback(u.Hostname()) {
+			return nil, fmt.Errorf("invalid URL %v: http is only allowed for loopback addresses, use https instead", baseURL)
+		}
+	default:
+		return nil, fmt.Errorf("invalid URL %v: the scheme must be https", baseURL)
+	}
+
+	tlsConfig := &tls.Config{}
+	if opts.CAFile != "" {
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in the CA file %v", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &httpClient{
+		Client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		token:   opts.Token,
+		remote:  true,
+	}, nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// absPath returns the path that the server expects for p. That's p's absolute
+// path for a local server. A remote server can't resolve local paths, so p's
+// relative to its mountpoint instead.
+func (c *httpClient) absPath(p string) (string, error) {
+	if c.remote {
+		return filepath.ToSlash(filepath.Join("/", p)), nil
+	}
+	path, err := filepath.Abs(p)
+	if err != nil {
+		return "", fmt.Errorf("could not calculate the absolute path of %v: %v", p, err)
+	}
+	return path, nil
 }
 
 func unmarshalErrorResp(resp *http.Response) error {
@@ -76,30 +176,40 @@ func unmarshalErrorResp(resp *http.Response) error {
 	return &errorObj
 }
 
-func (c *domainSocketClient) doRequest(method, endpoint string, params url.Values, body io.Reader) (io.ReadCloser, error) {
+func (c *httpClient) newRequest(method, endpoint string, params url.Values, body io.Reader) (*http.Request, error) {
 	// Do common parameter munging.
 	if paths, ok := params["path"]; ok {
 		if len(paths) != 1 {
 			panic("path parameter should have a single element")
 		}
-		path, err := filepath.Abs(paths[0])
+		path, err := c.absPath(paths[0])
 		if err != nil {
-			return nil, fmt.Errorf("could not calculate the absolute path of %v: %v", path, err)
+			return nil, err
 		}
 		params["path"] = []string{path}
 	}
 
-	req, err := http.NewRequest(method, domainSocketBaseURL, body)
+	req, err := http.NewRequest(method, c.baseURL+endpoint, body)
 	if err != nil {
 		return nil, err
 	}
 
-	req.URL.Path = endpoint
 	req.URL.RawQuery = params.Encode()
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
 
 	journal := activity.JournalForPID(os.Getpid())
 	req.Header.Set(apitypes.JournalIDHeader, journal.ID)
 	req.Header.Set(apitypes.JournalDescHeader, journal.Description)
+	return req, nil
+}
+
+func (c *httpClient) doRequest(method, endpoint string, params url.Values, body io.Reader) (io.ReadCloser, error) {
+	req, err := c.newRequest(method, endpoint, params, body)
+	if err != nil {
+		return nil, err
+	}
 	resp, err := c.Do(req)
 	if err != nil {
 		return nil, err
@@ -112,7 +222,7 @@ func (c *domainSocketClient) doRequest(method, endpoint string, params url.Value
 	return nil, unmarshalErrorResp(resp)
 }
 
-func (c *domainSocketClient) doRequestAndParseJSONBody(method, endpoint string, params url.Values, body io.Reader, result interface{}) error {
+func (c *httpClient) doRequestAndParseJSONBody(method, endpoint string, params url.Values, body io.Reader, result interface{}) error {
 	respBody, err := c.doRequest(method, endpoint, params, body)
 	if err != nil {
 		return err
@@ -131,12 +241,12 @@ func (c *domainSocketClient) doRequestAndParseJSONBody(method, endpoint string,
 	return nil
 }
 
-func (c *domainSocketClient) getRequest(endpoint string, params url.Values, result interface{}) error {
+func (c *httpClient) getRequest(endpoint string, params url.Values, result interface{}) error {
 	return c.doRequestAndParseJSONBody(http.MethodGet, endpoint, params, nil, result)
 }
 
 // Info retrieves the information of the resource located at "path"
-func (c *domainSocketClient) Info(path string) (apitypes.Entry, error) {
+func (c *httpClient) Info(path string) (apitypes.Entry, error) {
 	var e apitypes.Entry
 	if err := c.getRequest("/fs/info", url.Values{"path": []string{path}}, &e); err != nil {
 		return e, err
@@ -146,7 +256,7 @@ func (c *domainSocketClient) Info(path string) (apitypes.Entry, error) {
 }
 
 // List lists the resources located at "path".
-func (c *domainSocketClient) List(path string) ([]apitypes.Entry, error) {
+func (c *httpClient) List(path string) ([]apitypes.Entry, error) {
 	var ls []apitypes.Entry
 	if err := c.getRequest("/fs/list", url.Values{"path": []string{path}}, &ls); err != nil {
 		return nil, err
@@ -156,7 +266,7 @@ func (c *domainSocketClient) List(path string) ([]apitypes.Entry, error) {
 }
 
 // Metadata gets the metadata of the resource located at "path".
-func (c *domainSocketClient) Metadata(path string) (map[string]interface{}, error) {
+func (c *httpClient) Metadata(path string) (map[string]interface{}, error) {
 	var metadata map[string]interface{}
 	if err := c.getRequest("/fs/metadata", url.Values{"path": []string{path}}, &metadata); err != nil {
 		return nil, err
@@ -166,7 +276,7 @@ func (c *domainSocketClient) Metadata(path string) (map[string]interface{}, erro
 }
 
 // Stream updates for the resource located at "path".
-func (c *domainSocketClient) Stream(path string) (io.ReadCloser, error) {
+func (c *httpClient) Stream(path string) (io.ReadCloser, error) {
 	respBody, err := c.doRequest(http.MethodGet, "/fs/stream", url.Values{"path": []string{path}}, nil)
 	if err != nil {
 		return nil, err
@@ -176,17 +286,25 @@ func (c *domainSocketClient) Stream(path string) (io.ReadCloser, error) {
 }
 
 // Exec invokes the given command + args on the resource located at "path".
+// If stdin is not nil, then it's streamed to the command's stdin.
 //
 // The resulting channel contains events, ordered as we receive them from the
 // server. The channel will be closed when there are no more events.
-func (c *domainSocketClient) Exec(path string, command string, args []string, opts apitypes.ExecOptions) (<-chan apitypes.ExecPacket, error) {
+func (c *httpClient) Exec(path string, command string, args []string, opts apitypes.ExecOptions, stdin io.Reader) (<-chan apitypes.ExecPacket, error) {
+	opts.Stdin = stdin != nil
 	payload := apitypes.ExecBody{Cmd: command, Args: args, Opts: opts}
 	jsonBody, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
 
-	respBody, err := c.doRequest(http.MethodPost, "/fs/exec", url.Values{"path": []string{path}}, bytes.NewReader(jsonBody))
+	var respBody io.ReadCloser
+	params := url.Values{"path": []string{path}}
+	if stdin == nil {
+		respBody, err = c.doRequest(http.MethodPost, "/fs/exec", params, bytes.NewReader(jsonBody))
+	} else {
+		respBody, err = c.doExecUpgradeRequest(params, bytes.NewReader(jsonBody), stdin, opts.TerminalSizes)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -214,9 +332,83 @@ func (c *domainSocketClient) Exec(path string, command string, args []string, op
 	return events, nil
 }
 
+// doExecUpgradeRequest sends an exec request that upgrades the connection to
+// the wash-exec protocol, then streams stdin and terminalSizes over it. It
+// returns the connection so that the caller can read the exec's packets.
+func (c *httpClient) doExecUpgradeRequest(
+	params url.Values,
+	body io.Reader,
+	stdin io.Reader,
+	terminalSizes <-chan plugin.TerminalSize,
+) (io.ReadCloser, error) {
+	req, err := c.newRequest(http.MethodPost, "/fs/exec", params, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", apitypes.ExecUpgradeProtocol)
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, unmarshalErrorResp(resp)
+	}
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		errz.Log(resp.Body.Close())
+		return nil, fmt.Errorf("the server did not upgrade the connection to %v", apitypes.ExecUpgradeProtocol)
+	}
+
+	go sendExecInput(conn, stdin, terminalSizes)
+	return conn, nil
+}
+
+// sendExecInput sends stdin's content as ExecInputPackets, followed by an EOF
+// packet once stdin's exhausted. It also sends each of terminalSizes' sizes
+// until it's closed.
+func sendExecInput(w io.Writer, stdin io.Reader, terminalSizes <-chan plugin.TerminalSize) {
+	var mux sync.Mutex
+	encoder := json.NewEncoder(w)
+	send := func(packet apitypes.ExecInputPacket) error {
+		mux.Lock()
+		defer mux.Unlock()
+		return encoder.Encode(packet)
+	}
+
+	if terminalSizes != nil {
+		go func() {
+			for size := range terminalSizes {
+				size := size
+				if err := send(apitypes.ExecInputPacket{Resize: &size}); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			if encodeErr := send(apitypes.ExecInputPacket{Data: buf[:n]}); encodeErr != nil {
+				// The connection's closed, so the exec's finished.
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("could not read stdin: %v", err)
+			}
+			_ = send(apitypes.ExecInputPacket{EOF: true})
+			return
+		}
+	}
+}
+
 // History returns a command history channel for the current wash server session.
 // If follow is false, it closes when all current activity has been delivered.
-func (c *domainSocketClient) History(follow bool) (chan apitypes.Activity, error) {
+func (c *httpClient) History(follow bool) (chan apitypes.Activity, error) {
 	var params url.Values
 	if follow {
 		params = url.Values{"follow": []string{"true"}}
@@ -247,7 +439,7 @@ func (c *domainSocketClient) History(follow bool) (chan apitypes.Activity, error
 
 // ActivityJournal returns a reader for the journal associated with a particular command in history.
 // If follow is true, it streams new updates instead of returning the whole journal.
-func (c *domainSocketClient) ActivityJournal(index int, follow bool) (io.ReadCloser, error) {
+func (c *httpClient) ActivityJournal(index int, follow bool) (io.ReadCloser, error) {
 	var params url.Values
 	if follow {
 		params = url.Values{"follow": []string{"true"}}
@@ -256,7 +448,7 @@ func (c *domainSocketClient) ActivityJournal(index int, follow bool) (io.ReadClo
 }
 
 // Clear the cache at "path".
-func (c *domainSocketClient) Clear(path string) ([]string, error) {
+func (c *httpClient) Clear(path string) ([]string, error) {
 	respBody, err := c.doRequest(http.MethodDelete, "/cache", url.Values{"path": []string{path}}, nil)
 	if err != nil {
 		return nil, err
@@ -276,8 +468,54 @@ func (c *domainSocketClient) Clear(path string) ([]string, error) {
 	return result, nil
 }
 
+// CacheStats describes the cached results at "path". If path is empty, then it
+// describes the entire cache.
+func (c *httpClient) CacheStats(path string) (apitypes.CacheStats, error) {
+	var stats apitypes.CacheStats
+	params := url.Values{}
+	if path != "" {
+		params["path"] = []string{path}
+	}
+	err := c.getRequest("/cache", params, &stats)
+	return stats, err
+}
+
+// Audit returns the audit records that match the filter
+func (c *httpClient) Audit(filter apitypes.AuditFilter) ([]apitypes.AuditRecord, error) {
+	params := url.Values{}
+	if filter.Path != "" {
+		params["path"] = []string{filter.Path}
+	}
+	if filter.Action != "" {
+		params["action"] = []string{filter.Action}
+	}
+	if filter.Journal != "" {
+		params["journal"] = []string{filter.Journal}
+	}
+	if !filter.Since.IsZero() {
+		params["since"] = []string{filter.Since.Format(time.RFC3339Nano)}
+	}
+
+	var records []apitypes.AuditRecord
+	err := c.getRequest("/audit", params, &records)
+	return records, err
+}
+
+// ReloadPlugins reloads the named plugin. If name is empty, then it reloads all of
+// the plugins.
+func (c *httpClient) ReloadPlugins(name string) ([]apitypes.PluginReloadResult, error) {
+	params := url.Values{}
+	if name != "" {
+		params["plugin"] = []string{name}
+	}
+
+	var results []apitypes.PluginReloadResult
+	err := c.doRequestAndParseJSONBody(http.MethodPost, "/plugins/reload", params, nil, &results)
+	return results, err
+}
+
 // Schema returns the entry's schema
-func (c *domainSocketClient) Schema(path string) (*apitypes.EntrySchema, error) {
+func (c *httpClient) Schema(path string) (*apitypes.EntrySchema, error) {
 	var schema *apitypes.EntrySchema
 	if err := c.getRequest("/fs/schema", url.Values{"path": []string{path}}, &schema); err != nil {
 		return schema, err
@@ -286,7 +524,7 @@ func (c *domainSocketClient) Schema(path string) (*apitypes.EntrySchema, error)
 }
 
 // Screenview submits a screenview to Google Analytics
-func (c *domainSocketClient) Screenview(name string, params analytics.Params) error {
+func (c *httpClient) Screenview(name string, params analytics.Params) error {
 	payload := apitypes.ScreenviewBody{
 		Name:   name,
 		Params: params,
@@ -300,14 +538,14 @@ func (c *domainSocketClient) Screenview(name string, params analytics.Params) er
 }
 
 // Delete deletes the entry at "path"
-func (c *domainSocketClient) Delete(path string) (bool, error) {
+func (c *httpClient) Delete(path string) (bool, error) {
 	var deleted bool
 	err := c.doRequestAndParseJSONBody(http.MethodDelete, "/fs/delete", url.Values{"path": []string{path}}, nil, &deleted)
 	return deleted, err
 }
 
 // Signal sends the given signal to tne entry at "path"
-func (c *domainSocketClient) Signal(path string, signal string) error {
+func (c *httpClient) Signal(path string, signal string) error {
 	payload := apitypes.SignalBody{Signal: signal}
 	jsonBody, err := json.Marshal(payload)
 	if err != nil {
@@ -316,3 +554,55 @@ func (c *domainSocketClient) Signal(path string, signal string) error {
 	_, err = c.doRequest(http.MethodPost, "/fs/signal", url.Values{"path": []string{path}}, bytes.NewReader(jsonBody))
 	return err
 }
+
+// PlanDelete returns the steps that deleting the entry at "path" would take
+// without deleting it.
+func (c *httpClient) PlanDelete(path string) ([]string, error) {
+	var plan []string
+	params := url.Values{"path": []string{path}, "dry_run": []string{"true"}}
+	err := c.doRequestAndParseJSONBody(http.MethodDelete, "/fs/delete", params, nil, &plan)
+	return plan, err
+}
+
+// PlanSignal returns the steps that sending the given signal to the entry at
+// "path" would take without sending it.
+func (c *httpClient) PlanSignal(path string, signal string) ([]string, error) {
+	payload := apitypes.SignalBody{Signal: signal}
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var plan []string
+	params := url.Values{"path": []string{path}, "dry_run": []string{"true"}}
+	err = c.doRequestAndParseJSONBody(http.MethodPost, "/fs/signal", params, bytes.NewReader(jsonBody), &plan)
+	return plan, err
+}
+
+// Create creates a new child named "name" in the entry at "path". If isParent
+// is true, then the child will be a parent.
+func (c *httpClient) Create(path string, name string, isParent bool) (apitypes.Entry, error) {
+	var e apitypes.Entry
+	payload := apitypes.CreateBody{Name: name, IsParent: isParent}
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return e, err
+	}
+	err = c.doRequestAndParseJSONBody(http.MethodPost, "/fs/create", url.Values{"path": []string{path}}, bytes.NewReader(jsonBody), &e)
+	return e, err
+}
+
+// Rename renames (or moves) the entry at "path" to "newPath"
+func (c *httpClient) Rename(path string, newPath string) (apitypes.Entry, error) {
+	var e apitypes.Entry
+	absNewPath, err := c.absPath(newPath)
+	if err != nil {
+		return e, err
+	}
+	payload := apitypes.RenameBody{NewPath: absNewPath}
+	jsonBody, err := json.Marshal(payload)
+	if err != nil {
+		return e, err
+	}
+	err = c.doRequestAndParseJSONBody(http.MethodPost, "/fs/rename", url.Values{"path": []string{path}}, bytes.NewReader(jsonBody), &e)
+	return e, err
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// swagger:parameters createEntry
+//nolint:deadcode,unused
+type createBody struct {
+	params
+	// in: body
+	Body apitypes.CreateBody
+}
+
+// swagger:route POST /fs/create create createEntry
+//
+// Creates a new child of the entry at the specified path.
+//
+// On success, returns an Entry object describing the created child.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: Entry
+//       400: errorResp
+//       403: errorResp
+//       404: errorResp
+//       500: errorResp
+var createHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	ctx := r.Context()
+	entry, path, errResp := getEntryFromRequest(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	if !plugin.CreateAction().IsSupportedOn(entry) {
+		return unsupportedActionResponse(path, plugin.CreateAction())
+	}
+
+	if r.Body == nil {
+		return badActionRequestResponse(path, plugin.CreateAction(), "Please send a JSON request body")
+	}
+
+	var body apitypes.CreateBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return badActionRequestResponse(path, plugin.CreateAction(), err.Error())
+	}
+
+	created, err := plugin.CreateWithAnalytics(ctx, entry.(plugin.Creatable), body.Name, body.IsParent)
+	if err != nil {
+		if plugin.IsPolicyDeniedErr(err) {
+			return actionDeniedResponse(path, plugin.CreateAction(), err)
+		}
+		if plugin.IsInvalidInputErr(err) {
+			return badActionRequestResponse(path, plugin.CreateAction(), err.Error())
+		}
+		return erroredActionResponse(path, plugin.CreateAction(), err.Error())
+	}
+
+	apiEntry := apitypes.NewEntry(created)
+	apiEntry.Path = path + "/" + apiEntry.CName
+	activity.Record(ctx, "API: Create %v %v", path, apiEntry.Path)
+
+	jsonEncoder := json.NewEncoder(w)
+	if err = jsonEncoder.Encode(&apiEntry); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal the created entry for %v: %v", path, err))
+	}
+	return nil
+}}
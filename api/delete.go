@@ -9,18 +9,29 @@ import (
 	"github.com/puppetlabs/wash/plugin"
 )
 
+// swagger:parameters deleteEntry signalEntry
+//nolint:deadcode,unused
+type dryRunParams struct {
+	// only plan the action when true
+	//
+	// in: query
+	DryRun bool `json:"dry_run"`
+}
+
 // swagger:route DELETE /fs/delete delete deleteEntry
 //
 // Deletes the entry at the specified path.
 //
 // On success, returns a boolean that describes whether the delete was applied immediately
-// or is pending.
+// or is pending. If dry_run is true, then the entry isn't deleted. Instead, returns a list
+// of the steps that deleting it would take.
 //
 //     Schemes: http
 //
 //     Responses:
 //       200:
 //       400: errorResp
+//       403: errorResp
 //       404: errorResp
 //       500: errorResp
 var deleteHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
@@ -32,12 +43,30 @@ var deleteHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *er
 	if !plugin.DeleteAction().IsSupportedOn(entry) {
 		return unsupportedActionResponse(path, plugin.DeleteAction())
 	}
+	dryRun, errResp := getBoolParam(r.URL, "dry_run")
+	if errResp != nil {
+		return errResp
+	}
+	if dryRun {
+		ctx = plugin.WithDryRun(ctx)
+	}
 	deleted, err := plugin.DeleteWithAnalytics(ctx, entry.(plugin.Deletable))
 	if err != nil {
+		if plugin.IsPolicyDeniedErr(err) {
+			return actionDeniedResponse(path, plugin.DeleteAction(), err)
+		}
 		return erroredActionResponse(path, plugin.DeleteAction(), err.Error())
 	}
-	activity.Record(ctx, "API: Delete %v %v", path, deleted)
 	jsonEncoder := json.NewEncoder(w)
+	if dryRun {
+		plan := plugin.DryRunPlan(ctx)
+		activity.Record(ctx, "API: Delete %v planned %v", path, plan)
+		if err = jsonEncoder.Encode(plan); err != nil {
+			return unknownErrorResponse(fmt.Errorf("Could not marshal delete's plan for %v: %v", path, err))
+		}
+		return nil
+	}
+	activity.Record(ctx, "API: Delete %v %v", path, deleted)
 	if err = jsonEncoder.Encode(deleted); err != nil {
 		return unknownErrorResponse(fmt.Errorf("Could not marshal delete's result for %v: %v", path, err))
 	}
@@ -189,6 +189,19 @@ func erroredActionResponse(path string, a plugin.Action, reason string) *errorRe
 	return &errorResponse{statusCode, body}
 }
 
+func actionDeniedResponse(path string, a plugin.Action, err error) *errorResponse {
+	fields := apitypes.ErrorFields{
+		"path":   path,
+		"action": a.Name,
+	}
+	body := newErrorObj(
+		apitypes.ActionDenied,
+		err.Error(),
+		fields,
+	)
+	return &errorResponse{http.StatusForbidden, body}
+}
+
 func duplicateCNameResponse(e plugin.DuplicateCNameErr) *errorResponse {
 	fields := apitypes.ErrorFields{
 		"parent_id":                   e.ParentID,
@@ -229,3 +242,11 @@ func nonWashPathResponse(path string) *errorResponse {
 		apitypes.ErrorFields{"path": path},
 	)}
 }
+
+func unauthorizedResponse(reason string) *errorResponse {
+	return &errorResponse{http.StatusUnauthorized, newErrorObj(
+		apitypes.Unauthorized,
+		fmt.Sprintf("Unauthorized: %v", reason),
+		apitypes.ErrorFields{},
+	)}
+}
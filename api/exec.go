@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -48,6 +51,9 @@ type execResponse struct {
 //
 // Executes a command on the remote system described by the supplied path.
 //
+// If opts.stdin is true, then the connection is upgraded to the wash-exec protocol
+// so that the client can stream the command's stdin as ExecInputPackets.
+//
 //     Consumes:
 //     - application/json
 //
@@ -57,8 +63,10 @@ type execResponse struct {
 //     Schemes: http
 //
 //     Responses:
+//       101: execResponse
 //       200: execResponse
 //       400: errorResp
+//       403: errorResp
 //       404: errorResp
 //       500: errorResp
 var execHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
@@ -87,21 +95,79 @@ var execHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *erro
 	}
 
 	activity.Record(ctx, "API: Exec %v %+v", path, body)
-	opts := plugin.ExecOptions{}
+	if body.Opts.Timeout < 0 {
+		return badActionRequestResponse(path, plugin.ExecAction(), "the timeout cannot be negative")
+	}
+	if body.Opts.Stdin {
+		if body.Opts.Input != "" {
+			return badActionRequestResponse(path, plugin.ExecAction(), "input and stdin cannot both be set")
+		}
+		if !isExecUpgradeRequest(r) {
+			msg := fmt.Sprintf("streaming stdin requires upgrading the connection to %v", apitypes.ExecUpgradeProtocol)
+			return badActionRequestResponse(path, plugin.ExecAction(), msg)
+		}
+		if _, ok := w.(http.Hijacker); !ok {
+			return unknownErrorResponse(fmt.Errorf("Cannot stream stdin to %v, response handler does not support hijacking", path))
+		}
+	}
+
+	// The exec's cancelled once the request's finished. For streamed stdin,
+	// it's also cancelled if the client closes the connection.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	opts := plugin.ExecOptions{
+		Tty:     body.Opts.Tty,
+		Env:     body.Opts.Env,
+		Dir:     body.Opts.Dir,
+		User:    body.Opts.User,
+		Timeout: body.Opts.Timeout,
+	}
+	var stdin *io.PipeWriter
+	var terminalSizes chan plugin.TerminalSize
 	if body.Opts.Input != "" {
 		opts.Stdin = strings.NewReader(body.Opts.Input)
+	} else if body.Opts.Stdin {
+		var stdinReader *io.PipeReader
+		stdinReader, stdin = io.Pipe()
+		// Closing the reader unblocks any pending writes if the command
+		// doesn't consume all of its input.
+		defer stdinReader.Close()
+		opts.Stdin = stdinReader
+		if body.Opts.Tty {
+			terminalSizes = make(chan plugin.TerminalSize, 1)
+			opts.TerminalSizes = terminalSizes
+		}
 	}
 	cmd, err := plugin.ExecWithAnalytics(ctx, entry.(plugin.Execable), body.Cmd, body.Args, opts)
 	if err != nil {
+		if plugin.IsPolicyDeniedErr(err) {
+			return actionDeniedResponse(path, plugin.ExecAction(), err)
+		}
 		return erroredActionResponse(path, plugin.ExecAction(), err.Error())
 	}
 
-	// Ensure every write is a flush, and do an initial flush to send the header.
-	w.WriteHeader(http.StatusOK)
-	fw.Flush()
+	var enc *json.Encoder
+	if stdin != nil {
+		conn, input, err := upgradeExecConnection(w, r)
+		if err != nil {
+			return unknownErrorResponse(fmt.Errorf("Could not upgrade the connection for %v: %v", path, err))
+		}
+		defer func() {
+			// Cancel first so that streamExecInput knows that the closed
+			// connection isn't an error.
+			cancel()
+			conn.Close()
+		}()
+		go streamExecInput(ctx, cancel, input, stdin, terminalSizes)
+		enc = json.NewEncoder(conn)
+	} else {
+		// Ensure every write is a flush, and do an initial flush to send the header.
+		w.WriteHeader(http.StatusOK)
+		fw.Flush()
+		enc = json.NewEncoder(&streamableResponseWriter{fw})
+	}
 
 	// Stream the command's output
-	enc := json.NewEncoder(&streamableResponseWriter{fw})
 	for chunk := range cmd.OutputCh() {
 		packet := apitypes.ExecPacket{TypeField: chunk.StreamID, Timestamp: chunk.Timestamp}
 		if err := chunk.Err; err != nil {
@@ -125,3 +191,89 @@ var execHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *erro
 
 	return nil
 }}
+
+func isExecUpgradeRequest(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), apitypes.ExecUpgradeProtocol) &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// upgradeExecConnection hijacks the request's connection and upgrades it to
+// the wash-exec protocol. It returns the connection and a reader for the
+// client's input.
+func upgradeExecConnection(w http.ResponseWriter, r *http.Request) (net.Conn, io.Reader, error) {
+	// Discard what's left of the request body so that it isn't mistaken for
+	// the client's input.
+	if _, err := io.Copy(ioutil.Discard, r.Body); err != nil {
+		return nil, nil, err
+	}
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: " + apitypes.ExecUpgradeProtocol + "\r\n\r\n"
+	if _, err := buf.WriteString(resp); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if err := buf.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, buf.Reader, nil
+}
+
+// streamExecInput writes the data in the client's input packets to stdin, and
+// sends their terminal sizes to terminalSizes if it's not nil. It cancels the
+// exec if the client closes the connection or sends an invalid packet.
+func streamExecInput(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	input io.Reader,
+	stdin *io.PipeWriter,
+	terminalSizes chan plugin.TerminalSize,
+) {
+	decoder := json.NewDecoder(input)
+	for {
+		var packet apitypes.ExecInputPacket
+		if err := decoder.Decode(&packet); err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				activity.Record(ctx, "API: Exec: stopped reading stdin: %v", err)
+			}
+			stdin.CloseWithError(err)
+			if terminalSizes != nil {
+				close(terminalSizes)
+			}
+			cancel()
+			return
+		}
+		if packet.Resize != nil && terminalSizes != nil {
+			sendLatestTerminalSize(terminalSizes, *packet.Resize)
+		}
+		if len(packet.Data) > 0 {
+			// An error means that stdin's closed, in which case the data is
+			// dropped.
+			_, _ = stdin.Write(packet.Data)
+		}
+		if packet.EOF {
+			stdin.Close()
+		}
+	}
+}
+
+// sendLatestTerminalSize sends size without blocking. If an earlier size hasn't
+// been received yet, then it's replaced because only the latest size matters.
+func sendLatestTerminalSize(terminalSizes chan plugin.TerminalSize, size plugin.TerminalSize) {
+	for {
+		select {
+		case terminalSizes <- size:
+			return
+		default:
+			select {
+			case <-terminalSizes:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type ExecTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ExecTestSuite) TestIsExecUpgradeRequest() {
+	req := httptest.NewRequest(http.MethodPost, "/fs/exec", nil)
+	suite.False(isExecUpgradeRequest(req))
+
+	req.Header.Set("Upgrade", apitypes.ExecUpgradeProtocol)
+	suite.False(isExecUpgradeRequest(req))
+
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	suite.True(isExecUpgradeRequest(req))
+
+	req.Header.Set("Upgrade", "websocket")
+	suite.False(isExecUpgradeRequest(req))
+}
+
+func (suite *ExecTestSuite) TestStreamExecInput() {
+	var input bytes.Buffer
+	enc := json.NewEncoder(&input)
+	suite.NoError(enc.Encode(apitypes.ExecInputPacket{Data: []byte("hello ")}))
+	suite.NoError(enc.Encode(apitypes.ExecInputPacket{Data: []byte("world")}))
+	suite.NoError(enc.Encode(apitypes.ExecInputPacket{EOF: true}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stdinReader, stdin := io.Pipe()
+	go streamExecInput(ctx, cancel, &input, stdin, nil)
+
+	data, err := ioutil.ReadAll(stdinReader)
+	suite.NoError(err)
+	suite.Equal("hello world", string(data))
+
+	// The exec's cancelled once the client closes the connection, which is
+	// represented by the input's EOF.
+	<-ctx.Done()
+}
+
+func (suite *ExecTestSuite) TestStreamExecInput_TerminalSizes() {
+	var input bytes.Buffer
+	enc := json.NewEncoder(&input)
+	suite.NoError(enc.Encode(apitypes.ExecInputPacket{Resize: &plugin.TerminalSize{Rows: 24, Cols: 80}}))
+	suite.NoError(enc.Encode(apitypes.ExecInputPacket{Data: []byte("ls\n")}))
+	suite.NoError(enc.Encode(apitypes.ExecInputPacket{Resize: &plugin.TerminalSize{Rows: 50, Cols: 120}}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stdinReader, stdin := io.Pipe()
+	terminalSizes := make(chan plugin.TerminalSize, 1)
+	go streamExecInput(ctx, cancel, &input, stdin, terminalSizes)
+
+	data, err := ioutil.ReadAll(stdinReader)
+	suite.NoError(err)
+	suite.Equal("ls\n", string(data))
+
+	// Only the latest size is kept if the earlier sizes weren't received, and
+	// terminalSizes is closed once the input's finished.
+	<-ctx.Done()
+	var sizes []plugin.TerminalSize
+	for size := range terminalSizes {
+		sizes = append(sizes, size)
+	}
+	suite.Equal([]plugin.TerminalSize{{Rows: 50, Cols: 120}}, sizes)
+}
+
+func (suite *ExecTestSuite) TestStreamExecInput_InvalidPacket() {
+	ctx, cancel := context.WithCancel(context.Background())
+	stdinReader, stdin := io.Pipe()
+	go streamExecInput(ctx, cancel, strings.NewReader("not a packet"), stdin, nil)
+
+	_, err := ioutil.ReadAll(stdinReader)
+	suite.Error(err)
+	<-ctx.Done()
+}
+
+func (suite *ExecTestSuite) TestUpgradeExecConnection() {
+	// This handler echoes each input packet's data as a stdout packet.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, input, err := upgradeExecConnection(w, r)
+		if !suite.NoError(err) {
+			return
+		}
+		defer conn.Close()
+		dec, enc := json.NewDecoder(input), json.NewEncoder(conn)
+		for {
+			var packet apitypes.ExecInputPacket
+			if err := dec.Decode(&packet); err != nil || packet.EOF {
+				return
+			}
+			suite.NoError(enc.Encode(apitypes.ExecPacket{TypeField: apitypes.Stdout, Data: string(packet.Data)}))
+		}
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("{}"))
+	if !suite.NoError(err) {
+		return
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", apitypes.ExecUpgradeProtocol)
+	resp, err := http.DefaultClient.Do(req)
+	if !suite.NoError(err) {
+		return
+	}
+	suite.Equal(http.StatusSwitchingProtocols, resp.StatusCode)
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !suite.True(ok) {
+		return
+	}
+	defer conn.Close()
+
+	dec, enc := json.NewDecoder(conn), json.NewEncoder(conn)
+	for _, data := range []string{"foo", "bar"} {
+		suite.NoError(enc.Encode(apitypes.ExecInputPacket{Data: []byte(data)}))
+		var packet apitypes.ExecPacket
+		if suite.NoError(dec.Decode(&packet)) {
+			suite.Equal(data, packet.Data)
+		}
+	}
+	suite.NoError(enc.Encode(apitypes.ExecInputPacket{EOF: true}))
+	var packet apitypes.ExecPacket
+	suite.Equal(io.EOF, dec.Decode(&packet))
+}
+
+func TestExec(t *testing.T) {
+	suite.Run(t, new(ExecTestSuite))
+}
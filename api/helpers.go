@@ -44,6 +44,11 @@ func getPathFromRequest(r *http.Request) (string, *errorResponse) {
 // Common subset used by getEntryFromRequest and getWashPathFromRequest.
 // getEntryFromRequest needs both the path and the wash path.
 func toWashPath(ctx context.Context, path string) (string, *errorResponse) {
+	if isRemoteRequest(ctx) {
+		// A remote request's path is already relative to the mountpoint. Thus,
+		// it's always a Wash path so remote clients can't reach the local files.
+		return path, nil
+	}
 	mountpoint := ctx.Value(mountpointKey).(string)
 	trimmedPath := strings.TrimPrefix(path, mountpoint)
 	if trimmedPath == path {
@@ -68,21 +73,19 @@ func getEntryFromRequest(r *http.Request) (plugin.Entry, string, *errorResponse)
 	if errResp != nil {
 		return nil, "", errResp
 	}
+	return getEntryFromPath(r.Context(), path)
+}
 
-	ctx := r.Context()
+// getEntryFromPath returns the entry located at the given absolute path.
+func getEntryFromPath(ctx context.Context, path string) (plugin.Entry, string, *errorResponse) {
 	trimmedPath, errResp := toWashPath(ctx, path)
 	if errResp != nil {
 		if errResp.body.Kind != apitypes.NonWashPath {
 			panic("Unexpected error from getWashPathFromFullPath")
 		}
 
-		// Local file/directory, so convert it to a Wash entry
-		//
-		// TODO: The code here means that the Wash server cannot be
-		// mounted on a remote machine. This is not an immediate issue,
-		// but it does mean that we'll need to re-evaluate this code once
-		// we get to the point where supporting remote Wash servers is
-		// desirable.
+		// Local file/directory, so convert it to a Wash entry. Note that
+		// toWashPath never returns a non-Wash path for a remote request.
 		e, err := apifs.NewEntry(ctx, path)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -120,6 +120,37 @@ func (suite *HelpersTestSuite) TestGetEntryFromPath() {
 	plug.AssertExpectations(suite.T())
 }
 
+func (suite *HelpersTestSuite) TestGetEntryFromPathForRemoteRequest() {
+	reg := plugin.NewRegistry()
+	plug := &mockRoot{EntryBase: plugin.NewEntry("mine")}
+	plug.SetTestID("/mine")
+	suite.NoError(reg.RegisterPlugin(plug, map[string]interface{}{}))
+	ctx := context.WithValue(context.Background(), pluginRegistryKey, reg)
+	ctx = context.WithValue(ctx, mountpointKey, "/mountpoint")
+	ctx = context.WithValue(ctx, remoteRequestKey, true)
+
+	// A remote request's paths are relative to the mountpoint
+	entry, path, err := getEntryFromRequest(getRequest(ctx, "/"))
+	if suite.Nil(err) {
+		suite.Equal("/", path)
+		suite.Equal(reg.Name(), plugin.Name(entry))
+	}
+
+	entry, path, err = getEntryFromRequest(getRequest(ctx, "/mine"))
+	if suite.Nil(err) {
+		suite.Equal("/mine", path)
+		suite.Equal(plug.Name(), plugin.Name(entry))
+	}
+
+	// so local files are never found
+	for _, path := range []string{"/tmp", "/mountpoint/mine", "/../tmp"} {
+		_, _, err = getEntryFromRequest(getRequest(ctx, path))
+		if suite.NotNil(err, path) {
+			suite.Equal(http.StatusNotFound, err.statusCode, path)
+		}
+	}
+}
+
 func (suite *HelpersTestSuite) TestGetBoolParam() {
 	var u url.URL
 	for query, expect := range map[string]bool{"": false, "param=true": true, "param=false": false} {
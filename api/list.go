@@ -18,6 +18,15 @@ type entryList struct {
 	Entries []apitypes.Entry
 }
 
+// swagger:parameters listEntries
+//nolint:deadcode,unused
+type listParams struct {
+	// stream the children as newline-delimited JSON when true
+	//
+	// in: query
+	Stream bool
+}
+
 // swagger:route GET /fs/list list listEntries
 //
 // Lists children of a path
@@ -25,8 +34,13 @@ type entryList struct {
 // Returns a list of Entry objects describing children of the given path.
 // The "metadata" key is set to the partial metadata.
 //
+// If stream is true, then the children are streamed as newline-delimited
+// JSON as soon as they're listed. If an error occurs after streaming has
+// started, then the last line is an error object.
+//
 //     Produces:
 //     - application/json
+//     - application/x-ndjson
 //
 //     Schemes: http
 //
@@ -46,16 +60,64 @@ var listHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *erro
 		return unsupportedActionResponse(path, plugin.ListAction())
 	}
 
+	stream, errResp := getBoolParam(r.URL, "stream")
+	if errResp != nil {
+		return errResp
+	}
+
 	parent := entry.(plugin.Parent)
+	if stream {
+		return streamEntries(w, r, parent, path)
+	}
+
 	entries, err := plugin.ListWithAnalytics(ctx, parent)
 	if err != nil {
-		if cnameErr, ok := err.(plugin.DuplicateCNameErr); ok {
-			return duplicateCNameResponse(cnameErr)
-		}
+		return listErrorResponse(path, err)
+	}
+
+	result := toAPIEntries(entries, path)
+	activity.Record(ctx, "API: List %v %v items", path, len(result))
+
+	jsonEncoder := json.NewEncoder(w)
+	if err = jsonEncoder.Encode(result); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal list results for %v: %v", path, err))
+	}
+	return nil
+}}
+
+// streamEntries writes the parent's children as newline-delimited JSON. Each
+// page of children is flushed as soon as it's listed.
+func streamEntries(w http.ResponseWriter, r *http.Request, parent plugin.Parent, path string) *errorResponse {
+	ctx := r.Context()
+	fw, ok := w.(flushableWriter)
+	if !ok {
+		return unknownErrorResponse(fmt.Errorf("Cannot stream %v, response handler does not support flushing", path))
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
 
-		return erroredActionResponse(path, plugin.ListAction(), err.Error())
+	jsonEncoder := json.NewEncoder(fw)
+	count := 0
+	err := plugin.ListPagesWithAnalytics(ctx, parent, func(page *plugin.EntryMap) error {
+		for _, apiEntry := range toAPIEntries(page, path) {
+			if err := jsonEncoder.Encode(apiEntry); err != nil {
+				return fmt.Errorf("could not marshal %v: %v", apiEntry.Path, err)
+			}
+		}
+		fw.Flush()
+		count += page.Len()
+		return nil
+	})
+	if err != nil {
+		return listErrorResponse(path, err)
 	}
 
+	activity.Record(ctx, "API: Streamed list %v %v items", path, count)
+	return nil
+}
+
+// toAPIEntries converts the listed entries to API entries, sorted by name so
+// that they have a deterministic order.
+func toAPIEntries(entries *plugin.EntryMap, path string) []apitypes.Entry {
 	result := make([]apitypes.Entry, 0, entries.Len())
 	entries.Range(func(_ string, entry plugin.Entry) bool {
 		apiEntry := apitypes.NewEntry(entry)
@@ -63,13 +125,13 @@ var listHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *erro
 		result = append(result, apiEntry)
 		return true
 	})
-	// Sort entries so they have a deterministic order.
 	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
-	activity.Record(ctx, "API: List %v %v items", path, len(result))
+	return result
+}
 
-	jsonEncoder := json.NewEncoder(w)
-	if err = jsonEncoder.Encode(result); err != nil {
-		return unknownErrorResponse(fmt.Errorf("Could not marshal list results for %v: %v", path, err))
+func listErrorResponse(path string, err error) *errorResponse {
+	if cnameErr, ok := err.(plugin.DuplicateCNameErr); ok {
+		return duplicateCNameResponse(cnameErr)
 	}
-	return nil
-}}
+	return erroredActionResponse(path, plugin.ListAction(), err.Error())
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// swagger:parameters reloadPlugins
+//nolint:deadcode,unused
+type reloadPluginsParams struct {
+	// the name of the plugin to reload. Defaults to all of the plugins.
+	//
+	// in: query
+	Plugin string
+}
+
+// swagger:response
+//nolint:deadcode,unused
+type reloadPluginsResponse struct {
+	// in: body
+	Results []apitypes.PluginReloadResult
+}
+
+// swagger:route POST /plugins/reload plugins reloadPlugins
+//
+// Reload plugins
+//
+// Re-reads Wash's config, then re-initializes the specified plugin and clears
+// its cached results. If no plugin is specified, then all of the plugins are
+// reloaded. Newly configured plugins are registered, and plugins that are no
+// longer configured are unregistered. A plugin that fails to initialize is
+// reported in its result.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: reloadPluginsResponse
+//       404: errorResp
+//       500: errorResp
+var reloadPluginsHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	name := r.URL.Query().Get("plugin")
+	registry := r.Context().Value(pluginRegistryKey).(*plugin.Registry)
+
+	results, err := registry.ReloadPlugins(name)
+	if err != nil {
+		var unknownPluginErr plugin.UnknownPluginErr
+		if errors.As(err, &unknownPluginErr) {
+			return pluginDoesNotExistResponse(unknownPluginErr.Name)
+		}
+		return unknownErrorResponse(err)
+	}
+	activity.Record(r.Context(), "API: Reload plugins %v: %+v", name, results)
+
+	jsonEncoder := json.NewEncoder(w)
+	if err := jsonEncoder.Encode(results); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal the plugin reload results: %v", err))
+	}
+	return nil
+}}
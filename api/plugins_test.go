@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type PluginsHandlerTestSuite struct {
+	suite.Suite
+	router *mux.Router
+}
+
+func (suite *PluginsHandlerTestSuite) SetupSuite() {
+	plugin.SetTestCache(newMockCache())
+	suite.router = mux.NewRouter()
+	suite.router.Handle("/plugins/reload", reloadPluginsHandler).Methods(http.MethodPost)
+}
+
+func (suite *PluginsHandlerTestSuite) TearDownSuite() {
+	plugin.UnsetTestCache()
+}
+
+func (suite *PluginsHandlerTestSuite) newRegistry(loaderErr error) *plugin.Registry {
+	reg := plugin.NewRegistry()
+	suite.NoError(reg.RegisterPlugin(&mockRoot{EntryBase: plugin.NewEntry("mine")}, nil))
+	suite.NoError(reg.RegisterPlugin(&mockRoot{EntryBase: plugin.NewEntry("other")}, nil))
+	reg.SetPluginLoader(func() (map[string]plugin.Root, map[string]map[string]interface{}, error) {
+		if loaderErr != nil {
+			return nil, nil, loaderErr
+		}
+		roots := map[string]plugin.Root{
+			"mine":  &mockRoot{EntryBase: plugin.NewEntry("mine")},
+			"other": &mockRoot{EntryBase: plugin.NewEntry("other")},
+		}
+		return roots, nil, nil
+	})
+	return reg
+}
+
+func (suite *PluginsHandlerTestSuite) reload(reg *plugin.Registry, url string) *httptest.ResponseRecorder {
+	ctx := context.WithValue(context.Background(), pluginRegistryKey, reg)
+	req := httptest.NewRequest(http.MethodPost, url, nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *PluginsHandlerTestSuite) TestReloadPlugin() {
+	reg := suite.newRegistry(nil)
+	other := reg.Plugins()["other"]
+
+	w := suite.reload(reg, "http://example.com/plugins/reload?plugin=mine")
+	suite.Equal(http.StatusOK, w.Code)
+	var results []apitypes.PluginReloadResult
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &results))
+	suite.Equal([]apitypes.PluginReloadResult{{Name: "mine"}}, results)
+	suite.Same(other, reg.Plugins()["other"])
+}
+
+func (suite *PluginsHandlerTestSuite) TestReloadAllPlugins() {
+	reg := suite.newRegistry(nil)
+
+	w := suite.reload(reg, "http://example.com/plugins/reload")
+	suite.Equal(http.StatusOK, w.Code)
+	var results []apitypes.PluginReloadResult
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &results))
+	suite.Equal([]apitypes.PluginReloadResult{{Name: "mine"}, {Name: "other"}}, results)
+}
+
+func (suite *PluginsHandlerTestSuite) TestReloadPluginErrors() {
+	reg := suite.newRegistry(nil)
+	w := suite.reload(reg, "http://example.com/plugins/reload?plugin=unknown")
+	suite.Equal(http.StatusNotFound, w.Code)
+	var errResp apitypes.ErrorObj
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &errResp))
+	suite.Equal(apitypes.PluginDoesNotExist, errResp.Kind)
+
+	reg = suite.newRegistry(errors.New("invalid config"))
+	w = suite.reload(reg, "http://example.com/plugins/reload")
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &errResp))
+	suite.Equal(apitypes.UnknownError, errResp.Kind)
+	suite.Contains(errResp.Msg, "invalid config")
+}
+
+func (suite *PluginsHandlerTestSuite) TestRejectsGet() {
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/plugins/reload", nil)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	suite.Equal(http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestPluginsHandler(t *testing.T) {
+	suite.Run(t, new(PluginsHandlerTestSuite))
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RemoteOptions configures the API's optional TCP listener. Requests to the
+// TCP listener are served over TLS and must include the token as a bearer
+// token in their Authorization header. The UNIX socket is unaffected; it
+// relies on its file permissions instead.
+type RemoteOptions struct {
+	// Address is the TCP address to listen on, e.g. ":8443". An empty Address
+	// disables the TCP listener.
+	Address string
+	// CertFile and KeyFile are the PEM-encoded TLS certificate and private key.
+	// If neither file exists, then a self-signed certificate is generated and
+	// saved to them so that clients can be configured to trust it.
+	CertFile string
+	KeyFile  string
+	// Token is the bearer token that clients must present. It's required.
+	Token string
+}
+
+func (opts RemoteOptions) enabled() bool {
+	return opts.Address != ""
+}
+
+func (opts RemoteOptions) listen() (net.Listener, error) {
+	if opts.Token == "" {
+		return nil, fmt.Errorf("a token is required to listen at %v", opts.Address)
+	}
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, fmt.Errorf("a certificate and key file are required to listen at %v", opts.Address)
+	}
+	cert, err := loadOrGenerateCertificate(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", opts.Address, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	})
+}
+
+// tokenAuthMiddleWare rejects requests that don't include token as a bearer
+// token.
+func tokenAuthMiddleWare(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "Bearer "
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, prefix) ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, prefix)), []byte(token)) != 1 {
+			log.Infof("API: Rejected unauthorized request %v %v from %v", r.Method, r.URL, r.RemoteAddr)
+			errResp := unauthorizedResponse("a valid bearer token is required")
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.WriteHeader(errResp.statusCode)
+			if _, err := fmt.Fprintln(w, errResp.Error()); err != nil {
+				log.Warnf("API: Failed writing error response: %v", err)
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// remoteRequestMiddleWare marks requests as remote requests. A remote request's
+// paths are relative to the mountpoint so that it can't access the server's
+// local files.
+func remoteRequestMiddleWare(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), remoteRequestKey, true)))
+	})
+}
+
+func isRemoteRequest(ctx context.Context) bool {
+	remote, _ := ctx.Value(remoteRequestKey).(bool)
+	return remote
+}
+
+// loadOrGenerateCertificate loads the TLS certificate from certFile and keyFile.
+// If neither exists, then it generates a self-signed certificate and saves it
+// to them.
+func loadOrGenerateCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	_, certErr := os.Stat(certFile)
+	_, keyErr := os.Stat(keyFile)
+	if os.IsNotExist(certErr) && os.IsNotExist(keyErr) {
+		log.Infof("API: Generating a self-signed certificate at %v", certFile)
+		if err := generateCertificate(certFile, keyFile); err != nil {
+			return tls.Certificate{}, fmt.Errorf("could not generate a self-signed certificate: %v", err)
+		}
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("could not load the TLS certificate: %v", err)
+	}
+	return cert, nil
+}
+
+func generateCertificate(certFile, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	hosts := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "localhost" {
+		hosts = append(hosts, hostname)
+	}
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Wash"}, CommonName: hosts[len(hosts)-1]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		// Mark it as a CA so that clients can trust it directly.
+		IsCA:        true,
+		DNSNames:    hosts,
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := writePEM(keyFile, "EC PRIVATE KEY", keyDER, 0600); err != nil {
+		return err
+	}
+	return writePEM(certFile, "CERTIFICATE", certDER, 0644)
+}
+
+func writePEM(path string, blockType string, der []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if err := pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/stretchr/testify/suite"
+)
+
+type RemoteTestSuite struct {
+	suite.Suite
+}
+
+func (suite *RemoteTestSuite) serve(header string) *httptest.ResponseRecorder {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/fs/list", nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	w := httptest.NewRecorder()
+	tokenAuthMiddleWare("secret", next).ServeHTTP(w, req)
+	return w
+}
+
+func (suite *RemoteTestSuite) TestTokenAuthMiddleWareAcceptsValidToken() {
+	suite.Equal(http.StatusOK, suite.serve("Bearer secret").Code)
+}
+
+func (suite *RemoteTestSuite) TestTokenAuthMiddleWareRejectsInvalidTokens() {
+	for _, header := range []string{"", "secret", "Bearer", "Bearer wrong", "Basic secret"} {
+		w := suite.serve(header)
+		suite.Equal(http.StatusUnauthorized, w.Code, header)
+		suite.Contains(w.Body.String(), apitypes.Unauthorized)
+	}
+}
+
+func (suite *RemoteTestSuite) TestLoadOrGenerateCertificate() {
+	dir, err := ioutil.TempDir("", "wash-api-tls")
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := filepath.Join(dir, "tls", "server.crt"), filepath.Join(dir, "tls", "server.key")
+
+	// The certificate's generated if it doesn't exist
+	cert, err := loadOrGenerateCertificate(certFile, keyFile)
+	if suite.NoError(err) {
+		suite.NotEmpty(cert.Certificate)
+	}
+	info, err := os.Stat(keyFile)
+	if suite.NoError(err) {
+		suite.Equal(os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// Then it's loaded on subsequent calls
+	loadedCert, err := loadOrGenerateCertificate(certFile, keyFile)
+	if suite.NoError(err) {
+		suite.Equal(cert.Certificate, loadedCert.Certificate)
+	}
+
+	// A missing key isn't regenerated
+	suite.NoError(os.Remove(keyFile))
+	_, err = loadOrGenerateCertificate(certFile, keyFile)
+	suite.Error(err)
+}
+
+func (suite *RemoteTestSuite) TestListenRequiresToken() {
+	_, err := RemoteOptions{Address: "127.0.0.1:0", CertFile: "crt", KeyFile: "key"}.listen()
+	suite.Regexp("token is required", err)
+}
+
+func TestRemote(t *testing.T) {
+	suite.Run(t, new(RemoteTestSuite))
+}
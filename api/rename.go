@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/puppetlabs/wash/activity"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// swagger:parameters renameEntry
+//nolint:deadcode,unused
+type renameBody struct {
+	params
+	// in: body
+	Body apitypes.RenameBody
+}
+
+// swagger:route POST /fs/rename rename renameEntry
+//
+// Renames (or moves) the entry at the specified path.
+//
+// On success, returns an Entry object describing the renamed entry.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http
+//
+//     Responses:
+//       200: Entry
+//       400: errorResp
+//       403: errorResp
+//       404: errorResp
+//       500: errorResp
+var renameHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
+	ctx := r.Context()
+	entry, path, errResp := getEntryFromRequest(r)
+	if errResp != nil {
+		return errResp
+	}
+
+	if !plugin.RenameAction().IsSupportedOn(entry) {
+		return unsupportedActionResponse(path, plugin.RenameAction())
+	}
+
+	if r.Body == nil {
+		return badActionRequestResponse(path, plugin.RenameAction(), "Please send a JSON request body")
+	}
+
+	var body apitypes.RenameBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return badActionRequestResponse(path, plugin.RenameAction(), err.Error())
+	}
+	if !filepath.IsAbs(body.NewPath) {
+		return badActionRequestResponse(path, plugin.RenameAction(), fmt.Sprintf("the new path %v must be absolute", body.NewPath))
+	}
+
+	newPath := filepath.Clean(body.NewPath)
+	newParentPath, newName := filepath.Split(newPath)
+	newParent, _, errResp := getEntryFromPath(ctx, filepath.Clean(newParentPath))
+	if errResp != nil {
+		return errResp
+	}
+	if !plugin.ListAction().IsSupportedOn(newParent) {
+		return badActionRequestResponse(path, plugin.RenameAction(), fmt.Sprintf("%v is not a directory", newParentPath))
+	}
+
+	renamed, err := plugin.RenameWithAnalytics(ctx, entry.(plugin.Renamable), newParent.(plugin.Parent), newName)
+	if err != nil {
+		if plugin.IsPolicyDeniedErr(err) {
+			return actionDeniedResponse(path, plugin.RenameAction(), err)
+		}
+		if plugin.IsInvalidInputErr(err) {
+			return badActionRequestResponse(path, plugin.RenameAction(), err.Error())
+		}
+		return erroredActionResponse(path, plugin.RenameAction(), err.Error())
+	}
+
+	apiEntry := apitypes.NewEntry(renamed)
+	apiEntry.Path = newPath
+	activity.Record(ctx, "API: Rename %v %v", path, newPath)
+
+	jsonEncoder := json.NewEncoder(w)
+	if err = jsonEncoder.Encode(&apiEntry); err != nil {
+		return unknownErrorResponse(fmt.Errorf("Could not marshal the renamed entry for %v: %v", path, err))
+	}
+	return nil
+}}
@@ -67,12 +67,12 @@ func (w *walkerImpl) walk(ctx context.Context, e *Entry, depth int) ([]Entry, er
 
 	childDepth := depth + 1
 	if int(childDepth) <= w.opts.Maxdepth && e.Supports(plugin.ListAction()) {
-		childrenMap, err := plugin.List(ctx, e.pluginEntry.(plugin.Parent))
-		if err != nil {
-			return nil, fmt.Errorf("could not get children of %v: %w\n", e.Path, err)
-		} else {
+		// Walk each page of children as soon as it's listed. This way, we can start
+		// visiting a large parent's children before its listing is finished.
+		var walkErr error
+		err := plugin.ListPages(ctx, e.pluginEntry.(plugin.Parent), func(page *plugin.EntryMap) error {
 			children := []Entry{}
-			childrenMap.Range(func(cname string, childPluginEntry plugin.Entry) bool {
+			page.Range(func(cname string, childPluginEntry plugin.Entry) bool {
 				child := newEntry(e, childPluginEntry)
 				if e.SchemaKnown() {
 					childSchema := e.Schema.GetChild(child.TypeID)
@@ -94,10 +94,17 @@ func (w *walkerImpl) walk(ctx context.Context, e *Entry, depth int) ([]Entry, er
 			for _, child := range children {
 				descendants, err := w.walk(ctx, &child, childDepth)
 				if err != nil {
-					return nil, err
+					walkErr = err
+					return err
 				}
 				entries = append(entries, descendants...)
 			}
+			return nil
+		})
+		if walkErr != nil {
+			return nil, walkErr
+		} else if err != nil {
+			return nil, fmt.Errorf("could not get children of %v: %w\n", e.Path, err)
 		}
 	}
 
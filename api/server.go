@@ -23,9 +23,10 @@ type key int
 const (
 	pluginRegistryKey key = iota
 	mountpointKey
+	remoteRequestKey
 )
 
-// swagger:parameters cacheDelete listEntries entryInfo getMetadata readContent streamUpdates deleteEntry signalEntry entrySchema
+// swagger:parameters cacheDelete cacheStats listEntries entryInfo getMetadata readContent streamUpdates deleteEntry signalEntry createEntry renameEntry entrySchema
 //nolint:deadcode,unused
 type params struct {
 	// uniquely identifies an entry
@@ -80,10 +81,14 @@ func (handle handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 //   2. A read-only channel that signals whether the server was shutdown.
 //
 //   3. An error object
+//
+// The API is always served at socketPath. If remote is enabled, then it's also
+// served over TLS at remote's TCP address.
 func StartAPI(
 	registry *plugin.Registry,
 	mountpoint string,
 	socketPath string,
+	remote RemoteOptions,
 	analyticsClient analytics.Client,
 ) (chan<- context.Context, <-chan struct{}, error) {
 	log.Infof("API: Listening at %s", socketPath)
@@ -106,6 +111,16 @@ func StartAPI(
 		return nil, nil, err
 	}
 
+	var remoteServer net.Listener
+	if remote.enabled() {
+		log.Infof("API: Listening at %s", remote.Address)
+		remoteServer, err = remote.listen()
+		if err != nil {
+			server.Close()
+			return nil, nil, err
+		}
+	}
+
 	prepareContextMiddleWare := func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			newctx := context.WithValue(r.Context(), pluginRegistryKey, registry)
@@ -134,7 +149,12 @@ func StartAPI(
 	r.Handle("/fs/schema", schemaHandler).Methods(http.MethodGet)
 	r.Handle("/fs/delete", deleteHandler).Methods(http.MethodDelete)
 	r.Handle("/fs/signal", signalHandler).Methods(http.MethodPost)
+	r.Handle("/fs/create", createHandler).Methods(http.MethodPost)
+	r.Handle("/fs/rename", renameHandler).Methods(http.MethodPost)
 	r.Handle("/cache", cacheHandler).Methods(http.MethodDelete)
+	r.Handle("/cache", cacheStatsHandler).Methods(http.MethodGet)
+	r.Handle("/plugins/reload", reloadPluginsHandler).Methods(http.MethodPost)
+	r.Handle("/audit", auditHandler).Methods(http.MethodGet)
 	r.Handle("/history", historyHandler).Methods(http.MethodGet)
 	r.Handle("/history/{index:[0-9]+}", historyEntryHandler).Methods(http.MethodGet)
 
@@ -153,6 +173,19 @@ func StartAPI(
 		log.Infof("API: Server was shut down")
 	}()
 
+	// Start the remote server. Its requests must be authenticated.
+	remoteHTTPServer := http.Server{Handler: tokenAuthMiddleWare(remote.Token, remoteRequestMiddleWare(r))}
+	if remoteServer != nil {
+		go func() {
+			err := remoteHTTPServer.Serve(remoteServer)
+			if err != nil && err != http.ErrServerClosed {
+				log.Warnf("API: %v", err)
+			}
+
+			log.Infof("API: Remote server was shut down")
+		}()
+	}
+
 	stopCh := make(chan context.Context)
 	go func() {
 		ctx := <-stopCh
@@ -162,6 +195,11 @@ func StartAPI(
 		if err != nil {
 			log.Warnf("API: Shutdown failed: %v", err)
 		}
+		if remoteServer != nil {
+			if err := remoteHTTPServer.Shutdown(ctx); err != nil {
+				log.Warnf("API: Remote server shutdown failed: %v", err)
+			}
+		}
 		close(serverStoppedCh)
 	}()
 
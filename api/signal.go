@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/puppetlabs/wash/activity"
@@ -11,7 +12,8 @@ import (
 
 // swagger:route POST /fs/signal signal signalEntry
 //
-// Sends a signal to the entry at the specified path.
+// Sends a signal to the entry at the specified path. If dry_run is true, then the signal
+// isn't sent. Instead, returns a list of the steps that sending it would take.
 //
 //     Consumes:
 //     - application/json
@@ -24,6 +26,7 @@ import (
 //     Responses:
 //       200:
 //       400: errorResp
+//       403: errorResp
 //       404: errorResp
 //       500: errorResp
 var signalHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *errorResponse {
@@ -46,13 +49,33 @@ var signalHandler = handler{fn: func(w http.ResponseWriter, r *http.Request) *er
 		return badActionRequestResponse(path, plugin.SignalAction(), err.Error())
 	}
 
+	dryRun, errResp := getBoolParam(r.URL, "dry_run")
+	if errResp != nil {
+		return errResp
+	}
+	if dryRun {
+		ctx = plugin.WithDryRun(ctx)
+	}
+
 	if err := plugin.SignalWithAnalytics(ctx, entry.(plugin.Signalable), body.Signal); err != nil {
+		if plugin.IsPolicyDeniedErr(err) {
+			return actionDeniedResponse(path, plugin.SignalAction(), err)
+		}
 		if plugin.IsInvalidInputErr(err) {
 			return badActionRequestResponse(path, plugin.SignalAction(), err.Error())
 		}
 		return erroredActionResponse(path, plugin.SignalAction(), err.Error())
 	}
 
+	if dryRun {
+		plan := plugin.DryRunPlan(ctx)
+		activity.Record(ctx, "API: Signal %v %v planned %v", path, body.Signal, plan)
+		if err := json.NewEncoder(w).Encode(plan); err != nil {
+			return unknownErrorResponse(fmt.Errorf("Could not marshal signal's plan for %v: %v", path, err))
+		}
+		return nil
+	}
+
 	activity.Record(ctx, "API: Signal %v %v", path, body.Signal)
 	return nil
 }}
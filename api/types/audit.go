@@ -0,0 +1,9 @@
+package apitypes
+
+import "github.com/puppetlabs/wash/audit"
+
+// AuditRecord describes a mutating action that was invoked on an entry
+type AuditRecord = audit.Record
+
+// AuditFilter selects audit records
+type AuditFilter = audit.Filter
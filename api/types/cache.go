@@ -0,0 +1,15 @@
+package apitypes
+
+import "github.com/puppetlabs/wash/plugin"
+
+// CacheStats describes the cache's contents
+type CacheStats = plugin.CacheStats
+
+// CacheOpStats describes an op's cached results
+type CacheOpStats = plugin.CacheOpStats
+
+// CachePluginStats describes a plugin's cached results
+type CachePluginStats = plugin.CachePluginStats
+
+// CachedResult describes an op's cached result
+type CachedResult = plugin.CachedResult
@@ -0,0 +1,9 @@
+package apitypes
+
+// CreateBody encapsulates the payload for a call to a plugin's Create function
+type CreateBody struct {
+	// Name of the entry that's to be created
+	Name string `json:"name"`
+	// IsParent is true if the created entry should be a parent (directory)
+	IsParent bool `json:"is_parent"`
+}
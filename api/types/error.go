@@ -43,4 +43,6 @@ const (
 	NonWashPath        = "puppetlabs.wash/non-wash-path"
 	InvalidBool        = "puppetlabs.wash/invalid-bool"
 	InvalidInt         = "puppetlabs.wash/invalid-int"
+	Unauthorized       = "puppetlabs.wash/unauthorized"
+	ActionDenied       = "puppetlabs.wash/action-denied"
 )
@@ -7,11 +7,32 @@ import (
 )
 
 // ExecOptions are options that can be passed as part of an Exec call.
-// These are not identical to plugin.ExecOptions because initially the API only
-// supports receiving a string of input, not a reader.
+// These are not identical to plugin.ExecOptions because the API receives
+// input as a string or as a stream of ExecInputPackets, not a reader.
 type ExecOptions struct {
 	// Input to pass on stdin when executing the command
 	Input string `json:"input"`
+	// Stream stdin to the command. If true, then the request must include the
+	// "Connection: Upgrade" and "Upgrade: wash-exec" headers. The server responds
+	// with 101 Switching Protocols, after which the client sends ExecInputPackets
+	// while the server sends ExecPackets over the same connection.
+	Stdin bool `json:"stdin,omitempty"`
+	// Allocate a TTY for the command. Use it with Stdin for an interactive session,
+	// in which case the client sends its terminal's size in ExecInputPackets.
+	Tty bool `json:"tty,omitempty"`
+	// TerminalSizes is used by clients to send their terminal's size. The client
+	// sends each received size in an ExecInputPacket. It's only relevant if both
+	// Stdin and Tty are set.
+	TerminalSizes <-chan plugin.TerminalSize `json:"-"`
+	// Environment variables to set when executing the command
+	Env map[string]string `json:"env,omitempty"`
+	// Working directory to execute the command in
+	Dir string `json:"dir,omitempty"`
+	// User to execute the command as
+	User string `json:"user,omitempty"`
+	// How long the command's allowed to run (in nanoseconds). If it's zero, then the
+	// command can run indefinitely.
+	Timeout time.Duration `json:"timeout,omitempty"`
 }
 
 // ExecBody encapsulates the payload for a call to a plugin's Exec function
@@ -24,6 +45,23 @@ type ExecBody struct {
 	Opts ExecOptions `json:"opts"`
 }
 
+// ExecUpgradeProtocol is the protocol that an exec's connection is upgraded to
+// when its stdin is streamed.
+const ExecUpgradeProtocol = "wash-exec"
+
+// ExecInputPacket is a single packet of input for an exec's stdin. Once all
+// of the input's sent, the client sends a packet with EOF set to close the
+// command's stdin.
+//
+// If the command has a TTY, then the client also sends a packet with Resize
+// set whenever its terminal's resized, starting with the terminal's initial
+// size.
+type ExecInputPacket struct {
+	Data   []byte               `json:"data,omitempty"`
+	EOF    bool                 `json:"eof,omitempty"`
+	Resize *plugin.TerminalSize `json:"resize,omitempty"`
+}
+
 // Enumerates packet types used by the API.
 const (
 	Stdout   plugin.ExecPacketType = plugin.Stdout
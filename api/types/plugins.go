@@ -0,0 +1,6 @@
+package apitypes
+
+import "github.com/puppetlabs/wash/plugin"
+
+// PluginReloadResult describes the outcome of reloading a plugin
+type PluginReloadResult = plugin.PluginReloadResult
@@ -0,0 +1,8 @@
+package apitypes
+
+// RenameBody encapsulates the payload for a call to a plugin's Rename function
+type RenameBody struct {
+	// NewPath is the entry's new absolute path. Its parent directory must
+	// already exist.
+	NewPath string `json:"new_path"`
+}
@@ -0,0 +1,179 @@
+// Package audit records Wash's mutating operations (like deleting or signaling
+// an entry) to an append-only log. Unlike the free-form activity journals, each
+// record is a single line of JSON so that the log can be queried to answer
+// questions like "who deleted which pod when".
+//
+// The plugin.<Method> wrappers record the operations, so plugins don't need to
+// do anything.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gobwas/glob"
+	log "github.com/sirupsen/logrus"
+)
+
+// Record describes a single mutating operation.
+type Record struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Journal is the ID of the activity journal for the command that invoked
+	// the operation, and Description is that command's description.
+	Journal     string `json:"journal"`
+	Description string `json:"description,omitempty"`
+	Action      string `json:"action"`
+	Path        string `json:"path"`
+	TypeID      string `json:"type_id"`
+	// Args are the action's arguments, e.g. the signal that was sent.
+	Args map[string]interface{} `json:"args,omitempty"`
+	// Result is the action's result, e.g. whether the entry was deleted.
+	Result   interface{} `json:"result,omitempty"`
+	ExitCode *int        `json:"exit_code,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	// DryRun is true if the action was only planned. Plan is what the action
+	// would've done.
+	DryRun bool     `json:"dry_run,omitempty"`
+	Plan   []string `json:"plan,omitempty"`
+}
+
+var logFile struct {
+	mux  sync.Mutex
+	path string
+	file *os.File
+}
+
+// Open opens the audit log at path, creating it if it doesn't exist. Records
+// are appended to it until Close is called.
+func Open(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open the audit log: %v", err)
+	}
+
+	logFile.mux.Lock()
+	defer logFile.mux.Unlock()
+	if logFile.file != nil {
+		logFile.file.Close()
+	}
+	logFile.path = path
+	logFile.file = f
+	return nil
+}
+
+// Close closes the audit log. Subsequent records are discarded.
+func Close() error {
+	logFile.mux.Lock()
+	defer logFile.mux.Unlock()
+	if logFile.file == nil {
+		return nil
+	}
+	err := logFile.file.Close()
+	logFile.file = nil
+	return err
+}
+
+// Log appends the record to the audit log. It's a no-op if the audit log
+// isn't open.
+func Log(r Record) {
+	line, err := json.Marshal(r)
+	if err != nil {
+		log.Warnf("Audit: could not marshal the %v record for %v: %v", r.Action, r.Path, err)
+		return
+	}
+	line = append(line, '\n')
+
+	logFile.mux.Lock()
+	defer logFile.mux.Unlock()
+	if logFile.file == nil {
+		return
+	}
+	if _, err := logFile.file.Write(line); err != nil {
+		log.Warnf("Audit: could not write the %v record for %v: %v", r.Action, r.Path, err)
+	}
+}
+
+// Filter selects audit records. Its zero value selects all of them.
+type Filter struct {
+	// Path is a glob that's matched against the record's path. A '*' does not
+	// match a '/' but a '**' does.
+	Path    string
+	Action  string
+	Journal string
+	// Since excludes records that started before it.
+	Since time.Time
+}
+
+// Query returns the records in the open audit log that match the filter, in the
+// order that they were logged.
+func Query(filter Filter) ([]Record, error) {
+	var pathGlob glob.Glob
+	if filter.Path != "" {
+		var err error
+		if pathGlob, err = glob.Compile(filter.Path, '/'); err != nil {
+			return nil, fmt.Errorf("invalid path %q: %v", filter.Path, err)
+		}
+	}
+
+	logFile.mux.Lock()
+	path := logFile.path
+	logFile.mux.Unlock()
+	if path == "" {
+		return nil, fmt.Errorf("the audit log is not enabled")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Record{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	records := []Record{}
+	scanner := bufio.NewScanner(f)
+	// Exec records include the command's arguments, so allow for long lines.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// A partially written line's possible if the server crashed, so skip
+			// it instead of failing the whole query.
+			log.Debugf("Audit: skipping line %v of %v: %v", lineNum, path, err)
+			continue
+		}
+		if filter.matches(r, pathGlob) {
+			records = append(records, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (filter Filter) matches(r Record, pathGlob glob.Glob) bool {
+	if pathGlob != nil && !pathGlob.Match(r.Path) {
+		return false
+	}
+	if filter.Action != "" && filter.Action != r.Action {
+		return false
+	}
+	if filter.Journal != "" && filter.Journal != r.Journal {
+		return false
+	}
+	if !filter.Since.IsZero() && r.Start.Before(filter.Since) {
+		return false
+	}
+	return true
+}
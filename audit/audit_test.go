@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditTestSuite struct {
+	suite.Suite
+	dir  string
+	path string
+}
+
+func (suite *AuditTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "wash-audit")
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.dir = dir
+	suite.path = filepath.Join(dir, "audit", "audit.log")
+	suite.NoError(Open(suite.path))
+}
+
+func (suite *AuditTestSuite) TearDownTest() {
+	suite.NoError(Close())
+	logFile.path = ""
+	suite.NoError(os.RemoveAll(suite.dir))
+}
+
+func (suite *AuditTestSuite) TestLogAndQuery() {
+	start := time.Now()
+	Log(Record{Start: start, Journal: "1", Action: "delete", Path: "/docker/containers/foo", Result: map[string]interface{}{"deleted": true}})
+	Log(Record{Start: start.Add(time.Second), Journal: "2", Action: "signal", Path: "/docker/containers/bar", Args: map[string]interface{}{"signal": "stop"}})
+	exitCode := 1
+	Log(Record{Start: start.Add(2 * time.Second), Journal: "2", Action: "exec", Path: "/aws/instances/baz", ExitCode: &exitCode})
+
+	records, err := Query(Filter{})
+	if suite.NoError(err) && suite.Len(records, 3) {
+		suite.Equal("delete", records[0].Action)
+		suite.Equal(map[string]interface{}{"deleted": true}, records[0].Result)
+		suite.Equal(map[string]interface{}{"signal": "stop"}, records[1].Args)
+		suite.Equal(1, *records[2].ExitCode)
+	}
+
+	queryPaths := func(filter Filter) []string {
+		records, err := Query(filter)
+		suite.NoError(err)
+		paths := []string{}
+		for _, r := range records {
+			paths = append(paths, r.Path)
+		}
+		return paths
+	}
+	suite.Equal([]string{"/docker/containers/foo", "/docker/containers/bar"}, queryPaths(Filter{Path: "/docker/**"}))
+	suite.Equal([]string{}, queryPaths(Filter{Path: "/docker/*"}))
+	suite.Equal([]string{"/docker/containers/bar"}, queryPaths(Filter{Action: "signal"}))
+	suite.Equal([]string{"/docker/containers/bar", "/aws/instances/baz"}, queryPaths(Filter{Journal: "2"}))
+	suite.Equal([]string{"/aws/instances/baz"}, queryPaths(Filter{Since: start.Add(2 * time.Second)}))
+
+	_, err = Query(Filter{Path: "/docker/["})
+	suite.Error(err)
+}
+
+func (suite *AuditTestSuite) TestLogAppends() {
+	Log(Record{Action: "delete", Path: "/foo"})
+	suite.NoError(Close())
+
+	// Records aren't logged while the audit log's closed
+	Log(Record{Action: "delete", Path: "/bar"})
+
+	suite.NoError(Open(suite.path))
+	Log(Record{Action: "delete", Path: "/baz"})
+
+	records, err := Query(Filter{})
+	if suite.NoError(err) && suite.Len(records, 2) {
+		suite.Equal("/foo", records[0].Path)
+		suite.Equal("/baz", records[1].Path)
+	}
+}
+
+func (suite *AuditTestSuite) TestQuerySkipsCorruptLines() {
+	Log(Record{Action: "delete", Path: "/foo"})
+	f, err := os.OpenFile(suite.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if suite.NoError(err) {
+		_, err = f.WriteString("{\"action\": \"del\n")
+		suite.NoError(err)
+		suite.NoError(f.Close())
+	}
+	Log(Record{Action: "delete", Path: "/bar"})
+
+	records, err := Query(Filter{})
+	if suite.NoError(err) {
+		suite.Len(records, 2)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/spf13/cobra"
+)
+
+func auditCommand() *cobra.Command {
+	use, aliases := generateShellAlias("audit")
+	auditCmd := &cobra.Command{
+		Use:     use + " [<path>]",
+		Aliases: aliases,
+		Short:   "Prints the audit log of mutating actions",
+		Long: `Wash audits the mutating actions (like delete, signal, write, and exec) that are invoked on entries.
+Print those audit records, optionally only the ones whose path matches <path>. <path> is a glob where
+'*' does not match a '/' but '**' does, e.g. 'wash audit "kubernetes/**"'.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: toRunE(auditMain),
+	}
+	auditCmd.Flags().StringP("action", "a", "", "Only print records of the specified action")
+	auditCmd.Flags().StringP("journal", "j", "", "Only print records of the specified journal ID")
+	auditCmd.Flags().DurationP("since", "s", 0, "Only print records from the specified duration ago, e.g. 1h")
+	auditCmd.Flags().StringP("output", "o", "table", "Set the output format (table or json)")
+	return auditCmd
+}
+
+func auditMain(cmd *cobra.Command, args []string) exitCode {
+	var filter apitypes.AuditFilter
+	var err error
+	if len(args) > 0 {
+		filter.Path = args[0]
+	}
+	if filter.Action, err = cmd.Flags().GetString("action"); err != nil {
+		panic(err.Error())
+	}
+	if filter.Journal, err = cmd.Flags().GetString("journal"); err != nil {
+		panic(err.Error())
+	}
+	since, err := cmd.Flags().GetDuration("since")
+	if err != nil {
+		panic(err.Error())
+	}
+	if since > 0 {
+		filter.Since = time.Now().Add(-since)
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		panic(err.Error())
+	}
+	if output != "table" && output != "json" {
+		cmdutil.ErrPrintf("output must be either table or json\n")
+		return exitCode{1}
+	}
+
+	conn := cmdutil.NewClient()
+	records, err := conn.Audit(filter)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	if output == "json" {
+		for _, record := range records {
+			line, err := json.Marshal(record)
+			if err != nil {
+				cmdutil.ErrPrintf("%v\n", err)
+				return exitCode{1}
+			}
+			cmdutil.Println(string(line))
+		}
+		return exitCode{0}
+	}
+
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, []string{
+			record.Start.Format("2006-01-02 15:04:05"),
+			record.Action,
+			record.Path,
+			formatAuditArgs(record.Args),
+			formatAuditResult(record),
+			record.Journal,
+		})
+	}
+	headers := []cmdutil.ColumnHeader{
+		{ShortName: "time", FullName: "TIME"},
+		{ShortName: "action", FullName: "ACTION"},
+		{ShortName: "path", FullName: "PATH"},
+		{ShortName: "args", FullName: "ARGS"},
+		{ShortName: "result", FullName: "RESULT"},
+		{ShortName: "journal", FullName: "JOURNAL"},
+	}
+	cmdutil.Print(cmdutil.NewTableWithHeaders(headers, rows).Format())
+	return exitCode{0}
+}
+
+func formatAuditArgs(args map[string]interface{}) string {
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", key, args[key]))
+	}
+	return strings.Join(pairs, " ")
+}
+
+func formatAuditResult(record apitypes.AuditRecord) string {
+	switch {
+	case record.Error != "":
+		return "error: " + record.Error
+	case record.ExitCode != nil:
+		return fmt.Sprintf("exit code %v", *record.ExitCode)
+	case record.Result != nil:
+		if result, ok := record.Result.(map[string]interface{}); ok {
+			return formatAuditArgs(result)
+		}
+		return fmt.Sprint(record.Result)
+	default:
+		return "ok"
+	}
+}
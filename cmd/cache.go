@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	apitypes "github.com/puppetlabs/wash/api/types"
+	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/spf13/cobra"
+)
+
+func cacheCommand() *cobra.Command {
+	use, aliases := generateShellAlias("cache")
+	cacheCmd := &cobra.Command{
+		Use:     use,
+		Aliases: aliases,
+		Short:   "Inspects or clears Wash's cache",
+		Long: `Wash caches most operations. Use this subcommand's stats and ls subcommands to see what's
+cached and how often it's used (e.g. to tune an entry's TTLs), or its clear subcommand to reset
+the cache.`,
+		Args: cobra.NoArgs,
+		RunE: toRunE(cacheMain),
+	}
+
+	statsCmd := &cobra.Command{
+		Use:   "stats [<path>]",
+		Short: "Prints statistics about the cache, or about the cached results at the specified path",
+		Long: `Prints each op's hits, misses, and cached results, and each plugin's cached results. If a path is
+specified, then only the cached results for that path and its children are included. Note that hits and
+misses are always counted across the entire cache.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: toRunE(cacheStatsMain),
+	}
+	addCommand(cacheCmd, statsCmd)
+
+	lsCmd := &cobra.Command{
+		Use:   "ls [<path>]",
+		Short: "Lists the cached results at the specified path, or current directory if not specified",
+		Long: `Lists the cached results of the specified path and its children, including each result's age,
+when it expires, and its approximate size. Defaults to the current directory if no path is provided.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: toRunE(cacheLsMain),
+	}
+	addCommand(cacheCmd, lsCmd)
+
+	clearCmd := &cobra.Command{
+		Use:   "clear [<path>]...",
+		Short: "Clears the cache at the specified paths, or current directory if not specified",
+		Long:  `Equivalent to 'wash clear'.`,
+		RunE:  toRunE(clearMain),
+	}
+	clearCmd.Flags().BoolP("verbose", "v", false, "Print paths that were cleared from the cache")
+	addCommand(cacheCmd, clearCmd)
+
+	return cacheCmd
+}
+
+func cacheMain(cmd *cobra.Command, args []string) exitCode {
+	if err := cmd.Help(); err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+	return exitCode{0}
+}
+
+func cacheStatsMain(cmd *cobra.Command, args []string) exitCode {
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	conn := cmdutil.NewClient()
+	stats, err := conn.CacheStats(path)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	ops := make([]string, 0, len(stats.Ops))
+	for op := range stats.Ops {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	opRows := make([][]string, 0, len(ops))
+	for _, op := range ops {
+		opStats := stats.Ops[op]
+		hitRate := "-"
+		if total := opStats.Hits + opStats.Misses; total > 0 {
+			hitRate = fmt.Sprintf("%.0f%%", 100*float64(opStats.Hits)/float64(total))
+		}
+		opRows = append(opRows, []string{
+			op,
+			fmt.Sprint(opStats.Hits),
+			fmt.Sprint(opStats.Misses),
+			hitRate,
+			fmt.Sprint(opStats.Results),
+			humanize.Bytes(uint64(opStats.Size)),
+		})
+	}
+	opHeaders := []cmdutil.ColumnHeader{
+		{ShortName: "op", FullName: "OP"},
+		{ShortName: "hits", FullName: "HITS"},
+		{ShortName: "misses", FullName: "MISSES"},
+		{ShortName: "hitrate", FullName: "HIT RATE"},
+		{ShortName: "results", FullName: "RESULTS"},
+		{ShortName: "size", FullName: "SIZE"},
+	}
+	cmdutil.Print(cmdutil.NewTableWithHeaders(opHeaders, opRows).Format())
+	cmdutil.Println()
+
+	plugins := make([]string, 0, len(stats.Plugins))
+	for plugin := range stats.Plugins {
+		plugins = append(plugins, plugin)
+	}
+	sort.Strings(plugins)
+	pluginRows := make([][]string, 0, len(plugins))
+	for _, plugin := range plugins {
+		pluginStats := stats.Plugins[plugin]
+		pluginRows = append(pluginRows, []string{
+			plugin,
+			fmt.Sprint(pluginStats.Results),
+			humanize.Bytes(uint64(pluginStats.Size)),
+		})
+	}
+	pluginHeaders := []cmdutil.ColumnHeader{
+		{ShortName: "plugin", FullName: "PLUGIN"},
+		{ShortName: "results", FullName: "RESULTS"},
+		{ShortName: "size", FullName: "SIZE"},
+	}
+	cmdutil.Print(cmdutil.NewTableWithHeaders(pluginHeaders, pluginRows).Format())
+	return exitCode{0}
+}
+
+func cacheLsMain(cmd *cobra.Command, args []string) exitCode {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	conn := cmdutil.NewClient()
+	stats, err := conn.CacheStats(path)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	now := time.Now()
+	rows := make([][]string, 0, len(stats.Results))
+	for _, result := range stats.Results {
+		rows = append(rows, []string{
+			result.Op,
+			result.Path,
+			formatCacheAge(now, result),
+			formatCacheExpiration(now, result),
+			humanize.Bytes(uint64(result.Size)),
+			formatCacheStatus(result),
+		})
+	}
+	headers := []cmdutil.ColumnHeader{
+		{ShortName: "op", FullName: "OP"},
+		{ShortName: "path", FullName: "PATH"},
+		{ShortName: "age", FullName: "AGE"},
+		{ShortName: "expires", FullName: "EXPIRES IN"},
+		{ShortName: "size", FullName: "SIZE"},
+		{ShortName: "status", FullName: "STATUS"},
+	}
+	cmdutil.Print(cmdutil.NewTableWithHeaders(headers, rows).Format())
+	return exitCode{0}
+}
+
+func formatCacheAge(now time.Time, result apitypes.CachedResult) string {
+	if result.Created.IsZero() {
+		return "-"
+	}
+	return now.Sub(result.Created).Round(time.Second).String()
+}
+
+func formatCacheExpiration(now time.Time, result apitypes.CachedResult) string {
+	if result.Expires.IsZero() {
+		return "never"
+	}
+	return result.Expires.Sub(now).Round(time.Second).String()
+}
+
+func formatCacheStatus(result apitypes.CachedResult) string {
+	switch {
+	case result.Error != "":
+		return "error: " + result.Error
+	case result.Stale:
+		return "stale"
+	default:
+		return "ok"
+	}
+}
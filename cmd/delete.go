@@ -15,11 +15,15 @@ func deleteCommand() *cobra.Command {
 		Use:   "delete <path> [<path>]",
 		Short: "Deletes the entries at the specified paths",
 		Long: `Deletes the entries at the specified paths, prompting the user for confirmation
-before deleting each entry.`,
+before deleting each entry.
+
+If --dry-run is set, then the entries aren't deleted. Instead, wash delete prints the
+steps that deleting them would take.`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: toRunE(deleteMain),
 	}
 	deleteCmd.Flags().BoolP("force", "f", false, "Skip confirmation")
+	deleteCmd.Flags().Bool("dry-run", false, "Print what would be deleted without deleting it")
 
 	return deleteCmd
 }
@@ -31,8 +35,17 @@ func deleteMain(cmd *cobra.Command, args []string) exitCode {
 		panic(err.Error())
 	}
 
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		panic(err.Error())
+	}
+
 	conn := cmdutil.NewClient()
 
+	if dryRun {
+		return printPlans(paths, conn.PlanDelete)
+	}
+
 	// Deletion's done in parallel for a better UX.
 	var pathsToDelete []string
 
@@ -78,3 +91,22 @@ func deleteMain(cmd *cobra.Command, args []string) exitCode {
 	// Return the exit code
 	return exitCode{ec}
 }
+
+// printPlans prints the plan for each path. It's used by the commands that
+// support a dry run.
+func printPlans(paths []string, plan func(path string) ([]string, error)) exitCode {
+	ec := 0
+	for _, path := range paths {
+		steps, err := plan(path)
+		if err != nil {
+			ec = 1
+			cmdutil.ErrPrintf("%v: %v\n", path, err)
+			continue
+		}
+		cmdutil.Printf("%v:\n", path)
+		for _, step := range steps {
+			cmdutil.Printf("  %v\n", step)
+		}
+	}
+	return exitCode{ec}
+}
@@ -227,6 +227,17 @@ func stringifySupportedActions(path string, entry apitypes.Entry) string {
 				fmt.Sprintf("- signal <signal> %s", path),
 				fmt.Sprintf("    e.g. signal start %s", path),
 			}
+		case plugin.CreateAction().Name:
+			actionDescriptionLines = []string{
+				fmt.Sprintf("- mkdir %s/<name>", path),
+				fmt.Sprintf("    Creates a child directory, if supported"),
+				fmt.Sprintf("- touch %s/<name>", path),
+				fmt.Sprintf("    Creates a child file, if supported"),
+			}
+		case plugin.RenameAction().Name:
+			actionDescriptionLines = []string{
+				fmt.Sprintf("- mv %s <new_path>", path),
+			}
 		}
 		for _, line := range actionDescriptionLines {
 			supportedActions.WriteString(fmt.Sprintf("    %v\n", line))
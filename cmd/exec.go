@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	apitypes "github.com/puppetlabs/wash/api/types"
 	cmdutil "github.com/puppetlabs/wash/cmd/util"
@@ -18,7 +21,13 @@ func execCommand() *cobra.Command {
 specified command and arguments. The results will be forwarded from the target on stdout, stderr,
 and exit code.`,
 		Example: `exec docker/containers/example_1 printenv USER
-  print the USER environment variable from a Docker container instance`,
+  print the USER environment variable from a Docker container instance
+
+exec -i docker/containers/example_1 sh -c 'wc -l' < big_file.txt
+  count the lines in a local file from a Docker container instance
+
+exec -e GREETING=hello -w /tmp --timeout 30s docker/containers/example_1 sh -c 'echo $GREETING from $(pwd)'
+  print "hello from /tmp" from a Docker container instance, stopping it if it takes longer than 30 seconds`,
 		Args: cobra.MinimumNArgs(2),
 		RunE: toRunE(execMain),
 	}
@@ -27,9 +36,48 @@ and exit code.`,
 	// instead get interpreted by this command as normal args, not flags.
 	execCmd.Flags().SetInterspersed(false)
 
+	execCmd.Flags().BoolP("interactive", "i", false, "Stream stdin to the command")
+	execCmd.Flags().StringArrayP("env", "e", []string{}, "Set an environment variable for the command (KEY=VALUE)")
+	execCmd.Flags().StringP("workdir", "w", "", "Run the command in the given working directory")
+	execCmd.Flags().StringP("user", "u", "", "Run the command as the given user")
+	execCmd.Flags().Duration("timeout", 0, "Stop the command if it runs longer than the given duration (e.g. 30s)")
+
 	return execCmd
 }
 
+// execOptionsFromFlags returns the exec options that were set by the
+// command's flags.
+func execOptionsFromFlags(cmd *cobra.Command) (apitypes.ExecOptions, error) {
+	var opts apitypes.ExecOptions
+	env, err := cmd.Flags().GetStringArray("env")
+	if err != nil {
+		panic(err.Error())
+	}
+	for _, kv := range env {
+		segments := strings.SplitN(kv, "=", 2)
+		if len(segments) != 2 || segments[0] == "" {
+			return opts, fmt.Errorf("invalid environment variable %q: expected KEY=VALUE", kv)
+		}
+		if opts.Env == nil {
+			opts.Env = make(map[string]string)
+		}
+		opts.Env[segments[0]] = segments[1]
+	}
+	if opts.Dir, err = cmd.Flags().GetString("workdir"); err != nil {
+		panic(err.Error())
+	}
+	if opts.User, err = cmd.Flags().GetString("user"); err != nil {
+		panic(err.Error())
+	}
+	if opts.Timeout, err = cmd.Flags().GetDuration("timeout"); err != nil {
+		panic(err.Error())
+	}
+	if opts.Timeout < 0 {
+		return opts, fmt.Errorf("the timeout cannot be negative")
+	}
+	return opts, nil
+}
+
 func printPackets(pkts <-chan apitypes.ExecPacket) (int, error) {
 	exit := 0
 	foundErroredPacket := false
@@ -78,9 +126,24 @@ func execMain(cmd *cobra.Command, args []string) exitCode {
 	command = args[1]
 	commandArgs = args[2:]
 
+	opts, err := execOptionsFromFlags(cmd)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
 	conn := cmdutil.NewClient()
 
-	ch, err := conn.Exec(path, command, commandArgs, apitypes.ExecOptions{})
+	interactive, err := cmd.Flags().GetBool("interactive")
+	if err != nil {
+		panic(err.Error())
+	}
+	var stdin io.Reader
+	if interactive {
+		stdin = os.Stdin
+	}
+
+	ch, err := conn.Exec(path, command, commandArgs, opts, stdin)
 	if err != nil {
 		cmdutil.ErrPrintf("%v\n", err)
 		return exitCode{1}
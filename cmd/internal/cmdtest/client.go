@@ -39,8 +39,8 @@ func (c *MockClient) Stream(path string) (io.ReadCloser, error) {
 }
 
 // Exec mocks Client#Exec
-func (c *MockClient) Exec(path string, command string, args []string, opts apitypes.ExecOptions) (<-chan apitypes.ExecPacket, error) {
-	margs := c.Called(path, command, args, opts)
+func (c *MockClient) Exec(path string, command string, args []string, opts apitypes.ExecOptions, stdin io.Reader) (<-chan apitypes.ExecPacket, error) {
+	margs := c.Called(path, command, args, opts, stdin)
 	return margs.Get(0).(<-chan apitypes.ExecPacket), margs.Error(1)
 }
 
@@ -62,6 +62,24 @@ func (c *MockClient) Clear(path string) ([]string, error) {
 	return args.Get(0).([]string), args.Error(1)
 }
 
+// CacheStats mocks Client#CacheStats
+func (c *MockClient) CacheStats(path string) (apitypes.CacheStats, error) {
+	args := c.Called(path)
+	return args.Get(0).(apitypes.CacheStats), args.Error(1)
+}
+
+// ReloadPlugins mocks Client#ReloadPlugins
+func (c *MockClient) ReloadPlugins(name string) ([]apitypes.PluginReloadResult, error) {
+	args := c.Called(name)
+	return args.Get(0).([]apitypes.PluginReloadResult), args.Error(1)
+}
+
+// Audit mocks Client#Audit
+func (c *MockClient) Audit(filter apitypes.AuditFilter) ([]apitypes.AuditRecord, error) {
+	args := c.Called(filter)
+	return args.Get(0).([]apitypes.AuditRecord), args.Error(1)
+}
+
 // Schema mocks Client#Schema
 func (c *MockClient) Schema(path string) (*apitypes.EntrySchema, error) {
 	args := c.Called(path)
@@ -85,3 +103,27 @@ func (c *MockClient) Signal(path string, signal string) error {
 	args := c.Called(path, signal)
 	return args.Error(0)
 }
+
+// PlanDelete mocks Client#PlanDelete
+func (c *MockClient) PlanDelete(path string) ([]string, error) {
+	args := c.Called(path)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// PlanSignal mocks Client#PlanSignal
+func (c *MockClient) PlanSignal(path string, signal string) ([]string, error) {
+	args := c.Called(path, signal)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// Create mocks Client#Create
+func (c *MockClient) Create(path string, name string, isParent bool) (apitypes.Entry, error) {
+	args := c.Called(path, name, isParent)
+	return args.Get(0).(apitypes.Entry), args.Error(1)
+}
+
+// Rename mocks Client#Rename
+func (c *MockClient) Rename(path string, newPath string) (apitypes.Entry, error) {
+	args := c.Called(path, newPath)
+	return args.Get(0).(apitypes.Entry), args.Error(1)
+}
@@ -15,8 +15,11 @@ import (
 
 // Contains all the keys for Wash's shared config
 const (
-	SocketKey   = "socket"
-	EmbeddedKey = "embedded"
+	SocketKey      = "socket"
+	EmbeddedKey    = "embedded"
+	RemoteKey      = "remote.url"
+	RemoteTokenKey = "remote.token"
+	RemoteCAKey    = "remote.ca"
 )
 
 // Socket is the path to the Wash server's UNIX
@@ -24,6 +27,14 @@ const (
 var Socket string
 var Embedded bool
 
+// Remote is the URL of a remote Wash server's API. If it's
+// set, then commands use it instead of Socket. RemoteToken
+// is the remote server's bearer token, and RemoteCA is the
+// certificate file that's used to verify it.
+var Remote string
+var RemoteToken string
+var RemoteCA string
+
 // Init initializes the config package. It loads Wash's defaults and
 // sets up viper
 func Init() error {
@@ -51,6 +62,9 @@ func Init() error {
 	// Load the shared config
 	Socket = viper.GetString(SocketKey)
 	Embedded = viper.GetBool(EmbeddedKey)
+	Remote = viper.GetString(RemoteKey)
+	RemoteToken = viper.GetString(RemoteTokenKey)
+	RemoteCA = viper.GetString(RemoteCAKey)
 
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"reflect"
 	"runtime/pprof"
 	"strings"
 	"sync"
@@ -13,6 +14,7 @@ import (
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/analytics"
 	"github.com/puppetlabs/wash/api"
+	"github.com/puppetlabs/wash/audit"
 	"github.com/puppetlabs/wash/fuse"
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/puppetlabs/wash/plugin/aws"
@@ -31,6 +33,17 @@ var InternalPlugins = map[string]plugin.Root{
 	"kubernetes": &kubernetes.Root{},
 }
 
+// NewInternalPlugin returns a new root for the named core plugin, or nil if there's
+// no such plugin. Reloaded plugins use a new root so that the registered root isn't
+// modified while it's in use.
+func NewInternalPlugin(name string) plugin.Root {
+	root, ok := InternalPlugins[name]
+	if !ok {
+		return nil
+	}
+	return reflect.New(reflect.TypeOf(root).Elem()).Interface().(plugin.Root)
+}
+
 // Opts exposes additional configuration for server operation.
 type Opts struct {
 	CPUProfilePath string
@@ -38,6 +51,17 @@ type Opts struct {
 	// LogLevel can be "warn", "info", "debug", or "trace".
 	LogLevel     string
 	PluginConfig map[string]map[string]interface{}
+	// PluginLoader re-reads the plugins and their config. It's used to reload
+	// them while the server's running. If it's nil, then they can't be reloaded.
+	PluginLoader plugin.PluginLoader
+	Cache        plugin.CacheOptions
+	// Remote configures the API's optional TCP listener.
+	Remote api.RemoteOptions
+	// Policy controls which entries the mutating actions can be invoked on.
+	Policy plugin.Policy
+	// AuditFile is where the mutating actions are audited. If it's empty, then
+	// they aren't audited.
+	AuditFile string
 }
 
 // SetupLogging configures log level and output file according to configured options.
@@ -74,6 +98,7 @@ type Server struct {
 	logFH            *os.File
 	api              controlChannels
 	fuse             controlChannels
+	registry         *plugin.Registry
 	plugins          map[string]plugin.Root
 	analyticsClient  analytics.Client
 	forVerifyInstall bool
@@ -111,6 +136,7 @@ func (s *Server) Start() (bool, error) {
 	}
 
 	registry := plugin.NewRegistry()
+	s.registry = registry
 
 	successfullyLoadedPlugins := true
 	if !s.forVerifyInstall {
@@ -118,8 +144,21 @@ func (s *Server) Start() (bool, error) {
 		if len(registry.Plugins()) == 0 {
 			return successfullyLoadedPlugins, fmt.Errorf("no plugins loaded. If you're planning on using Wash just for its external plugins, then go to https://puppetlabs.github.io/wash/docs/external-plugins")
 		}
+		registry.SetPluginLoader(s.opts.PluginLoader)
+
+		if err := plugin.InitCache(s.opts.Cache); err != nil {
+			return successfullyLoadedPlugins, err
+		}
 
-		plugin.InitCache()
+		if err := plugin.SetPolicy(s.opts.Policy); err != nil {
+			return successfullyLoadedPlugins, fmt.Errorf("invalid policy: %v", err)
+		}
+
+		if s.opts.AuditFile != "" {
+			if err := audit.Open(s.opts.AuditFile); err != nil {
+				return successfullyLoadedPlugins, err
+			}
+		}
 
 		analyticsConfig, err := analytics.GetConfig()
 		if err != nil {
@@ -132,6 +171,7 @@ func (s *Server) Start() (bool, error) {
 		registry,
 		s.mountpoint,
 		s.socket,
+		s.opts.Remote,
 		s.analyticsClient,
 	)
 	if err != nil {
@@ -195,9 +235,21 @@ func (s *Server) shutdown() {
 		pprof.StopCPUProfile()
 	}
 
+	// Stop any plugin processes, like an external plugin's RPC process.
+	s.registry.ClosePlugins()
+
 	// Close any open journals on shutdown to ensure remaining entries are flushed to disk.
 	activity.CloseAll()
 
+	if err := audit.Close(); err != nil {
+		log.Warnf("Failed to close the audit log: %v", err)
+	}
+
+	// Close the cache to ensure a persistent cache is saved to disk.
+	if err := plugin.CloseCache(); err != nil {
+		log.Warnf("Failed to save the cache: %v", err)
+	}
+
 	// Flush any outstanding analytics hits. We do this asynchronously
 	// so that the server process isn't blocked on its cleanup (in case
 	// the network is slow).
@@ -271,7 +323,7 @@ func (s *Server) loadPlugins(registry *plugin.Registry) bool {
 	wg.Wait()
 	if len(failedPlugins) > 0 {
 		log.Warnf(
-			"You can use 'docs <plugin>' (e.g. 'docs %v') to view set-up instructions for %v. However, you'll need to exit then restart the shell in order to properly reload them.\n",
+			"You can use 'docs <plugin>' (e.g. 'docs %v') to view set-up instructions for %v. Once they're set up, use 'wash plugins reload' to reload them.\n",
 			failedPlugins[0],
 			strings.Join(failedPlugins, ", "),
 		)
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/spf13/cobra"
+)
+
+func pluginsCommand() *cobra.Command {
+	use, aliases := generateShellAlias("plugins")
+	pluginsCmd := &cobra.Command{
+		Use:     use,
+		Aliases: aliases,
+		Short:   "Manages the plugins that are loaded by the Wash daemon",
+		Long: `Use this subcommand's reload subcommand to pick up changes to your config file or to an
+external plugin's script without restarting the Wash daemon.`,
+		Args: cobra.NoArgs,
+		RunE: toRunE(pluginsMain),
+	}
+
+	reloadCmd := &cobra.Command{
+		Use:   "reload [<plugin>]",
+		Short: "Reloads the specified plugin, or all plugins if not specified",
+		Long: `Re-reads the config file, then re-initializes the specified plugin and clears its cached results.
+The other plugins are left untouched. If no plugin is specified, then all plugins are reloaded. Newly
+configured plugins are loaded, and plugins that are no longer configured are unloaded.
+
+Only the plugins are reloaded. Other settings, like the policy, the audit log and the API's options,
+keep the values that the daemon started with until it's restarted.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: toRunE(pluginsReloadMain),
+	}
+	addCommand(pluginsCmd, reloadCmd)
+
+	return pluginsCmd
+}
+
+func pluginsMain(cmd *cobra.Command, args []string) exitCode {
+	if err := cmd.Help(); err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+	return exitCode{0}
+}
+
+func pluginsReloadMain(cmd *cobra.Command, args []string) exitCode {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	conn := cmdutil.NewClient()
+	results, err := conn.ReloadPlugins(name)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	ec := 0
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			ec = 1
+			cmdutil.ErrPrintf("%v failed to load: %v\n", result.Name, result.Error)
+		case result.Unregistered:
+			cmdutil.Println("Unloaded", result.Name)
+		default:
+			cmdutil.Println("Reloaded", result.Name)
+		}
+	}
+	return exitCode{ec}
+}
@@ -162,7 +162,7 @@ var dispatchers = []struct {
 	{}, // Unknown
 	{ // POSIX shell
 		execPS: func(conn client.Client, name string) (<-chan apitypes.ExecPacket, error) {
-			return conn.Exec(name, "sh", []string{}, apitypes.ExecOptions{Input: psScript})
+			return conn.Exec(name, "sh", []string{}, apitypes.ExecOptions{Input: psScript}, nil)
 		},
 		parseOutput: parseStatLines,
 	},
@@ -170,7 +170,7 @@ var dispatchers = []struct {
 		execPS: func(conn client.Client, name string) (<-chan apitypes.ExecPacket, error) {
 			cmd := "Get-Process | Where TotalProcessorTime | Where Path | " +
 				"Select-Object -Property Id,TotalProcessorTime,Path | ConvertTo-Csv"
-			return conn.Exec(name, cmd, []string{}, apitypes.ExecOptions{})
+			return conn.Exec(name, cmd, []string{}, apitypes.ExecOptions{}, nil)
 		},
 		parseOutput: parseCsvLines,
 	},
@@ -156,11 +156,15 @@ then starts your system shell with shortcuts configured for wash subcommands.`,
 	addCommand(rootCmd, metaCommand())
 	addCommand(rootCmd, lsCommand())
 	addCommand(rootCmd, execCommand())
+	addCommand(rootCmd, shellCommand())
 	addCommand(rootCmd, psCommand())
 	addCommand(rootCmd, findCommand())
 	addCommand(rootCmd, clearCommand())
+	addCommand(rootCmd, cacheCommand())
+	addCommand(rootCmd, pluginsCommand())
 	addCommand(rootCmd, tailCommand())
 	addCommand(rootCmd, historyCommand())
+	addCommand(rootCmd, auditCommand())
 	addCommand(rootCmd, infoCommand())
 	addCommand(rootCmd, streeCommand())
 	addCommand(rootCmd, docsCommand())
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Benchkram/errz"
+	"github.com/puppetlabs/wash/api"
 	apifs "github.com/puppetlabs/wash/api/fs"
 	"github.com/puppetlabs/wash/cmd/internal/config"
 	"github.com/puppetlabs/wash/cmd/internal/server"
@@ -96,25 +97,107 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 		return nil, server.Opts{}, err
 	}
 
+	plugins, pluginConfig, err := pluginsFor(configFile, plugin.IsInteractive())
+	if err != nil {
+		return nil, server.Opts{}, err
+	}
+
+	// Reload the plugins from the current config. Don't prompt for the enabled plugins
+	// because the server's already running. Only the plugins are reloaded; the other
+	// options (like the policy and audit file) keep the values in opts.
+	pluginLoader := func() (map[string]plugin.Root, map[string]map[string]interface{}, error) {
+		if err := config.ReadFrom(configFile); err != nil {
+			return nil, nil, err
+		}
+		return pluginsFor(configFile, false)
+	}
+
+	// Persist the cache if requested so that a restarted server doesn't re-fetch
+	// the cached metadata and the listings of plugin.PersistentParents.
+	var cacheOpts plugin.CacheOptions
+	if viper.GetBool("cache.persist") {
+		cacheOpts.File = viper.GetString("cache.file")
+		if cacheOpts.File == "" {
+			cdir, err := os.UserCacheDir()
+			if err != nil {
+				return nil, server.Opts{}, fmt.Errorf("could not determine the cache file's location: %v", err)
+			}
+			cacheOpts.File = filepath.Join(cdir, "wash", "cache")
+		}
+	}
+
+	// Audit the mutating actions.
+	auditFile := viper.GetString("audit.file")
+	if auditFile == "" {
+		cdir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, server.Opts{}, fmt.Errorf("could not determine the audit log's location: %v", err)
+		}
+		auditFile = filepath.Join(cdir, "wash", "audit.log")
+	}
+
+	// Restrict the mutating actions if a policy's configured.
+	var policy plugin.Policy
+	if err := viper.UnmarshalKey("policy", &policy); err != nil {
+		return nil, server.Opts{}, fmt.Errorf("failed to unmarshal the policy key: %v", err)
+	}
+
+	// Optionally serve the API over TCP so that remote clients can use it.
+	var remoteOpts api.RemoteOptions
+	if address := viper.GetString("api.listen"); address != "" {
+		configDir := filepath.Dir(config.DefaultFileAbsPath())
+		remoteOpts = api.RemoteOptions{
+			Address:  address,
+			CertFile: viper.GetString("api.tls.cert"),
+			KeyFile:  viper.GetString("api.tls.key"),
+			Token:    viper.GetString("api.token"),
+		}
+		if remoteOpts.CertFile == "" && remoteOpts.KeyFile == "" {
+			remoteOpts.CertFile = filepath.Join(configDir, "tls", "server.crt")
+			remoteOpts.KeyFile = filepath.Join(configDir, "tls", "server.key")
+		}
+		if remoteOpts.Token == "" {
+			return nil, server.Opts{}, fmt.Errorf("the api.token key must be set to listen at %v", address)
+		}
+	}
+
+	// Return the options
+	return plugins, server.Opts{
+		CPUProfilePath: viper.GetString("cpuprofile"),
+		LogFile:        viper.GetString("logfile"),
+		LogLevel:       viper.GetString("loglevel"),
+		PluginConfig:   pluginConfig,
+		PluginLoader:   pluginLoader,
+		Cache:          cacheOpts,
+		Remote:         remoteOpts,
+		Policy:         policy,
+		AuditFile:      auditFile,
+	}, nil
+}
+
+// pluginsFor returns the plugins that are configured in configFile, and their config.
+// The config must've already been read. If prompt is true and the enabled core plugins
+// aren't configured, then the user's prompted for them.
+func pluginsFor(configFile string, prompt bool) (map[string]plugin.Root, map[string]map[string]interface{}, error) {
 	plugins := make(map[string]plugin.Root)
+	var err error
 
 	// Check the internal plugins
 	if viper.IsSet("plugins") || viper.IsSet("external-plugins") {
 		for _, name := range viper.GetStringSlice("plugins") {
-			if plug, ok := server.InternalPlugins[name]; ok {
+			if plug := server.NewInternalPlugin(name); plug != nil {
 				plugins[name] = plug
 			} else {
 				log.Warnf("Requested unknown plugin %s", name)
 			}
 		}
-	} else if !plugin.IsInteractive() {
+	} else if !prompt {
 		// This is an edge-case for a user but a common case for
 		// CI. Thus, load all the plugins so that we don't break
-		// the latter. Note that we copy server.InternalPlugins
-		// so that we don't mutate it.
+		// the latter.
 		log.Warnf("Running non-interactively without having set the 'plugins'/'external-plugins' keys in %v. Loading all core plugins by default", configFile)
-		for name, plug := range server.InternalPlugins {
-			plugins[name] = plug
+		for name := range server.InternalPlugins {
+			plugins[name] = server.NewInternalPlugin(name)
 		}
 	} else {
 		// Assume first-time user. First, we prompt them to get a list
@@ -122,7 +205,7 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 		// enabled plugins back to their specified config file.
 		plugins, err = promptEnabledPlugins()
 		if err != nil {
-			return nil, server.Opts{}, err
+			return nil, nil, err
 		}
 		enabledPlugins := []string{}
 		for plugin := range plugins {
@@ -142,7 +225,7 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 			if len(enabledPlugins) <= 0 {
 				action = "enable"
 			}
-			cmdutil.Printf("You can %v them by modifying the 'plugins' key in your config\nfile (%v), and then restarting the shell\n\n", action, configFile)
+			cmdutil.Printf("You can %v them by modifying the 'plugins' key in your config\nfile (%v), and then running 'wash plugins reload'\n\n", action, configFile)
 		}
 	}
 
@@ -150,7 +233,7 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 	// they're valid scripts, then convert them to plugin.Root types.
 	var externalPlugins []external.PluginSpec
 	if err := viper.UnmarshalKey("external-plugins", &externalPlugins); err != nil {
-		return nil, server.Opts{}, fmt.Errorf("failed to unmarshal the external-plugins key: %v", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal the external-plugins key: %v", err)
 	}
 	for _, spec := range externalPlugins {
 		intPlugin, err := spec.Load()
@@ -177,13 +260,7 @@ func serverOptsFor(cmd *cobra.Command) (map[string]plugin.Root, server.Opts, err
 		pluginConfig["local"] = map[string]interface{}{"basepath": localfsPath}
 	}
 
-	// Return the options
-	return plugins, server.Opts{
-		CPUProfilePath: viper.GetString("cpuprofile"),
-		LogFile:        viper.GetString("logfile"),
-		LogLevel:       viper.GetString("loglevel"),
-		PluginConfig:   pluginConfig,
-	}, nil
+	return plugins, pluginConfig, nil
 }
 
 func promptEnabledPlugins() (map[string]plugin.Root, error) {
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+
+	apitypes "github.com/puppetlabs/wash/api/types"
+	cmdutil "github.com/puppetlabs/wash/cmd/util"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+func shellCommand() *cobra.Command {
+	shellCmd := &cobra.Command{
+		Use:   "shell <path>",
+		Short: "Starts an interactive shell on the entry at the specified path",
+		Long: `Starts an interactive login shell on the entry at the specified path. The entry
+must support the exec action.
+
+The shell's picked based on the entry's os.login_shell attribute. POSIX shell
+entries get bash if it's installed, and sh otherwise. PowerShell entries get
+powershell.`,
+		Example: `shell docker/containers/example_1
+  start a shell on a Docker container instance`,
+		Args: cobra.ExactArgs(1),
+		RunE: toRunE(shellMain),
+	}
+
+	return shellCmd
+}
+
+func shellMain(cmd *cobra.Command, args []string) exitCode {
+	path := args[0]
+
+	stdinFd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(stdinFd) {
+		cmdutil.ErrPrintf("wash shell must be run from a terminal\n")
+		return exitCode{1}
+	}
+
+	conn := cmdutil.NewClient()
+
+	entry, err := conn.Info(path)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+	if !entry.Supports(plugin.ExecAction()) {
+		cmdutil.ErrPrintf("%v: the entry does not support the exec action\n", path)
+		return exitCode{1}
+	}
+
+	var loginShell plugin.Shell
+	if entry.Attributes.HasOS() {
+		loginShell = entry.Attributes.OS().LoginShell
+	}
+	shell, shellArgs, opts := loginShellCommand(loginShell)
+
+	// Send the terminal's initial size, then send its new size whenever
+	// it's resized.
+	terminalSizes := make(chan plugin.TerminalSize, 1)
+	sendTerminalSize := func() {
+		if cols, rows, err := terminal.GetSize(stdinFd); err == nil {
+			terminalSizes <- plugin.TerminalSize{Rows: uint16(rows), Cols: uint16(cols)}
+		}
+	}
+	sendTerminalSize()
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGWINCH)
+	defer signal.Stop(sigCh)
+	go func() {
+		for range sigCh {
+			sendTerminalSize()
+		}
+	}()
+	opts.Tty = true
+	opts.TerminalSizes = terminalSizes
+
+	ch, err := conn.Exec(path, shell, shellArgs, opts, os.Stdin)
+	if err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
+
+	// Put the terminal in raw mode so that its input (including control
+	// characters like Ctrl+C) is sent as-is to the remote TTY.
+	state, err := terminal.MakeRaw(stdinFd)
+	if err != nil {
+		cmdutil.ErrPrintf("could not put the terminal in raw mode: %v\n", err)
+		return exitCode{1}
+	}
+	code, err := printPackets(ch)
+	if restoreErr := terminal.Restore(stdinFd, state); restoreErr != nil {
+		cmdutil.ErrPrintf("could not restore the terminal: %v\n", restoreErr)
+	}
+	if err != nil {
+		return exitCode{1}
+	}
+
+	return exitCode{code}
+}
+
+// loginShellCommand returns the command that starts a login shell, along with
+// the exec options to run it with.
+func loginShellCommand(loginShell plugin.Shell) (string, []string, apitypes.ExecOptions) {
+	var opts apitypes.ExecOptions
+	if loginShell == plugin.PowerShell {
+		return "powershell", []string{"-NoLogo"}, opts
+	}
+
+	// Assume POSIX if the login shell's unknown.
+	if term := os.Getenv("TERM"); term != "" {
+		opts.Env = map[string]string{"TERM": term}
+	}
+	script := "if command -v bash >/dev/null 2>&1; then exec bash -l; fi; exec sh -l"
+	return "sh", []string{"-c", script}, opts
+}
@@ -12,9 +12,14 @@ func signalCommand() *cobra.Command {
 	signalCmd := &cobra.Command{
 		Use:   "signal <signal> [path]...",
 		Short: "Sends the specified signal to the entries at the specified paths",
-		Args:  cobra.MinimumNArgs(2),
-		RunE:  toRunE(signalMain),
+		Long: `Sends the specified signal to the entries at the specified paths.
+
+If --dry-run is set, then the signal isn't sent. Instead, wash signal prints the
+steps that sending it would take.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: toRunE(signalMain),
 	}
+	signalCmd.Flags().Bool("dry-run", false, "Print what the signal would do without sending it")
 
 	return signalCmd
 }
@@ -23,8 +28,19 @@ func signalMain(cmd *cobra.Command, args []string) exitCode {
 	signal := args[0]
 	paths := args[1:]
 
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		panic(err.Error())
+	}
+
 	conn := cmdutil.NewClient()
 
+	if dryRun {
+		return printPlans(paths, func(path string) ([]string, error) {
+			return conn.PlanSignal(path, signal)
+		})
+	}
+
 	// Perform the operation in parallel
 	ec := 0
 	var wg sync.WaitGroup
@@ -1,12 +1,29 @@
 package cmdutil
 
 import (
+	"os"
+
 	"github.com/puppetlabs/wash/api/client"
 	"github.com/puppetlabs/wash/cmd/internal/config"
 )
 
-// NewClient returns a new Wash client for the given subcommand.
+// NewClient returns a new Wash client for the given subcommand. It
+// uses the remote Wash server if one's configured, otherwise the
+// local server's socket.
 // Tests can set NewClient to a stub that returns a mock client.
 var NewClient = func() client.Client {
-	return client.ForUNIXSocket(config.Socket)
-}
\ No newline at end of file
+	if config.Remote == "" {
+		return client.ForUNIXSocket(config.Socket)
+	}
+	conn, err := client.ForURL(config.Remote, client.URLOptions{
+		Token:  config.RemoteToken,
+		CAFile: config.RemoteCA,
+	})
+	if err != nil {
+		// The remote config is invalid so none of the subcommand's
+		// requests could succeed.
+		ErrPrintf("Could not connect to the remote Wash server: %v\n", err)
+		os.Exit(1)
+	}
+	return conn
+}
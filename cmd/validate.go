@@ -34,6 +34,8 @@ invoking all supported methods on an example at each level. If the plugin provid
 will be used to explore one example of each type of entry. Exploration can be stopped with Ctrl-C
 when needed.
 
+External plugins report the protocol version and capabilities that were negotiated at init.
+
 Each line represents validation of an entry type. The 'lrsx' fields represent support for 'list',
 'read', 'stream', and 'execute' methods respectively, with '-' representing lack of support for a
 method.`,
@@ -107,9 +109,15 @@ func validateMain(cmd *cobra.Command, args []string) exitCode {
 		cmdutil.ErrPrintf("%v\n", formatErr("Error loading plugin", "init", err))
 		return exitCode{1}
 	}
+	if ext, ok := root.(interface{ Protocol() external.Protocol }); ok {
+		cmdutil.Printf("Negotiated protocol %v\n", ext.Protocol())
+	}
 
 	rand.Seed(time.Now().UnixNano())
-	plugin.InitCache()
+	if err := plugin.InitCache(plugin.CacheOptions{}); err != nil {
+		cmdutil.ErrPrintf("%v\n", err)
+		return exitCode{1}
+	}
 	var wg sync.WaitGroup
 	wg.Add(2)
 
@@ -4,7 +4,9 @@ package datastore
 import (
 	"math"
 	"regexp"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	// TODO: Once https://github.com/patrickmn/go-cache/pull/75
@@ -17,9 +19,38 @@ import (
 // Cache is an interface for a cache.
 type Cache interface {
 	GetOrUpdate(category, key string, ttl time.Duration, resetTTLOnHit bool, generateValue func() (interface{}, error)) (interface{}, error)
+	GetOrRefresh(category, key string, ttl time.Duration, staleTTL time.Duration, generateValue func() (interface{}, error), refreshValue func() (interface{}, error)) (interface{}, error)
 	Get(category, key string) (interface{}, error)
 	Flush()
 	Delete(matcher *regexp.Regexp) []string
+	Items(matcher *regexp.Regexp) []Item
+	Stats() map[string]CategoryStats
+}
+
+// Item describes an item in a cache.
+type Item struct {
+	Category string
+	Key      string
+	// Value is the cached value. It's an error if the cached operation failed.
+	Value interface{}
+	// Created is when the value was cached. It's zero if that's unknown.
+	Created time.Time
+	// Expires is when the item expires. It's zero if the item never expires.
+	Expires time.Time
+	// Stale is true if the value is being served while it's refreshed. See
+	// GetOrRefresh.
+	Stale bool
+}
+
+// CategoryStats describes how often a category's items were found in the cache.
+type CategoryStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type categoryCounters struct {
+	hits   uint64
+	misses uint64
 }
 
 // MemCache is an in-memory cache. It supports concurrent get/set, as well as the ability
@@ -34,6 +65,22 @@ type MemCache struct {
 	locks       sync.Map
 	hasEviction bool
 	limit       int
+	// refreshing contains the keys that are being refreshed in the background
+	refreshing sync.Map
+	// onSet is called whenever an item's set
+	onSet func()
+	// created contains the time that each item was set
+	created sync.Map
+	// counters contains the *categoryCounters of each category
+	counters sync.Map
+}
+
+// staleableValue is a value cached by GetOrRefresh. It can still be returned
+// after it's stale.
+type staleableValue struct {
+	Value interface{}
+	// FreshUntil is when the value becomes stale, in Unix nanoseconds
+	FreshUntil int64
 }
 
 var _ = Cache(&MemCache{})
@@ -85,8 +132,11 @@ func (cache *MemCache) Get(category, key string) (interface{}, error) {
 	key = formKey(category, key)
 	value, found := cache.instance.Get(key)
 	if found {
-		if err, ok := value.(error); ok {
-			return nil, err
+		switch t := value.(type) {
+		case error:
+			return nil, t
+		case *staleableValue:
+			return t.Value, nil
 		}
 		return value, nil
 	}
@@ -111,11 +161,12 @@ func (cache *MemCache) GetOrUpdate(category, key string, ttl time.Duration, rese
 	// a single cache.
 	key = formKey(category, key)
 	value, found := cache.instance.Get(key)
+	cache.count(category, found)
 	if found {
 		log.Tracef("Cache hit on %v", key)
 		if resetTTLOnHit {
 			// Update last-access time
-			cache.instance.Set(key, value, ttl)
+			cache.set(key, value, ttl)
 		}
 		if err, ok := value.(error); ok {
 			return nil, err
@@ -126,27 +177,155 @@ func (cache *MemCache) GetOrUpdate(category, key string, ttl time.Duration, rese
 	// Cache misses should be rarer, so print them as debug messages.
 	log.Debugf("Cache miss on %v", key)
 
-	if cache.limit > 0 && cache.instance.ItemCount() >= cache.limit {
-		// Retain write lock when deleting items to avoid concurrent map read/write.
-		cache.mux.RUnlock()
-		cache.mux.Lock()
-		cache.deleteClosestToExpiration()
-		cache.mux.Unlock()
-		cache.mux.RLock()
-	}
-
+	cache.makeRoom()
 	value, err := generateValue()
 	// Cache error responses as well. These are often authentication or availability failures
 	// and we don't want to continually query the API on failures.
 	if err != nil {
-		cache.instance.Set(key, err, ttl)
+		cache.set(key, err, ttl)
 		return nil, err
 	}
 
+	cache.set(key, value, ttl)
+	return value, nil
+}
+
+// Extend extends the expiration of the value stored at the given key so that
+// it expires no sooner than ttl from now. Unlike GetOrUpdate's resetTTLOnHit,
+// it never shortens the expiration. It does nothing if there's no value at
+// the key.
+func (cache *MemCache) Extend(category, key string, ttl time.Duration) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	l := cache.lockForKey(category, key)
+	l.Lock()
+	defer l.Unlock()
+
+	key = formKey(category, key)
+	value, expiration, found := cache.instance.GetWithExpiration(key)
+	if !found || expiration.IsZero() || !time.Now().Add(ttl).After(expiration) {
+		return
+	}
 	cache.instance.Set(key, value, ttl)
+	if cache.onSet != nil {
+		cache.onSet()
+	}
+}
+
+// GetOrRefresh is like GetOrUpdate, except that a value is still returned
+// for up to staleTTL after its ttl expires. Returning a stale value triggers
+// a background refresh of it with refreshValue. Unlike generateValue, which
+// is invoked on behalf of the caller, refreshValue's result is shared by all
+// callers so it shouldn't be cancelled with any one of them. Concurrent
+// refreshes of the same key are deduplicated. If a refresh fails, then the
+// stale value is kept until the next refresh.
+//
+// Errors are cached like they are for GetOrUpdate. They're not returned once
+// their ttl expires.
+func (cache *MemCache) GetOrRefresh(category, key string, ttl time.Duration, staleTTL time.Duration, generateValue func() (interface{}, error), refreshValue func() (interface{}, error)) (interface{}, error) {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	l := cache.lockForKey(category, key)
+	l.Lock()
+	defer l.Unlock()
+
+	fullKey := formKey(category, key)
+	value, found := cache.instance.Get(fullKey)
+	cache.count(category, found)
+	if found {
+		log.Tracef("Cache hit on %v", fullKey)
+		switch t := value.(type) {
+		case error:
+			return nil, t
+		case *staleableValue:
+			if time.Now().UnixNano() >= t.FreshUntil {
+				cache.refresh(category, key, t, ttl, staleTTL, refreshValue)
+			}
+			return t.Value, nil
+		}
+		return value, nil
+	}
+
+	// Cache misses should be rarer, so print them as debug messages.
+	log.Debugf("Cache miss on %v", fullKey)
+
+	cache.makeRoom()
+	value, err := generateValue()
+	if err != nil {
+		cache.set(fullKey, err, ttl)
+		return nil, err
+	}
+
+	cache.set(fullKey, newStaleableValue(value, ttl), ttl+staleTTL)
 	return value, nil
 }
 
+func (cache *MemCache) set(key string, value interface{}, ttl time.Duration) {
+	cache.instance.Set(key, value, ttl)
+	cache.created.Store(key, time.Now())
+	if cache.onSet != nil {
+		cache.onSet()
+	}
+}
+
+func newStaleableValue(value interface{}, ttl time.Duration) *staleableValue {
+	return &staleableValue{
+		Value:      value,
+		FreshUntil: time.Now().Add(ttl).UnixNano(),
+	}
+}
+
+// refresh refreshes the stale value in the background. It should be called
+// while holding the key's lock.
+func (cache *MemCache) refresh(category, key string, stale *staleableValue, ttl time.Duration, staleTTL time.Duration, refreshValue func() (interface{}, error)) {
+	fullKey := formKey(category, key)
+	if _, refreshing := cache.refreshing.LoadOrStore(fullKey, true); refreshing {
+		return
+	}
+
+	log.Debugf("Refreshing stale cache entry %v", fullKey)
+	go func() {
+		defer cache.refreshing.Delete(fullKey)
+
+		value, err := refreshValue()
+		if err != nil {
+			log.Debugf("Failed to refresh cache entry %v: %v", fullKey, err)
+			return
+		}
+
+		cache.mux.RLock()
+		defer cache.mux.RUnlock()
+
+		l := cache.lockForKey(category, key)
+		l.Lock()
+		defer l.Unlock()
+
+		// Only replace the stale value. Otherwise, we could overwrite a newer
+		// value or restore a value that was deleted while we were refreshing
+		// it (e.g. because the entry was modified).
+		if current, found := cache.instance.Get(fullKey); !found || current != stale {
+			log.Debugf("Discarding refresh of cache entry %v, it was replaced or deleted", fullKey)
+			return
+		}
+		cache.set(fullKey, newStaleableValue(value, ttl), ttl+staleTTL)
+	}()
+}
+
+// makeRoom deletes the item closest to expiration if the cache is at its
+// limit. It should be called while holding a read lock on cache.mux.
+func (cache *MemCache) makeRoom() {
+	if cache.limit > 0 && cache.instance.ItemCount() >= cache.limit {
+		// Retain write lock when deleting items to avoid concurrent map read/write.
+		cache.mux.RUnlock()
+		cache.mux.Lock()
+		cache.deleteClosestToExpiration()
+		cache.mux.Unlock()
+		cache.mux.RLock()
+	}
+}
+
 func (cache *MemCache) deleteClosestToExpiration() {
 	var candidate string
 	now := time.Now().UnixNano()
@@ -162,6 +341,7 @@ func (cache *MemCache) deleteClosestToExpiration() {
 		panic("should have found a candidate")
 	}
 	cache.instance.Delete(candidate)
+	cache.created.Delete(candidate)
 }
 
 // Flush deletes all items from the cache. Also resets cache capacity.
@@ -181,6 +361,10 @@ func (cache *MemCache) Flush() {
 		cache.instance.DeleteExpired()
 	}
 	cache.instance.Flush()
+	cache.created.Range(func(k, _ interface{}) bool {
+		cache.created.Delete(k)
+		return true
+	})
 }
 
 // Delete removes entries from the cache that match the provided regexp.
@@ -195,6 +379,7 @@ func (cache *MemCache) Delete(matcher *regexp.Regexp) []string {
 		if matcher.MatchString(k) {
 			log.Debugf("Deleting cache entry %v", k)
 			cache.instance.Delete(k)
+			cache.created.Delete(k)
 			deleted = append(deleted, k)
 		} else {
 			log.Debugf("Skipping %v", k)
@@ -202,3 +387,65 @@ func (cache *MemCache) Delete(matcher *regexp.Regexp) []string {
 	}
 	return deleted
 }
+
+// Items returns the unexpired items whose keys match the provided regexp.
+// A key has the form <category>::<key>.
+func (cache *MemCache) Items(matcher *regexp.Regexp) []Item {
+	cache.mux.RLock()
+	defer cache.mux.RUnlock()
+
+	now := time.Now().UnixNano()
+	var items []Item
+	for k, it := range cache.instance.Items() {
+		if !matcher.MatchString(k) {
+			continue
+		}
+		segments := strings.SplitN(k, "::", 2)
+		item := Item{Value: it.Object}
+		if len(segments) == 2 {
+			item.Category, item.Key = segments[0], segments[1]
+		} else {
+			item.Key = k
+		}
+		if created, ok := cache.created.Load(k); ok {
+			item.Created = created.(time.Time)
+		}
+		if it.Expiration > 0 {
+			item.Expires = time.Unix(0, it.Expiration)
+		}
+		if stale, ok := it.Object.(*staleableValue); ok {
+			item.Value = stale.Value
+			item.Stale = now >= stale.FreshUntil
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// Stats returns each category's stats. Only GetOrUpdate and GetOrRefresh
+// calls are counted.
+func (cache *MemCache) Stats() map[string]CategoryStats {
+	stats := make(map[string]CategoryStats)
+	cache.counters.Range(func(category, obj interface{}) bool {
+		counters := obj.(*categoryCounters)
+		stats[category.(string)] = CategoryStats{
+			Hits:   atomic.LoadUint64(&counters.hits),
+			Misses: atomic.LoadUint64(&counters.misses),
+		}
+		return true
+	})
+	return stats
+}
+
+func (cache *MemCache) count(category string, hit bool) {
+	obj, ok := cache.counters.Load(category)
+	if !ok {
+		obj, _ = cache.counters.LoadOrStore(category, &categoryCounters{})
+	}
+	counters := obj.(*categoryCounters)
+	if hit {
+		atomic.AddUint64(&counters.hits, 1)
+	} else {
+		atomic.AddUint64(&counters.misses, 1)
+	}
+}
@@ -3,6 +3,7 @@ package datastore
 import (
 	"errors"
 	"regexp"
+	"sync"
 	"testing"
 	"time"
 
@@ -99,6 +100,31 @@ func (suite *MemCacheTestSuite) TestGetOrUpdateWithReset() {
 	suite.thing.AssertNumberOfCalls(suite.T(), "update", 2)
 }
 
+func (suite *MemCacheTestSuite) TestExtend() {
+	suite.thing.On("update").Return(anything, nil)
+	suite.validate(suite.mem.GetOrUpdate("cat", "an entry", time.Minute, false, suite.update))
+	_, expiration, _ := suite.mem.instance.GetWithExpiration("cat::an entry")
+
+	// A shorter ttl doesn't shorten the expiration.
+	suite.mem.Extend("cat", "an entry", time.Nanosecond)
+	time.Sleep(time.Nanosecond)
+	_, actual, ok := suite.mem.instance.GetWithExpiration("cat::an entry")
+	if suite.True(ok) {
+		suite.Equal(expiration, actual)
+	}
+
+	suite.mem.Extend("cat", "an entry", time.Hour)
+	_, actual, ok = suite.mem.instance.GetWithExpiration("cat::an entry")
+	if suite.True(ok) {
+		suite.True(actual.After(expiration.Add(50 * time.Minute)))
+	}
+
+	// Missing values aren't added.
+	suite.mem.Extend("cat", "other entry", time.Hour)
+	_, ok = suite.mem.instance.Get("cat::other entry")
+	suite.False(ok)
+}
+
 func (suite *MemCacheTestSuite) TestGet() {
 	val, err := suite.mem.Get("foo", "bar")
 	suite.Nil(val)
@@ -121,6 +147,147 @@ func (suite *MemCacheTestSuite) TestGet() {
 	suite.EqualError(err, "an error")
 }
 
+func (suite *MemCacheTestSuite) TestGetOrRefreshFresh() {
+	suite.thing.On("update").Return(anything, nil)
+
+	suite.validate(suite.mem.GetOrRefresh("cat", "an entry", time.Second, time.Second, suite.update, suite.update))
+	suite.validate(suite.mem.GetOrRefresh("cat", "an entry", time.Second, time.Second, suite.update, suite.update))
+	suite.thing.AssertNumberOfCalls(suite.T(), "update", 1)
+
+	val, err := suite.mem.Get("cat", "an entry")
+	suite.NoError(err)
+	suite.Equal(anything, val)
+}
+
+// refresher generates sequentially numbered values. Generating a value blocks
+// until release is called.
+type refresher struct {
+	mux     sync.Mutex
+	calls   int
+	release chan struct{}
+	err     error
+}
+
+func newRefresher() *refresher {
+	return &refresher{release: make(chan struct{}, 10)}
+}
+
+func (r *refresher) generate() (interface{}, error) {
+	r.mux.Lock()
+	r.calls++
+	calls := r.calls
+	err := r.err
+	r.mux.Unlock()
+	if calls > 1 {
+		<-r.release
+	}
+	if err != nil {
+		return nil, err
+	}
+	return calls, nil
+}
+
+func (r *refresher) numCalls() int {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.calls
+}
+
+func (suite *MemCacheTestSuite) waitForRefresh(key string) {
+	suite.Eventually(func() bool {
+		_, refreshing := suite.mem.refreshing.Load(key)
+		return !refreshing
+	}, time.Second, time.Millisecond)
+}
+
+func (suite *MemCacheTestSuite) TestGetOrRefreshStale() {
+	r := newRefresher()
+	val, err := suite.mem.GetOrRefresh("cat", "an entry", time.Millisecond, time.Hour, r.generate, r.generate)
+	suite.NoError(err)
+	suite.Equal(1, val)
+	time.Sleep(2 * time.Millisecond)
+
+	// The stale value's returned while it's refreshed, and concurrent refreshes are
+	// deduplicated.
+	for i := 0; i < 3; i++ {
+		val, err = suite.mem.GetOrRefresh("cat", "an entry", time.Millisecond, time.Hour, r.generate, r.generate)
+		suite.NoError(err)
+		suite.Equal(1, val)
+	}
+	r.release <- struct{}{}
+	suite.waitForRefresh("cat::an entry")
+	suite.Equal(2, r.numCalls())
+
+	val, err = suite.mem.Get("cat", "an entry")
+	suite.NoError(err)
+	suite.Equal(2, val)
+}
+
+func (suite *MemCacheTestSuite) TestGetOrRefreshOnlyRefreshesWithRefreshValue() {
+	generate := func() (interface{}, error) { return "generated", nil }
+	refresh := func() (interface{}, error) { return "refreshed", nil }
+	val, err := suite.mem.GetOrRefresh("cat", "an entry", time.Millisecond, time.Hour, generate, refresh)
+	suite.NoError(err)
+	suite.Equal("generated", val)
+	time.Sleep(2 * time.Millisecond)
+
+	val, err = suite.mem.GetOrRefresh("cat", "an entry", time.Millisecond, time.Hour, generate, refresh)
+	suite.NoError(err)
+	suite.Equal("generated", val)
+	suite.waitForRefresh("cat::an entry")
+
+	val, err = suite.mem.Get("cat", "an entry")
+	suite.NoError(err)
+	suite.Equal("refreshed", val)
+}
+
+func (suite *MemCacheTestSuite) TestGetOrRefreshKeepsStaleValueOnError() {
+	r := newRefresher()
+	_, err := suite.mem.GetOrRefresh("cat", "an entry", time.Millisecond, time.Hour, r.generate, r.generate)
+	suite.NoError(err)
+	time.Sleep(2 * time.Millisecond)
+
+	r.err = errors.New("an error")
+	r.release <- struct{}{}
+	val, err := suite.mem.GetOrRefresh("cat", "an entry", time.Millisecond, time.Hour, r.generate, r.generate)
+	suite.NoError(err)
+	suite.Equal(1, val)
+	suite.waitForRefresh("cat::an entry")
+
+	val, err = suite.mem.Get("cat", "an entry")
+	suite.NoError(err)
+	suite.Equal(1, val)
+}
+
+func (suite *MemCacheTestSuite) TestGetOrRefreshDoesNotRestoreDeletedValues() {
+	r := newRefresher()
+	_, err := suite.mem.GetOrRefresh("cat", "an entry", time.Millisecond, time.Hour, r.generate, r.generate)
+	suite.NoError(err)
+	time.Sleep(2 * time.Millisecond)
+
+	_, err = suite.mem.GetOrRefresh("cat", "an entry", time.Millisecond, time.Hour, r.generate, r.generate)
+	suite.NoError(err)
+	suite.mem.Delete(regexp.MustCompile("an entry"))
+	r.release <- struct{}{}
+	suite.waitForRefresh("cat::an entry")
+
+	val, err := suite.mem.Get("cat", "an entry")
+	suite.NoError(err)
+	suite.Nil(val)
+}
+
+func (suite *MemCacheTestSuite) TestGetOrRefreshExpire() {
+	suite.thing.On("update").Return(anything, nil)
+
+	suite.validate(suite.mem.GetOrRefresh("cat", "an entry", time.Nanosecond, time.Nanosecond, suite.update, suite.update))
+	time.Sleep(time.Millisecond)
+	_, ok := suite.mem.instance.Get("cat::an entry")
+	suite.False(ok)
+
+	suite.validate(suite.mem.GetOrRefresh("cat", "an entry", time.Second, time.Second, suite.update, suite.update))
+	suite.thing.AssertNumberOfCalls(suite.T(), "update", 2)
+}
+
 func (suite *MemCacheTestSuite) TestFlush() {
 	suite.mem.instance.Set("an entry", struct{}{}, time.Nanosecond)
 	time.Sleep(time.Nanosecond)
@@ -143,6 +310,59 @@ func (suite *MemCacheTestSuite) TestDelete() {
 	suite.NotNil(suite.mem.instance.Get("another entry"))
 }
 
+func (suite *MemCacheTestSuite) TestItems() {
+	suite.thing.On("update").Return(anything, nil)
+	start := time.Now()
+	suite.validate(suite.mem.GetOrUpdate("cat", "/a", time.Hour, false, suite.update))
+	suite.validate(suite.mem.GetOrRefresh("cat", "/a/b", time.Nanosecond, time.Hour, suite.update, suite.update))
+	_, err := suite.mem.GetOrUpdate("other", "/a", time.Hour, false, func() (interface{}, error) {
+		return nil, errors.New("an error")
+	})
+	suite.Error(err)
+	suite.mem.instance.Set("dog::/a", anything, time.Hour)
+	time.Sleep(time.Millisecond)
+
+	items := suite.mem.Items(regexp.MustCompile("^cat::"))
+	suite.Len(items, 2)
+	for _, item := range items {
+		suite.Equal("cat", item.Category)
+		suite.Equal(anything, item.Value)
+		suite.False(item.Created.Before(start))
+		suite.True(item.Expires.After(start))
+		suite.Equal(item.Key == "/a/b", item.Stale)
+	}
+
+	items = suite.mem.Items(regexp.MustCompile("^other::"))
+	if suite.Len(items, 1) {
+		suite.Equal("/a", items[0].Key)
+		suite.EqualError(items[0].Value.(error), "an error")
+	}
+
+	// Items that weren't set by the cache do not have a created time
+	items = suite.mem.Items(regexp.MustCompile("^dog::"))
+	if suite.Len(items, 1) {
+		suite.True(items[0].Created.IsZero())
+	}
+
+	suite.mem.Delete(regexp.MustCompile("^cat::/a$"))
+	suite.Len(suite.mem.Items(regexp.MustCompile("^cat::")), 1)
+}
+
+func (suite *MemCacheTestSuite) TestStats() {
+	suite.thing.On("update").Return(anything, nil)
+	suite.Empty(suite.mem.Stats())
+
+	suite.validate(suite.mem.GetOrUpdate("cat", "an entry", time.Hour, false, suite.update))
+	suite.validate(suite.mem.GetOrUpdate("cat", "an entry", time.Hour, false, suite.update))
+	suite.validate(suite.mem.GetOrRefresh("dog", "an entry", time.Hour, time.Hour, suite.update, suite.update))
+	_, _ = suite.mem.Get("cat", "an entry")
+
+	suite.Equal(map[string]CategoryStats{
+		"cat": {Hits: 1, Misses: 1},
+		"dog": {Hits: 0, Misses: 1},
+	}, suite.mem.Stats())
+}
+
 func TestMemCache(t *testing.T) {
 	suite.Run(t, new(MemCacheTestSuite))
 }
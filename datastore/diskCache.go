@@ -0,0 +1,251 @@
+package datastore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	// TODO: Once https://github.com/patrickmn/go-cache/pull/75
+	// is merged, go back to importing the main go-cache repo.
+	cache "github.com/ekinanp/go-cache"
+	log "github.com/sirupsen/logrus"
+)
+
+// diskCacheVersion is bumped whenever the cache file's format changes. Cache
+// files with a different version are ignored.
+const diskCacheVersion = 1
+
+// noExpiration is go-cache's TTL for items that never expire.
+const noExpiration = cache.NoExpiration
+
+func init() {
+	// Register the types that make up decoded JSON (like an entry's metadata)
+	// so that it can be persisted.
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+	gob.Register(&staleableValue{})
+}
+
+type diskCacheFile struct {
+	Version int
+	Items   map[string]diskCacheItem
+}
+
+type diskCacheItem struct {
+	// Value is the gob-encoded value
+	Value []byte
+	// Expiration is the item's expiration time in Unix nanoseconds. 0 means
+	// that the item never expires.
+	Expiration int64
+	// Created is when the item was cached in Unix nanoseconds. 0 means that
+	// it's unknown.
+	Created int64
+}
+
+// DiskCache is a MemCache that's persisted to a single file so that it
+// survives restarts. It's saved periodically and when it's closed.
+//
+// Only values that can be gob-encoded are persisted. That means a value's
+// concrete type must be registered with gob.Register if it's not a basic
+// type. A value can also implement gob.GobEncoder and return an error if it
+// can't be persisted, like a listing whose parent isn't a
+// plugin.PersistentParent. Other values (including cached errors) are only
+// kept in memory.
+type DiskCache struct {
+	*MemCache
+	path     string
+	saveMux  sync.Mutex
+	dirty    bool
+	dirtyMux sync.Mutex
+	stopCh   chan struct{}
+	stopped  sync.WaitGroup
+}
+
+var _ = Cache(&DiskCache{})
+
+// NewDiskCache creates a new DiskCache object that's persisted to the given
+// path. It loads any unexpired items that were previously saved to path, then
+// saves the cache every saveInterval. A saveInterval of 0 disables periodic
+// saves.
+func NewDiskCache(path string, saveInterval time.Duration) (*DiskCache, error) {
+	cache := &DiskCache{
+		MemCache: NewMemCache(),
+		path:     path,
+		stopCh:   make(chan struct{}),
+	}
+	if err := cache.load(); err != nil {
+		return nil, err
+	}
+	// Persist newly cached values on the next save.
+	cache.onSet = cache.markDirty
+
+	if saveInterval > 0 {
+		cache.stopped.Add(1)
+		go func() {
+			defer cache.stopped.Done()
+			ticker := time.NewTicker(saveInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := cache.Save(); err != nil {
+						log.Warnf("Failed to save the cache to %v: %v", cache.path, err)
+					}
+				case <-cache.stopCh:
+					return
+				}
+			}
+		}()
+	}
+	return cache, nil
+}
+
+func (cache *DiskCache) markDirty() {
+	cache.dirtyMux.Lock()
+	cache.dirty = true
+	cache.dirtyMux.Unlock()
+}
+
+// Flush deletes all items from the cache. The cache file's emptied on the
+// next save.
+func (cache *DiskCache) Flush() {
+	cache.MemCache.Flush()
+	cache.markDirty()
+}
+
+// Delete removes entries from the cache that match the provided regexp. They're
+// removed from the cache file on the next save.
+func (cache *DiskCache) Delete(matcher *regexp.Regexp) []string {
+	deleted := cache.MemCache.Delete(matcher)
+	if len(deleted) > 0 {
+		cache.markDirty()
+	}
+	return deleted
+}
+
+// Save writes the cache's unexpired items to its file if they've changed since
+// the last save.
+func (cache *DiskCache) Save() error {
+	cache.saveMux.Lock()
+	defer cache.saveMux.Unlock()
+
+	cache.dirtyMux.Lock()
+	dirty := cache.dirty
+	cache.dirty = false
+	cache.dirtyMux.Unlock()
+	if !dirty {
+		return nil
+	}
+
+	file := diskCacheFile{
+		Version: diskCacheVersion,
+		Items:   make(map[string]diskCacheItem),
+	}
+	for key, item := range cache.instance.Items() {
+		if _, ok := item.Object.(error); ok {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(&item.Object); err != nil {
+			log.Tracef("Not persisting cache entry %v: %v", key, err)
+			continue
+		}
+		diskItem := diskCacheItem{Value: buf.Bytes(), Expiration: item.Expiration}
+		if created, ok := cache.created.Load(key); ok {
+			diskItem.Created = created.(time.Time).UnixNano()
+		}
+		file.Items[key] = diskItem
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(file); err != nil {
+		cache.markDirty()
+		return err
+	}
+	if err := writeFileAtomically(cache.path, buf.Bytes()); err != nil {
+		cache.markDirty()
+		return err
+	}
+	log.Debugf("Saved %v cache entries to %v", len(file.Items), cache.path)
+	return nil
+}
+
+// Close stops the periodic saves, then saves the cache.
+func (cache *DiskCache) Close() error {
+	close(cache.stopCh)
+	cache.stopped.Wait()
+	return cache.Save()
+}
+
+func (cache *DiskCache) load() error {
+	content, err := ioutil.ReadFile(cache.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("could not read the cache from %v: %v", cache.path, err)
+	}
+
+	var file diskCacheFile
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&file); err != nil {
+		// A corrupt cache file isn't fatal, we'll just start with an empty cache.
+		log.Warnf("Ignoring the cache in %v, could not decode it: %v", cache.path, err)
+		return nil
+	}
+	if file.Version != diskCacheVersion {
+		log.Infof("Ignoring the cache in %v, it has an outdated format", cache.path)
+		return nil
+	}
+
+	now := time.Now().UnixNano()
+	loaded := 0
+	for key, item := range file.Items {
+		ttl := noExpiration
+		if item.Expiration > 0 {
+			if item.Expiration <= now {
+				continue
+			}
+			ttl = time.Duration(item.Expiration - now)
+		}
+		var value interface{}
+		if err := gob.NewDecoder(bytes.NewReader(item.Value)).Decode(&value); err != nil {
+			log.Debugf("Not loading cache entry %v: %v", key, err)
+			continue
+		}
+		cache.instance.Set(key, value, ttl)
+		if item.Created > 0 {
+			cache.created.Store(key, time.Unix(0, item.Created))
+		}
+		loaded++
+	}
+	log.Debugf("Loaded %v cache entries from %v", loaded, cache.path)
+	return nil
+}
+
+// writeFileAtomically writes data to a temporary file then renames it to path
+// so that readers never see a partially written file.
+func writeFileAtomically(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
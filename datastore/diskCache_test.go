@@ -0,0 +1,147 @@
+package datastore
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DiskCacheTestSuite struct {
+	suite.Suite
+	dir  string
+	path string
+}
+
+func (suite *DiskCacheTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "wash-disk-cache")
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.dir = dir
+	suite.path = filepath.Join(dir, "cache")
+}
+
+func (suite *DiskCacheTestSuite) TearDownTest() {
+	suite.NoError(os.RemoveAll(suite.dir))
+}
+
+func (suite *DiskCacheTestSuite) newCache() *DiskCache {
+	cache, err := NewDiskCache(suite.path, 0)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	return cache
+}
+
+func (suite *DiskCacheTestSuite) set(cache *DiskCache, key string, ttl time.Duration, value interface{}, err error) {
+	_, _ = cache.GetOrUpdate("cat", key, ttl, false, func() (interface{}, error) {
+		return value, err
+	})
+}
+
+func (suite *DiskCacheTestSuite) TestNewDiskCacheWithoutFile() {
+	cache := suite.newCache()
+	suite.Empty(cache.instance.Items())
+	suite.NoError(cache.Close())
+	// Nothing changed, so there's nothing to save.
+	_, err := os.Stat(suite.path)
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *DiskCacheTestSuite) TestSaveAndLoad() {
+	cache := suite.newCache()
+	suite.set(cache, "string", time.Hour, "a value", nil)
+	suite.set(cache, "object", time.Hour, map[string]interface{}{"a": []interface{}{"b", 1.0}}, nil)
+	suite.set(cache, "expiring", time.Millisecond, "gone", nil)
+	suite.NoError(cache.Close())
+
+	time.Sleep(5 * time.Millisecond)
+	cache = suite.newCache()
+	defer func() { suite.NoError(cache.Close()) }()
+
+	value, err := cache.Get("cat", "string")
+	if suite.NoError(err) {
+		suite.Equal("a value", value)
+	}
+	value, err = cache.Get("cat", "object")
+	if suite.NoError(err) {
+		suite.Equal(map[string]interface{}{"a": []interface{}{"b", 1.0}}, value)
+	}
+	value, err = cache.Get("cat", "expiring")
+	suite.NoError(err)
+	suite.Nil(value)
+
+	// The items' created times are also persisted
+	for _, item := range cache.Items(regexp.MustCompile(".*")) {
+		suite.False(item.Created.IsZero())
+	}
+}
+
+func (suite *DiskCacheTestSuite) TestSaveSkipsErrorsAndUnencodableValues() {
+	type unregistered struct{ ch chan int }
+
+	cache := suite.newCache()
+	suite.set(cache, "error", time.Hour, nil, errors.New("failed"))
+	suite.set(cache, "unencodable", time.Hour, unregistered{}, nil)
+	suite.set(cache, "string", time.Hour, "a value", nil)
+	suite.NoError(cache.Close())
+
+	cache = suite.newCache()
+	defer func() { suite.NoError(cache.Close()) }()
+	suite.Len(cache.instance.Items(), 1)
+	value, err := cache.Get("cat", "string")
+	if suite.NoError(err) {
+		suite.Equal("a value", value)
+	}
+}
+
+func (suite *DiskCacheTestSuite) TestDeleteRemovesItemsOnSave() {
+	cache := suite.newCache()
+	suite.set(cache, "foo", time.Hour, "foo", nil)
+	suite.set(cache, "bar", time.Hour, "bar", nil)
+	suite.NoError(cache.Save())
+
+	suite.Equal([]string{"cat::foo"}, cache.Delete(regexp.MustCompile("foo")))
+	suite.NoError(cache.Close())
+
+	cache = suite.newCache()
+	defer func() { suite.NoError(cache.Close()) }()
+	value, err := cache.Get("cat", "foo")
+	suite.NoError(err)
+	suite.Nil(value)
+	value, err = cache.Get("cat", "bar")
+	if suite.NoError(err) {
+		suite.Equal("bar", value)
+	}
+}
+
+func (suite *DiskCacheTestSuite) TestFlushEmptiesFileOnSave() {
+	cache := suite.newCache()
+	suite.set(cache, "foo", time.Hour, "foo", nil)
+	suite.NoError(cache.Save())
+
+	cache.Flush()
+	suite.NoError(cache.Close())
+
+	cache = suite.newCache()
+	defer func() { suite.NoError(cache.Close()) }()
+	suite.Empty(cache.instance.Items())
+}
+
+func (suite *DiskCacheTestSuite) TestCorruptFileIsIgnored() {
+	suite.NoError(ioutil.WriteFile(suite.path, []byte("not a cache"), 0600))
+
+	cache := suite.newCache()
+	defer func() { suite.NoError(cache.Close()) }()
+	suite.Empty(cache.instance.Items())
+}
+
+func TestDiskCache(t *testing.T) {
+	suite.Run(t, new(DiskCacheTestSuite))
+}
@@ -7,6 +7,7 @@ import (
 	"os/user"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"bazil.org/fuse"
@@ -233,3 +234,12 @@ func ServeFuseFS(
 
 	return stopCh, stoppedCh, nil
 }
+
+// toFUSEErr converts an error that's returned by one of the plugin.<Method>
+// wrappers into its equivalent FUSE error.
+func toFUSEErr(err error) error {
+	if plugin.IsPolicyDeniedErr(err) {
+		return syscall.EPERM
+	}
+	return err
+}
@@ -3,7 +3,9 @@ package fuse
 import (
 	"context"
 	"os"
+	"sync"
 	"syscall"
+	"unsafe"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
@@ -20,13 +22,16 @@ type dir struct {
 
 var _ fs.Node = (*dir)(nil)
 var _ = fs.NodeRequestLookuper(&dir{})
-var _ = fs.HandleReadDirAller(&dir{})
+var _ = fs.NodeOpener(&dir{})
+var _ = fs.NodeMkdirer(&dir{})
+var _ = fs.NodeCreater(&dir{})
+var _ = fs.NodeRenamer(&dir{})
 
 func newDir(p *dir, e plugin.Parent) *dir {
 	return &dir{newFuseNode("d", p, e)}
 }
 
-func (d *dir) children(ctx context.Context) (*plugin.EntryMap, error) {
+func (d *dir) parentEntry(ctx context.Context) (plugin.Parent, error) {
 	// Check for an updated entry in case it has static state.
 	updatedEntry, err := d.refind(ctx)
 	if err != nil {
@@ -34,14 +39,32 @@ func (d *dir) children(ctx context.Context) (*plugin.EntryMap, error) {
 		return nil, err
 	}
 
-	// Cache List requests. FUSE often lists the contents then immediately calls find on individual entries.
 	if plugin.ListAction().IsSupportedOn(updatedEntry) {
-		return plugin.ListWithAnalytics(ctx, updatedEntry.(plugin.Parent))
+		return updatedEntry.(plugin.Parent), nil
 	}
 
 	return nil, syscall.ENOENT
 }
 
+func (d *dir) children(ctx context.Context) (*plugin.EntryMap, error) {
+	parent, err := d.parentEntry(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache List requests. FUSE often lists the contents then immediately calls find on individual entries.
+	return plugin.ListWithAnalytics(ctx, parent)
+}
+
+// inode returns the directory's inode. It matches the inode that FUSE
+// generates for the directory when it's looked up.
+func (d *dir) inode() uint64 {
+	if d.parent == nil {
+		return 1
+	}
+	return fs.GenerateDynamicInode(d.parent.inode(), plugin.CName(d.entry))
+}
+
 // Lookup searches a directory for children.
 func (d *dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.LookupResponse) (fs.Node, error) {
 	// Find is only occasionally useful and happens a lot. Log it to debug like other activity, but
@@ -71,30 +94,11 @@ func (d *dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.Lo
 	return newFile(d, entry), nil
 }
 
-// ReadDirAll lists all children of the directory.
-func (d *dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
-	activity.Record(ctx, "FUSE: List %v", d)
-
-	entries, err := d.children(ctx)
-	if err != nil {
-		activity.Warnf(ctx, "FUSE: List %v errored: %v", d, err)
-		return nil, err
-	}
-
-	res := make([]fuse.Dirent, 0, entries.Len())
-	entries.Range(func(cname string, entry plugin.Entry) bool {
-		var de fuse.Dirent
-		de.Name = cname
-		if plugin.ListAction().IsSupportedOn(entry) {
-			de.Type = fuse.DT_Dir
-		} else {
-			de.Type = fuse.DT_File
-		}
-		res = append(res, de)
-		return true
-	})
-	activity.Record(ctx, "FUSE: Listed in %v: %+v", d, res)
-	return res, nil
+// Open opens the directory for reading. The returned handle serves the
+// directory's children as they're listed so that large directories can be
+// read before their listing's finished.
+func (d *dir) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
+	return &dirHandle{d: d}, nil
 }
 
 func (d *dir) Attr(ctx context.Context, a *fuse.Attr) error {
@@ -111,7 +115,11 @@ func (d *dir) Attr(ctx context.Context, a *fuse.Attr) error {
 	// is not strictly necessary for the other FUSE operations, we choose to
 	// leave it alone.
 
-	applyAttr(a, plugin.Attributes(entry), os.ModeDir|0550)
+	mode := os.FileMode(0550)
+	if plugin.CreateAction().IsSupportedOn(entry) {
+		mode |= 0220
+	}
+	applyAttr(a, plugin.Attributes(entry), os.ModeDir|mode)
 	// Attr is not a particularly interesting call and happens a lot. Log it to debug like other
 	// activity, but leave it out of activity because it introduces history entries for lots of
 	// miscellaneous shell activity.
@@ -122,3 +130,312 @@ func (d *dir) Attr(ctx context.Context, a *fuse.Attr) error {
 	}
 	return nil
 }
+
+// create creates a new child of the directory.
+func (d *dir) create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	// Check for an updated entry in case it has static state.
+	updatedEntry, err := d.refind(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !plugin.CreateAction().IsSupportedOn(updatedEntry) {
+		return nil, syscall.ENOTSUP
+	}
+	return plugin.CreateWithAnalytics(ctx, updatedEntry.(plugin.Creatable), name, isParent)
+}
+
+// Mkdir creates a new child directory.
+func (d *dir) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (fs.Node, error) {
+	activity.Record(ctx, "FUSE: Mkdir %v in %v", req.Name, d)
+
+	entry, err := d.create(ctx, req.Name, true)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Mkdir %v in %v errored: %v", req.Name, d, err)
+		return nil, toFUSEErr(err)
+	}
+
+	childdir := newDir(d, entry.(plugin.Parent))
+	activity.Record(ctx, "FUSE: Created directory %v", childdir)
+	return childdir, nil
+}
+
+// Create creates and opens a new child file.
+func (d *dir) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
+	activity.Record(ctx, "FUSE: Create %v in %v", req.Name, d)
+
+	entry, err := d.create(ctx, req.Name, false)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Create %v in %v errored: %v", req.Name, d, err)
+		return nil, nil, toFUSEErr(err)
+	}
+
+	f := newFile(d, entry)
+	activity.Record(ctx, "FUSE: Created file %v", f)
+	openReq := &fuse.OpenRequest{Header: req.Header, Dir: false, Flags: req.Flags}
+	handle, err := f.Open(ctx, openReq, &resp.OpenResponse)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, handle, nil
+}
+
+// Rename renames a child of the directory, moving it into newDir.
+func (d *dir) Rename(ctx context.Context, req *fuse.RenameRequest, newDir fs.Node) error {
+	dest := newDir.(*dir)
+	activity.Record(ctx, "FUSE: Rename %v/%v to %v/%v", d, req.OldName, dest, req.NewName)
+
+	entries, err := d.children(ctx)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Rename %v in %v errored: %v", req.OldName, d, err)
+		return err
+	}
+	entry, ok := entries.Load(req.OldName)
+	if !ok {
+		activity.Warnf(ctx, "FUSE: Rename %v in %v errored: not found", req.OldName, d)
+		return syscall.ENOENT
+	}
+	if !plugin.RenameAction().IsSupportedOn(entry) {
+		activity.Warnf(ctx, "FUSE: Rename unsupported on %v/%v", d, req.OldName)
+		return syscall.ENOTSUP
+	}
+
+	// Check for an updated entry in case it has static state.
+	newParent, err := dest.refind(ctx)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Rename %v/%v to %v/%v errored: %v", d, req.OldName, dest, req.NewName, err)
+		return err
+	}
+
+	_, err = plugin.RenameWithAnalytics(ctx, entry.(plugin.Renamable), newParent.(plugin.Parent), req.NewName)
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Rename %v/%v to %v/%v errored: %v", d, req.OldName, dest, req.NewName, err)
+		return toFUSEErr(err)
+	}
+	return nil
+}
+
+// ==== FUSE Directory Handle ====
+
+type dirHandle struct {
+	d       *dir
+	mux     sync.Mutex
+	listing *dirListing
+}
+
+var _ = fs.HandleReader(&dirHandle{})
+var _ = fs.HandleReleaser(&dirHandle{})
+
+// Read reads the directory's children starting at req.Offset. It waits
+// until those children have been listed.
+func (h *dirHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	h.mux.Lock()
+	if req.Offset == 0 || h.listing == nil {
+		// Offset 0 means the directory's being read from the start (e.g. via
+		// rewinddir), so start a new listing to refresh its contents.
+		if h.listing != nil {
+			h.listing.cancel()
+		}
+		h.listing = h.d.startListing(ctx)
+	}
+	listing := h.listing
+	h.mux.Unlock()
+
+	data, err := listing.waitFor(ctx, req.Offset)
+	if err != nil {
+		return err
+	}
+	if len(data) > req.Size {
+		data = data[:req.Size]
+	}
+	resp.Data = resp.Data[:copy(resp.Data[:req.Size], data)]
+	return nil
+}
+
+// Release stops any in-progress listing.
+func (h *dirHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if h.listing != nil {
+		h.listing.cancel()
+	}
+	return nil
+}
+
+// maxUnreadDirents is the most bytes of encoded dirents that a listing buffers
+// before it waits for them to be read.
+const maxUnreadDirents = 64 * 1024
+
+// dirListing accumulates the encoded dirents of a directory's children as
+// each page of them is listed. Dirents are dropped once they've been read
+// so that large directories aren't kept in memory, and listing waits for
+// them to be read once maxUnreadDirents are buffered.
+type dirListing struct {
+	mux sync.Mutex
+	// start is the listing offset of data's first byte, and end is the
+	// listing offset after its last byte.
+	start int64
+	end   int64
+	data  []byte
+	done  bool
+	err   error
+	// changed is closed when data's appended or dropped, or the listing
+	// finishes.
+	changed chan struct{}
+	cancel  context.CancelFunc
+}
+
+// startListing lists the directory's children in the background. The
+// listing outlives the request that started it, so it only keeps the
+// request context's values.
+func (d *dir) startListing(ctx context.Context) *dirListing {
+	activity.Record(ctx, "FUSE: List %v", d)
+
+	listCtx, cancel := context.WithCancel(plugin.DetachedContext(ctx))
+	listing := &dirListing{cancel: cancel, changed: make(chan struct{})}
+	go func() {
+		defer cancel()
+		parent, err := d.parentEntry(listCtx)
+		if err != nil {
+			listing.finish(err)
+			return
+		}
+
+		inode := d.inode()
+		count := 0
+		err = plugin.ListPagesWithAnalytics(listCtx, parent, func(page *plugin.EntryMap) error {
+			if err := listCtx.Err(); err != nil {
+				return err
+			}
+			dirents := make([]fuse.Dirent, 0, page.Len())
+			page.Range(func(cname string, entry plugin.Entry) bool {
+				de := fuse.Dirent{
+					Inode: fs.GenerateDynamicInode(inode, cname),
+					Name:  cname,
+				}
+				if plugin.ListAction().IsSupportedOn(entry) {
+					de.Type = fuse.DT_Dir
+				} else {
+					de.Type = fuse.DT_File
+				}
+				dirents = append(dirents, de)
+				return true
+			})
+			if err := listing.append(listCtx, dirents); err != nil {
+				return err
+			}
+			count += page.Len()
+			return nil
+		})
+		if err != nil {
+			activity.Warnf(listCtx, "FUSE: List %v errored: %v", d, err)
+		} else {
+			activity.Record(listCtx, "FUSE: Listed %v items in %v", count, d)
+		}
+		listing.finish(err)
+	}()
+	return listing
+}
+
+// append encodes dirents onto the listing. It waits for the listing's data
+// to be read while maxUnreadDirents are buffered, so it returns ctx's error
+// if ctx is cancelled.
+func (l *dirListing) append(ctx context.Context, dirents []fuse.Dirent) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	for _, de := range dirents {
+		for len(l.data) >= maxUnreadDirents {
+			l.notify()
+			changed := l.changed
+			l.mux.Unlock()
+			select {
+			case <-changed:
+				l.mux.Lock()
+			case <-ctx.Done():
+				l.mux.Lock()
+				return ctx.Err()
+			}
+		}
+		l.data, l.end = appendDirent(l.data, l.end, de)
+	}
+	l.notify()
+	return nil
+}
+
+func (l *dirListing) finish(err error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.done = true
+	l.err = err
+	l.notify()
+}
+
+// notify wakes up the readers that are waiting for the listing to change.
+// The caller must hold l.mux.
+func (l *dirListing) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// waitFor waits until there's data past offset or the listing's finished,
+// then returns the data from offset onwards. It only returns the listing's
+// error once all the listed data's been read. The data before offset is
+// dropped because it's been read; the kernel reads a directory sequentially
+// unless it's rewound, which starts a new listing.
+func (l *dirListing) waitFor(ctx context.Context, offset int64) ([]byte, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if offset < l.start || offset > l.end {
+		return nil, syscall.EINVAL
+	}
+	if read := offset - l.start; read > 0 {
+		// Copy the unread data so that the read data can be freed, then let
+		// the listing continue if it's waiting for the data to be read.
+		l.data = append([]byte(nil), l.data[read:]...)
+		l.start = offset
+		l.notify()
+	}
+	for l.end <= offset && !l.done {
+		changed := l.changed
+		l.mux.Unlock()
+		select {
+		case <-changed:
+			l.mux.Lock()
+		case <-ctx.Done():
+			l.mux.Lock()
+			return nil, syscall.EINTR
+		}
+	}
+	if l.end <= offset {
+		return nil, l.err
+	}
+	return l.data, nil
+}
+
+// fuseDirent is the header of the kernel's struct fuse_dirent. It's followed
+// by the dirent's name, padded to a multiple of 8 bytes.
+type fuseDirent struct {
+	ino     uint64
+	off     uint64
+	namelen uint32
+	typ     uint32
+}
+
+const fuseDirentSize = unsafe.Sizeof(fuseDirent{})
+
+// appendDirent encodes de onto data, where end is the listing offset after
+// data's last byte. It returns the listing offset after de, which is also
+// encoded as de's offset because it's where the next dirent starts.
+func appendDirent(data []byte, end int64, de fuse.Dirent) ([]byte, int64) {
+	size := (int64(fuseDirentSize) + int64(len(de.Name)) + 7) &^ 7
+	header := fuseDirent{
+		ino:     de.Inode,
+		off:     uint64(end + size),
+		namelen: uint32(len(de.Name)),
+		typ:     uint32(de.Type),
+	}
+	n := len(data)
+	data = append(data, (*[fuseDirentSize]byte)(unsafe.Pointer(&header))[:]...)
+	data = append(data, de.Name...)
+	data = append(data, make([]byte, size-int64(len(data)-n))...)
+	return data, end + size
+}
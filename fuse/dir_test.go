@@ -0,0 +1,130 @@
+package fuse
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"bazil.org/fuse"
+	"github.com/stretchr/testify/suite"
+)
+
+type dirListingTestSuite struct {
+	suite.Suite
+}
+
+func newTestDirListing() *dirListing {
+	return &dirListing{cancel: func() {}, changed: make(chan struct{})}
+}
+
+func direntsOf(names ...string) []fuse.Dirent {
+	var dirents []fuse.Dirent
+	for i, name := range names {
+		dirents = append(dirents, fuse.Dirent{Inode: uint64(i + 2), Name: name, Type: fuse.DT_File})
+	}
+	return dirents
+}
+
+func (suite *dirListingTestSuite) TestWaitFor_DropsReadData() {
+	l := newTestDirListing()
+	suite.NoError(l.append(context.Background(), direntsOf("a", "b")))
+	var expected []byte
+	for _, de := range direntsOf("a", "b", "c") {
+		expected = fuse.AppendDirent(expected, de)
+	}
+	firstLen := int64(len(fuse.AppendDirent(nil, direntsOf("a")[0])))
+
+	data, err := l.waitFor(context.Background(), firstLen)
+	suite.NoError(err)
+	suite.Equal(expected[firstLen:2*firstLen], data)
+
+	// The dirents that are appended after data's been dropped keep their
+	// offsets relative to the start of the listing.
+	suite.NoError(l.append(context.Background(), direntsOf("a", "b", "c")[2:]))
+	data, err = l.waitFor(context.Background(), firstLen)
+	suite.NoError(err)
+	suite.Equal(expected[firstLen:], data)
+	suite.Equal(firstLen, l.start)
+
+	// Read data can't be read again
+	_, err = l.waitFor(context.Background(), 0)
+	suite.Equal(syscall.EINVAL, err)
+}
+
+func (suite *dirListingTestSuite) TestWaitFor_WaitsForData() {
+	l := newTestDirListing()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		suite.NoError(l.append(context.Background(), direntsOf("a")))
+	}()
+	data, err := l.waitFor(context.Background(), 0)
+	suite.NoError(err)
+	suite.Equal(fuse.AppendDirent(nil, direntsOf("a")[0]), data)
+}
+
+func (suite *dirListingTestSuite) TestWaitFor_ReturnsErrorAfterData() {
+	l := newTestDirListing()
+	suite.NoError(l.append(context.Background(), direntsOf("a")))
+	l.finish(errors.New("failed"))
+
+	data, err := l.waitFor(context.Background(), 0)
+	suite.NoError(err)
+	_, err = l.waitFor(context.Background(), int64(len(data)))
+	suite.EqualError(err, "failed")
+}
+
+func (suite *dirListingTestSuite) TestWaitFor_Interrupted() {
+	l := newTestDirListing()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := l.waitFor(ctx, 0)
+	suite.Equal(syscall.EINTR, err)
+}
+
+func (suite *dirListingTestSuite) TestAppend_WaitsForDataToBeRead() {
+	l := newTestDirListing()
+	direntLen := int64(len(fuse.AppendDirent(nil, direntsOf("a")[0])))
+	names := make([]string, maxUnreadDirents/direntLen+1)
+	for i := range names {
+		names[i] = "a"
+	}
+
+	appended := make(chan error)
+	go func() {
+		appended <- l.append(context.Background(), direntsOf(names...))
+	}()
+	select {
+	case <-appended:
+		suite.Fail("append should wait once maxUnreadDirents are buffered")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Reading past the first dirent drops it, which lets the last one be appended.
+	_, err := l.waitFor(context.Background(), direntLen)
+	suite.NoError(err)
+	suite.NoError(<-appended)
+	data, err := l.waitFor(context.Background(), direntLen)
+	suite.NoError(err)
+	suite.Len(data, int(maxUnreadDirents))
+}
+
+func (suite *dirListingTestSuite) TestAppend_Cancelled() {
+	l := newTestDirListing()
+	l.data = make([]byte, maxUnreadDirents)
+	l.end = maxUnreadDirents
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	suite.Equal(context.Canceled, l.append(ctx, direntsOf("a")))
+}
+
+func TestDirListing(t *testing.T) {
+	suite.Run(t, new(dirListingTestSuite))
+}
@@ -33,6 +33,11 @@ import (
 //   - `data` stores only the data to be written, and is not initialized from `plugin.Read`
 //   - the file's size will be reported as its readable size; it will not reflect calls to `write`
 //
+// A *block-writable* entry is file-like, but its writes aren't buffered in `data`. Instead, each
+// write and size change is passed to a `plugin.BlockWriter` that's opened on the first one, and
+// the writer's closed to commit them on `Flush`. Reads are served by `plugin.Read`, so they return
+// the committed content; block-writable entries can't be opened with a ReadWrite handle.
+//
 // Note that writes only result in calling `plugin.Write` when a file handle is closed by the OS
 // (triggering a call to `Flush` as noted in https://libfuse.github.io/doxygen/structfuse__operations.html#ad4ec9c309072a92dd82ddb20efa4ab14)
 // Writing with multiple handles will be protected by `mux`, but all writes will operate on the
@@ -50,6 +55,8 @@ type file struct {
 	writers map[fuse.HandleID]struct{}
 	// Only valid if len(writers) > 0
 	data []byte
+	// Only set while writing a block-writable entry
+	blockWriter plugin.BlockWriter
 	// Size of readable content, necessary for *non-file-like* entries
 	readSize uint64
 }
@@ -58,6 +65,10 @@ func newFile(p *dir, e plugin.Entry) *file {
 	return &file{fuseNode: newFuseNode("f", p, e), writers: make(map[fuse.HandleID]struct{})}
 }
 
+func (f *file) isBlockWritable() bool {
+	return plugin.IsBlockWritable(f.entry)
+}
+
 func (f *file) isFileLikeEntry() bool {
 	attr := plugin.Attributes(f.entry)
 	return attr.HasSize()
@@ -152,6 +163,12 @@ func (f *file) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenR
 		return nil, syscall.ENOTSUP
 	}
 
+	if f.isBlockWritable() && req.Flags.IsReadWrite() {
+		// Reads return the committed content, so they wouldn't reflect in-progress writes.
+		activity.Warnf(ctx, "FUSE: Open Read/Write is not supported on block-writable entry %v", f)
+		return nil, syscall.ENOTSUP
+	}
+
 	if !f.isFileLikeEntry() && req.Flags.IsReadWrite() {
 		// Error ReadWrite on non-file-like entries because it probably won't work well.
 		activity.Warnf(ctx, "FUSE: Open Read/Write is not supported on non-file-like entry %v", f)
@@ -219,7 +236,7 @@ func (f *file) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadR
 	f.mux.Lock()
 	defer f.mux.Unlock()
 
-	if f.useLocalContent() {
+	if f.useLocalContent() && !f.isBlockWritable() {
 		fuseutil.HandleRead(req, resp, f.data)
 	} else {
 		data, err := plugin.ReadWithAnalytics(ctx, f.entry, int64(req.Size), req.Offset)
@@ -244,6 +261,22 @@ func (f *file) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.Wri
 	// Ensure handle is in list of writers.
 	f.writers[req.Handle] = struct{}{}
 
+	if f.isBlockWritable() {
+		if err := f.openBlockWriter(ctx); err != nil {
+			return err
+		}
+		if err := f.blockWriter.WriteAt(ctx, req.Data, req.Offset); err != nil {
+			activity.Warnf(ctx, "FUSE: Error writing %v bytes at %v to %v: %v", len(req.Data), req.Offset, f, err)
+			return toFUSEErr(err)
+		}
+		if newLen := uint64(req.Offset) + uint64(len(req.Data)); f.readSize < newLen {
+			f.readSize = newLen
+		}
+		resp.Size = len(req.Data)
+		activity.Record(ctx, "FUSE: Write %v bytes starting at %v from %v", resp.Size, req.Offset, f)
+		return nil
+	}
+
 	if f.isFileLikeEntry() {
 		// If starting write beyond the current length, read to fill it in.
 		if start := int64(len(f.data)); req.Offset > start {
@@ -271,6 +304,20 @@ func (f *file) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.Wri
 	return nil
 }
 
+// openBlockWriter opens a writer for a block-writable entry if one isn't already open.
+func (f *file) openBlockWriter(ctx context.Context) error {
+	if f.blockWriter != nil {
+		return nil
+	}
+	writer, err := plugin.OpenWriterWithAnalytics(ctx, f.entry.(plugin.BlockWritable))
+	if err != nil {
+		activity.Warnf(ctx, "FUSE: Error opening a writer for %v: %v", f, err)
+		return toFUSEErr(err)
+	}
+	f.blockWriter = writer
+	return nil
+}
+
 func (f *file) load(ctx context.Context, start, end int64) ([]byte, error) {
 	if !f.isFileLikeEntry() {
 		panic("load called on non-file-like entry")
@@ -297,6 +344,20 @@ func (f *file) Flush(ctx context.Context, req *fuse.FlushRequest) error {
 		return nil
 	}
 
+	if f.isBlockWritable() {
+		// Commit the writes if there were any since the last Flush.
+		if f.blockWriter == nil {
+			return nil
+		}
+		err := f.blockWriter.Close(ctx)
+		f.blockWriter = nil
+		if err != nil {
+			activity.Warnf(ctx, "FUSE: Error writing %v: %v", f, err)
+			return toFUSEErr(err)
+		}
+		return nil
+	}
+
 	// If this handle had an open writer, write current data.
 	dataLen := int64(len(f.data))
 	if f.isFileLikeEntry() {
@@ -324,7 +385,7 @@ func (f *file) Flush(ctx context.Context, req *fuse.FlushRequest) error {
 
 	if err := plugin.WriteWithAnalytics(ctx, f.entry.(plugin.Writable), f.data); err != nil {
 		activity.Warnf(ctx, "FUSE: Error writing %v: %v", f, err)
-		return err
+		return toFUSEErr(err)
 	}
 
 	// Non-file-like entries start from scratch on each Write operation, and have their cache
@@ -353,7 +414,16 @@ func (f *file) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse
 		// and changing the file size is similar to initiating a write.
 		f.writers[req.Handle] = struct{}{}
 
-		if f.isFileLikeEntry() {
+		if f.isBlockWritable() {
+			if err := f.openBlockWriter(ctx); err != nil {
+				return err
+			}
+			if err := f.blockWriter.Truncate(ctx, int64(req.Size)); err != nil {
+				activity.Warnf(ctx, "FUSE: Error truncating %v to %v bytes: %v", f, req.Size, err)
+				return toFUSEErr(err)
+			}
+			f.readSize = req.Size
+		} else if f.isFileLikeEntry() {
 			// Update known size.
 			f.readSize = req.Size
 		} else {
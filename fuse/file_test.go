@@ -2,6 +2,7 @@ package fuse
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"bazil.org/fuse"
@@ -647,6 +648,83 @@ func (suite *fileTestSuite) TestWriteRead_NonFileLikeEntry() {
 	m.AssertExpectations(suite.T())
 }
 
+func (suite *fileTestSuite) TestBlockWrite() {
+	m := plugintest.NewMockBlockReadBlockWrite()
+	m.Attributes().SetSize(4)
+	w := &plugintest.MockBlockWriter{}
+	m.On("OpenWriter", suite.ctx).Return(w, nil).Once()
+	w.On("Truncate", suite.ctx, int64(0)).Return(nil).Once()
+	w.On("WriteAt", suite.ctx, []byte("hello"), int64(0)).Return(nil).Once()
+	w.On("WriteAt", suite.ctx, []byte(" there"), int64(5)).Return(nil).Once()
+	// Called on the first Flush only.
+	w.On("Close", suite.ctx).Return(nil).Once()
+
+	f := newFile(nil, m)
+	var resp fuse.OpenResponse
+	_, err := f.Open(suite.ctx, &fuse.OpenRequest{Flags: fuse.OpenReadWrite}, &resp)
+	suite.Error(err)
+	handle, err := f.Open(suite.ctx, &fuse.OpenRequest{Flags: fuse.OpenWriteOnly}, &resp)
+	if !suite.NoError(err) || !suite.assertFileHandle(handle) {
+		suite.FailNow("Unusable handle")
+	}
+
+	setReq := fuse.SetattrRequest{Valid: fuse.SetattrHandle | fuse.SetattrSize, Handle: 1, Size: 0}
+	var setResp fuse.SetattrResponse
+	suite.NoError(f.Setattr(suite.ctx, &setReq, &setResp))
+	suite.Equal(uint64(0), setResp.Attr.Size)
+
+	for _, req := range []fuse.WriteRequest{
+		{Offset: 0, Data: []byte("hello"), Handle: 1},
+		{Offset: 5, Data: []byte(" there"), Handle: 1},
+	} {
+		var writeResp fuse.WriteResponse
+		suite.NoError(handle.(fs.HandleWriter).Write(suite.ctx, &req, &writeResp))
+		suite.Equal(len(req.Data), writeResp.Size)
+	}
+
+	// The size reflects the writes before they're committed.
+	var attr fuse.Attr
+	suite.NoError(f.Attr(suite.ctx, &attr))
+	suite.Equal(uint64(11), attr.Size)
+
+	err = handle.(fs.HandleFlusher).Flush(suite.ctx, &fuse.FlushRequest{Handle: 1})
+	suite.NoError(err)
+
+	relReq := fuse.ReleaseRequest{ReleaseFlags: fuse.ReleaseFlush, Handle: 1}
+	err = handle.(fs.HandleReleaser).Release(suite.ctx, &relReq)
+	suite.NoError(err)
+
+	m.AssertExpectations(suite.T())
+	w.AssertExpectations(suite.T())
+}
+
+func (suite *fileTestSuite) TestBlockWrite_Error() {
+	m := plugintest.NewMockBlockReadBlockWrite()
+	m.Attributes().SetSize(4)
+	w := &plugintest.MockBlockWriter{}
+	m.On("OpenWriter", suite.ctx).Return(w, nil).Once()
+	w.On("WriteAt", suite.ctx, []byte("hello"), int64(2)).Return(fmt.Errorf("writes must be sequential")).Once()
+	w.On("Close", suite.ctx).Return(fmt.Errorf("a write failed")).Once()
+
+	f := newFile(nil, m)
+	var resp fuse.OpenResponse
+	handle, err := f.Open(suite.ctx, &fuse.OpenRequest{Flags: fuse.OpenWriteOnly}, &resp)
+	if !suite.NoError(err) || !suite.assertFileHandle(handle) {
+		suite.FailNow("Unusable handle")
+	}
+
+	writeReq := fuse.WriteRequest{Offset: 2, Data: []byte("hello"), Handle: 1}
+	var writeResp fuse.WriteResponse
+	err = handle.(fs.HandleWriter).Write(suite.ctx, &writeReq, &writeResp)
+	suite.EqualError(err, "writes must be sequential")
+
+	err = handle.(fs.HandleFlusher).Flush(suite.ctx, &fuse.FlushRequest{Handle: 1})
+	suite.EqualError(err, "a write failed")
+
+	m.AssertExpectations(suite.T())
+	w.AssertExpectations(suite.T())
+}
+
 func TestFile(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	suite.Run(t, &fileTestSuite{ctx: ctx})
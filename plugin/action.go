@@ -8,6 +8,7 @@ const (
 	UnsupportedSignature MethodSignature = iota
 	DefaultSignature
 	BlockReadableSignature
+	BlockWritableSignature
 )
 
 // Action represents a Wash action.
@@ -69,6 +70,9 @@ var streamAction = newAction("stream", "Streamable", func(e Entry) MethodSignatu
 })
 
 var writeAction = newAction("write", "Writable", func(e Entry) MethodSignature {
+	if _, ok := e.(BlockWritable); ok {
+		return BlockWritableSignature
+	}
 	if _, ok := e.(Writable); ok {
 		return DefaultSignature
 	}
@@ -96,6 +100,20 @@ var signalAction = newAction("signal", "Signalable", func(e Entry) MethodSignatu
 	return UnsupportedSignature
 })
 
+var createAction = newAction("create", "Creatable", func(e Entry) MethodSignature {
+	if _, ok := e.(Creatable); ok {
+		return DefaultSignature
+	}
+	return UnsupportedSignature
+})
+
+var renameAction = newAction("rename", "Renamable", func(e Entry) MethodSignature {
+	if _, ok := e.(Renamable); ok {
+		return DefaultSignature
+	}
+	return UnsupportedSignature
+})
+
 // ListAction represents the list action
 func ListAction() Action {
 	return listAction
@@ -131,6 +149,16 @@ func SignalAction() Action {
 	return signalAction
 }
 
+// CreateAction represents the create action
+func CreateAction() Action {
+	return createAction
+}
+
+// RenameAction represents the rename action
+func RenameAction() Action {
+	return renameAction
+}
+
 // Actions returns all of the available Wash actions as a map
 // of <action_name> => <action_object>.
 func Actions() map[string]Action {
@@ -14,6 +14,13 @@ func ListWithAnalytics(ctx context.Context, p Parent) (*EntryMap, error) {
 	return List(ctx, p)
 }
 
+// ListPagesWithAnalytics is a wrapper to plugin.ListPages. Use it when you need to
+// report a 'List' invocation to analytics. Otherwise, use plugin.ListPages
+func ListPagesWithAnalytics(ctx context.Context, p Parent, onPage func(*EntryMap) error) error {
+	submitMethodInvocation(ctx, p, "List")
+	return ListPages(ctx, p, onPage)
+}
+
 // ReadWithAnalytics is a wrapper to plugin.Read. Use it when you need to report
 // a 'Read' invocation to analytics. Otherwise, use plugin.Read.
 func ReadWithAnalytics(ctx context.Context, e Entry, size int64, offset int64) ([]byte, error) {
@@ -35,6 +42,13 @@ func WriteWithAnalytics(ctx context.Context, w Writable, b []byte) error {
 	return Write(ctx, w, b)
 }
 
+// OpenWriterWithAnalytics is a wrapper to plugin.OpenWriter. Use it when you need to report
+// a 'Write' invocation to analytics. Otherwise, use plugin.OpenWriter.
+func OpenWriterWithAnalytics(ctx context.Context, w BlockWritable) (BlockWriter, error) {
+	submitMethodInvocation(ctx, w, "Write")
+	return OpenWriter(ctx, w)
+}
+
 // ExecWithAnalytics is a wrapper to e#Exec. Use it when you need to report an 'Exec'
 // invocation to analytics. Otherwise, use e#Exec.
 func ExecWithAnalytics(ctx context.Context, e Execable, cmd string, args []string, opts ExecOptions) (ExecCommand, error) {
@@ -56,6 +70,13 @@ func DeleteWithAnalytics(ctx context.Context, d Deletable) (bool, error) {
 	return Delete(ctx, d)
 }
 
+// CreateWithAnalytics is a wrapper to plugin.Create. Use it when you need to report a
+// 'Create' invocation to analytics. Otherwise, use plugin.Create.
+func CreateWithAnalytics(ctx context.Context, c Creatable, name string, isParent bool) (Entry, error) {
+	submitMethodInvocation(ctx, c, "Create")
+	return Create(ctx, c, name, isParent)
+}
+
 func submitMethodInvocation(ctx context.Context, e Entry, method string) {
 	isCorePluginEntry := e.Schema() != nil
 	if !isCorePluginEntry {
@@ -71,3 +92,10 @@ func submitMethodInvocation(ctx context.Context, e Entry, method string) {
 		method,
 	)
 }
+
+// RenameWithAnalytics is a wrapper to plugin.Rename. Use it when you need to report a
+// 'Rename' invocation to analytics. Otherwise, use plugin.Rename.
+func RenameWithAnalytics(ctx context.Context, r Renamable, newParent Parent, newName string) (Entry, error) {
+	submitMethodInvocation(ctx, r, "Rename")
+	return Rename(ctx, r, newParent, newName)
+}
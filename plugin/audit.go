@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/audit"
+)
+
+// startAuditRecord starts the audit record for invoking the action on e.
+func startAuditRecord(ctx context.Context, e Entry, a Action, args map[string]interface{}) *audit.Record {
+	record := &audit.Record{
+		Start:  time.Now(),
+		Action: a.Name,
+		Path:   e.eb().id,
+		TypeID: TypeID(e),
+		Args:   args,
+		DryRun: IsDryRun(ctx),
+	}
+	if journal, ok := ctx.Value(activity.JournalKey).(activity.Journal); ok {
+		record.Journal = journal.ID
+		record.Description = journal.Description
+	}
+	return record
+}
+
+// finishAuditRecord logs the audit record with the action's result.
+func finishAuditRecord(record *audit.Record, result interface{}, err error) {
+	record.End = time.Now()
+	record.Result = result
+	if err != nil {
+		record.Error = err.Error()
+	}
+	audit.Log(*record)
+}
+
+// planDryRun adds the steps returned by plan to ctx's dry run plan. The steps
+// are also added to the audit record.
+func planDryRun(ctx context.Context, record *audit.Record, plan func() ([]string, error)) error {
+	steps, err := plan()
+	if err != nil {
+		return err
+	}
+	addToDryRunPlan(ctx, steps)
+	record.Plan = steps
+	return nil
+}
+
+// auditedExecCommand logs the exec's audit record once the command's exit code
+// is available or ctx ends, whichever happens first. It doesn't wait for the
+// exit code to be retrieved because callers that only stream the output might
+// never retrieve it.
+type auditedExecCommand struct {
+	ExecCommand
+	done     chan struct{}
+	exitCode int
+	err      error
+}
+
+func newAuditedExecCommand(ctx context.Context, cmd ExecCommand, record *audit.Record) *auditedExecCommand {
+	audited := &auditedExecCommand{ExecCommand: cmd, done: make(chan struct{})}
+	go func() {
+		defer close(audited.done)
+		audited.exitCode, audited.err = cmd.ExitCode()
+	}()
+	go func() {
+		select {
+		case <-audited.done:
+			if audited.err == nil {
+				exitCode := audited.exitCode
+				record.ExitCode = &exitCode
+			}
+			finishAuditRecord(record, nil, audited.err)
+		case <-ctx.Done():
+			finishAuditRecord(record, nil, fmt.Errorf("the command did not finish: %v", ctx.Err()))
+		}
+	}()
+	return audited
+}
+
+func (cmd *auditedExecCommand) ExitCode() (int, error) {
+	<-cmd.done
+	return cmd.exitCode, cmd.err
+}
+
+// auditedBlockWriter logs the write's audit record once the writer's closed.
+// During a dry run, its BlockWriter is nil and it plans the write instead.
+type auditedBlockWriter struct {
+	BlockWriter
+	ctx     context.Context
+	record  *audit.Record
+	id      string
+	written int64
+	steps   []string
+}
+
+func (w *auditedBlockWriter) WriteAt(ctx context.Context, p []byte, offset int64) error {
+	w.written += int64(len(p))
+	if w.BlockWriter == nil {
+		return nil
+	}
+	return w.BlockWriter.WriteAt(ctx, p, offset)
+}
+
+func (w *auditedBlockWriter) Truncate(ctx context.Context, size int64) error {
+	if w.BlockWriter == nil {
+		w.steps = append(w.steps, fmt.Sprintf("truncate %v to %v bytes", w.id, size))
+		return nil
+	}
+	return w.BlockWriter.Truncate(ctx, size)
+}
+
+func (w *auditedBlockWriter) Close(ctx context.Context) (err error) {
+	w.record.Args["size"] = w.written
+	defer func() { finishAuditRecord(w.record, nil, err) }()
+
+	if w.BlockWriter == nil {
+		return planDryRun(w.ctx, w.record, func() ([]string, error) {
+			if w.written > 0 || len(w.steps) == 0 {
+				w.steps = append(w.steps, fmt.Sprintf("write %v bytes to %v", w.written, w.id))
+			}
+			return w.steps, nil
+		})
+	}
+	return w.BlockWriter.Close(ctx)
+}
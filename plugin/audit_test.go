@@ -0,0 +1,193 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/audit"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuditTestSuite struct {
+	suite.Suite
+	cache *cacheTestsMockCache
+	dir   string
+	ctx   context.Context
+}
+
+func (suite *AuditTestSuite) SetupTest() {
+	suite.cache = &cacheTestsMockCache{}
+	SetTestCache(suite.cache)
+
+	dir, err := ioutil.TempDir("", "wash-plugin-audit")
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.dir = dir
+	suite.NoError(audit.Open(filepath.Join(dir, "audit.log")))
+	suite.ctx = context.WithValue(context.Background(), activity.JournalKey, activity.Journal{ID: "1", Description: "wash rm"})
+}
+
+func (suite *AuditTestSuite) TearDownTest() {
+	UnsetTestCache()
+	suite.NoError(audit.Close())
+	suite.NoError(os.RemoveAll(suite.dir))
+}
+
+func (suite *AuditTestSuite) records() []audit.Record {
+	records, err := audit.Query(audit.Filter{})
+	suite.NoError(err)
+	return records
+}
+
+func (suite *AuditTestSuite) TestDelete_Audited() {
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+	e.On("Delete", mock.Anything).Return(false, nil)
+	suite.cache.On("Delete", mock.Anything).Return([]string{})
+
+	_, err := Delete(suite.ctx, e)
+	suite.NoError(err)
+
+	records := suite.records()
+	if suite.Len(records, 1) {
+		r := records[0]
+		suite.Equal("delete", r.Action)
+		suite.Equal("/foo/bar", r.Path)
+		suite.Equal(TypeID(e), r.TypeID)
+		suite.Equal("1", r.Journal)
+		suite.Equal("wash rm", r.Description)
+		suite.Equal(map[string]interface{}{"deleted": false}, r.Result)
+		suite.Empty(r.Error)
+		suite.False(r.Start.IsZero())
+		suite.False(r.End.Before(r.Start))
+	}
+}
+
+func (suite *AuditTestSuite) TestDelete_AuditsDryRuns() {
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+
+	_, err := Delete(WithDryRun(suite.ctx), e)
+	suite.NoError(err)
+
+	records := suite.records()
+	if suite.Len(records, 1) {
+		suite.True(records[0].DryRun)
+		suite.Equal([]string{"delete /foo/bar"}, records[0].Plan)
+		suite.Nil(records[0].Result)
+	}
+}
+
+func (suite *AuditTestSuite) TestSignal_AuditsErrors() {
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+	e.On("Schema").Return((*EntrySchema)(nil))
+	e.On("Signal", mock.Anything, "stop").Return(fmt.Errorf("failed"))
+
+	suite.Error(Signal(suite.ctx, e, "stop"))
+
+	records := suite.records()
+	if suite.Len(records, 1) {
+		suite.Equal("signal", records[0].Action)
+		suite.Equal(map[string]interface{}{"signal": "stop"}, records[0].Args)
+		suite.Equal("failed", records[0].Error)
+	}
+}
+
+func (suite *AuditTestSuite) TestWrite_AuditsPolicyDenials() {
+	suite.NoError(SetPolicy(Policy{ReadOnly: true}))
+	defer func() { suite.NoError(SetPolicy(Policy{})) }()
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+
+	// Use a context without a journal so that the denial isn't written to one
+	suite.Error(Write(context.Background(), e, []byte("hello")))
+
+	records := suite.records()
+	if suite.Len(records, 1) {
+		suite.Equal("write", records[0].Action)
+		suite.Equal(map[string]interface{}{"size": 5.0}, records[0].Args)
+		suite.Regexp("denied by read-only mode", records[0].Error)
+	}
+}
+
+func (suite *AuditTestSuite) TestOpenWriter_AuditedWhenClosed() {
+	e := &methodWrappersTestsMockBlockWritableEntry{newMethodWrappersTestsMockEntry("bar")}
+	e.SetTestID("/foo/bar")
+	writer := &methodWrappersTestsMockBlockWriter{}
+	e.On("OpenWriter", suite.ctx).Return(writer, nil)
+	writer.On("WriteAt", suite.ctx, []byte("hello"), int64(0)).Return(nil)
+	writer.On("Close", suite.ctx).Return(fmt.Errorf("failed"))
+
+	w, err := OpenWriter(suite.ctx, e)
+	if !suite.NoError(err) {
+		return
+	}
+	suite.NoError(w.WriteAt(suite.ctx, []byte("hello"), 0))
+	suite.Empty(suite.records())
+
+	suite.EqualError(w.Close(suite.ctx), "failed")
+	records := suite.records()
+	if suite.Len(records, 1) {
+		suite.Equal("write", records[0].Action)
+		suite.Equal("/foo/bar", records[0].Path)
+		suite.Equal(map[string]interface{}{"size": 5.0}, records[0].Args)
+		suite.Equal("failed", records[0].Error)
+	}
+}
+
+func (suite *AuditTestSuite) waitForRecords(n int) []audit.Record {
+	var records []audit.Record
+	suite.Eventually(func() bool {
+		records = suite.records()
+		return len(records) == n
+	}, time.Second, time.Millisecond)
+	return records
+}
+
+func (suite *AuditTestSuite) TestExec_AuditedWhenExitCodeIsAvailable() {
+	inner := NewExecCommand(context.Background())
+	record := startAuditRecord(suite.ctx, newMethodWrappersTestsMockEntry("bar"), ExecAction(), map[string]interface{}{"command": "echo"})
+	cmd := newAuditedExecCommand(suite.ctx, inner, record)
+	suite.Empty(suite.records())
+
+	// The record's logged without retrieving the exit code
+	inner.SetExitCode(2)
+	records := suite.waitForRecords(1)
+	if suite.Len(records, 1) {
+		suite.Equal("exec", records[0].Action)
+		suite.Equal(2, *records[0].ExitCode)
+	}
+
+	exitCode, err := cmd.ExitCode()
+	suite.NoError(err)
+	suite.Equal(2, exitCode)
+	suite.Len(suite.records(), 1)
+}
+
+func (suite *AuditTestSuite) TestExec_AuditedWhenContextEnds() {
+	ctx, cancel := context.WithCancel(suite.ctx)
+	inner := NewExecCommand(context.Background())
+	record := startAuditRecord(ctx, newMethodWrappersTestsMockEntry("bar"), ExecAction(), map[string]interface{}{"command": "sleep"})
+	newAuditedExecCommand(ctx, inner, record)
+	suite.Empty(suite.records())
+
+	cancel()
+	records := suite.waitForRecords(1)
+	if suite.Len(records, 1) {
+		suite.Nil(records[0].ExitCode)
+		suite.Regexp("did not finish.*canceled", records[0].Error)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	suite.Run(t, new(AuditTestSuite))
+}
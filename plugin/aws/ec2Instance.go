@@ -180,7 +180,7 @@ func (inst *ec2Instance) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&ec2InstanceConsoleOutput{}).Schema(),
 		(&plugin.MetadataJSONFile{}).Schema(),
-		(&volume.FS{}).Schema(),
+		(&volume.SFTP{}).Schema(),
 	}
 }
 
@@ -215,9 +215,9 @@ func (inst *ec2Instance) List(ctx context.Context) ([]plugin.Entry, error) {
 		entries = append(entries, latestConsoleOutput)
 	}
 
-	// Include a view of the remote filesystem using volume.FS. Use a small maxdepth because
+	// Include a view of the remote filesystem using volume.SFTP. Use a small maxdepth because
 	// VMs can have lots of files and SSH is fast.
-	entries = append(entries, volume.NewFS(ctx, "fs", inst, 3))
+	entries = append(entries, volume.NewSFTP(ctx, "fs", inst, 3))
 
 	return entries, nil
 }
@@ -275,10 +275,20 @@ func (inst *ec2Instance) Delete(ctx context.Context) (bool, error) {
 func (inst *ec2Instance) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
 	// TBD: how to get WinRM connection info. Only work with Kerberos? Require a mini-inventory from wash.yaml?
 
-	meta, err := inst.Metadata(ctx)
+	identity, err := inst.SSHIdentity(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return transport.ExecSSH(ctx, identity, append([]string{cmd}, args...), opts)
+}
+
+// SSHIdentity returns the identity used to connect to the instance via SSH. It's used by Exec and
+// by the instance's SFTP volume.
+func (inst *ec2Instance) SSHIdentity(ctx context.Context) (transport.Identity, error) {
+	meta, err := inst.Metadata(ctx)
+	if err != nil {
+		return transport.Identity{}, err
+	}
 	var hostname string
 	if name, ok := meta["PublicDnsName"]; ok && name != nil {
 		hostname = name.(string)
@@ -288,7 +298,7 @@ func (inst *ec2Instance) Exec(ctx context.Context, cmd string, args []string, op
 		hostname = ipaddr.(string)
 		activity.Record(ctx, "No public address was found for %v, trying private IP address %v", inst, hostname)
 	} else {
-		return nil, fmt.Errorf("No available interface found for %v", inst)
+		return transport.Identity{}, fmt.Errorf("No available interface found for %v", inst)
 	}
 
 	var identityfile string
@@ -319,7 +329,7 @@ func (inst *ec2Instance) Exec(ctx context.Context, cmd string, args []string, op
 	//
 	// fallbackuser and identiyfile can be overridden in ~/.ssh/config.
 	//
-	return transport.ExecSSH(ctx, transport.Identity{Host: hostname, FallbackUser: fallbackuser, IdentityFile: identityfile}, append([]string{cmd}, args...), opts)
+	return transport.Identity{Host: hostname, FallbackUser: fallbackuser, IdentityFile: identityfile}, nil
 }
 
 func (inst *ec2Instance) Signal(ctx context.Context, signal string) error {
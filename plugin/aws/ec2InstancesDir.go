@@ -2,6 +2,7 @@ package aws
 
 import (
 	"context"
+	"fmt"
 
 	awsSDK "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -75,3 +76,20 @@ func (is *ec2InstancesDir) List(ctx context.Context) ([]plugin.Entry, error) {
 
 	return entries, nil
 }
+
+// PersistChild returns no state because an instance's recreated from its partial metadata.
+func (is *ec2InstancesDir) PersistChild(child plugin.Entry) ([]byte, error) {
+	return nil, nil
+}
+
+// RestoreChild recreates an instance from its partial metadata.
+func (is *ec2InstancesDir) RestoreChild(ctx context.Context, child plugin.PersistedEntry) (plugin.Entry, error) {
+	var metadata ec2InstanceMetadata
+	if err := child.DecodePartialMetadata(&metadata); err != nil {
+		return nil, err
+	}
+	if metadata.Instance == nil {
+		return nil, fmt.Errorf("the partial metadata does not include the instance")
+	}
+	return newEC2Instance(ctx, metadata.Instance, is.session, is.client), nil
+}
@@ -1,8 +1,10 @@
 package aws
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -28,6 +30,26 @@ import (
 // it makes it difficult to refresh the shared s3Bucket object when the original object
 // is evicted from the cache.
 func listObjects(ctx context.Context, client *s3Client.S3, bucket string, prefix string) ([]plugin.Entry, error) {
+	var entries []plugin.Entry
+	marker := ""
+	for {
+		page, nextMarker, err := listObjectsPage(ctx, client, bucket, prefix, marker)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page...)
+		if nextMarker == "" {
+			return entries, nil
+		}
+		marker = nextMarker
+	}
+}
+
+// listObjectsPage is a helper that lists a single page of the objects which start
+// with a specific prefix. The page starts after the given marker. It returns the
+// page's entries along with the next page's marker, which is empty if there are
+// no more pages.
+func listObjectsPage(ctx context.Context, client *s3Client.S3, bucket string, prefix string, marker string) ([]plugin.Entry, string, error) {
 	// TODO: Clarify this a bit more later. For now, this should be enough.
 	//
 	// Everything's an object in S3. There is no such thing as a "hierarchy", meaning
@@ -106,9 +128,12 @@ func listObjects(ctx context.Context, client *s3Client.S3, bucket string, prefix
 		Prefix:    awsSDK.String(prefix),
 		Delimiter: awsSDK.String("/"),
 	}
+	if marker != "" {
+		request.Marker = awsSDK.String(marker)
+	}
 	resp, err := client.ListObjectsWithContext(ctx, request)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	numPrefixes := len(resp.CommonPrefixes)
 	numObjects := len(resp.Contents)
@@ -144,7 +169,18 @@ func listObjects(ctx context.Context, client *s3Client.S3, bucket string, prefix
 		entries = append(entries, newS3Object(o, name, bucket, key, client))
 	}
 
-	return entries, nil
+	// NextMarker is only returned when a delimiter is specified. However if it's missing
+	// and the response is truncated, then the last key in the response can be used as the
+	// marker instead.
+	var nextMarker string
+	if awsSDK.BoolValue(resp.IsTruncated) {
+		nextMarker = awsSDK.StringValue(resp.NextMarker)
+		if nextMarker == "" && numObjects > 0 {
+			nextMarker = awsSDK.StringValue(resp.Contents[numObjects-1].Key)
+		}
+	}
+
+	return entries, nextMarker, nil
 }
 
 // deleteObjects is a helper that deletes all objects that start with a specific prefix.
@@ -156,6 +192,112 @@ func deleteObjects(ctx context.Context, client *s3Client.S3, bucket string, pref
 	return s3manager.NewBatchDeleteWithClient(client).Delete(ctx, iterator)
 }
 
+// planDeleteObjects is a helper that returns the steps that deleteObjects would
+// take, i.e. the objects that it would delete.
+func planDeleteObjects(ctx context.Context, client *s3Client.S3, bucket string, prefix string) ([]string, error) {
+	request := &s3Client.ListObjectsInput{
+		Bucket: awsSDK.String(bucket),
+		Prefix: awsSDK.String(prefix),
+	}
+	steps := []string{}
+	err := client.ListObjectsPagesWithContext(ctx, request, func(page *s3Client.ListObjectsOutput, lastPage bool) bool {
+		for _, o := range page.Contents {
+			steps = append(steps, fmt.Sprintf("delete object s3://%v/%v", bucket, awsSDK.StringValue(o.Key)))
+		}
+		return true
+	})
+	return steps, err
+}
+
+// createObject is a helper that creates an empty object named name under a
+// specific prefix. If isParent is true, then the created object's key ends with
+// a "/" so that it's represented as an s3ObjectPrefix.
+func createObject(ctx context.Context, client *s3Client.S3, bucket string, prefix string, name string, isParent bool) (plugin.Entry, error) {
+	key := prefix + name
+	if isParent {
+		key += "/"
+	}
+	request := &s3Client.PutObjectInput{
+		Bucket: awsSDK.String(bucket),
+		Key:    awsSDK.String(key),
+		Body:   bytes.NewReader([]byte{}),
+	}
+	resp, err := client.PutObjectWithContext(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "S3 object create response: %+v", *resp)
+
+	if isParent {
+		return newS3ObjectPrefix(name, bucket, key, client), nil
+	}
+	o := &s3Client.Object{
+		Key:          awsSDK.String(key),
+		ETag:         resp.ETag,
+		LastModified: awsSDK.Time(time.Now()),
+		Size:         awsSDK.Int64(0),
+	}
+	return newS3Object(o, name, bucket, key, client), nil
+}
+
+// renameDestination is a helper that returns the bucket, prefix and client
+// of a rename's new parent. The new parent must be an S3 bucket or an S3
+// object prefix.
+func renameDestination(ctx context.Context, newParent plugin.Parent) (string, string, *s3Client.S3, error) {
+	switch p := newParent.(type) {
+	case *s3Bucket:
+		if _, err := p.getRegion(ctx); err != nil {
+			return "", "", nil, err
+		}
+		return p.Name(), "", p.client, nil
+	case *s3ObjectPrefix:
+		return p.bucket, p.prefix, p.client, nil
+	default:
+		return "", "", nil, fmt.Errorf("%v is not an S3 bucket or an S3 object prefix", plugin.ID(newParent))
+	}
+}
+
+// copyObject is a helper that copies the srcBucket/srcKey object to the
+// dstBucket/dstKey object.
+func copyObject(ctx context.Context, client *s3Client.S3, srcBucket string, srcKey string, dstBucket string, dstKey string) (*s3Client.CopyObjectOutput, error) {
+	request := &s3Client.CopyObjectInput{
+		Bucket:     awsSDK.String(dstBucket),
+		Key:        awsSDK.String(dstKey),
+		CopySource: awsSDK.String((&url.URL{Path: srcBucket + "/" + srcKey}).EscapedPath()),
+	}
+	resp, err := client.CopyObjectWithContext(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy %v to %v: %w", srcKey, dstKey, err)
+	}
+	activity.Record(ctx, "S3 object copy response: %+v", *resp)
+	return resp, nil
+}
+
+// copyObjects is a helper that copies all objects that start with srcPrefix
+// so that they start with dstPrefix instead. The buckets can be in different
+// regions, so srcClient lists the source objects while dstClient copies them.
+func copyObjects(ctx context.Context, srcClient *s3Client.S3, srcBucket string, srcPrefix string, dstClient *s3Client.S3, dstBucket string, dstPrefix string) error {
+	request := &s3Client.ListObjectsInput{
+		Bucket: awsSDK.String(srcBucket),
+		Prefix: awsSDK.String(srcPrefix),
+	}
+	var copyErr error
+	err := srcClient.ListObjectsPagesWithContext(ctx, request, func(page *s3Client.ListObjectsOutput, lastPage bool) bool {
+		for _, o := range page.Contents {
+			key := awsSDK.StringValue(o.Key)
+			dstKey := dstPrefix + strings.TrimPrefix(key, srcPrefix)
+			if _, copyErr = copyObject(ctx, dstClient, srcBucket, key, dstBucket, dstKey); copyErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return copyErr
+}
+
 // s3Bucket represents an S3 bucket.
 type s3Bucket struct {
 	plugin.EntryBase
@@ -201,6 +343,20 @@ func (b *s3Bucket) List(ctx context.Context) ([]plugin.Entry, error) {
 	return listObjects(ctx, b.client, b.Name(), "")
 }
 
+func (b *s3Bucket) ListPage(ctx context.Context, marker string) ([]plugin.Entry, string, error) {
+	if _, err := b.getRegion(ctx); err != nil {
+		return nil, "", err
+	}
+	return listObjectsPage(ctx, b.client, b.Name(), "", marker)
+}
+
+func (b *s3Bucket) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	if _, err := b.getRegion(ctx); err != nil {
+		return nil, err
+	}
+	return createObject(ctx, b.client, b.Name(), "", name, isParent)
+}
+
 func (b *s3Bucket) Delete(ctx context.Context) (bool, error) {
 	// According to https://docs.aws.amazon.com/AmazonS3/latest/dev/delete-or-empty-bucket.html,
 	// we must delete the bucket's objects and object versions (for versioned buckets) before
@@ -216,6 +372,14 @@ func (b *s3Bucket) Delete(ctx context.Context) (bool, error) {
 	return true, err
 }
 
+func (b *s3Bucket) PlanDelete(ctx context.Context) ([]string, error) {
+	steps, err := planDeleteObjects(ctx, b.client, b.Name(), "")
+	if err != nil {
+		return nil, err
+	}
+	return append(steps, fmt.Sprintf("delete bucket s3://%v", b.Name())), nil
+}
+
 type bucketMetadata struct {
 	TagSet []*s3Client.Tag
 	Region string
@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
@@ -24,6 +25,9 @@ func newS3Dir(ctx context.Context, session *session.Session) *s3Dir {
 	s3Dir := &s3Dir{
 		EntryBase: plugin.NewEntry("s3"),
 	}
+	// Buckets are rarely created or deleted, so a stale bucket list is fine
+	// while it's refreshed in the background.
+	s3Dir.SetStaleTTLOf(plugin.ListOp, 1*time.Hour)
 	s3Dir.session = session
 
 	// All S3 buckets can be listed from any region. Normalize the configured region so we can still
@@ -67,3 +71,13 @@ func (s *s3Dir) List(ctx context.Context) ([]plugin.Entry, error) {
 
 	return buckets, nil
 }
+
+// PersistChild returns no state because a bucket's recreated from its name and crtime.
+func (s *s3Dir) PersistChild(child plugin.Entry) ([]byte, error) {
+	return nil, nil
+}
+
+// RestoreChild recreates a bucket from its name and crtime.
+func (s *s3Dir) RestoreChild(ctx context.Context, child plugin.PersistedEntry) (plugin.Entry, error) {
+	return newS3Bucket(child.Name, child.Attributes.Crtime(), s.session), nil
+}
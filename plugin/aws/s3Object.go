@@ -3,6 +3,7 @@ package aws
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
 	"strconv"
 
@@ -11,6 +12,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	awsSDK "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	s3Client "github.com/aws/aws-sdk-go/service/s3"
 )
 
@@ -109,6 +111,50 @@ func (o *s3Object) Write(ctx context.Context, p []byte) error {
 	return nil
 }
 
+// OpenWriter returns a writer that uploads the object's new content in parts, so
+// that large objects can be written and appended to. The object's cached size
+// could be stale, so its current size is fetched instead.
+func (o *s3Object) OpenWriter(ctx context.Context) (plugin.BlockWriter, error) {
+	resp, err := o.client.HeadObjectWithContext(ctx, &s3Client.HeadObjectInput{
+		Bucket: awsSDK.String(o.bucket),
+		Key:    awsSDK.String(o.key),
+	})
+	if err != nil {
+		if awserr, ok := err.(awserr.Error); ok && awserr.Code() == "NotFound" {
+			// The object was deleted, so writing it creates a new object.
+			return &s3ObjectWriter{obj: o}, nil
+		}
+		return nil, fmt.Errorf("failed to get the current size of %v: %w", o.key, err)
+	}
+	size := awsSDK.Int64Value(resp.ContentLength)
+	return &s3ObjectWriter{obj: o, base: size, size: size, etag: resp.ETag}, nil
+}
+
+// Rename copies the object to its new key then deletes the original, since S3
+// does not support renaming objects.
+func (o *s3Object) Rename(ctx context.Context, newParent plugin.Parent, newName string) (plugin.Entry, error) {
+	bucket, prefix, client, err := renameDestination(ctx, newParent)
+	if err != nil {
+		return nil, err
+	}
+	key := prefix + newName
+	resp, err := copyObject(ctx, client, o.bucket, o.key, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := o.Delete(ctx); err != nil {
+		return nil, fmt.Errorf("copied the object to %v, but failed to delete the original: %v", key, err)
+	}
+
+	obj := &s3Client.Object{
+		Key:          awsSDK.String(key),
+		ETag:         resp.CopyObjectResult.ETag,
+		LastModified: resp.CopyObjectResult.LastModified,
+		Size:         awsSDK.Int64(int64(o.Attributes().Size())),
+	}
+	return newS3Object(obj, newName, bucket, key, client), nil
+}
+
 func (o *s3Object) Delete(ctx context.Context) (bool, error) {
 	_, err := o.client.DeleteObjectWithContext(ctx, &s3Client.DeleteObjectInput{
 		Bucket: aws.String(o.bucket),
@@ -2,6 +2,7 @@ package aws
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/puppetlabs/wash/plugin"
 
@@ -46,11 +47,45 @@ func (d *s3ObjectPrefix) List(ctx context.Context) ([]plugin.Entry, error) {
 	return listObjects(ctx, d.client, d.bucket, d.prefix)
 }
 
+// ListPage lists a single page of the S3 objects and S3 object
+// prefixes that are prefixed by the current S3 object prefix
+func (d *s3ObjectPrefix) ListPage(ctx context.Context, marker string) ([]plugin.Entry, string, error) {
+	return listObjectsPage(ctx, d.client, d.bucket, d.prefix, marker)
+}
+
+// Create creates an S3 object (or an S3 object prefix if isParent
+// is true) that's prefixed by the current S3 object prefix
+func (d *s3ObjectPrefix) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	return createObject(ctx, d.client, d.bucket, d.prefix, name, isParent)
+}
+
+// Rename moves all S3 objects that are prefixed by the current S3 object
+// prefix so that they're prefixed by the new prefix instead. S3 does not
+// support renaming objects, so each object is copied then deleted.
+func (d *s3ObjectPrefix) Rename(ctx context.Context, newParent plugin.Parent, newName string) (plugin.Entry, error) {
+	bucket, prefix, client, err := renameDestination(ctx, newParent)
+	if err != nil {
+		return nil, err
+	}
+	newPrefix := prefix + newName + "/"
+	if err := copyObjects(ctx, d.client, d.bucket, d.prefix, client, bucket, newPrefix); err != nil {
+		return nil, err
+	}
+	if err := deleteObjects(ctx, d.client, d.bucket, d.prefix); err != nil {
+		return nil, fmt.Errorf("copied the objects to %v, but failed to delete the originals: %v", newPrefix, err)
+	}
+	return newS3ObjectPrefix(newName, bucket, newPrefix, client), nil
+}
+
 func (d *s3ObjectPrefix) Delete(ctx context.Context) (bool, error) {
 	err := deleteObjects(ctx, d.client, d.bucket, d.prefix)
 	return true, err
 }
 
+func (d *s3ObjectPrefix) PlanDelete(ctx context.Context) ([]string, error) {
+	return planDeleteObjects(ctx, d.client, d.bucket, d.prefix)
+}
+
 const s3ObjectPrefixDescription = `
 This represents a common prefix shared by multiple S3 objects. See the
 bucket's docs for more details on why we have this kind of entry.
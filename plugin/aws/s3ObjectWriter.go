@@ -0,0 +1,258 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+
+	"github.com/puppetlabs/wash/activity"
+
+	awsSDK "github.com/aws/aws-sdk-go/aws"
+	s3Client "github.com/aws/aws-sdk-go/service/s3"
+)
+
+const (
+	// S3 requires every part of a multipart upload except for the last one to be at least 5 MiB,
+	// and copied parts can be at most 5 GiB.
+	s3MinPartSize     = 5 * 1024 * 1024
+	s3MaxCopyPartSize = 5 * 1024 * 1024 * 1024
+	// The size of the parts that a writer uploads. An upload can have at most 10,000 parts, so
+	// this limits the object to about 160 GiB.
+	s3PartSize = 16 * 1024 * 1024
+)
+
+// s3ObjectWriter writes an S3 object's new content as a multipart upload, so that it only holds
+// one part in memory. S3 objects can't be modified in place, so writes must be sequential. The
+// writer keeps the first base bytes of the object's current content, which lets it append to a
+// large object by copying its content into the upload instead of downloading it. Truncating the
+// object is only supported before anything's written.
+//
+// The base bytes are only kept if the object still has the ETag it had when the writer was opened,
+// so that a concurrent change to the object isn't mixed into the new content.
+type s3ObjectWriter struct {
+	obj  *s3Object
+	base int64
+	size int64
+	etag *string
+	// The data that's waiting to be uploaded as a part
+	buf bytes.Buffer
+	// Set once the first base bytes are either in buf or uploaded
+	started  bool
+	changed  bool
+	uploadID *string
+	parts    []*s3Client.CompletedPart
+	// The first error, which prevents the writes from being committed
+	err error
+}
+
+func (w *s3ObjectWriter) WriteAt(ctx context.Context, p []byte, offset int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	if offset != w.size {
+		return w.fail(fmt.Errorf("S3 objects can only be written sequentially; expected a write at offset %v, not %v", w.size, offset))
+	}
+	if err := w.start(ctx); err != nil {
+		return w.fail(err)
+	}
+	w.buf.Write(p)
+	w.size += int64(len(p))
+	w.changed = true
+	if w.buf.Len() >= s3PartSize {
+		if err := w.uploadBuf(ctx); err != nil {
+			return w.fail(err)
+		}
+	}
+	return nil
+}
+
+func (w *s3ObjectWriter) Truncate(ctx context.Context, size int64) error {
+	if w.err != nil {
+		return w.err
+	}
+	switch {
+	case size == w.size:
+		return nil
+	case w.started:
+		return w.fail(fmt.Errorf("S3 objects can only be truncated before they're written"))
+	case size > w.size:
+		return w.fail(fmt.Errorf("S3 objects can only be extended by writing to them"))
+	}
+	w.base = size
+	w.size = size
+	w.changed = true
+	return nil
+}
+
+// Close completes the upload, or aborts it if a write failed.
+func (w *s3ObjectWriter) Close(ctx context.Context) error {
+	if w.err != nil {
+		w.abort(ctx)
+		return w.err
+	}
+	if !w.changed {
+		return nil
+	}
+	if err := w.start(ctx); err != nil {
+		w.abort(ctx)
+		return err
+	}
+
+	if w.uploadID == nil {
+		// The content's small enough to upload in one request.
+		return w.obj.Write(ctx, w.buf.Bytes())
+	}
+	if w.buf.Len() > 0 {
+		if err := w.uploadBuf(ctx); err != nil {
+			w.abort(ctx)
+			return err
+		}
+	}
+	resp, err := w.obj.client.CompleteMultipartUploadWithContext(ctx, &s3Client.CompleteMultipartUploadInput{
+		Bucket:          awsSDK.String(w.obj.bucket),
+		Key:             awsSDK.String(w.obj.key),
+		UploadId:        w.uploadID,
+		MultipartUpload: &s3Client.CompletedMultipartUpload{Parts: w.parts},
+	})
+	if err != nil {
+		w.abort(ctx)
+		return fmt.Errorf("failed to complete the upload of %v: %w", w.obj.key, err)
+	}
+	activity.Record(ctx, "S3 object multipart upload response: %+v", *resp)
+	return nil
+}
+
+// start adds the first base bytes of the object's current content to the upload. Small content
+// is read into buf. Larger content is copied into the upload.
+func (w *s3ObjectWriter) start(ctx context.Context) error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if w.base == 0 {
+		return nil
+	}
+	if w.base < s3MinPartSize {
+		data, err := w.readBase(ctx)
+		if err != nil {
+			return err
+		}
+		w.buf.Write(data)
+		return nil
+	}
+
+	var offset int64
+	for offset < w.base {
+		partSize := w.base - offset
+		if partSize > s3MaxCopyPartSize {
+			partSize = s3MaxCopyPartSize
+			if remaining := w.base - offset - partSize; remaining < s3MinPartSize {
+				// Split what's left into two parts that are both large enough.
+				partSize = (w.base - offset) / 2
+			}
+		}
+		if err := w.uploadPartCopy(ctx, offset, partSize); err != nil {
+			return err
+		}
+		offset += partSize
+	}
+	return nil
+}
+
+func (w *s3ObjectWriter) readBase(ctx context.Context) ([]byte, error) {
+	resp, err := w.obj.client.GetObjectWithContext(ctx, &s3Client.GetObjectInput{
+		Bucket:  awsSDK.String(w.obj.bucket),
+		Key:     awsSDK.String(w.obj.key),
+		Range:   awsSDK.String(fmt.Sprintf("bytes=0-%v", w.base-1)),
+		IfMatch: w.etag,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			activity.Record(ctx, "Error closing S3 GetObject response body: %v", err)
+		}
+	}()
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (w *s3ObjectWriter) createUpload(ctx context.Context) error {
+	if w.uploadID != nil {
+		return nil
+	}
+	resp, err := w.obj.client.CreateMultipartUploadWithContext(ctx, &s3Client.CreateMultipartUploadInput{
+		Bucket: awsSDK.String(w.obj.bucket),
+		Key:    awsSDK.String(w.obj.key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start an upload of %v: %w", w.obj.key, err)
+	}
+	activity.Record(ctx, "S3 object create multipart upload response: %+v", *resp)
+	w.uploadID = resp.UploadId
+	return nil
+}
+
+func (w *s3ObjectWriter) uploadBuf(ctx context.Context) error {
+	if err := w.createUpload(ctx); err != nil {
+		return err
+	}
+	partNumber := awsSDK.Int64(int64(len(w.parts) + 1))
+	resp, err := w.obj.client.UploadPartWithContext(ctx, &s3Client.UploadPartInput{
+		Bucket:     awsSDK.String(w.obj.bucket),
+		Key:        awsSDK.String(w.obj.key),
+		UploadId:   w.uploadID,
+		PartNumber: partNumber,
+		Body:       bytes.NewReader(w.buf.Bytes()),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload part %v of %v: %w", *partNumber, w.obj.key, err)
+	}
+	w.parts = append(w.parts, &s3Client.CompletedPart{ETag: resp.ETag, PartNumber: partNumber})
+	w.buf.Reset()
+	return nil
+}
+
+func (w *s3ObjectWriter) uploadPartCopy(ctx context.Context, offset int64, size int64) error {
+	if err := w.createUpload(ctx); err != nil {
+		return err
+	}
+	partNumber := awsSDK.Int64(int64(len(w.parts) + 1))
+	resp, err := w.obj.client.UploadPartCopyWithContext(ctx, &s3Client.UploadPartCopyInput{
+		Bucket:            awsSDK.String(w.obj.bucket),
+		Key:               awsSDK.String(w.obj.key),
+		UploadId:          w.uploadID,
+		PartNumber:        partNumber,
+		CopySource:        awsSDK.String((&url.URL{Path: w.obj.bucket + "/" + w.obj.key}).EscapedPath()),
+		CopySourceRange:   awsSDK.String(fmt.Sprintf("bytes=%v-%v", offset, offset+size-1)),
+		CopySourceIfMatch: w.etag,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy part %v of %v: %w", *partNumber, w.obj.key, err)
+	}
+	w.parts = append(w.parts, &s3Client.CompletedPart{ETag: resp.CopyPartResult.ETag, PartNumber: partNumber})
+	return nil
+}
+
+func (w *s3ObjectWriter) fail(err error) error {
+	w.err = err
+	return err
+}
+
+// abort discards the upload's parts so that they aren't stored (and billed for) indefinitely.
+func (w *s3ObjectWriter) abort(ctx context.Context) {
+	if w.uploadID == nil {
+		return
+	}
+	_, err := w.obj.client.AbortMultipartUploadWithContext(ctx, &s3Client.AbortMultipartUploadInput{
+		Bucket:   awsSDK.String(w.obj.bucket),
+		Key:      awsSDK.String(w.obj.key),
+		UploadId: w.uploadID,
+	})
+	if err != nil {
+		activity.Warnf(ctx, "Failed to abort the upload of %v: %v", w.obj.key, err)
+	}
+	w.uploadID = nil
+}
@@ -2,29 +2,74 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/datastore"
 )
 
 // KeyType is used to create a unique key type for looking up context values.
 type keyType int
 
-// id is used to identify the parent's ID in a context.
-const parentID keyType = iota
+const (
+	// id is used to identify the parent's ID in a context.
+	parentID keyType = iota
+	// dryRunKey is used to identify a dry run's plan in a context.
+	dryRunKey
+)
 
 var cache datastore.Cache
 
+// CacheOptions configures the cache that's initialized by InitCache.
+type CacheOptions struct {
+	// File is the file that the cache is persisted to. If it's empty, then
+	// the cache is only kept in memory.
+	//
+	// Note that only cached values that can be gob-encoded are persisted.
+	// This includes metadata, the results of CachedOp calls whose types are
+	// registered with gob.Register, and the listings of PersistentParents.
+	// Other listings and content contain live entries, so they're never
+	// persisted and a restarted server re-fetches them.
+	File string
+	// SaveInterval is how often a persistent cache is saved to File. It
+	// defaults to 1 minute.
+	SaveInterval time.Duration
+}
+
 // InitCache initializes the cache
-func InitCache() {
-	if notRunningTests() {
-		cache = datastore.NewMemCache()
-	} else {
+func InitCache(opts CacheOptions) error {
+	if !notRunningTests() {
 		panic("InitCache can only be called in production. Tests should call SetTestCache instead.")
 	}
+
+	if opts.File == "" {
+		cache = datastore.NewMemCache()
+		return nil
+	}
+
+	if opts.SaveInterval == 0 {
+		opts.SaveInterval = 1 * time.Minute
+	}
+	diskCache, err := datastore.NewDiskCache(opts.File, opts.SaveInterval)
+	if err != nil {
+		return err
+	}
+	cache = diskCache
+	return nil
+}
+
+// CloseCache closes the cache. For a persistent cache, this saves it to disk.
+func CloseCache() error {
+	if closer, ok := cache.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
 // SetTestCache sets the cache to the provided mock. It can only be called by the tests.
@@ -96,16 +141,138 @@ func allOpKeysIncludingChildrenRegex(path string) *regexp.Regexp {
 func ClearCacheFor(path string, clearParentList bool) []string {
 	rx := allOpKeysIncludingChildrenRegex(path)
 	deleted := cache.Delete(rx)
+	invalidatePagedListings(rx)
 
 	if clearParentList {
 		parentID, _ := splitID(path)
 		listOpName := defaultOpCodeToNameMap[ListOp]
-		deleted = append(deleted, cache.Delete(opKeyRegex(listOpName, parentID))...)
+		parentRx := opKeyRegex(listOpName, parentID)
+		deleted = append(deleted, cache.Delete(parentRx)...)
+		invalidatePagedListings(parentRx)
 	}
 
 	return deleted
 }
 
+// CachedResult describes an op's cached result
+type CachedResult struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	// Created is when the result was cached. It's zero if that's unknown.
+	Created time.Time `json:"created"`
+	// Expires is when the result expires. It's zero if the result never expires.
+	Expires time.Time `json:"expires"`
+	// Stale is true if the result's being refreshed. See EntryBase#SetStaleTTLOf.
+	Stale bool `json:"stale,omitempty"`
+	// Error is set if the op failed
+	Error string `json:"error,omitempty"`
+	// Size is the result's approximate size in bytes
+	Size int64 `json:"size"`
+}
+
+// CacheOpStats describes an op's cached results, and how often its results
+// were found in the cache.
+type CacheOpStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Results int    `json:"results"`
+	Size    int64  `json:"size"`
+}
+
+// CachePluginStats describes a plugin's cached results
+type CachePluginStats struct {
+	Results int   `json:"results"`
+	Size    int64 `json:"size"`
+}
+
+// CacheStats describes the cache's contents
+type CacheStats struct {
+	Ops     map[string]CacheOpStats     `json:"ops"`
+	Plugins map[string]CachePluginStats `json:"plugins"`
+	Results []CachedResult              `json:"results"`
+}
+
+// CacheStatsFor returns statistics about the cached results of the provided
+// path and its children. Note that each op's hits and misses are counted
+// across all paths.
+func CacheStatsFor(path string) CacheStats {
+	stats := CacheStats{
+		Ops:     make(map[string]CacheOpStats),
+		Plugins: make(map[string]CachePluginStats),
+		Results: []CachedResult{},
+	}
+	for op, opStats := range cache.Stats() {
+		stats.Ops[op] = CacheOpStats{Hits: opStats.Hits, Misses: opStats.Misses}
+	}
+
+	for _, item := range cache.Items(allOpKeysIncludingChildrenRegex(path)) {
+		result := CachedResult{
+			Op:      item.Category,
+			Path:    item.Key,
+			Created: item.Created,
+			Expires: item.Expires,
+			Stale:   item.Stale,
+			Size:    cachedResultSize(item.Value),
+		}
+		if err, ok := item.Value.(error); ok {
+			result.Error = err.Error()
+		}
+		stats.Results = append(stats.Results, result)
+
+		opStats := stats.Ops[result.Op]
+		opStats.Results++
+		opStats.Size += result.Size
+		stats.Ops[result.Op] = opStats
+
+		pluginName := strings.SplitN(strings.TrimPrefix(result.Path, "/"), "/", 2)[0]
+		if pluginName == "" {
+			pluginName = "/"
+		}
+		pluginStats := stats.Plugins[pluginName]
+		pluginStats.Results++
+		pluginStats.Size += result.Size
+		stats.Plugins[pluginName] = pluginStats
+	}
+
+	sort.Slice(stats.Results, func(i, j int) bool {
+		if stats.Results[i].Path == stats.Results[j].Path {
+			return stats.Results[i].Op < stats.Results[j].Op
+		}
+		return stats.Results[i].Path < stats.Results[j].Path
+	})
+	return stats
+}
+
+// cachedResultSize returns the approximate size of a cached result in bytes.
+func cachedResultSize(value interface{}) int64 {
+	switch t := value.(type) {
+	case *EntryMap:
+		var size int64
+		t.Range(func(cname string, entry Entry) bool {
+			size += int64(len(cname)+len(entry.eb().id)) + jsonSize(entry.eb().partialMetadata())
+			return true
+		})
+		return size
+	case *entryContentImpl:
+		return int64(t.size())
+	case *blockReadableEntryContent:
+		// Block-readable content is not cached
+		return 0
+	case error:
+		return int64(len(t.Error()))
+	default:
+		return jsonSize(t)
+	}
+}
+
+func jsonSize(value interface{}) int64 {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return int64(len(bytes))
+}
+
 // returns (parentID, cname)
 func splitID(entryID string) (string, string) {
 	segments := strings.Split(entryID, "/")
@@ -140,7 +307,7 @@ func CachedOp(ctx context.Context, opName string, entry Entry, ttl time.Duration
 		panic("plugin.CachedOp: received a negative TTL")
 	}
 
-	return cachedOp(ctx, opName, entry, ttl, op)
+	return cachedOp(ctx, opName, entry, ttl, 0, op, nil)
 }
 
 // DuplicateCNameErr represents a duplicate cname error, which
@@ -177,7 +344,7 @@ func (c DuplicateCNameErr) Error() string {
 // CachedList returns a map of <entry_cname> => <entry_object> to optimize
 // querying a specific entry.
 func cachedList(ctx context.Context, p Parent) (*EntryMap, error) {
-	cachedEntries, err := cachedDefaultOp(ctx, ListOp, p, func() (interface{}, error) {
+	cachedEntries, err := cachedDefaultOp(ctx, ListOp, p, func(ctx context.Context) (interface{}, error) {
 		// Including the entry's ID allows plugin authors to use any Cached* methods defined on the
 		// children after their creation. This is necessary when the child's Cached* methods are used
 		// to calculate its attributes. Note that the child's ID is set in cachedOp.
@@ -187,32 +354,11 @@ func cachedList(ctx context.Context, p Parent) (*EntryMap, error) {
 		}
 
 		searchedEntries := newEntryMap()
+		searchedEntries.parent, _ = p.(PersistentParent)
 		for _, entry := range entries {
-			cname := CName(entry)
-
-			if duplicateEntry, ok := searchedEntries.mp[cname]; ok {
-				return nil, DuplicateCNameErr{
-					ParentID:                 p.eb().id,
-					FirstChildName:           duplicateEntry.eb().name,
-					FirstChildSlashReplacer:  duplicateEntry.eb().slashReplacer,
-					SecondChildName:          entry.eb().name,
-					SecondChildSlashReplacer: entry.eb().slashReplacer,
-					CName:                    cname,
-				}
-			}
-
-			if entry.eb().isInaccessible {
-				// Skip entries that are expected to be inaccessible.
-				continue
+			if _, err := addChild(p, searchedEntries, entry); err != nil {
+				return nil, err
 			}
-
-			searchedEntries.mp[cname] = entry
-
-			// Ensure ID is set on all entries so that we can use it for caching later in places
-			// where the context doesn't include the parent's ID.
-			setChildID(p.eb().id, entry)
-
-			passAlongWrappedTypes(p, entry)
 		}
 
 		return searchedEntries, nil
@@ -222,12 +368,77 @@ func cachedList(ctx context.Context, p Parent) (*EntryMap, error) {
 		return nil, err
 	}
 
-	return cachedEntries.(*EntryMap), nil
+	// A listing that was loaded from a persisted cache is restored the first time
+	// that it's used. If that fails, then p's re-listed.
+	entries := cachedEntries.(*EntryMap)
+	if err := entries.restore(ctx, p); err != nil {
+		activity.Warnf(ctx, "Re-listing %v, could not restore its persisted listing: %v", p.eb().id, err)
+		cache.Delete(opKeyRegex(defaultOpCodeToNameMap[ListOp], p.eb().id))
+		return cachedList(ctx, p)
+	}
+	return entries, nil
+}
+
+// cachedListPages lists a PagedParent's children one page at a time, invoking
+// onPage on each page. Concurrent callers share the listing. The entire listing
+// is cached once the last page is listed unless it has more than
+// maxCachedPagedListSize children. If p's list result is already cached, or if
+// p is not a PagedParent, then onPage is invoked once with all of p's children.
+//
+// Unlike cachedList, onPage is invoked outside of the cache's update transaction
+// so that it can safely invoke other cached operations (like listing p's children).
+func cachedListPages(ctx context.Context, p Parent, onPage func(*EntryMap) error) error {
+	if pp, ok := p.(PagedParent); ok && p.eb().id != "" {
+		if l, next := joinPagedListing(pp); l != nil {
+			return l.read(ctx, next, onPage)
+		}
+	}
+
+	entries, err := cachedList(ctx, p)
+	if err != nil {
+		return err
+	}
+	return onPage(entries)
+}
+
+// addChild adds p's child to the entries map, returning false if the child is
+// inaccessible. It returns a DuplicateCNameErr if entries already contains a
+// child with the same cname.
+//
+// NOTE: addChild does not lock the map, so it should only be used while the map
+// is being constructed.
+func addChild(p Parent, entries *EntryMap, entry Entry) (bool, error) {
+	cname := CName(entry)
+
+	if duplicateEntry, ok := entries.mp[cname]; ok {
+		return false, DuplicateCNameErr{
+			ParentID:                 p.eb().id,
+			FirstChildName:           duplicateEntry.eb().name,
+			FirstChildSlashReplacer:  duplicateEntry.eb().slashReplacer,
+			SecondChildName:          entry.eb().name,
+			SecondChildSlashReplacer: entry.eb().slashReplacer,
+			CName:                    cname,
+		}
+	}
+
+	if entry.eb().isInaccessible {
+		// Skip entries that are expected to be inaccessible.
+		return false, nil
+	}
+
+	entries.mp[cname] = entry
+
+	// Ensure ID is set on all entries so that we can use it for caching later in places
+	// where the context doesn't include the parent's ID.
+	setChildID(p.eb().id, entry)
+
+	passAlongWrappedTypes(p, entry)
+	return true, nil
 }
 
 // cachedRead caches an entry's Read method
 func cachedRead(ctx context.Context, e Entry) (entryContent, error) {
-	cachedContent, err := cachedDefaultOp(ctx, ReadOp, e, func() (interface{}, error) {
+	cachedContent, err := cachedDefaultOp(ctx, ReadOp, e, func(ctx context.Context) (interface{}, error) {
 		switch signature := ReadAction().signature(e); signature {
 		case DefaultSignature:
 			// Both external and core plugin entries that have the default Read signature
@@ -274,7 +485,7 @@ func cachedRead(ctx context.Context, e Entry) (entryContent, error) {
 
 // cachedMetadata caches an entry's Metadata method
 func cachedMetadata(ctx context.Context, e Entry) (JSONObject, error) {
-	cachedMetadata, err := cachedDefaultOp(ctx, MetadataOp, e, func() (interface{}, error) {
+	cachedMetadata, err := cachedDefaultOp(ctx, MetadataOp, e, func(ctx context.Context) (interface{}, error) {
 		return e.Metadata(ctx)
 	})
 
@@ -285,16 +496,32 @@ func cachedMetadata(ctx context.Context, e Entry) (JSONObject, error) {
 	return cachedMetadata.(JSONObject), nil
 }
 
-// Common helper for CachedList, CachedOpen and CachedMetadata
-func cachedDefaultOp(ctx context.Context, opCode defaultOpCode, entry Entry, op opFunc) (interface{}, error) {
+// Common helper for CachedList, CachedOpen and CachedMetadata. op is passed
+// the context that it should use.
+func cachedDefaultOp(ctx context.Context, opCode defaultOpCode, entry Entry, op func(context.Context) (interface{}, error)) (interface{}, error) {
 	opName := defaultOpCodeToNameMap[opCode]
 	ttl := entry.eb().ttl[opCode]
 
-	return cachedOp(ctx, opName, entry, ttl, op)
+	if staleTTL := entry.eb().staleTTL[opCode]; staleTTL > 0 && ttl > 0 {
+		// A stale result is refreshed in the background, and every caller shares
+		// the refreshed result. Thus, the refresh shouldn't be cancelled with
+		// ctx. Cache misses still use ctx so that callers can cancel them.
+		refreshCtx := DetachedContext(ctx)
+		return cachedOp(ctx, opName, entry, ttl, staleTTL, func() (interface{}, error) {
+			return op(ctx)
+		}, func() (interface{}, error) {
+			return op(refreshCtx)
+		})
+	}
+
+	return cachedOp(ctx, opName, entry, ttl, 0, func() (interface{}, error) {
+		return op(ctx)
+	}, nil)
 }
 
-// Common helper for CachedOp and cachedDefaultOp.
-func cachedOp(ctx context.Context, opName string, entry Entry, ttl time.Duration, op opFunc) (interface{}, error) {
+// Common helper for CachedOp and cachedDefaultOp. refreshOp refreshes stale
+// results, so it's only used if staleTTL > 0.
+func cachedOp(ctx context.Context, opName string, entry Entry, ttl time.Duration, staleTTL time.Duration, op opFunc, refreshOp opFunc) (interface{}, error) {
 	if cache == nil {
 		if notRunningTests() {
 			panic("The cache was not initialized. You can initialize the cache by invoking plugin.InitCache()")
@@ -316,6 +543,9 @@ func cachedOp(ctx context.Context, opName string, entry Entry, ttl time.Duration
 		}
 	}
 
+	if staleTTL > 0 {
+		return cache.GetOrRefresh(opName, entry.eb().id, ttl, staleTTL, op, refreshOp)
+	}
 	return cache.GetOrUpdate(opName, entry.eb().id, ttl, false, op)
 }
 
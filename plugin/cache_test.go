@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/emirpasic/gods/maps/linkedhashmap"
+	"github.com/puppetlabs/wash/datastore"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
@@ -26,6 +27,21 @@ func (m *cacheTestsMockCache) GetOrUpdate(cat, key string, ttl time.Duration, re
 	return args.Get(0), args.Error(1)
 }
 
+func (m *cacheTestsMockCache) GetOrRefresh(cat, key string, ttl time.Duration, staleTTL time.Duration, generateValue func() (interface{}, error), refreshValue func() (interface{}, error)) (interface{}, error) {
+	args := m.Called(cat, key, ttl, staleTTL, generateValue, refreshValue)
+	return args.Get(0), args.Error(1)
+}
+
+func (m *cacheTestsMockCache) Items(matcher *regexp.Regexp) []datastore.Item {
+	args := m.Called(matcher)
+	return args.Get(0).([]datastore.Item)
+}
+
+func (m *cacheTestsMockCache) Stats() map[string]datastore.CategoryStats {
+	args := m.Called()
+	return args.Get(0).(map[string]datastore.CategoryStats)
+}
+
 func (m *cacheTestsMockCache) Flush() {
 	// Don't need anything for Flush, so leave it alone for now
 }
@@ -234,6 +250,41 @@ func (suite *CacheTestSuite) TestCachedOp() {
 	suite.cache.AssertCalled(suite.T(), "GetOrUpdate", opName, entry.eb().id, opTTL, false, mock.MatchedBy(generateValueMatcher))
 }
 
+func (suite *CacheTestSuite) TestCacheStatsFor() {
+	created := time.Now()
+	expires := created.Add(time.Minute)
+	children := newEntryMap()
+	child := newCacheTestsMockEntry("child")
+	child.SetTestID("/my_plugin/foo/child")
+	children.mp["child"] = child
+
+	suite.cache.On("Stats").Return(map[string]datastore.CategoryStats{
+		"List":     {Hits: 2, Misses: 1},
+		"Metadata": {Hits: 0, Misses: 3},
+	})
+	suite.cache.On("Items", allOpKeysIncludingChildrenRegex("/my_plugin")).Return([]datastore.Item{
+		{Category: "Read", Key: "/my_plugin/foo/child", Value: newEntryContent([]byte("hello")), Created: created, Expires: expires},
+		{Category: "List", Key: "/my_plugin/foo", Value: children, Created: created, Expires: expires, Stale: true},
+		{Category: "Metadata", Key: "/my_plugin/foo", Value: fmt.Errorf("failed")},
+	})
+
+	stats := CacheStatsFor("/my_plugin")
+	childSize := int64(len("child")+len("/my_plugin/foo/child")) + jsonSize(child.partialMetadata())
+	suite.Equal(map[string]CacheOpStats{
+		"List":     {Hits: 2, Misses: 1, Results: 1, Size: childSize},
+		"Metadata": {Hits: 0, Misses: 3, Results: 1, Size: int64(len("failed"))},
+		"Read":     {Results: 1, Size: 5},
+	}, stats.Ops)
+	suite.Equal(map[string]CachePluginStats{
+		"my_plugin": {Results: 3, Size: childSize + int64(len("failed")) + 5},
+	}, stats.Plugins)
+	suite.Equal([]CachedResult{
+		{Op: "List", Path: "/my_plugin/foo", Created: created, Expires: expires, Stale: true, Size: childSize},
+		{Op: "Metadata", Path: "/my_plugin/foo", Error: "failed", Size: int64(len("failed"))},
+		{Op: "Read", Path: "/my_plugin/foo/child", Created: created, Expires: expires, Size: 5},
+	}, stats.Results)
+}
+
 func (suite *CacheTestSuite) TestDuplicateCNameErr() {
 	err := DuplicateCNameErr{
 		ParentID:                 "/my_plugin/foo",
@@ -297,6 +348,32 @@ func (suite *CacheTestSuite) testCachedDefaultOp(
 		suite.Equal(mungedOpValue, v)
 	}
 	suite.cache.AssertCalled(suite.T(), "GetOrUpdate", opName, entry.eb().id, opTTL, false, mock.MatchedBy(generateValueMatcher))
+
+	// Test that cachedDefaultOp calls cache#GetOrRefresh for an entry
+	// that's set a stale TTL. A cache miss should use the caller's
+	// context so that it can be cancelled, while a refresh's context
+	// shouldn't be cancelled with the caller's context.
+	staleTTL := 1 * time.Minute
+	entry.SetStaleTTLOf(op, staleTTL)
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	var opCtxs []context.Context
+	opCtxMatcher := func(generateValue func() (interface{}, error)) bool {
+		if !generateValueMatcher(generateValue) {
+			return false
+		}
+		opCtxs = append(opCtxs, entry.Calls[len(entry.Calls)-1].Arguments.Get(0).(context.Context))
+		return true
+	}
+	suite.cache.On("GetOrRefresh", opName, entry.eb().id, opTTL, staleTTL, mock.MatchedBy(opCtxMatcher), mock.MatchedBy(opCtxMatcher)).Return(mungedOpValue, nil).Once()
+	v, err = cachedDefaultOp(cancelledCtx, entry)
+	if suite.NoError(err) {
+		suite.Equal(mungedOpValue, v)
+	}
+	if suite.Len(opCtxs, 2) {
+		suite.Error(opCtxs[0].Err())
+		suite.NoError(opCtxs[1].Err())
+	}
 }
 
 func toMap(children []Entry) map[string]Entry {
@@ -380,6 +457,173 @@ func (suite *CacheTestSuite) TestCachedListSetEntryID() {
 	}
 }
 
+type cacheTestsMockPagedEntry struct {
+	*cacheTestsMockEntry
+}
+
+func newCacheTestsMockPagedEntry(name string) *cacheTestsMockPagedEntry {
+	return &cacheTestsMockPagedEntry{newCacheTestsMockEntry(name)}
+}
+
+func (e *cacheTestsMockPagedEntry) ListPage(ctx context.Context, token string) ([]Entry, string, error) {
+	args := e.Called(ctx, token)
+	return args.Get(0).([]Entry), args.String(1), args.Error(2)
+}
+
+// collectPages returns an onPage callback that records each page's
+// cnames.
+func collectPages(pages *[][]string) func(*EntryMap) error {
+	return func(page *EntryMap) error {
+		var cnames []string
+		page.Range(func(cname string, _ Entry) bool {
+			cnames = append(cnames, cname)
+			return true
+		})
+		*pages = append(*pages, cnames)
+		return nil
+	}
+}
+
+func (suite *CacheTestSuite) TestCachedListPages_NotPaged_InvokesOnPageOnce() {
+	ctx := context.Background()
+	entry := newCacheTestsMockEntry("foo")
+	entry.DisableDefaultCaching()
+	entry.SetTestID("/foo")
+	entry.On("List", mock.Anything).Return([]Entry{newCacheTestsMockEntry("bar")}, nil).Once()
+
+	var pages [][]string
+	err := cachedListPages(ctx, entry, collectPages(&pages))
+	if suite.NoError(err) {
+		suite.Equal([][]string{{"bar"}}, pages)
+	}
+}
+
+func (suite *CacheTestSuite) TestCachedListPages_Paged_InvokesOnPageForEachPage() {
+	ctx := context.Background()
+	entry := newCacheTestsMockPagedEntry("foo")
+	entry.DisableDefaultCaching()
+	entry.SetTestID("/foo")
+	suite.cache.On("Get", "List", "/foo").Return(nil, nil).Once()
+
+	child1 := newCacheTestsMockEntry("bar")
+	child2 := newCacheTestsMockEntry("baz")
+	entry.On("ListPage", mock.Anything, "").Return([]Entry{child1}, "next", nil).Once()
+	entry.On("ListPage", mock.Anything, "next").Return([]Entry{child2}, "", nil).Once()
+
+	var pages [][]string
+	err := cachedListPages(ctx, entry, collectPages(&pages))
+	if suite.NoError(err) {
+		suite.Equal([][]string{{"bar"}, {"baz"}}, pages)
+		suite.Equal("/foo/bar", child1.eb().id)
+		suite.Equal("/foo/baz", child2.eb().id)
+		entry.AssertExpectations(suite.T())
+	}
+}
+
+func (suite *CacheTestSuite) TestCachedListPages_Paged_CNameErrorsAcrossPages() {
+	ctx := context.Background()
+	entry := newCacheTestsMockPagedEntry("foo")
+	entry.DisableDefaultCaching()
+	entry.SetTestID("/foo")
+	suite.cache.On("Get", "List", "/foo").Return(nil, nil).Once()
+
+	entry.On("ListPage", mock.Anything, "").Return([]Entry{newCacheTestsMockEntry("bar/")}, "next", nil).Once()
+	entry.On("ListPage", mock.Anything, "next").Return([]Entry{newCacheTestsMockEntry("bar#")}, "", nil).Once()
+
+	err := cachedListPages(ctx, entry, func(*EntryMap) error { return nil })
+	suite.IsType(DuplicateCNameErr{}, err)
+}
+
+func (suite *CacheTestSuite) TestCachedListPages_Paged_StopsWhenOnPageErrors() {
+	ctx := context.Background()
+	entry := newCacheTestsMockPagedEntry("foo")
+	entry.DisableDefaultCaching()
+	entry.SetTestID("/foo")
+	suite.cache.On("Get", "List", "/foo").Return(nil, nil).Once()
+	entry.On("ListPage", mock.Anything, "").Return([]Entry{newCacheTestsMockEntry("bar")}, "next", nil).Once()
+
+	expectedErr := fmt.Errorf("an error")
+	err := cachedListPages(ctx, entry, func(*EntryMap) error { return expectedErr })
+	suite.Equal(expectedErr, err)
+	entry.AssertExpectations(suite.T())
+}
+
+func (suite *CacheTestSuite) TestCachedListPages_Paged_UsesCachedListing() {
+	ctx := context.Background()
+	entry := newCacheTestsMockPagedEntry("foo")
+	entry.SetTestID("/foo")
+
+	cachedEntries := newEntryMap()
+	cachedEntries.mp["bar"] = newCacheTestsMockEntry("bar")
+	suite.cache.On("Get", "List", "/foo").Return(cachedEntries, nil).Once()
+	suite.cache.On("GetOrUpdate", "List", "/foo", mock.Anything, false, mock.Anything).Return(cachedEntries, nil).Once()
+
+	var pages [][]string
+	err := cachedListPages(ctx, entry, collectPages(&pages))
+	if suite.NoError(err) {
+		suite.Equal([][]string{{"bar"}}, pages)
+		entry.AssertNotCalled(suite.T(), "ListPage", mock.Anything, mock.Anything)
+	}
+}
+
+func (suite *CacheTestSuite) TestCachedListPages_Paged_SharesListingBetweenConcurrentCallers() {
+	ctx := context.Background()
+	entry := newCacheTestsMockPagedEntry("foo")
+	entry.DisableDefaultCaching()
+	entry.SetTestID("/foo")
+	suite.cache.On("Get", "List", "/foo").Return(nil, nil).Once()
+	entry.On("ListPage", mock.Anything, "").Return([]Entry{newCacheTestsMockEntry("bar")}, "next", nil).Once()
+	entry.On("ListPage", mock.Anything, "next").Return([]Entry{newCacheTestsMockEntry("baz")}, "", nil).Once()
+
+	// The first caller blocks on its first page, so the second caller lists the
+	// second page.
+	firstPage := make(chan struct{})
+	release := make(chan struct{})
+	var firstPages [][]string
+	firstErr := make(chan error)
+	go func() {
+		collect := collectPages(&firstPages)
+		firstErr <- cachedListPages(ctx, entry, func(page *EntryMap) error {
+			if len(firstPages) == 0 {
+				close(firstPage)
+				<-release
+			}
+			return collect(page)
+		})
+	}()
+	<-firstPage
+
+	var pages [][]string
+	err := cachedListPages(ctx, entry, collectPages(&pages))
+	close(release)
+	if suite.NoError(err) {
+		suite.Equal([][]string{{"bar"}, {"baz"}}, pages)
+	}
+	if suite.NoError(<-firstErr) {
+		suite.Equal([][]string{{"bar"}, {"baz"}}, firstPages)
+	}
+	entry.AssertExpectations(suite.T())
+}
+
+func (suite *CacheTestSuite) TestCachedListPages_Paged_DoesNotCacheLargeListings() {
+	defer func(size int) { maxCachedPagedListSize = size }(maxCachedPagedListSize)
+	maxCachedPagedListSize = 1
+
+	ctx := context.Background()
+	entry := newCacheTestsMockPagedEntry("foo")
+	entry.SetTestID("/foo")
+	suite.cache.On("Get", "List", "/foo").Return(nil, nil).Once()
+	entry.On("ListPage", mock.Anything, "").Return([]Entry{newCacheTestsMockEntry("bar")}, "next", nil).Once()
+	entry.On("ListPage", mock.Anything, "next").Return([]Entry{newCacheTestsMockEntry("baz")}, "", nil).Once()
+
+	var pages [][]string
+	err := cachedListPages(ctx, entry, collectPages(&pages))
+	if suite.NoError(err) {
+		suite.Equal([][]string{{"bar"}, {"baz"}}, pages)
+		suite.cache.AssertNotCalled(suite.T(), "GetOrUpdate", "List", "/foo", mock.Anything, mock.Anything, mock.Anything)
+	}
+}
+
 func (suite *CacheTestSuite) TestCachedRead_DefaultOp() {
 	// This also tests a successful read of a ReadableCorePluginEntry
 	mockRawContent := []byte("some raw content")
@@ -2,16 +2,14 @@ package docker
 
 import (
 	"context"
-	"fmt"
-	"io"
 	"strings"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
-	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 	vol "github.com/puppetlabs/wash/volume"
 )
 
@@ -104,68 +102,7 @@ func (c *container) Delete(ctx context.Context) (bool, error) {
 func (c *container) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
 	command := append([]string{cmd}, args...)
 	activity.Record(ctx, "Exec %v on %v", command, c.Name())
-
-	cfg := types.ExecConfig{Cmd: command, AttachStdout: true, AttachStderr: true, Tty: opts.Tty}
-	if opts.Stdin != nil || opts.Tty {
-		cfg.AttachStdin = true
-	}
-	created, err := c.client.ContainerExecCreate(ctx, c.id, cfg)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.client.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{})
-	if err != nil {
-		return nil, err
-	}
-
-	// If stdin is supplied, asynchronously copy it to container exec input.
-	if opts.Stdin != nil {
-		go func() {
-			_, writeErr := io.Copy(resp.Conn, opts.Stdin)
-			// If using a Tty, wait until done reading in case we need to send Ctrl-C; when a Tty is
-			// allocated commands expect user input and will respond to control signals. Otherwise close
-			// input now to ensure commands that depend on EOF execute correctly.
-			if !opts.Tty {
-				respErr := resp.CloseWrite()
-				activity.Record(ctx, "Closed execution input stream for %v: %v, %v", c.Name(), writeErr, respErr)
-			}
-		}()
-	}
-
-	execCmd := plugin.NewExecCommand(ctx)
-	execCmd.SetStopFunc(func() {
-		// Close the response on cancellation. Copying will block until there's more to read from the
-		// exec output. For an action with no more output it may never return.
-		if opts.Tty {
-			// If resp.Conn is still open, send Ctrl-C over resp.Conn before closing it.
-			_, err := resp.Conn.Write([]byte{0x03})
-			activity.Record(ctx, "Sent ETX on context termination: %v", err)
-		}
-		resp.Close()
-	})
-	// Asynchronously copy container exec output, then fetch the exit code once
-	// the copy's finished.
-	go func() {
-		_, err := stdcopy.StdCopy(execCmd.Stdout(), execCmd.Stderr(), resp.Reader)
-		activity.Record(ctx, "Exec on %v complete: %v", c.Name(), err)
-		execCmd.CloseStreamsWithError(err)
-		resp.Close()
-
-		// Command's finished. Now send the exit code.
-		resp, err := c.client.ContainerExecInspect(ctx, created.ID)
-		if err != nil {
-			execCmd.SetExitCodeErr(err)
-			return
-		}
-		if resp.Running {
-			execCmd.SetExitCodeErr(fmt.Errorf("the command was marked as 'Running' even though the output streams reached EOF"))
-			return
-		}
-		activity.Record(ctx, "Exec on %v exited %v", c.Name(), resp.ExitCode)
-		execCmd.SetExitCode(resp.ExitCode)
-	}()
-	return execCmd, nil
+	return transport.ExecDocker(ctx, c.client, c.id, command, opts)
 }
 
 func (c *container) Signal(ctx context.Context, signal string) error {
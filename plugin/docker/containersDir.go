@@ -46,3 +46,17 @@ func (cs *containersDir) List(ctx context.Context) ([]plugin.Entry, error) {
 	}
 	return keys, nil
 }
+
+// PersistChild returns no state because a container's recreated from its partial metadata.
+func (cs *containersDir) PersistChild(child plugin.Entry) ([]byte, error) {
+	return nil, nil
+}
+
+// RestoreChild recreates a container from its partial metadata.
+func (cs *containersDir) RestoreChild(ctx context.Context, child plugin.PersistedEntry) (plugin.Entry, error) {
+	var inst types.Container
+	if err := child.DecodePartialMetadata(&inst); err != nil {
+		return nil, err
+	}
+	return newContainer(inst, cs.client), nil
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	volpkg "github.com/puppetlabs/wash/volume"
@@ -64,20 +65,32 @@ func (v *volume) List(ctx context.Context) ([]plugin.Entry, error) {
 	return volpkg.List(ctx, v)
 }
 
+func (v *volume) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	return volpkg.Create(ctx, v, name, isParent)
+}
+
 func (v *volume) Delete(ctx context.Context) (bool, error) {
 	err := v.client.VolumeRemove(ctx, v.Name(), true)
 	return true, err
 }
 
-// Create a container that mounts a volume to a default mountpoint and runs a command.
-func (v *volume) createContainer(ctx context.Context, cmd []string) (string, error) {
-	// Use tty to avoid messing with the extra log formatting.
-	cfg := docontainer.Config{Image: "busybox", Cmd: cmd, Tty: true}
+// Create a container that mounts a volume to a default mountpoint and runs a command. The
+// volume's mounted read-write if writable is true. If openStdin is true, then the container
+// waits for its stdin to be attached.
+func (v *volume) createContainer(ctx context.Context, cmd []string, writable bool, openStdin bool) (string, error) {
+	// Use tty to avoid messing with the extra log formatting. Stdin's content could be
+	// mangled by a tty though, so skip it if stdin's open.
+	cfg := docontainer.Config{Image: "busybox", Cmd: cmd, Tty: !openStdin}
+	if openStdin {
+		cfg.OpenStdin = true
+		cfg.StdinOnce = true
+		cfg.AttachStdin = true
+	}
 	mounts := []mount.Mount{{
 		Type:     mount.TypeVolume,
 		Source:   v.Name(),
 		Target:   mountpoint,
-		ReadOnly: true,
+		ReadOnly: !writable,
 	}}
 	hostcfg := docontainer.HostConfig{Mounts: mounts}
 	netcfg := network.NetworkingConfig{}
@@ -111,10 +124,10 @@ func (v *volume) createContainer(ctx context.Context, cmd []string) (string, err
 }
 
 // Runs cmd in a temporary container. If the exit code is 0, then it returns the cmd's output.
-// Otherwise, it wraps the cmd's output in an error object.
-func (v *volume) runInTemporaryContainer(ctx context.Context, cmd []string) ([]byte, error) {
-	// Create a container that mounts a volume and deletes its file. Run rm -rf on it.
-	cid, err := v.createContainer(ctx, cmd)
+// Otherwise, it wraps the cmd's output in an error object. The volume's mounted read-write if
+// writable is true. If stdin is not nil, then its content is sent to the cmd's stdin.
+func (v *volume) runInTemporaryContainer(ctx context.Context, cmd []string, writable bool, stdin io.Reader) ([]byte, error) {
+	cid, err := v.createContainer(ctx, cmd, writable, stdin != nil)
 	if err != nil {
 		return nil, err
 	}
@@ -123,11 +136,32 @@ func (v *volume) runInTemporaryContainer(ctx context.Context, cmd []string) ([]b
 		activity.Record(ctx, "Deleted container %v: %v", cid, err)
 	}()
 
+	var attached types.HijackedResponse
+	if stdin != nil {
+		// Attach before starting the container so that none of the cmd's input is missed.
+		attached, err = v.client.ContainerAttach(ctx, cid, types.ContainerAttachOptions{Stream: true, Stdin: true})
+		if err != nil {
+			return nil, err
+		}
+		defer attached.Close()
+	}
+
 	activity.Record(ctx, "Starting container %v", cid)
 	if err := v.client.ContainerStart(ctx, cid, types.ContainerStartOptions{}); err != nil {
 		return nil, err
 	}
 
+	if stdin != nil {
+		activity.Record(ctx, "Sending stdin to container %v", cid)
+		if _, err := io.Copy(attached.Conn, stdin); err != nil {
+			return nil, err
+		}
+		// Closing our end of stdin signals EOF to the cmd.
+		if err := attached.CloseWrite(); err != nil {
+			return nil, err
+		}
+	}
+
 	activity.Record(ctx, "Waiting for container %v", cid)
 	waitC, errC := v.client.ContainerWait(ctx, cid, docontainer.WaitConditionNotRunning)
 	var statusCode int64
@@ -153,20 +187,28 @@ func (v *volume) runInTemporaryContainer(ctx context.Context, cmd []string) ([]b
 		activity.Record(ctx, "Closed log for %v: %v", cid, output.Close())
 	}()
 
-	bytes, err := ioutil.ReadAll(output)
+	var bits []byte
+	if stdin != nil {
+		// The container doesn't have a tty, so its log's multiplexed.
+		var buf bytes.Buffer
+		_, err = stdcopy.StdCopy(&buf, &buf, output)
+		bits = buf.Bytes()
+	} else {
+		bits, err = ioutil.ReadAll(output)
+	}
 	if err != nil {
 		return nil, err
 	}
 	if statusCode != 0 {
-		return nil, errors.New(strings.Trim(string(bytes), "\n"))
+		return nil, errors.New(strings.Trim(string(bits), "\n"))
 	}
-	return bytes, nil
+	return bits, nil
 }
 
 func (v *volume) VolumeList(ctx context.Context, path string) (volpkg.DirMap, error) {
 	// Use a larger maxdepth because volumes have relatively few files and VolumeList is slow.
 	maxdepth := 10
-	output, err := v.runInTemporaryContainer(ctx, volpkg.StatCmdPOSIX(mountpoint+path, maxdepth))
+	output, err := v.runInTemporaryContainer(ctx, volpkg.StatCmdPOSIX(mountpoint+path, maxdepth), false, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -175,7 +217,7 @@ func (v *volume) VolumeList(ctx context.Context, path string) (volpkg.DirMap, er
 
 func (v *volume) VolumeRead(ctx context.Context, path string) ([]byte, error) {
 	// Create a container that mounts a volume and waits. Use it to download a file.
-	cid, err := v.createContainer(ctx, []string{"sleep", "60"})
+	cid, err := v.createContainer(ctx, []string{"sleep", "60"}, false, false)
 	if err != nil {
 		return nil, err
 	}
@@ -216,7 +258,7 @@ func (v *volume) VolumeRead(ctx context.Context, path string) ([]byte, error) {
 
 func (v *volume) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
 	// Create a container that mounts a volume and tails a file. Run it and capture the output.
-	cid, err := v.createContainer(ctx, []string{"tail", "-f", mountpoint + path})
+	cid, err := v.createContainer(ctx, []string{"tail", "-f", mountpoint + path}, false, false)
 	if err != nil {
 		return nil, err
 	}
@@ -255,18 +297,36 @@ func (v *volume) VolumeStream(ctx context.Context, path string) (io.ReadCloser,
 }
 
 func (v *volume) VolumeDelete(ctx context.Context, path string) (bool, error) {
-	_, err := v.runInTemporaryContainer(ctx, []string{"rm", "-rf", mountpoint + path})
+	_, err := v.runInTemporaryContainer(ctx, []string{"rm", "-rf", mountpoint + path}, true, nil)
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
+func (v *volume) VolumeWrite(ctx context.Context, path string, b []byte) error {
+	cmd := []string{"sh", "-c", `cat > "$1"`, "sh", mountpoint + path}
+	_, err := v.runInTemporaryContainer(ctx, cmd, true, bytes.NewReader(b))
+	return err
+}
+
+func (v *volume) VolumeCreate(ctx context.Context, path string) error {
+	_, err := v.runInTemporaryContainer(ctx, []string{"touch", mountpoint + path}, true, nil)
+	return err
+}
+
+func (v *volume) VolumeMkdir(ctx context.Context, path string) error {
+	_, err := v.runInTemporaryContainer(ctx, []string{"mkdir", mountpoint + path}, true, nil)
+	return err
+}
+
 const volumeDescription = `
 This is a Docker volume. We create a temporary Docker container whenever
 Wash invokes a currently uncached List/Read/Stream action on it or one of
 its children. For List, we run 'find -exec stat' on the container and parse
 its output. For Read, we run 'sleep 60' then proceed to download the file
 content from the container. For Stream, we run 'tail -f' and pass over its
-output.
+output. Write and Create also use a temporary container, which mounts the
+volume read-write. For Write, we run 'cat' and send the new content to its
+stdin. For Create, we run 'touch' or 'mkdir'.
 `
@@ -2,8 +2,10 @@ package docker
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/docker/docker/api/types/filters"
+	volumetypes "github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
@@ -48,3 +50,16 @@ func (vs *volumesDir) List(ctx context.Context) ([]plugin.Entry, error) {
 	}
 	return keys, nil
 }
+
+// Create creates a new volume. Equivalent to 'docker volume create <name>'
+func (vs *volumesDir) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	if !isParent {
+		return nil, fmt.Errorf("%v can only contain volumes, which are directories", vs)
+	}
+	vol, err := vs.client.VolumeCreate(ctx, volumetypes.VolumeCreateBody{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Created volume %v", vol.Name)
+	return newVolume(vs.client, &vol)
+}
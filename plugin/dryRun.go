@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+)
+
+type dryRunPlan struct {
+	mux   sync.Mutex
+	steps []string
+}
+
+// WithDryRun returns a context for a dry run. During a dry run, the plugin.Delete,
+// plugin.Signal and plugin.Write wrappers don't act. Instead, they add what they
+// would do to the context's plan. Use DryRunPlan to retrieve it.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey, &dryRunPlan{})
+}
+
+// IsDryRun returns true if ctx is for a dry run.
+func IsDryRun(ctx context.Context) bool {
+	_, ok := ctx.Value(dryRunKey).(*dryRunPlan)
+	return ok
+}
+
+// DryRunPlan returns the steps that were planned during ctx's dry run, in the
+// order that they were planned. It returns nil if ctx isn't for a dry run.
+func DryRunPlan(ctx context.Context) []string {
+	plan, ok := ctx.Value(dryRunKey).(*dryRunPlan)
+	if !ok {
+		return nil
+	}
+	plan.mux.Lock()
+	defer plan.mux.Unlock()
+	return append([]string{}, plan.steps...)
+}
+
+// addToDryRunPlan adds the steps to ctx's dry run plan.
+func addToDryRunPlan(ctx context.Context, steps []string) {
+	plan := ctx.Value(dryRunKey).(*dryRunPlan)
+	plan.mux.Lock()
+	defer plan.mux.Unlock()
+	plan.steps = append(plan.steps, steps...)
+}
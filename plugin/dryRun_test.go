@@ -0,0 +1,138 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DryRunTestSuite struct {
+	suite.Suite
+	cache *cacheTestsMockCache
+}
+
+func (suite *DryRunTestSuite) SetupTest() {
+	suite.cache = &cacheTestsMockCache{}
+	SetTestCache(suite.cache)
+}
+
+func (suite *DryRunTestSuite) TearDownTest() {
+	UnsetTestCache()
+	suite.cache = nil
+}
+
+func (suite *DryRunTestSuite) TestIsDryRun() {
+	suite.False(IsDryRun(context.Background()))
+	suite.Nil(DryRunPlan(context.Background()))
+
+	ctx := WithDryRun(context.Background())
+	suite.True(IsDryRun(ctx))
+	suite.Empty(DryRunPlan(ctx))
+}
+
+func (suite *DryRunTestSuite) TestDelete_Planner() {
+	ctx := WithDryRun(context.Background())
+	e := &dryRunTestsMockPlanner{methodWrappersTestsMockEntry: newMethodWrappersTestsMockEntry("bar")}
+	e.SetTestID("/foo/bar")
+	e.On("PlanDelete", ctx).Return([]string{"delete baz", "delete bar"}, nil)
+
+	deleted, err := Delete(ctx, e)
+	if suite.NoError(err) {
+		suite.False(deleted)
+		suite.Equal([]string{"delete baz", "delete bar"}, DryRunPlan(ctx))
+		// Neither Delete nor the cache were called, so the mocks would've
+		// panicked if they were.
+		e.AssertExpectations(suite.T())
+	}
+}
+
+func (suite *DryRunTestSuite) TestDelete_PlannerError() {
+	ctx := WithDryRun(context.Background())
+	e := &dryRunTestsMockPlanner{methodWrappersTestsMockEntry: newMethodWrappersTestsMockEntry("bar")}
+	e.SetTestID("/foo/bar")
+	e.On("PlanDelete", ctx).Return([]string(nil), fmt.Errorf("failed"))
+
+	_, err := Delete(ctx, e)
+	suite.EqualError(err, "failed")
+	suite.Empty(DryRunPlan(ctx))
+}
+
+func (suite *DryRunTestSuite) TestDelete_NotAPlanner() {
+	ctx := WithDryRun(context.Background())
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+
+	deleted, err := Delete(ctx, e)
+	if suite.NoError(err) {
+		suite.False(deleted)
+		suite.Equal([]string{"delete /foo/bar"}, DryRunPlan(ctx))
+	}
+}
+
+func (suite *DryRunTestSuite) TestSignal() {
+	ctx := WithDryRun(context.Background())
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+	e.On("Schema").Return((*EntrySchema)(nil))
+
+	suite.NoError(Signal(ctx, e, "STOP"))
+	suite.Equal([]string{"send the stop signal to /foo/bar"}, DryRunPlan(ctx))
+}
+
+func (suite *DryRunTestSuite) TestSignal_ValidatesTheSignal() {
+	ctx := WithDryRun(context.Background())
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+	schema := &EntrySchema{}
+	schema.AddSignal("start", "starts the entry")
+	e.On("Schema").Return(schema)
+
+	err := Signal(ctx, e, "stop")
+	suite.True(IsInvalidInputErr(err))
+	suite.Empty(DryRunPlan(ctx))
+}
+
+func (suite *DryRunTestSuite) TestWrite() {
+	ctx := WithDryRun(context.Background())
+	e := newMethodWrappersTestsMockEntry("bar")
+	e.SetTestID("/foo/bar")
+
+	suite.NoError(Write(ctx, e, []byte("hello")))
+	suite.NoError(Write(ctx, e, []byte("hi")))
+	suite.Equal([]string{"write 5 bytes to /foo/bar", "write 2 bytes to /foo/bar"}, DryRunPlan(ctx))
+}
+
+func (suite *DryRunTestSuite) TestOpenWriter() {
+	ctx := WithDryRun(context.Background())
+	e := &methodWrappersTestsMockBlockWritableEntry{newMethodWrappersTestsMockEntry("bar")}
+	e.SetTestID("/foo/bar")
+
+	w, err := OpenWriter(ctx, e)
+	if suite.NoError(err) {
+		suite.NoError(w.Truncate(ctx, 0))
+		suite.NoError(w.WriteAt(ctx, []byte("hello"), 0))
+		suite.NoError(w.WriteAt(ctx, []byte("hi"), 5))
+		suite.Empty(DryRunPlan(ctx))
+		suite.NoError(w.Close(ctx))
+	}
+	suite.Equal([]string{"truncate /foo/bar to 0 bytes", "write 7 bytes to /foo/bar"}, DryRunPlan(ctx))
+	// The entry's OpenWriter isn't called during a dry run.
+	e.AssertExpectations(suite.T())
+}
+
+func TestDryRun(t *testing.T) {
+	suite.Run(t, new(DryRunTestSuite))
+}
+
+type dryRunTestsMockPlanner struct {
+	*methodWrappersTestsMockEntry
+}
+
+func (m *dryRunTestsMockPlanner) PlanDelete(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+var _ = DeletePlanner(&dryRunTestsMockPlanner{})
@@ -43,6 +43,7 @@ type EntryBase struct {
 	slashReplacer            rune
 	id                       string
 	ttl                      [3]time.Duration
+	staleTTL                 [3]time.Duration
 	wrappedTypes             SchemaMap
 	isPrefetched             bool
 	isInaccessible           bool
@@ -186,6 +187,22 @@ func (e *EntryBase) TTLOf(op defaultOpCode) time.Duration {
 	return e.ttl[op]
 }
 
+// SetStaleTTLOf lets the specified op's cached result be used for up to
+// staleTTL after its TTL expires. Using an expired result refreshes it in
+// the background, so callers do not have to wait for the op to finish. This
+// is useful for ops that are slow but whose results rarely change.
+//
+// A staleTTL of 0 disables this behavior, which is the default.
+func (e *EntryBase) SetStaleTTLOf(op defaultOpCode, staleTTL time.Duration) *EntryBase {
+	e.staleTTL[op] = staleTTL
+	return e
+}
+
+// StaleTTLOf returns the stale TTL set for the specified op
+func (e *EntryBase) StaleTTLOf(op defaultOpCode) time.Duration {
+	return e.staleTTL[op]
+}
+
 // DisableCachingFor disables caching for the specified op
 func (e *EntryBase) DisableCachingFor(op defaultOpCode) *EntryBase {
 	e.SetTTLOf(op, -1)
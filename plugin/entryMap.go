@@ -7,6 +7,14 @@ import "sync"
 type EntryMap struct {
 	mp  map[string]Entry
 	mux sync.RWMutex
+	// parent is set if the map's a PersistentParent's listing, so that it can be
+	// persisted.
+	parent PersistentParent
+	// loaded is true if the map was loaded from a persisted cache. Its persisted
+	// children are recreated by restore.
+	loaded     bool
+	persisted  []persistedChild
+	restoreErr error
 }
 
 func newEntryMap() *EntryMap {
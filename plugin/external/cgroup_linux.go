@@ -0,0 +1,193 @@
+package external
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// memoryCgroup limits the combined memory of a script invocation's processes. Each invocation
+// gets its own cgroup under Wash's cgroup, so Wash's cgroup must be able to have children with
+// the memory controller (e.g. by running Wash as a systemd service with Delegate=yes). Both
+// cgroup v2 and the cgroup v1 memory hierarchy are supported.
+type memoryCgroup struct {
+	dir string
+	// events contains the cgroup's oom_kill count. It's memory.events for cgroup v2, and
+	// memory.oom_control for cgroup v1.
+	events string
+}
+
+var memoryCgroupParent struct {
+	once sync.Once
+	dir  string
+	v2   bool
+	err  error
+}
+
+var memoryCgroupCount uint64
+
+// newMemoryCgroup creates a cgroup that limits its processes to limit bytes of memory.
+func newMemoryCgroup(limit uint64) (*memoryCgroup, error) {
+	parent := &memoryCgroupParent
+	parent.once.Do(func() {
+		parent.dir, parent.v2, parent.err = findMemoryCgroupParent()
+	})
+	if parent.err != nil {
+		return nil, parent.err
+	}
+
+	name := fmt.Sprintf("wash-%v-%v", os.Getpid(), atomic.AddUint64(&memoryCgroupCount, 1))
+	cg := &memoryCgroup{dir: filepath.Join(parent.dir, name)}
+	if err := os.Mkdir(cg.dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create a cgroup: %v", err)
+	}
+
+	limitStr := strconv.FormatUint(limit, 10)
+	var err error
+	if parent.v2 {
+		cg.events = "memory.events"
+		err = cg.write("memory.max", limitStr)
+		// Swap would let the processes use more memory than the limit, and killing the whole
+		// cgroup on OOM means that no process is left running without its siblings. Both are
+		// best-effort because they depend on the kernel's configuration.
+		_ = cg.write("memory.swap.max", "0")
+		_ = cg.write("memory.oom.group", "1")
+	} else {
+		cg.events = "memory.oom_control"
+		err = cg.write("memory.limit_in_bytes", limitStr)
+		_ = cg.write("memory.memsw.limit_in_bytes", limitStr)
+	}
+	if err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("could not set the cgroup's memory limit: %v", err)
+	}
+	return cg, nil
+}
+
+// findMemoryCgroupParent returns the directory of Wash's cgroup in a hierarchy with the memory
+// controller, and whether it's a cgroup v2 hierarchy.
+func findMemoryCgroupParent() (string, bool, error) {
+	data, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", false, fmt.Errorf("could not find Wash's cgroup: %v", err)
+	}
+
+	// Each line is hierarchy-ID:controllers:path. The cgroup v2 hierarchy has ID 0 and no
+	// controllers.
+	var v2Path, v1Path string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		if fields[0] == "0" && fields[1] == "" {
+			v2Path = fields[2]
+			continue
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			if controller == "memory" {
+				v1Path = fields[2]
+			}
+		}
+	}
+
+	err = fmt.Errorf("the memory controller is not available")
+	if v2Path != "" {
+		// cgroup v2 is either the only hierarchy, or it's mounted alongside cgroup v1.
+		for _, mount := range []string{"/sys/fs/cgroup", "/sys/fs/cgroup/unified"} {
+			dir := filepath.Join(mount, v2Path)
+			controllers, readErr := ioutil.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+			if readErr != nil || !hasField(controllers, "memory") {
+				continue
+			}
+			if err = enableMemoryController(dir); err == nil {
+				return dir, true, nil
+			}
+		}
+	}
+	if v1Path != "" {
+		dir := filepath.Join("/sys/fs/cgroup/memory", v1Path)
+		if _, statErr := os.Stat(filepath.Join(dir, "memory.limit_in_bytes")); statErr == nil {
+			return dir, false, nil
+		}
+	}
+	return "", false, err
+}
+
+// enableMemoryController enables the memory controller for the children of the cgroup v2
+// directory dir.
+func enableMemoryController(dir string) error {
+	subtreeControl := filepath.Join(dir, "cgroup.subtree_control")
+	if controllers, err := ioutil.ReadFile(subtreeControl); err == nil && hasField(controllers, "memory") {
+		return nil
+	}
+	// This fails if Wash's cgroup contains processes and isn't the root cgroup, because cgroup
+	// v2 only lets leaf cgroups contain processes.
+	if err := ioutil.WriteFile(subtreeControl, []byte("+memory"), 0644); err != nil {
+		return fmt.Errorf("could not enable the memory controller for the children of %v: %v", dir, err)
+	}
+	return nil
+}
+
+func hasField(data []byte, field string) bool {
+	for _, f := range strings.Fields(string(data)) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (cg *memoryCgroup) write(file string, value string) error {
+	return ioutil.WriteFile(filepath.Join(cg.dir, file), []byte(value), 0644)
+}
+
+// procsFile returns the file that a process's PID is written to so that it joins the cgroup.
+func (cg *memoryCgroup) procsFile() string {
+	return filepath.Join(cg.dir, "cgroup.procs")
+}
+
+// oomKilled returns true if the kernel killed one of the cgroup's processes for exceeding
+// the memory limit.
+func (cg *memoryCgroup) oomKilled() bool {
+	data, err := ioutil.ReadFile(filepath.Join(cg.dir, cg.events))
+	if err != nil {
+		return false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			count, err := strconv.ParseUint(fields[1], 10, 64)
+			return err == nil && count > 0
+		}
+	}
+	return false
+}
+
+// remove removes the cgroup. If the script's processes are still exiting, then it's removed in
+// the background once they've exited. It's left behind (and keeps limiting them) if they're
+// still running after a second.
+func (cg *memoryCgroup) remove() {
+	if err := syscall.Rmdir(cg.dir); err != syscall.EBUSY {
+		return
+	}
+	go func() {
+		for i := 0; i < 10; i++ {
+			time.Sleep(100 * time.Millisecond)
+			if err := syscall.Rmdir(cg.dir); err != syscall.EBUSY {
+				return
+			}
+		}
+	}()
+}
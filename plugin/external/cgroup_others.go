@@ -0,0 +1,24 @@
+//go:build !linux
+// +build !linux
+
+package external
+
+import "fmt"
+
+// memoryCgroup limits the combined memory of a script invocation's processes. cgroups are only
+// available on Linux.
+type memoryCgroup struct{}
+
+func newMemoryCgroup(limit uint64) (*memoryCgroup, error) {
+	return nil, fmt.Errorf("memory limits are only supported on Linux")
+}
+
+func (cg *memoryCgroup) procsFile() string {
+	return ""
+}
+
+func (cg *memoryCgroup) oomKilled() bool {
+	return false
+}
+
+func (cg *memoryCgroup) remove() {}
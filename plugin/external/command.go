@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/kballard/go-shellquote"
 	"github.com/puppetlabs/wash/activity"
 )
@@ -23,11 +24,19 @@ type Command interface {
 	SetStdout(stdout io.Writer)
 	SetStderr(stderr io.Writer)
 	SetStdin(stdin io.Reader)
+	StdinPipe() (io.WriteCloser, error)
 	StdoutPipe() (io.ReadCloser, error)
 	StderrPipe() (io.ReadCloser, error)
 	ExitCode() int
+	// SetLimits limits the command's run time and resources. It must be called before Start.
+	SetLimits(limits commandLimits)
+	// LimitError returns an error describing the limit that the command exceeded, if any.
+	LimitError() error
 }
 
+// How often a command's cgroup is checked for processes that exceeded the memory limit.
+const memoryWatchInterval = 100 * time.Millisecond
+
 type command struct {
 	*exec.Cmd
 	ctx         context.Context
@@ -36,6 +45,11 @@ type command struct {
 	waitResult  error
 	waitDoneCh  chan struct{}
 	waitOnce    sync.Once
+	limits      commandLimits
+	limitMux    sync.Mutex
+	limitErr    error
+	timer       *time.Timer
+	cgroup      *memoryCgroup
 }
 
 // NewCommand creates a new command object that's tied to the passed-in
@@ -63,8 +77,26 @@ func NewCommand(ctx context.Context, cmd string, args ...string) Command {
 
 // Start is a wrapper to exec.Cmd#Start
 func (cmd *command) Start() error {
+	var cgroupProcs string
+	if memory := cmd.limits.memory; memory > 0 {
+		cgroup, err := newMemoryCgroup(memory)
+		if err != nil {
+			return fmt.Errorf("cannot limit the script's memory: %v", err)
+		}
+		cmd.cgroup = cgroup
+		cgroupProcs = cgroup.procsFile()
+	}
+	if prelude := limitsPrelude(cmd.limits, cgroupProcs); prelude != "" {
+		// Set the limits in a shell that then execs the command, so that they apply before it
+		// starts running.
+		cmd.Args = append([]string{"/bin/sh", "-c", prelude + ` && exec "$0" "$@"`, cmd.Path}, cmd.Args[1:]...)
+		cmd.Path = "/bin/sh"
+	}
 	err := cmd.Cmd.Start()
 	if err != nil {
+		if cmd.cgroup != nil {
+			cmd.cgroup.remove()
+		}
 		return err
 	}
 	// Get the command's PGID for logging. If this fails, we'll try
@@ -75,6 +107,19 @@ func (cmd *command) Start() error {
 	} else {
 		cmd.pgid = pgid
 	}
+	if timeout := cmd.limits.timeout; timeout > 0 {
+		cmd.timer = time.AfterFunc(timeout, func() {
+			select {
+			case <-cmd.waitDoneCh:
+				return
+			default:
+			}
+			cmd.exceeded(fmt.Errorf("the script timed out after %v", timeout))
+		})
+	}
+	if cmd.cgroup != nil {
+		go cmd.watchMemory()
+	}
 	// Setup the context-cancellation cleanup
 	go func() {
 		var desc string
@@ -154,11 +199,99 @@ func (cmd *command) Wait() error {
 	// our own version.
 	cmd.waitOnce.Do(func() {
 		cmd.waitResult = cmd.Cmd.Wait()
+		if cmd.timer != nil {
+			cmd.timer.Stop()
+		}
+		if cpu := cmd.limits.cpu; cpu > 0 && cmd.exceededCPU() {
+			// Kill any processes that the command started.
+			cmd.exceeded(fmt.Errorf("the script exceeded its CPU time limit of %v", cpu))
+		}
+		if cmd.cgroup != nil {
+			if cmd.cgroup.oomKilled() {
+				cmd.exceeded(cmd.memoryLimitError())
+			}
+			cmd.cgroup.remove()
+		}
 		close(cmd.waitDoneCh)
 	})
 	return cmd.waitResult
 }
 
+// SetLimits limits the command's run time and resources. If the command runs for longer than
+// its timeout, or if any of its processes are killed for exceeding the memory limit, then its
+// process group is killed. Its memory is limited with a cgroup, which limits the processes that
+// it starts combined. Its CPU time is limited with ulimit, which the processes that it starts
+// inherit.
+func (cmd *command) SetLimits(limits commandLimits) {
+	cmd.limits = limits
+}
+
+// LimitError returns an error describing the limit that the command exceeded, if any. It should
+// be called after Wait.
+func (cmd *command) LimitError() error {
+	cmd.limitMux.Lock()
+	defer cmd.limitMux.Unlock()
+	return cmd.limitErr
+}
+
+// exceeded records that the command exceeded a limit, then kills its process group.
+func (cmd *command) exceeded(err error) {
+	cmd.limitMux.Lock()
+	if cmd.limitErr != nil {
+		cmd.limitMux.Unlock()
+		return
+	}
+	cmd.limitErr = err
+	cmd.limitMux.Unlock()
+
+	activity.Record(cmd.ctx, "%v: %v. Sending SIGKILL signal", cmd, err)
+	if err := cmd.signal(syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		activity.Record(cmd.ctx, "%v: Failed to send SIGKILL signal: %v", cmd, err)
+	}
+}
+
+// watchMemory kills the command's process group if any of its processes are killed for
+// exceeding the memory limit, since the others may not work without it.
+func (cmd *command) watchMemory() {
+	ticker := time.NewTicker(memoryWatchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cmd.waitDoneCh:
+			return
+		case <-ticker.C:
+			if cmd.cgroup.oomKilled() {
+				cmd.exceeded(cmd.memoryLimitError())
+				return
+			}
+		}
+	}
+}
+
+func (cmd *command) memoryLimitError() error {
+	return fmt.Errorf("the script exceeded its memory limit of %v", humanize.Bytes(cmd.limits.memory))
+}
+
+// exceededCPU returns true if the command was killed for exceeding its CPU time limit.
+func (cmd *command) exceededCPU() bool {
+	if cmd.ProcessState == nil {
+		return false
+	}
+	status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return false
+	}
+	switch status.Signal() {
+	case syscall.SIGXCPU:
+		return true
+	case syscall.SIGKILL:
+		// SIGKILL's sent at the hard limit, which is a second after the soft limit.
+		return cmd.ProcessState.UserTime()+cmd.ProcessState.SystemTime() >= cmd.limits.cpu
+	default:
+		return false
+	}
+}
+
 func (cmd *command) signal(sig syscall.Signal) error {
 	if cmd.Process == nil {
 		panic("cmd.signal called with cmd.Process == nil")
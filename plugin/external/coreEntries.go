@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strconv"
 
+	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/puppetlabs/wash/volume"
 )
@@ -15,7 +18,20 @@ type coreEntry interface {
 }
 
 var coreEntries = map[string]coreEntry{
-	"__volume::fs__": volumeFS{},
+	"__volume::fs__":            volumeFS{},
+	"__volume::exec__":          volumeExec{},
+	"__volume::writable_exec__": volumeExec{writable: true},
+	"__metadata_json__":         metadataJSON{},
+	"__log::tail__":             logTail{},
+}
+
+// decodeCoreEntryOptions decodes the core entry's state into opts. An empty state means
+// that the defaults are used.
+func decodeCoreEntryOptions(e decodedExternalPluginEntry, opts interface{}) error {
+	if e.State == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(e.State), opts)
 }
 
 type volumeFS struct{}
@@ -35,3 +51,117 @@ func (volumeFS) createInstance(ctx context.Context, parent *pluginEntry, e decod
 func (volumeFS) template() plugin.Entry {
 	return &volume.FS{}
 }
+
+type volumeExec struct {
+	writable bool
+}
+
+func (v volumeExec) createInstance(ctx context.Context, parent *pluginEntry, e decodedExternalPluginEntry) (plugin.Entry, error) {
+	var opts struct {
+		Maxdepth uint
+		volume.Commands
+	}
+	// Use a default of 3 if unspecified.
+	opts.Maxdepth = 3
+
+	if err := decodeCoreEntryOptions(e, &opts); err != nil {
+		return nil, fmt.Errorf("volume exec options invalid: %v", err)
+	}
+
+	var fs plugin.Entry
+	var err error
+	if v.writable {
+		fs, err = volume.NewWritableCommandFS(ctx, e.Name, parent, opts.Commands, int(opts.Maxdepth))
+	} else {
+		fs, err = volume.NewCommandFS(ctx, e.Name, parent, opts.Commands, int(opts.Maxdepth))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("volume exec options invalid: %v", err)
+	}
+	return fs, nil
+}
+
+func (v volumeExec) template() plugin.Entry {
+	if v.writable {
+		return &volume.WritableCommandFS{}
+	}
+	return &volume.CommandFS{}
+}
+
+type metadataJSON struct{}
+
+func (metadataJSON) createInstance(ctx context.Context, parent *pluginEntry, e decodedExternalPluginEntry) (plugin.Entry, error) {
+	return plugin.NewMetadataJSONFile(ctx, parent)
+}
+
+func (metadataJSON) template() plugin.Entry {
+	return &plugin.MetadataJSONFile{}
+}
+
+type logTail struct{}
+
+func (logTail) createInstance(ctx context.Context, parent *pluginEntry, e decodedExternalPluginEntry) (plugin.Entry, error) {
+	var opts struct {
+		Path  string
+		Lines uint
+	}
+	// Use a default of 100 if unspecified.
+	opts.Lines = 100
+
+	if err := decodeCoreEntryOptions(e, &opts); err != nil {
+		return nil, fmt.Errorf("log tail options invalid: %v", err)
+	}
+	if opts.Path == "" {
+		return nil, fmt.Errorf("log tail options invalid: the log's path is required")
+	}
+
+	log := &logFile{
+		EntryBase: plugin.NewEntry(e.Name),
+	}
+	log.executor = parent
+	log.path = opts.Path
+	log.lines = int(opts.Lines)
+	return log, nil
+}
+
+func (logTail) template() plugin.Entry {
+	return &logFile{}
+}
+
+// logFile is a log file on its parent. Reading it returns the last lines of the log, and
+// streaming it follows the log. Both exec a command on the parent.
+type logFile struct {
+	plugin.EntryBase
+	executor plugin.Execable
+	path     string
+	lines    int
+}
+
+func (l *logFile) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(l, "log").SetDescription(logFileDescription)
+}
+
+func (l *logFile) Read(ctx context.Context) ([]byte, error) {
+	activity.Record(ctx, "Reading the last %v lines of %v on %v", l.lines, l.path, plugin.ID(l.executor))
+	command := volume.SelectShellCommand(l.executor,
+		[]string{"tail", "-n", strconv.Itoa(l.lines), l.path},
+		[]string{"Get-Content -Tail " + strconv.Itoa(l.lines) + " '" + l.path + "'"},
+	)
+	return volume.ExecOutput(ctx, l.executor, command)
+}
+
+func (l *logFile) Stream(ctx context.Context) (io.ReadCloser, error) {
+	activity.Record(ctx, "Streaming %v on %v", l.path, plugin.ID(l.executor))
+	command := volume.SelectShellCommand(l.executor,
+		[]string{"tail", "-n", strconv.Itoa(l.lines), "-f", l.path},
+		[]string{"Get-Content -Wait -Tail " + strconv.Itoa(l.lines) + " '" + l.path + "'"},
+	)
+	return volume.ExecStream(ctx, l.executor, command, true)
+}
+
+const logFileDescription = `
+This is a log file on its parent. Reading it returns the last lines of the log,
+and tailing it follows the log. Wash execs 'tail' on the parent (or Get-Content
+if the parent's login shell is PowerShell) whenever it invokes a Read or Stream
+action on the log.
+`
@@ -0,0 +1,110 @@
+package external
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/kballard/go-shellquote"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// ResourceLimits are the resources that an external plugin script's invocations can use.
+type ResourceLimits struct {
+	// Memory is the maximum amount of memory that an invocation's processes can use combined,
+	// like "512MB". It's set with a cgroup, so it limits the memory that's actually used rather
+	// than virtual memory.
+	Memory string
+	// CPU is the maximum amount of CPU time that each of an invocation's processes can use. It's
+	// set with ulimit, so each process is limited separately.
+	CPU time.Duration
+}
+
+// scriptLimits are the limits on each invocation of a plugin script.
+type scriptLimits struct {
+	timeouts map[string]time.Duration
+	memory   uint64
+	cpu      time.Duration
+}
+
+// commandLimits are the limits on a single invocation of a plugin script.
+type commandLimits struct {
+	timeout time.Duration
+	memory  uint64
+	cpu     time.Duration
+}
+
+// The methods that can be given a timeout. "default" applies to the methods that aren't given
+// one, except exec and stream.
+func timeoutMethods() []string {
+	methods := []string{"default", "init", "metadata", "schema"}
+	for name := range plugin.Actions() {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+func newScriptLimits(timeouts map[string]time.Duration, resources ResourceLimits) (scriptLimits, error) {
+	limits := scriptLimits{timeouts: timeouts, cpu: resources.CPU}
+
+	methods := timeoutMethods()
+	for method, timeout := range timeouts {
+		i := sort.SearchStrings(methods, method)
+		if i == len(methods) || methods[i] != method {
+			return scriptLimits{}, fmt.Errorf("cannot set a timeout for unknown method %v; valid methods are %v", method, strings.Join(methods, ", "))
+		}
+		if timeout < 0 {
+			return scriptLimits{}, fmt.Errorf("the %v timeout cannot be negative", method)
+		}
+	}
+	if resources.Memory != "" {
+		memory, err := humanize.ParseBytes(resources.Memory)
+		if err != nil {
+			return scriptLimits{}, fmt.Errorf("invalid memory limit %v: %v", resources.Memory, err)
+		}
+		limits.memory = memory
+	}
+	if resources.CPU < 0 {
+		return scriptLimits{}, fmt.Errorf("the CPU limit cannot be negative")
+	}
+	if (limits.memory > 0 || limits.cpu > 0) && !rlimitsSupported {
+		return scriptLimits{}, fmt.Errorf("memory and CPU limits are only supported on Linux")
+	}
+	return limits, nil
+}
+
+// forMethod returns the limits on an invocation of method.
+func (l scriptLimits) forMethod(method string) commandLimits {
+	timeout, ok := l.timeouts[method]
+	if !ok && method != "exec" && method != "stream" {
+		timeout = l.timeouts["default"]
+	}
+	return commandLimits{timeout: timeout, memory: l.memory, cpu: l.cpu}
+}
+
+// forProcess returns the limits on a long-running plugin process, which can't time out.
+func (l scriptLimits) forProcess() commandLimits {
+	return commandLimits{memory: l.memory, cpu: l.cpu}
+}
+
+// limitsPrelude returns the shell commands that join the memory limit's cgroup (if cgroupProcs
+// isn't empty) and set the CPU time limit, or "" if there aren't any.
+func limitsPrelude(limits commandLimits, cgroupProcs string) string {
+	var cmds []string
+	if cgroupProcs != "" {
+		// The shell joins the cgroup before it execs the command so that all of the command's
+		// memory is limited.
+		cmds = append(cmds, "echo $$ > "+shellquote.Join(cgroupProcs))
+	}
+	if limits.cpu > 0 {
+		// The process is sent SIGXCPU at the soft limit, then SIGKILL at the hard limit in case
+		// it handled SIGXCPU. The soft limit's set first so that it's never above the hard limit.
+		seconds := int64(math.Ceil(limits.cpu.Seconds()))
+		cmds = append(cmds, fmt.Sprintf("ulimit -S -t %v", seconds), fmt.Sprintf("ulimit -H -t %v", seconds+1))
+	}
+	return strings.Join(cmds, " && ")
+}
@@ -0,0 +1,72 @@
+package external
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LimitsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *LimitsTestSuite) TestNewScriptLimits() {
+	limits, err := newScriptLimits(
+		map[string]time.Duration{"default": time.Minute, "list": 2 * time.Minute},
+		ResourceLimits{Memory: "512MB", CPU: 10 * time.Second},
+	)
+	if suite.NoError(err) {
+		suite.Equal(uint64(512000000), limits.memory)
+		suite.Equal(10*time.Second, limits.cpu)
+	}
+
+	_, err = newScriptLimits(map[string]time.Duration{"lsit": time.Minute}, ResourceLimits{})
+	suite.Regexp("^cannot set a timeout for unknown method lsit; valid methods are .*list", err)
+
+	_, err = newScriptLimits(map[string]time.Duration{"list": -time.Minute}, ResourceLimits{})
+	suite.EqualError(err, "the list timeout cannot be negative")
+
+	_, err = newScriptLimits(nil, ResourceLimits{Memory: "lots"})
+	suite.Regexp("^invalid memory limit lots", err)
+}
+
+func (suite *LimitsTestSuite) TestForMethod() {
+	limits := scriptLimits{
+		timeouts: map[string]time.Duration{"default": time.Minute, "list": 2 * time.Minute, "stream": time.Hour},
+		memory:   1024,
+		cpu:      time.Second,
+	}
+	suite.Equal(commandLimits{timeout: 2 * time.Minute, memory: 1024, cpu: time.Second}, limits.forMethod("list"))
+	suite.Equal(commandLimits{timeout: time.Minute, memory: 1024, cpu: time.Second}, limits.forMethod("read"))
+	suite.Equal(commandLimits{timeout: time.Hour, memory: 1024, cpu: time.Second}, limits.forMethod("stream"))
+	// exec runs for as long as the command does, so the default doesn't apply.
+	suite.Equal(commandLimits{memory: 1024, cpu: time.Second}, limits.forMethod("exec"))
+	suite.Equal(commandLimits{memory: 1024, cpu: time.Second}, limits.forProcess())
+}
+
+func (suite *LimitsTestSuite) TestTimeoutKillsProcessGroup() {
+	cmd := NewCommand(context.Background(), "sh", "-c", "sleep 10 & sleep 10")
+	cmd.SetLimits(commandLimits{timeout: 100 * time.Millisecond})
+	inv := &invocationImpl{Command: cmd}
+
+	start := time.Now()
+	err := inv.RunAndWait(context.Background())
+	suite.Regexp("^the script timed out after 100ms\nCOMMAND: .*sleep 10 & sleep 10", err)
+	// The background sleep holds the script's stdout open, so Wait would block until it exits
+	// if it wasn't killed with its parent.
+	suite.True(time.Since(start) < 5*time.Second)
+}
+
+func (suite *LimitsTestSuite) TestNoLimitError() {
+	cmd := NewCommand(context.Background(), "sh", "-c", "exit 0")
+	cmd.SetLimits(commandLimits{timeout: time.Minute})
+	inv := &invocationImpl{Command: cmd}
+	suite.NoError(inv.RunAndWait(context.Background()))
+	suite.NoError(cmd.LimitError())
+}
+
+func TestLimits(t *testing.T) {
+	suite.Run(t, new(LimitsTestSuite))
+}
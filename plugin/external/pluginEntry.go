@@ -65,8 +65,9 @@ func (m *methodTuple) UnmarshalJSON(data []byte) error {
 }
 
 type execImpl struct {
-	Transport string             `json:"transport"`
-	Options   transport.Identity `json:"options"`
+	Transport string          `json:"transport"`
+	Options   json.RawMessage `json:"options"`
+	executor  transport.Executor
 }
 
 func (e decodedExternalPluginEntry) getMungedMethods() (map[string]methodInfo, error) {
@@ -104,6 +105,15 @@ func (e decodedExternalPluginEntry) getMungedMethods() (map[string]methodInfo, e
 				return nil, fmt.Errorf("Read method must provide a string, not %v", string(tuple.Value))
 			}
 			info.tupleValue = []byte(content)
+		case "write":
+			// ["write", <block_writable?>]
+			var blockWritable bool
+			if err := json.Unmarshal(tuple.Value, &blockWritable); err != nil {
+				return nil, fmt.Errorf("Write method must provide a boolean, not %v", string(tuple.Value))
+			}
+			if blockWritable {
+				info.signature = plugin.BlockWritableSignature
+			}
 		case "list":
 			var decodedEntries []decodedExternalPluginEntry
 			if err := json.Unmarshal(tuple.Value, &decodedEntries); err != nil {
@@ -126,8 +136,13 @@ func (e decodedExternalPluginEntry) getMungedMethods() (map[string]methodInfo, e
 			var impl execImpl
 			if err := json.Unmarshal(tuple.Value, &impl); err != nil {
 				return nil, fmt.Errorf("result for exec must specify an implementation transport and options")
-			} else if impl.Transport != "ssh" {
-				return nil, fmt.Errorf("unsupported transport %v requested, only ssh is supported", impl.Transport)
+			}
+			t, err := transport.Lookup(impl.Transport)
+			if err != nil {
+				return nil, err
+			}
+			if impl.executor, err = t.NewExecutor(impl.Options); err != nil {
+				return nil, err
 			}
 			info.tupleValue = impl
 		}
@@ -191,6 +206,8 @@ func (e decodedExternalPluginEntry) toExternalPluginEntry(ctx context.Context, s
 		state:       e.State,
 		schemaKnown: schemaKnown,
 		rawTypeID:   e.TypeID,
+		rawMethods:  e.Methods,
+		cacheTTLs:   e.CacheTTLs,
 	}
 	entry.SetAttributes(e.Attributes)
 	entry.SetPartialMetadata(e.PartialMetadata)
@@ -229,6 +246,9 @@ type pluginEntry struct {
 	methods   map[string]methodInfo
 	state     string
 	rawTypeID string
+	// rawMethods and cacheTTLs are kept so that the entry can be persisted.
+	rawMethods []json.RawMessage
+	cacheTTLs  decodedCacheTTLs
 	// schemaKnown is set by the root. We use it to enforce the invariant
 	// "If the root implements schema, all entries must implement schema"
 	// when decoding external plugin entries.
@@ -407,28 +427,60 @@ func (e *pluginEntry) List(ctx context.Context) ([]plugin.Entry, error) {
 
 	entries := make([]plugin.Entry, len(decodedEntries))
 	for i, decodedExternalPluginEntry := range decodedEntries {
-		if coreEnt, ok := coreEntries[decodedExternalPluginEntry.TypeID]; ok {
-			entry, err := coreEnt.createInstance(ctx, e, decodedExternalPluginEntry)
-			if err != nil {
-				return nil, err
-			}
-			entries[i] = entry
-			continue
-		}
-
-		entry, err := decodedExternalPluginEntry.toExternalPluginEntry(ctx, e.schemaKnown, false)
+		entry, err := e.newChild(ctx, decodedExternalPluginEntry)
 		if err != nil {
 			return nil, err
 		}
-
-		entry.script = e.script
-		entry.schemaGraphs = e.schemaGraphs
 		entries[i] = entry
 	}
 
 	return entries, nil
 }
 
+// newChild converts the decoded entry into one of e's children.
+func (e *pluginEntry) newChild(ctx context.Context, decodedEntry decodedExternalPluginEntry) (plugin.Entry, error) {
+	if coreEnt, ok := coreEntries[decodedEntry.TypeID]; ok {
+		return coreEnt.createInstance(ctx, e, decodedEntry)
+	}
+
+	entry, err := decodedEntry.toExternalPluginEntry(ctx, e.schemaKnown, false)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.script = e.script
+	entry.schemaGraphs = e.schemaGraphs
+	return entry, nil
+}
+
+// PersistChild persists the parts of the child's decoded form that aren't persisted by
+// Wash, so that RestoreChild can decode it again. Core entries can't be persisted.
+func (e *pluginEntry) PersistChild(child plugin.Entry) ([]byte, error) {
+	c, ok := child.(*pluginEntry)
+	if !ok {
+		return nil, fmt.Errorf("core entries cannot be persisted")
+	}
+	return json.Marshal(decodedExternalPluginEntry{
+		TypeID:    c.rawTypeID,
+		Methods:   c.rawMethods,
+		CacheTTLs: c.cacheTTLs,
+		State:     c.state,
+	})
+}
+
+// RestoreChild decodes a child that was persisted by PersistChild.
+func (e *pluginEntry) RestoreChild(ctx context.Context, child plugin.PersistedEntry) (plugin.Entry, error) {
+	var decodedEntry decodedExternalPluginEntry
+	if err := json.Unmarshal(child.State, &decodedEntry); err != nil {
+		return nil, err
+	}
+	decodedEntry.Name = child.Name
+	decodedEntry.SlashReplacer = string(child.SlashReplacer)
+	decodedEntry.Attributes = child.Attributes
+	decodedEntry.PartialMetadata = child.PartialMetadata
+	return e.newChild(ctx, decodedEntry)
+}
+
 func (e *pluginEntry) Read(ctx context.Context) ([]byte, error) {
 	if impl := e.methods["read"].tupleValue; impl != nil {
 		return impl.([]byte), nil
@@ -455,6 +507,35 @@ func (e *pluginEntry) Write(ctx context.Context, p []byte) error {
 	return inv.RunAndWait(ctx)
 }
 
+// OpenWriter returns a writer that invokes the block-writable calling convention of the
+// script's write method for each block.
+func (e *pluginEntry) OpenWriter(ctx context.Context) (plugin.BlockWriter, error) {
+	return &blockWriter{entry: e}, nil
+}
+
+// blockWriter writes an external plugin entry's data in blocks. Each block's written with the
+// script's write method, and truncations use its truncate method. The script's commit method is
+// invoked when the writer's closed so that the script can apply any changes that it buffered.
+type blockWriter struct {
+	entry *pluginEntry
+}
+
+func (w *blockWriter) WriteAt(ctx context.Context, p []byte, offset int64) error {
+	inv := w.entry.script.NewInvocation(ctx, "write", w.entry, strconv.FormatInt(offset, 10), strconv.Itoa(len(p)))
+	inv.SetStdin(bytes.NewReader(p))
+	return inv.RunAndWait(ctx)
+}
+
+func (w *blockWriter) Truncate(ctx context.Context, size int64) error {
+	_, err := w.entry.script.InvokeAndWait(ctx, "truncate", w.entry, strconv.FormatInt(size, 10))
+	return err
+}
+
+func (w *blockWriter) Close(ctx context.Context) error {
+	_, err := w.entry.script.InvokeAndWait(ctx, "commit", w.entry)
+	return err
+}
+
 func (e *pluginEntry) Metadata(ctx context.Context) (plugin.JSONObject, error) {
 	if !e.implements("metadata") {
 		// The entry does not override the "Metadata" method so invoke
@@ -483,6 +564,36 @@ func (e *pluginEntry) Signal(ctx context.Context, signal string) error {
 	return err
 }
 
+const entryFormat = "{\"name\":\"entry1\",\"methods\":[\"read\",\"write\"]}"
+
+func (e *pluginEntry) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	inv, err := e.script.InvokeAndWait(ctx, "create", e, name, strconv.FormatBool(isParent))
+	if err != nil {
+		return nil, err
+	}
+	var decodedEntry decodedExternalPluginEntry
+	if err := json.Unmarshal(inv.Stdout().Bytes(), &decodedEntry); err != nil {
+		return nil, newStdoutDecodeErr(ctx, "the created entry", err, inv, entryFormat)
+	}
+	return e.newChild(ctx, decodedEntry)
+}
+
+func (e *pluginEntry) Rename(ctx context.Context, newParent plugin.Parent, newName string) (plugin.Entry, error) {
+	parent, ok := newParent.(*pluginEntry)
+	if !ok || parent.script.Path() != e.script.Path() {
+		return nil, fmt.Errorf("%v is not an entry of the %v plugin", plugin.ID(newParent), pluginName(e))
+	}
+	inv, err := e.script.InvokeAndWait(ctx, "rename", e, plugin.ID(parent), parent.state, newName)
+	if err != nil {
+		return nil, err
+	}
+	var decodedEntry decodedExternalPluginEntry
+	if err := json.Unmarshal(inv.Stdout().Bytes(), &decodedEntry); err != nil {
+		return nil, newStdoutDecodeErr(ctx, "the renamed entry", err, inv, entryFormat)
+	}
+	return parent.newChild(ctx, decodedEntry)
+}
+
 func (e *pluginEntry) Delete(ctx context.Context) (deleted bool, err error) {
 	inv, err := e.script.InvokeAndWait(ctx, "delete", e)
 	if err != nil {
@@ -587,28 +698,26 @@ func (e *pluginEntry) Stream(ctx context.Context) (io.ReadCloser, error) {
 	}
 }
 
-// Used for mocking tests.
-var execSSHFn = transport.ExecSSH
+// serializedExecOptions are the options that are passed to the plugin's exec method.
+type serializedExecOptions struct {
+	plugin.ExecOptions
+	Stdin bool `json:"stdin"`
+	// Timeout is in seconds. The plugin script's killed once it elapses,
+	// but it's included so that the script can pass it along to its API.
+	Timeout float64 `json:"timeout,omitempty"`
+}
 
 func (e *pluginEntry) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
 	if result := e.methods["exec"].tupleValue; result != nil {
 		impl := result.(execImpl)
-		if impl.Transport != "ssh" {
-			panic("transport must be ssh")
-		}
-
-		args = append([]string{cmd}, args...)
-		return execSSHFn(ctx, impl.Options, args, opts)
+		return impl.executor.Exec(ctx, append([]string{cmd}, args...), opts)
 	}
 
 	// Serialize opts to JSON
-	type serializedOptions struct {
-		plugin.ExecOptions
-		Stdin bool `json:"stdin"`
-	}
-	serializedOpts := serializedOptions{
+	serializedOpts := serializedExecOptions{
 		ExecOptions: opts,
 		Stdin:       opts.Stdin != nil,
+		Timeout:     opts.Timeout.Seconds(),
 	}
 	optsJSON, err := json.Marshal(serializedOpts)
 	if err != nil {
@@ -639,7 +748,9 @@ func (e *pluginEntry) Exec(ctx context.Context, cmd string, args []string, opts
 		err := inv.Wait()
 		execCmd.CloseStreamsWithError(nil)
 		exitCode := inv.ExitCode()
-		if exitCode < 0 {
+		if limitErr := inv.LimitError(); limitErr != nil {
+			execCmd.SetExitCodeErr(newInvokeError(limitErr.Error(), inv))
+		} else if exitCode < 0 {
 			execCmd.SetExitCodeErr(err)
 		} else {
 			execCmd.SetExitCode(exitCode)
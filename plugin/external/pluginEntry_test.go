@@ -30,6 +30,10 @@ func (m *mockPluginScript) Path() string {
 	return m.path
 }
 
+func (m *mockPluginScript) Limits() scriptLimits {
+	return scriptLimits{}
+}
+
 func (m *mockPluginScript) InvokeAndWait(
 	ctx context.Context,
 	method string,
@@ -170,6 +174,29 @@ func (suite *ExternalPluginEntryTestSuite) TestDecodeExternalPluginEntryMethodTu
 	}
 }
 
+func (suite *ExternalPluginEntryTestSuite) TestDecodeExternalPluginEntryMethodTuple_Write() {
+	decodedEntry := decodedExternalPluginEntry{
+		Name:    "decodedEntry",
+		Methods: rawMethods(`["write", true]`),
+	}
+	entry, err := decodedEntry.toExternalPluginEntry(context.Background(), false, false)
+	if suite.NoError(err) {
+		suite.Equal(plugin.BlockWritableSignature, entry.methods["write"].signature)
+		suite.True(plugin.IsBlockWritable(entry))
+	}
+
+	decodedEntry.Methods = rawMethods(`["write", false]`)
+	entry, err = decodedEntry.toExternalPluginEntry(context.Background(), false, false)
+	if suite.NoError(err) {
+		suite.Equal(plugin.DefaultSignature, entry.methods["write"].signature)
+		suite.False(plugin.IsBlockWritable(entry))
+	}
+
+	decodedEntry.Methods = rawMethods(`["write", "foo"]`)
+	_, err = decodedEntry.toExternalPluginEntry(context.Background(), false, false)
+	suite.EqualError(err, `Write method must provide a boolean, not "foo"`)
+}
+
 func newMockDecodedEntry(name string) decodedExternalPluginEntry {
 	return decodedExternalPluginEntry{
 		Name:    name,
@@ -556,6 +583,7 @@ func (suite *ExternalPluginEntryTestSuite) TestList() {
 				script:       entry.script,
 				schemaGraphs: entry.schemaGraphs,
 				rawTypeID:    "bar",
+				rawMethods:   rawMethods(`"list"`),
 			},
 		}
 
@@ -697,12 +725,136 @@ func (suite *ExternalPluginEntryTestSuite) TestListWithCoreEntry() {
 		suite.Equal([]string{"list"}, plugin.SupportedActionsOf(entries[0]))
 		suite.Equal("bar", plugin.Name(entries[0]))
 
-		suite.Equal([]string{"list"}, plugin.SupportedActionsOf(entries[1]))
+		suite.ElementsMatch([]string{"list", "create"}, plugin.SupportedActionsOf(entries[1]))
 		suite.Equal("fs1", plugin.Name(entries[1]))
 		suite.IsType(&volume.FS{}, entries[1])
 	}
 }
 
+func (suite *ExternalPluginEntryTestSuite) TestListWithOtherCoreEntries() {
+	// Provide a test cache because listing the volume invokes `plugin.List`.
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	defer plugin.UnsetTestCache()
+
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+	entry.SetPartialMetadata(map[string]interface{}{"key": "value"})
+
+	stdout := []byte(`
+[
+	{"type_id": "__metadata_json__", "name": "metadata.json"},
+	{"type_id": "__log::tail__", "name": "syslog", "state": "{\"path\": \"/var/log/syslog\", \"lines\": 10}"},
+	{"type_id": "__volume::exec__", "name": "archive", "state": "{\"list\": [\"ls\", \"{path}\"], \"read\": [\"cat\", \"{path}\"]}"},
+	{"type_id": "__volume::writable_exec__", "name": "bucket", "state": "{\"list\": [\"ls\", \"{path}\"], \"read\": [\"cat\", \"{path}\"], \"write\": [\"put\", \"{path}\"], \"create\": [\"touch\", \"{path}\"], \"mkdir\": [\"mkdir\", \"{path}\"], \"delete\": [\"rm\", \"{path}\"]}"}
+]`)
+	mockScript.OnInvokeAndWait(ctx, "list", entry).Return(mockInvocation(stdout), nil).Once()
+
+	// Listing the volumes execs their list commands.
+	for i := 0; i < 2; i++ {
+		mockInv := &mockedInvocation{Command: NewCommand(ctx, "")}
+		mockInv.MockExec(nil, nil, 0)
+		mockScript.On("NewInvocation", mock.Anything, "exec", entry, mock.Anything).Return(mockInv).Once()
+	}
+
+	entries, err := entry.List(ctx)
+	if suite.NoError(err) && suite.Equal(4, len(entries)) {
+		suite.IsType(&plugin.MetadataJSONFile{}, entries[0])
+		suite.Equal("metadata.json", plugin.Name(entries[0]))
+		content, err := entries[0].(plugin.Readable).Read(ctx)
+		if suite.NoError(err) {
+			suite.JSONEq(`{"key":"value"}`, string(content))
+		}
+
+		suite.IsType(&logFile{}, entries[1])
+		suite.Equal("syslog", plugin.Name(entries[1]))
+		suite.ElementsMatch([]string{"read", "stream"}, plugin.SupportedActionsOf(entries[1]))
+		suite.Equal("/var/log/syslog", entries[1].(*logFile).path)
+		suite.Equal(10, entries[1].(*logFile).lines)
+
+		suite.IsType(&volume.CommandFS{}, entries[2])
+		suite.Equal("archive", plugin.Name(entries[2]))
+		suite.ElementsMatch([]string{"list"}, plugin.SupportedActionsOf(entries[2]))
+
+		suite.IsType(&volume.WritableCommandFS{}, entries[3])
+		suite.Equal("bucket", plugin.Name(entries[3]))
+		suite.ElementsMatch([]string{"list", "create"}, plugin.SupportedActionsOf(entries[3]))
+	}
+}
+
+func (suite *ExternalPluginEntryTestSuite) TestPersistAndRestoreChild() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+
+	ctx := context.Background()
+	stdout := []byte(`
+[
+	{"name": "bar/", "slash_replacer": ":", "methods": ["list", ["read", "content"]], "cache_ttls": {"list": 1}, "attributes": {"size": 7}, "partial_metadata": {"key": "value"}, "state": "some state"}
+]`)
+	mockScript.OnInvokeAndWait(ctx, "list", entry).Return(mockInvocation(stdout), nil).Once()
+	entries, err := entry.List(ctx)
+	if !suite.NoError(err) {
+		return
+	}
+
+	state, err := entry.PersistChild(entries[0])
+	if !suite.NoError(err) {
+		return
+	}
+	restored, err := entry.RestoreChild(ctx, plugin.PersistedEntry{
+		Name:            plugin.Name(entries[0]),
+		SlashReplacer:   ':',
+		Attributes:      plugin.Attributes(entries[0]),
+		PartialMetadata: plugin.PartialMetadata(entries[0]),
+		State:           state,
+	})
+	if suite.NoError(err) {
+		suite.Equal("bar:", plugin.CName(restored))
+		suite.ElementsMatch(plugin.SupportedActionsOf(entries[0]), plugin.SupportedActionsOf(restored))
+		suite.Equal(time.Second, restored.(*pluginEntry).TTLOf(plugin.ListOp))
+		attr := plugin.Attributes(restored)
+		suite.Equal(uint64(7), attr.Size())
+		suite.Equal(plugin.JSONObject{"key": "value"}, plugin.PartialMetadata(restored))
+		suite.Equal("some state", restored.(*pluginEntry).state)
+		suite.Equal(mockScript, restored.(*pluginEntry).script)
+		content, err := restored.(*pluginEntry).Read(ctx)
+		if suite.NoError(err) {
+			suite.Equal([]byte("content"), content)
+		}
+	}
+
+	// Core entries can't be persisted.
+	_, err = entry.PersistChild(&volume.FS{})
+	suite.Error(err)
+}
+
+func (suite *ExternalPluginEntryTestSuite) TestListWithInvalidCoreEntryOptions() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+
+	ctx := context.Background()
+	stdout := []byte(`[{"type_id": "__log::tail__", "name": "syslog", "state": "{\"lines\": 10}"}]`)
+	mockScript.OnInvokeAndWait(ctx, "list", entry).Return(mockInvocation(stdout), nil).Once()
+	_, err := entry.List(ctx)
+	suite.EqualError(err, "log tail options invalid: the log's path is required")
+
+	stdout = []byte(`[{"type_id": "__volume::exec__", "name": "archive", "state": "{\"list\": [\"ls\"]}"}]`)
+	mockScript.OnInvokeAndWait(ctx, "list", entry).Return(mockInvocation(stdout), nil).Once()
+	_, err = entry.List(ctx)
+	suite.EqualError(err, "volume exec options invalid: the list and read commands are required")
+}
+
 func (suite *ExternalPluginEntryTestSuite) TestListWithUnknownCoreEntry() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
 	entry := &pluginEntry{
@@ -747,8 +899,11 @@ func (suite *ExternalPluginEntryTestSuite) TestListWithExec_Transport() {
 				if suite.IsType(execImpl{}, info.tupleValue) {
 					exec := info.tupleValue.(execImpl)
 					suite.Equal("ssh", exec.Transport)
-					suite.Equal("example.com", exec.Options.Host)
-					suite.Equal("ubuntu", exec.Options.User)
+					if suite.IsType(transport.Identity{}, exec.executor) {
+						id := exec.executor.(transport.Identity)
+						suite.Equal("example.com", id.Host)
+						suite.Equal("ubuntu", id.User)
+					}
 				}
 			}
 		}
@@ -770,7 +925,25 @@ func (suite *ExternalPluginEntryTestSuite) TestListWithExec_TransportUnknown() {
 	mockScript.OnInvokeAndWait(ctx, "list", entry).Return(mockInvocation(stdout), nil).Once()
 
 	_, err := entry.List(ctx)
-	suite.EqualError(err, "unsupported transport foo requested, only ssh is supported")
+	suite.EqualError(err, "unsupported transport foo requested, supported transports are docker, kubectl, ssh, winrm")
+}
+
+func (suite *ExternalPluginEntryTestSuite) TestListWithExec_TransportInvalidOptions() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+
+	ctx := context.Background()
+	stdout := []byte(`[
+	{"name": "bar", "methods": [["exec", {"transport": "winrm", "options": {"user": "Administrator"}}]]}
+]`)
+	mockScript.OnInvokeAndWait(ctx, "list", entry).Return(mockInvocation(stdout), nil).Once()
+
+	_, err := entry.List(ctx)
+	suite.EqualError(err, "the winrm transport's host option must be provided")
 }
 
 func (suite *ExternalPluginEntryTestSuite) TestListWithExec_Unknown() {
@@ -854,6 +1027,53 @@ func (suite *ExternalPluginEntryTestSuite) TestWrite() {
 	suite.NoError(err)
 }
 
+func (suite *ExternalPluginEntryTestSuite) TestBlockWrite() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+
+	ctx := context.Background()
+	mockWrite := func(stdin string, err error, args ...string) {
+		mockInv := &mockedInvocation{Command: NewCommand(ctx, "")}
+		mockScript.On("NewInvocation", ctx, "write", entry, args).Return(mockInv).Once()
+		mockInv.On("RunAndWait", ctx).Return(err).Run(func(mock.Arguments) {
+			data, readErr := ioutil.ReadAll(mockInv.Command.(*command).Stdin)
+			suite.NoError(readErr)
+			suite.Equal(stdin, string(data))
+		}).Once()
+	}
+
+	w, err := entry.OpenWriter(ctx)
+	if !suite.NoError(err) {
+		return
+	}
+
+	mockScript.OnInvokeAndWait(ctx, "truncate", entry, "0").Return(mockInvocation(nil), nil).Once()
+	suite.NoError(w.Truncate(ctx, 0))
+
+	mockWrite("hello", nil, "0", "5")
+	suite.NoError(w.WriteAt(ctx, []byte("hello"), 0))
+
+	// Empty writes are passed along as-is, they don't truncate the entry.
+	mockWrite("", nil, "5", "0")
+	suite.NoError(w.WriteAt(ctx, []byte{}, 5))
+
+	mockErr := fmt.Errorf("execution error")
+	mockWrite(" there", mockErr, "5", "6")
+	suite.EqualError(w.WriteAt(ctx, []byte(" there"), 5), mockErr.Error())
+
+	mockScript.OnInvokeAndWait(ctx, "truncate", entry, "3").Return(mockInvocation(nil), mockErr).Once()
+	suite.EqualError(w.Truncate(ctx, 3), mockErr.Error())
+
+	// Closing the writer commits the writes.
+	mockScript.OnInvokeAndWait(ctx, "commit", entry).Return(mockInvocation(nil), nil).Once()
+	suite.NoError(w.Close(ctx))
+	mockScript.AssertExpectations(suite.T())
+}
+
 func (suite *ExternalPluginEntryTestSuite) TestDecodeWithErrors() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
 	entry := &pluginEntry{
@@ -1000,17 +1220,39 @@ func (suite *ExternalPluginEntryTestSuite) TestExec() {
 	}
 }
 
-func (suite *ExternalPluginEntryTestSuite) TestExec_Transport() {
-	// Mock transport.ExecSSH
-	savedFn := execSSHFn
-	var execMock mock.Mock
-	execSSHFn = func(ctx context.Context, id transport.Identity, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
-		args := execMock.Called(ctx, id, cmd, opts)
-		return args.Get(0).(plugin.ExecCommand), args.Error(1)
+func (suite *ExternalPluginEntryTestSuite) TestExec_SerializesOptions() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		methods:   map[string]methodInfo{"exec": methodInfo{}},
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+
+	ctx := context.Background()
+	mockInv := &mockedInvocation{Command: NewCommand(ctx, "")}
+	expectedOpts := `{"tty":false,"elevate":false,"env":{"FOO":"bar"},"dir":"/tmp","user":"root","stdin":false,"timeout":1.5}`
+	mockScript.On("NewInvocation", ctx, "exec", entry, []string{expectedOpts, "pwd"}).Return(mockInv).Once()
+	mockInv.MockExec(nil, nil, 0)
+
+	opts := plugin.ExecOptions{
+		Env:     map[string]string{"FOO": "bar"},
+		Dir:     "/tmp",
+		User:    "root",
+		Timeout: 1500 * time.Millisecond,
+	}
+	cmd, err := entry.Exec(ctx, "pwd", []string{}, opts)
+	if suite.NoError(err) {
+		<-cmd.OutputCh()
+		_, err := cmd.ExitCode()
+		suite.NoError(err)
+		mockScript.AssertExpectations(suite.T())
 	}
-	defer func() { execSSHFn = savedFn }()
+}
 
-	execVals := execImpl{Transport: "ssh", Options: transport.Identity{Host: "example.com"}}
+func (suite *ExternalPluginEntryTestSuite) TestExec_Transport() {
+	executor := &mockExecutor{}
+	execVals := execImpl{Transport: "mock", executor: executor}
 	entry := &pluginEntry{
 		EntryBase: plugin.NewEntry("foo"),
 		methods:   map[string]methodInfo{"exec": methodInfo{tupleValue: execVals}},
@@ -1021,18 +1263,27 @@ func (suite *ExternalPluginEntryTestSuite) TestExec_Transport() {
 	ctx, mockErr := context.Background(), fmt.Errorf("execution error")
 	args, result := []string{"echo", "hello"}, plugin.NewExecCommand(ctx)
 
-	// Test that if ExecSSH errors then Exec returns the error
-	execMock.On("func1", ctx, execVals.Options, args, opts).Return(result, mockErr).Once()
+	// Test that if the transport's executor errors then Exec returns the error
+	executor.On("Exec", ctx, args, opts).Return(result, mockErr).Once()
 	_, err := entry.Exec(ctx, "echo", []string{"hello"}, opts)
 	suite.EqualError(err, mockErr.Error())
 
-	// Test that if ExecSSH runs then Exec returns the result
-	execMock.On("func1", ctx, execVals.Options, args, opts).Return(result, nil).Once()
+	// Test that if the transport's executor runs then Exec returns the result
+	executor.On("Exec", ctx, args, opts).Return(result, nil).Once()
 	cmd, err := entry.Exec(ctx, "echo", []string{"hello"}, opts)
 	suite.NoError(err)
 	suite.Equal(cmd, result)
 }
 
+type mockExecutor struct {
+	mock.Mock
+}
+
+func (m *mockExecutor) Exec(ctx context.Context, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	args := m.Called(ctx, cmd, opts)
+	return args.Get(0).(plugin.ExecCommand), args.Error(1)
+}
+
 func (suite *ExternalPluginEntryTestSuite) TestSignal() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
 	entry := &pluginEntry{
@@ -1096,6 +1347,93 @@ func (suite *ExternalPluginEntryTestSuite) TestDelete() {
 	}
 }
 
+func (suite *ExternalPluginEntryTestSuite) TestCreate() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		methods:   map[string]methodInfo{"create": methodInfo{}, "list": methodInfo{}},
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+
+	ctx := context.Background()
+	mockInvokeAndWait := func(stdout []byte, err error) {
+		mockScript.OnInvokeAndWait(ctx, "create", entry, "bar", "true").Return(mockInvocation(stdout), err).Once()
+	}
+
+	// Test that if InvokeAndWait errors, then Create returns its error
+	mockErr := fmt.Errorf("execution error")
+	mockInvokeAndWait([]byte{}, mockErr)
+	_, err := entry.Create(ctx, "bar", true)
+	suite.EqualError(err, mockErr.Error())
+
+	// Test that Create returns an error if stdout does not have the right
+	// output format
+	mockInvokeAndWait([]byte("bad format"), nil)
+	_, err = entry.Create(ctx, "bar", true)
+	suite.Regexp(regexp.MustCompile("stdout"), err)
+
+	// Test that Create properly decodes the created entry from stdout
+	mockInvokeAndWait([]byte(`{"name":"bar","methods":["list"]}`), nil)
+	created, err := entry.Create(ctx, "bar", true)
+	if suite.NoError(err) {
+		suite.Equal("bar", plugin.Name(created))
+		suite.Equal([]string{"list"}, plugin.SupportedActionsOf(created))
+	}
+}
+
+func (suite *ExternalPluginEntryTestSuite) TestRename() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	entry := &pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		methods:   map[string]methodInfo{"rename": methodInfo{}},
+		script:    mockScript,
+	}
+	entry.SetTestID("/foo")
+	newParent := &pluginEntry{
+		EntryBase: plugin.NewEntry("bar"),
+		methods:   map[string]methodInfo{"list": methodInfo{}},
+		script:    mockScript,
+		state:     "some state",
+	}
+	newParent.SetTestID("/bar")
+
+	ctx := context.Background()
+	mockInvokeAndWait := func(stdout []byte, err error) {
+		mockScript.OnInvokeAndWait(ctx, "rename", entry, "/bar", "some state", "baz").Return(mockInvocation(stdout), err).Once()
+	}
+
+	// Test that Rename returns an error if the new parent belongs to a
+	// different plugin
+	otherParent := &pluginEntry{
+		EntryBase: plugin.NewEntry("other"),
+		script:    &mockPluginScript{path: "other_script"},
+	}
+	otherParent.SetTestID("/other")
+	_, err := entry.Rename(ctx, otherParent, "baz")
+	suite.Regexp("not an entry of the foo plugin", err)
+
+	// Test that if InvokeAndWait errors, then Rename returns its error
+	mockErr := fmt.Errorf("execution error")
+	mockInvokeAndWait([]byte{}, mockErr)
+	_, err = entry.Rename(ctx, newParent, "baz")
+	suite.EqualError(err, mockErr.Error())
+
+	// Test that Rename returns an error if stdout does not have the right
+	// output format
+	mockInvokeAndWait([]byte("bad format"), nil)
+	_, err = entry.Rename(ctx, newParent, "baz")
+	suite.Regexp(regexp.MustCompile("stdout"), err)
+
+	// Test that Rename properly decodes the renamed entry from stdout
+	mockInvokeAndWait([]byte(`{"name":"baz","methods":["rename"]}`), nil)
+	renamed, err := entry.Rename(ctx, newParent, "baz")
+	if suite.NoError(err) {
+		suite.Equal("baz", plugin.Name(renamed))
+		suite.Equal([]string{"rename"}, plugin.SupportedActionsOf(renamed))
+	}
+}
+
 // TODO: Add tests for stdoutStreamer, Stream and Exec
 // once the API for Stream and Exec's at a more stable
 // state.
@@ -4,15 +4,42 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/emirpasic/gods/maps/linkedhashmap"
 	"github.com/puppetlabs/wash/plugin"
 )
 
+// decodedExternalPluginRoot describes the plugin root that's returned by init. It includes
+// settings that apply to the whole plugin.
+type decodedExternalPluginRoot struct {
+	decodedExternalPluginEntry
+	// RPC opts into keeping a single plugin process running that's sent method invocations
+	// as JSON-RPC requests.
+	RPC bool `json:"rpc"`
+	// ProtocolVersion and Capabilities are the plugin's side of protocol negotiation.
+	ProtocolVersion int      `json:"protocol_version"`
+	Capabilities    []string `json:"capabilities"`
+}
+
 // pluginRoot represents an external plugin's root.
 type pluginRoot struct {
 	pluginEntry
+	protocol Protocol
+}
+
+// Close stops the plugin process if the plugin uses RPC.
+func (r *pluginRoot) Close() error {
+	if closer, ok := r.script.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Protocol returns the protocol that was negotiated with the plugin at init.
+func (r *pluginRoot) Protocol() Protocol {
+	return r.protocol
 }
 
 // Init initializes the external plugin root
@@ -29,7 +56,7 @@ func (r *pluginRoot) Init(cfg map[string]interface{}) error {
 	// initialization
 	ctx, cancelFunc := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelFunc()
-	inv, err := r.script.InvokeAndWait(ctx, "init", nil, string(cfgJSON))
+	inv, err := r.script.InvokeAndWait(ctx, "init", nil, string(cfgJSON), protocolOffer())
 	if err != nil {
 		select {
 		case <-ctx.Done():
@@ -38,7 +65,7 @@ func (r *pluginRoot) Init(cfg map[string]interface{}) error {
 			return err
 		}
 	}
-	var decodedRoot decodedExternalPluginEntry
+	var decodedRoot decodedExternalPluginRoot
 	if err := json.Unmarshal(inv.Stdout().Bytes(), &decodedRoot); err != nil {
 		return newStdoutDecodeErr(
 			context.Background(),
@@ -49,6 +76,11 @@ func (r *pluginRoot) Init(cfg map[string]interface{}) error {
 		)
 	}
 
+	protocol, err := negotiateProtocol(decodedRoot.ProtocolVersion, decodedRoot.Capabilities)
+	if err != nil {
+		return err
+	}
+
 	// Fill in required fields with data we already know.
 	if decodedRoot.Name == "" {
 		decodedRoot.Name = r.Name()
@@ -67,8 +99,12 @@ it's safe to omit name from the response to 'init'`, r.script.Path()))
 		panic(fmt.Sprintf("plugin root for %s must implement 'list'", r.script.Path()))
 	}
 	script := r.script
+	if decodedRoot.RPC {
+		script = newRPCPluginScript(script.Path(), script.Limits(), string(cfgJSON))
+	}
 	r.pluginEntry = *entry
 	r.pluginEntry.script = script
+	r.protocol = protocol
 
 	// Fill in the schema graph if provided
 	if val := r.methods["schema"].tupleValue; val != nil {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/emirpasic/gods/maps/linkedhashmap"
 	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
@@ -19,7 +20,7 @@ type ExternalPluginRootTestSuite struct {
 
 func (suite *ExternalPluginRootTestSuite) TestInit() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("foo"),
 		script:    mockScript,
 	}}
@@ -30,6 +31,7 @@ func (suite *ExternalPluginRootTestSuite) TestInit() {
 			"init",
 			nil,
 			"{}",
+			protocolOffer(),
 		).Return(mockInvocation(stdout), err).Once()
 	}
 
@@ -56,9 +58,12 @@ func (suite *ExternalPluginRootTestSuite) TestInit() {
 				methods: map[string]methodInfo{
 					"list": methodInfo{signature: plugin.DefaultSignature},
 				},
-				script:    root.script,
-				rawTypeID: "foo_type",
+				script:     root.script,
+				rawTypeID:  "foo_type",
+				rawMethods: rawMethods(`"list"`),
 			},
+			// Plugins that don't report a protocol version implement version 1.
+			protocol: Protocol{Version: 1},
 		}
 
 		suite.Equal(expectedRoot, root)
@@ -67,7 +72,7 @@ func (suite *ExternalPluginRootTestSuite) TestInit() {
 
 func (suite *ExternalPluginRootTestSuite) TestInitWithConfig() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("foo"),
 		script:    mockScript,
 	}}
@@ -77,14 +82,109 @@ func (suite *ExternalPluginRootTestSuite) TestInitWithConfig() {
 		"init",
 		nil,
 		`{"key":["value"]}`,
+		protocolOffer(),
 	).Return(mockInvocation([]byte("{}")), nil).Once()
 
 	suite.NoError(root.Init(map[string]interface{}{"key": []string{"value"}}))
 }
 
+func (suite *ExternalPluginRootTestSuite) TestInitNegotiatesProtocol() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	root := &pluginRoot{pluginEntry: pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		script:    mockScript,
+	}}
+
+	mockInvokeAndWait := func(stdout string) {
+		mockScript.OnInvokeAndWait(
+			mock.Anything,
+			"init",
+			nil,
+			"{}",
+			protocolOffer(),
+		).Return(mockInvocation([]byte(stdout)), nil).Once()
+	}
+
+	mockInvokeAndWait(`{"protocol_version":2,"capabilities":["rpc","block_read"]}`)
+	if suite.NoError(root.Init(nil)) {
+		suite.Equal(Protocol{Version: 2, Capabilities: []string{"rpc", "block_read"}}, root.Protocol())
+	}
+
+	mockInvokeAndWait(`{"protocol_version":3}`)
+	suite.EqualError(root.Init(nil), "the plugin implements protocol version 3, but Wash supports versions 1 to 2")
+
+	mockInvokeAndWait(`{"protocol_version":2,"capabilities":["rpc","teleport","time_travel"]}`)
+	suite.EqualError(root.Init(nil), "the plugin requires capabilities that Wash doesn't support: teleport, time_travel")
+}
+
+func (suite *ExternalPluginRootTestSuite) TestProtocolOffer() {
+	var offer struct {
+		ProtocolVersion    int      `json:"protocol_version"`
+		MinProtocolVersion int      `json:"min_protocol_version"`
+		Capabilities       []string `json:"capabilities"`
+	}
+	if suite.NoError(json.Unmarshal([]byte(protocolOffer()), &offer)) {
+		suite.Equal(2, offer.ProtocolVersion)
+		suite.Equal(1, offer.MinProtocolVersion)
+		suite.Contains(offer.Capabilities, "rpc")
+		suite.Contains(offer.Capabilities, "core_entry:volume::fs")
+	}
+}
+
+func (suite *ExternalPluginRootTestSuite) TestProtocolOffer_AdvertisesActionsTransportsAndExecOptions() {
+	caps := capabilities()
+	for name := range plugin.Actions() {
+		suite.Contains(caps, "action:"+name)
+	}
+	for _, name := range transport.Names() {
+		suite.Contains(caps, "exec_transport:"+name)
+	}
+
+	// Every option that's serialized for the plugin's exec method should be advertised.
+	opts := serializedExecOptions{
+		ExecOptions: plugin.ExecOptions{
+			Tty:     true,
+			Elevate: true,
+			Env:     map[string]string{"FOO": "bar"},
+			Dir:     "/tmp",
+			User:    "root",
+		},
+		Stdin:   true,
+		Timeout: 1,
+	}
+	optsJSON, err := json.Marshal(opts)
+	if suite.NoError(err) {
+		var serializedOpts map[string]interface{}
+		suite.NoError(json.Unmarshal(optsJSON, &serializedOpts))
+		for name := range serializedOpts {
+			suite.Contains(caps, "exec_option:"+name)
+		}
+	}
+}
+
+func (suite *ExternalPluginRootTestSuite) TestInitWithRPC() {
+	mockScript := &mockPluginScript{path: "plugin_script"}
+	root := &pluginRoot{pluginEntry: pluginEntry{
+		EntryBase: plugin.NewEntry("foo"),
+		script:    mockScript,
+	}}
+
+	mockScript.OnInvokeAndWait(
+		mock.Anything,
+		"init",
+		nil,
+		`{"key":"value"}`,
+		protocolOffer(),
+	).Return(mockInvocation([]byte(`{"rpc":true}`)), nil).Once()
+
+	if suite.NoError(root.Init(map[string]interface{}{"key": "value"})) {
+		suite.Equal(newRPCPluginScript("plugin_script", scriptLimits{}, `{"key":"value"}`), root.script)
+	}
+}
+
 func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_SetsSchemaKnownVariable() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("foo"),
 		script:    mockScript,
 	}}
@@ -94,6 +194,7 @@ func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_SetsSchemaKnownVari
 		"init",
 		nil,
 		"{}",
+		protocolOffer(),
 	).Return(mockInvocation([]byte("{\"type_id\":\"root\",\"methods\":[\"schema\",\"list\"]}")), nil).Once()
 
 	suite.NoError(root.Init(nil))
@@ -102,7 +203,7 @@ func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_SetsSchemaKnownVari
 
 func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_PrefetchedSchema_ReturnsErrorIfUnmarshallingSchemaFails() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("foo"),
 		script:    mockScript,
 	}}
@@ -112,6 +213,7 @@ func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_PrefetchedSchema_Re
 		"init",
 		nil,
 		"{}",
+		protocolOffer(),
 	).Return(mockInvocation([]byte("{\"type_id\":\"root\",\"methods\":[[\"schema\", \"foo\"],\"list\"]}")), nil).Once()
 
 	err := root.Init(nil)
@@ -123,7 +225,7 @@ func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_PrefetchedSchema_Re
 
 func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_PrefetchedSchema_PartitionsSchemaGraph() {
 	mockScript := &mockPluginScript{path: "plugin_script"}
-	root := &pluginRoot{pluginEntry{
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry("fooPlugin"),
 		script:    mockScript,
 	}}
@@ -154,6 +256,7 @@ func (suite *ExternalPluginRootTestSuite) TestInitWithSchema_PrefetchedSchema_Pa
 		"init",
 		nil,
 		"{}",
+		protocolOffer(),
 	).Return(mockInvocation(stdout), nil).Once()
 
 	// Perform the test
@@ -14,6 +14,7 @@ import (
 // pluginScript represents an external plugin's script
 type pluginScript interface {
 	Path() string
+	Limits() scriptLimits
 	InvokeAndWait(ctx context.Context, method string, entry *pluginEntry, args ...string) (invocation, error)
 	NewInvocation(ctx context.Context, method string, entry *pluginEntry, args ...string) invocation
 }
@@ -39,6 +40,9 @@ func (inv *invocationImpl) RunAndWait(ctx context.Context) error {
 
 	activity.Record(ctx, "Invoking %v", inv)
 	err := inv.Run()
+	if limitErr := inv.LimitError(); limitErr != nil {
+		return newInvokeError(limitErr.Error(), inv)
+	}
 	exitCode := inv.ExitCode()
 	if exitCode < 0 {
 		return newInvokeError(err.Error(), inv)
@@ -54,6 +58,10 @@ func (inv *invocationImpl) RunAndWait(ctx context.Context) error {
 	return nil
 }
 
+func (inv *invocationImpl) String() string {
+	return fmt.Sprint(inv.Command)
+}
+
 func (inv *invocationImpl) Stdout() *bytes.Buffer {
 	return &inv.stdout
 }
@@ -77,13 +85,18 @@ func newInvokeError(msg string, inv invocation) error {
 }
 
 type externalPluginScriptImpl struct {
-	path string
+	path   string
+	limits scriptLimits
 }
 
 func (s externalPluginScriptImpl) Path() string {
 	return s.path
 }
 
+func (s externalPluginScriptImpl) Limits() scriptLimits {
+	return s.limits
+}
+
 // InvokeAndWait invokes method on entry by shelling out to the plugin script.
 // It waits for the script to exit, then returns its standard output.
 func (s externalPluginScriptImpl) InvokeAndWait(
@@ -103,16 +116,20 @@ func (s externalPluginScriptImpl) NewInvocation(
 	entry *pluginEntry,
 	args ...string,
 ) invocation {
+	cmd := NewCommand(ctx, s.Path(), invocationArgs(method, entry, args...)...)
+	cmd.SetLimits(s.limits.forMethod(method))
+	return &invocationImpl{Command: cmd}
+}
+
+// invocationArgs returns the arguments that are passed to the plugin script to invoke method
+// on entry, i.e. <method> <path> <state> <args...>.
+func invocationArgs(method string, entry *pluginEntry, args ...string) []string {
 	if method == "init" {
-		return &invocationImpl{Command: NewCommand(ctx, s.Path(), append([]string{"init"}, args...)...)}
+		return append([]string{"init"}, args...)
 	}
 	if entry == nil {
 		msg := fmt.Sprintf("s.NewInvocation called with method '%v' and entry == nil", method)
 		panic(msg)
 	}
-	return &invocationImpl{Command: NewCommand(
-		ctx,
-		s.Path(),
-		append([]string{method, plugin.ID(entry), entry.state}, args...)...,
-	)}
+	return append([]string{method, plugin.ID(entry), entry.state}, args...)
 }
@@ -0,0 +1,359 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/puppetlabs/wash/activity"
+)
+
+// The amount of the RPC process' stderr that's included in errors when it exits.
+const rpcStderrLimit = 4096
+
+// rpcExitTimeout is how long the RPC process has to exit on its own after it closes its
+// stdout or after its stdin's closed. It's terminated if it doesn't exit in time.
+var rpcExitTimeout = 5 * time.Second
+
+// rpcPluginScript is a pluginScript that keeps a single plugin process running. Method
+// invocations are sent to it as JSON-RPC 2.0 requests on its stdin, and it responds on
+// its stdout. Plugins opt into this at init to avoid paying the script's startup cost on
+// every invocation.
+//
+// Only InvokeAndWait uses RPC. NewInvocation is used by methods that stream data over stdin
+// or stdout (write, stream and exec), so those still fork the script.
+//
+// Close stops the plugin process. The process is sent EOF on its stdin, so it should exit once
+// it reads it.
+type rpcPluginScript struct {
+	externalPluginScriptImpl
+	config string
+	mux    sync.Mutex
+	proc   *rpcProcess
+	closed bool
+}
+
+func newRPCPluginScript(path string, limits scriptLimits, config string) *rpcPluginScript {
+	return &rpcPluginScript{
+		externalPluginScriptImpl: externalPluginScriptImpl{path: path, limits: limits},
+		config:                   config,
+	}
+}
+
+// InvokeAndWait sends a request to invoke method on entry to the plugin process, starting it
+// if it's not running. It waits for the response, then returns the result as the invocation's
+// standard output. If the response is an error, then its message is returned as the
+// invocation's standard error.
+func (s *rpcPluginScript) InvokeAndWait(
+	ctx context.Context,
+	method string,
+	entry *pluginEntry,
+	args ...string,
+) (invocation, error) {
+	if method == "init" {
+		// init is how the plugin opts into RPC, so it's always invoked by forking the script.
+		return s.externalPluginScriptImpl.InvokeAndWait(ctx, method, entry, args...)
+	}
+
+	// The invocation isn't run. It's only used to return the response in the same form as
+	// externalPluginScriptImpl, and to describe the request in errors.
+	argv := invocationArgs(method, entry, args...)
+	inv := &invocationImpl{Command: NewCommand(ctx, s.Path(), argv...)}
+
+	proc, err := s.process(ctx)
+	if err != nil {
+		return inv, newInvokeError(err.Error(), inv)
+	}
+	activity.Record(ctx, "Invoking %v via RPC", inv)
+	callCtx := ctx
+	timeout := s.limits.forMethod(method).timeout
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	resp, err := proc.call(callCtx, method, argv[1:])
+	if err != nil {
+		if callCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			// The request's cancelled, but the process keeps running to handle other requests.
+			err = fmt.Errorf("the script timed out after %v", timeout)
+		}
+		if proc.exited() {
+			// The process' stderr might say why it exited.
+			inv.stderr.WriteString(proc.stderr.String())
+		}
+		return inv, newInvokeError(err.Error(), inv)
+	}
+	if resp.Error != nil {
+		inv.stderr.WriteString(resp.Error.Message)
+		activity.Record(ctx, "stderr: %v", inv.stderr.String())
+		return inv, newInvokeError(fmt.Sprintf("script returned an error with code %v", resp.Error.Code), inv)
+	}
+
+	// A string result is the method's output. Any other result is the method's output as JSON.
+	var output string
+	if err := json.Unmarshal(resp.Result, &output); err == nil {
+		inv.stdout.WriteString(output)
+	} else if string(resp.Result) != "null" {
+		inv.stdout.Write(resp.Result)
+	}
+	activity.Record(ctx, "stdout: %v", inv.stdout.String())
+	return inv, nil
+}
+
+// Close stops the plugin process. Invocations fail once the script's closed.
+func (s *rpcPluginScript) Close() error {
+	s.mux.Lock()
+	proc := s.proc
+	s.proc = nil
+	s.closed = true
+	s.mux.Unlock()
+	if proc != nil {
+		proc.close()
+	}
+	return nil
+}
+
+// process returns the running plugin process, starting a new one if it's not running.
+func (s *rpcPluginScript) process(ctx context.Context) (*rpcProcess, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.closed {
+		return nil, fmt.Errorf("the plugin process was stopped")
+	}
+	if s.proc != nil && !s.proc.exited() {
+		return s.proc, nil
+	}
+
+	// The process outlives the request that started it, so it's not tied to ctx.
+	cmd := NewCommand(context.Background(), s.Path(), "rpc", s.config)
+	cmd.SetLimits(s.limits.forProcess())
+	proc := &rpcProcess{
+		cmd:     cmd,
+		pending: make(map[int64]chan rpcResponse),
+		doneCh:  make(chan struct{}),
+		stderr:  &rpcStderr{path: s.Path()},
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.SetStderr(proc.stderr)
+	proc.stdin = stdin
+	activity.Record(ctx, "Starting %v", cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	go proc.readResponses(stdout)
+
+	s.proc = proc
+	return proc, nil
+}
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rpcProcess is a running plugin process. Requests can be sent concurrently; responses are
+// matched to them by ID, so the process can respond in any order.
+type rpcProcess struct {
+	cmd      Command
+	stdin    io.WriteCloser
+	stderr   *rpcStderr
+	writeMux sync.Mutex
+	mux      sync.Mutex
+	nextID   int64
+	pending  map[int64]chan rpcResponse
+	doneCh   chan struct{}
+	err      error
+}
+
+// call sends a request, then waits for its response. If ctx is cancelled before the response
+// arrives, the plugin is sent a cancel notification so that it can stop working on the request.
+func (p *rpcProcess) call(ctx context.Context, method string, params []string) (rpcResponse, error) {
+	respCh := make(chan rpcResponse, 1)
+	p.mux.Lock()
+	p.nextID++
+	id := p.nextID
+	p.pending[id] = respCh
+	p.mux.Unlock()
+	defer func() {
+		p.mux.Lock()
+		delete(p.pending, id)
+		p.mux.Unlock()
+	}()
+
+	if err := p.send(rpcRequest{ID: &id, Method: method, Params: params}); err != nil {
+		return rpcResponse{}, err
+	}
+
+	select {
+	case resp := <-respCh:
+		return resp, nil
+	case <-p.doneCh:
+		return rpcResponse{}, p.err
+	case <-ctx.Done():
+		if err := p.send(rpcRequest{Method: "cancel", Params: map[string]int64{"id": id}}); err != nil {
+			activity.Record(ctx, "Failed to cancel request %v: %v", id, err)
+		}
+		return rpcResponse{}, ctx.Err()
+	}
+}
+
+func (p *rpcProcess) send(req rpcRequest) error {
+	req.JSONRPC = "2.0"
+	p.writeMux.Lock()
+	defer p.writeMux.Unlock()
+	if p.exited() {
+		return p.err
+	}
+	// Encode writes a newline after each request, so the plugin can read them line by line.
+	if err := json.NewEncoder(p.stdin).Encode(req); err != nil {
+		return fmt.Errorf("could not send the request: %v", err)
+	}
+	return nil
+}
+
+// readResponses reads responses from stdout until the process exits or prints something that
+// isn't a response.
+func (p *rpcProcess) readResponses(stdout io.Reader) {
+	decoder := json.NewDecoder(stdout)
+	for {
+		var resp rpcResponse
+		if err := decoder.Decode(&resp); err != nil {
+			if errors.Is(err, os.ErrClosed) {
+				// Wait closes stdout once the process exits, which can happen before it's read
+				// to the end if the process was terminated.
+				err = io.EOF
+			}
+			p.exit(err)
+			return
+		}
+
+		p.mux.Lock()
+		respCh, ok := p.pending[resp.ID]
+		p.mux.Unlock()
+		if !ok {
+			// The request was cancelled.
+			activity.Record(context.Background(), "%v: discarding the response to request %v", p.cmd, resp.ID)
+			continue
+		}
+		select {
+		case respCh <- resp:
+		default:
+			// The plugin responded to the same request twice. Only the first response is used.
+		}
+	}
+}
+
+// exit stops the process, then fails all pending requests. If the process closed its stdout,
+// then it's given rpcExitTimeout to exit on its own before it's terminated.
+func (p *rpcProcess) exit(readErr error) {
+	if readErr != io.EOF {
+		p.cmd.Terminate()
+	}
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- p.cmd.Wait()
+	}()
+	var waitErr error
+	select {
+	case waitErr = <-waitCh:
+	case <-time.After(rpcExitTimeout):
+		activity.Record(context.Background(), "%v: closed its stdout but did not exit", p.cmd)
+		p.cmd.Terminate()
+		waitErr = <-waitCh
+	}
+
+	p.writeMux.Lock()
+	defer p.writeMux.Unlock()
+	switch limitErr := p.cmd.LimitError(); {
+	case limitErr != nil:
+		p.err = fmt.Errorf("the plugin process was killed: %v", limitErr)
+	case readErr != io.EOF:
+		p.err = fmt.Errorf("could not decode a response from stdout: %v", readErr)
+	case waitErr != nil:
+		p.err = fmt.Errorf("the plugin process exited: %v", waitErr)
+	default:
+		p.err = fmt.Errorf("the plugin process exited")
+	}
+	activity.Record(context.Background(), "%v: %v", p.cmd, p.err)
+	close(p.doneCh)
+}
+
+// close closes the process' stdin so that it exits, then waits for it to exit. It's
+// terminated if it doesn't exit within rpcExitTimeout.
+func (p *rpcProcess) close() {
+	p.writeMux.Lock()
+	if !p.exited() {
+		if err := p.stdin.Close(); err != nil {
+			activity.Record(context.Background(), "%v: could not close stdin: %v", p.cmd, err)
+		}
+	}
+	p.writeMux.Unlock()
+
+	select {
+	case <-p.doneCh:
+	case <-time.After(rpcExitTimeout):
+		activity.Record(context.Background(), "%v: did not exit after its stdin was closed", p.cmd)
+		p.cmd.Terminate()
+		<-p.doneCh
+	}
+}
+
+func (p *rpcProcess) exited() bool {
+	select {
+	case <-p.doneCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// rpcStderr logs the process' stderr, and keeps the end of it to include in errors.
+type rpcStderr struct {
+	path string
+	mux  sync.Mutex
+	tail []byte
+}
+
+func (s *rpcStderr) Write(p []byte) (int, error) {
+	activity.Record(context.Background(), "%v: stderr: %v", s.path, strings.TrimRight(string(p), "\n"))
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.tail = append(s.tail, p...)
+	if len(s.tail) > rpcStderrLimit {
+		s.tail = s.tail[len(s.tail)-rpcStderrLimit:]
+	}
+	return len(p), nil
+}
+
+func (s *rpcStderr) String() string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return string(s.tail)
+}
@@ -0,0 +1,260 @@
+package external
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/plugin/external/sdk"
+	"github.com/stretchr/testify/suite"
+)
+
+// When set, the test binary runs as an RPC plugin process instead of running the tests.
+const rpcPluginEnv = "WASH_TEST_RPC_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(rpcPluginEnv) != "" {
+		runRPCPlugin()
+		os.Exit(0)
+	}
+	if os.Getenv(sdkPluginEnv) != "" {
+		sdk.Run(&sdkTestRoot{})
+	}
+	os.Exit(m.Run())
+}
+
+// runRPCPlugin handles each request concurrently. It implements
+//   - list, which returns an entry named after its state
+//   - read, which returns its arguments
+//   - fail, which returns an error
+//   - block, which responds once unblock is requested
+//   - cancelled, which returns the IDs of the requests that were cancelled
+//   - exit, which exits
+//   - closeStdout, which closes its stdout but keeps running
+//   - ignoreStdin, which keeps it running after its stdin's closed
+func runRPCPlugin() {
+	var mux sync.Mutex
+	var cancelled []int64
+	unblockCh := make(chan struct{})
+	ignoreStdinCh := make(chan struct{})
+	defer func() {
+		select {
+		case <-ignoreStdinCh:
+			select {}
+		default:
+		}
+	}()
+
+	stdout := json.NewEncoder(os.Stdout)
+	respond := func(resp map[string]interface{}) {
+		resp["jsonrpc"] = "2.0"
+		mux.Lock()
+		defer mux.Unlock()
+		_ = stdout.Encode(resp)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			fmt.Fprintf(os.Stderr, "bad request: %v\n", err)
+			os.Exit(1)
+		}
+		if req.Method == "cancel" {
+			var params struct {
+				ID int64 `json:"id"`
+			}
+			_ = json.Unmarshal(req.Params, &params)
+			mux.Lock()
+			cancelled = append(cancelled, params.ID)
+			mux.Unlock()
+			continue
+		}
+
+		var params []string
+		_ = json.Unmarshal(req.Params, &params)
+		go func() {
+			switch req.Method {
+			case "list":
+				respond(map[string]interface{}{"id": req.ID, "result": []map[string]string{{"name": params[1]}}})
+			case "read":
+				respond(map[string]interface{}{"id": req.ID, "result": strings.Join(params, " ")})
+			case "fail":
+				respond(map[string]interface{}{"id": req.ID, "error": map[string]interface{}{"code": 1, "message": "something failed"}})
+			case "block":
+				<-unblockCh
+				respond(map[string]interface{}{"id": req.ID, "result": "unblocked"})
+			case "unblock":
+				close(unblockCh)
+				respond(map[string]interface{}{"id": req.ID, "result": nil})
+			case "cancelled":
+				mux.Lock()
+				ids := append([]int64{}, cancelled...)
+				mux.Unlock()
+				respond(map[string]interface{}{"id": req.ID, "result": ids})
+			case "exit":
+				fmt.Fprintln(os.Stderr, "exiting")
+				os.Exit(3)
+			case "closeStdout":
+				os.Stdout.Close()
+			case "ignoreStdin":
+				close(ignoreStdinCh)
+				respond(map[string]interface{}{"id": req.ID, "result": nil})
+			}
+		}()
+	}
+}
+
+type RPCPluginScriptTestSuite struct {
+	suite.Suite
+	script *rpcPluginScript
+	entry  *pluginEntry
+}
+
+func (suite *RPCPluginScriptTestSuite) SetupTest() {
+	if err := os.Setenv(rpcPluginEnv, "1"); err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.script = newRPCPluginScript(os.Args[0], scriptLimits{}, "{}")
+	suite.entry = &pluginEntry{EntryBase: plugin.NewEntry("foo"), state: "some state"}
+	suite.entry.SetTestID("/foo")
+}
+
+func (suite *RPCPluginScriptTestSuite) TearDownTest() {
+	suite.NoError(suite.script.Close())
+	os.Unsetenv(rpcPluginEnv)
+}
+
+// setExitTimeout sets rpcExitTimeout. It returns a function that restores it.
+func setExitTimeout(timeout time.Duration) func() {
+	oldTimeout := rpcExitTimeout
+	rpcExitTimeout = timeout
+	return func() {
+		rpcExitTimeout = oldTimeout
+	}
+}
+
+func (suite *RPCPluginScriptTestSuite) TestInvokeAndWait() {
+	inv, err := suite.script.InvokeAndWait(context.Background(), "read", suite.entry, "10", "0")
+	if suite.NoError(err) {
+		suite.Equal("/foo some state 10 0", inv.Stdout().String())
+	}
+
+	// Non-string results are returned as JSON. The process is re-used.
+	proc := suite.script.proc
+	inv, err = suite.script.InvokeAndWait(context.Background(), "list", suite.entry)
+	if suite.NoError(err) {
+		suite.JSONEq(`[{"name":"some state"}]`, inv.Stdout().String())
+	}
+	suite.True(proc == suite.script.proc)
+}
+
+func (suite *RPCPluginScriptTestSuite) TestInvokeAndWait_Error() {
+	inv, err := suite.script.InvokeAndWait(context.Background(), "fail", suite.entry)
+	suite.Regexp("script returned an error with code 1\nCOMMAND: .* fail /foo 'some state'\nSTDERR:\nsomething failed", err)
+	suite.Equal("something failed", inv.Stderr().String())
+}
+
+func (suite *RPCPluginScriptTestSuite) TestInvokeAndWait_Concurrent() {
+	blockedCh := make(chan error, 1)
+	go func() {
+		inv, err := suite.script.InvokeAndWait(context.Background(), "block", suite.entry)
+		if err == nil && inv.Stdout().String() != "unblocked" {
+			err = fmt.Errorf("unexpected result %v", inv.Stdout().String())
+		}
+		blockedCh <- err
+	}()
+
+	// The blocked request is answered after a later one.
+	_, err := suite.script.InvokeAndWait(context.Background(), "unblock", suite.entry)
+	suite.NoError(err)
+	select {
+	case err := <-blockedCh:
+		suite.NoError(err)
+	case <-time.After(5 * time.Second):
+		suite.Fail("timed out waiting for the blocked request")
+	}
+}
+
+func (suite *RPCPluginScriptTestSuite) TestInvokeAndWait_Cancelled() {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := suite.script.InvokeAndWait(ctx, "block", suite.entry)
+	suite.Regexp("context deadline exceeded", err)
+
+	inv, err := suite.script.InvokeAndWait(context.Background(), "cancelled", suite.entry)
+	if suite.NoError(err) {
+		suite.JSONEq("[1]", inv.Stdout().String())
+	}
+}
+
+func (suite *RPCPluginScriptTestSuite) TestInvokeAndWait_TimesOut() {
+	suite.script.limits = scriptLimits{timeouts: map[string]time.Duration{"default": 50 * time.Millisecond}}
+	_, err := suite.script.InvokeAndWait(context.Background(), "block", suite.entry)
+	suite.Regexp("^the script timed out after 50ms", err)
+
+	// The request's cancelled, but the process keeps running.
+	inv, err := suite.script.InvokeAndWait(context.Background(), "cancelled", suite.entry)
+	if suite.NoError(err) {
+		suite.JSONEq("[1]", inv.Stdout().String())
+	}
+}
+
+func (suite *RPCPluginScriptTestSuite) TestInvokeAndWait_ProcessExits() {
+	inv, err := suite.script.InvokeAndWait(context.Background(), "exit", suite.entry)
+	suite.Regexp("the plugin process exited: exit status 3", err)
+	suite.Equal("exiting\n", inv.Stderr().String())
+
+	// The process is restarted.
+	inv, err = suite.script.InvokeAndWait(context.Background(), "read", suite.entry)
+	if suite.NoError(err) {
+		suite.Equal("/foo some state", inv.Stdout().String())
+	}
+}
+
+func (suite *RPCPluginScriptTestSuite) TestInvokeAndWait_ProcessClosesStdout() {
+	defer setExitTimeout(50 * time.Millisecond)()
+	_, err := suite.script.InvokeAndWait(context.Background(), "closeStdout", suite.entry)
+	// The process is terminated since it didn't exit.
+	suite.Regexp("the plugin process exited: signal: terminated", err)
+}
+
+func (suite *RPCPluginScriptTestSuite) TestClose() {
+	_, err := suite.script.InvokeAndWait(context.Background(), "read", suite.entry)
+	suite.NoError(err)
+	proc := suite.script.proc
+
+	suite.NoError(suite.script.Close())
+	suite.True(proc.exited())
+	suite.Regexp("the plugin process exited$", proc.err)
+
+	// The process isn't restarted
+	_, err = suite.script.InvokeAndWait(context.Background(), "read", suite.entry)
+	suite.Regexp("the plugin process was stopped", err)
+}
+
+func (suite *RPCPluginScriptTestSuite) TestClose_TerminatesProcess() {
+	defer setExitTimeout(50 * time.Millisecond)()
+	_, err := suite.script.InvokeAndWait(context.Background(), "ignoreStdin", suite.entry)
+	suite.NoError(err)
+	proc := suite.script.proc
+
+	suite.NoError(suite.script.Close())
+	suite.True(proc.exited())
+	suite.Regexp("signal: terminated", proc.err)
+}
+
+func TestRPCPluginScript(t *testing.T) {
+	suite.Run(t, new(RPCPluginScriptTestSuite))
+}
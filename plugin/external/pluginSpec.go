@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/puppetlabs/wash/plugin"
 )
@@ -12,6 +13,11 @@ import (
 // PluginSpec represents an external plugin's specification.
 type PluginSpec struct {
 	Script string
+	// Timeouts maps a method to how long the script can take to invoke it. The "default" timeout
+	// applies to the methods that aren't listed, except for exec and stream.
+	Timeouts map[string]time.Duration
+	// Limits are the resources that each of the script's processes can use.
+	Limits ResourceLimits
 }
 
 // Name returns the plugin name, which is the basename of the script with extension removed.
@@ -31,9 +37,14 @@ func (s PluginSpec) Load() (plugin.Root, error) {
 		return nil, fmt.Errorf("script %v is not executable", s.Script)
 	}
 
-	root := &pluginRoot{pluginEntry{
+	limits, err := newScriptLimits(s.Timeouts, s.Limits)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &pluginRoot{pluginEntry: pluginEntry{
 		EntryBase: plugin.NewEntry(s.Name()),
-		script:    externalPluginScriptImpl{path: s.Script},
+		script:    externalPluginScriptImpl{path: s.Script, limits: limits},
 	}}
 	return root, nil
 }
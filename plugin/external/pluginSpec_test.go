@@ -2,6 +2,7 @@ package external
 
 import (
 	"testing"
+	"time"
 
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/stretchr/testify/assert"
@@ -31,3 +32,13 @@ func TestRegisterExternalPluginNotFile(t *testing.T) {
 	_, err := spec.Load()
 	assert.EqualError(t, err, "script testdata/notfile is not a file")
 }
+
+func TestLoadExternalPluginInvalidLimits(t *testing.T) {
+	spec := PluginSpec{Script: "testdata/external.sh", Timeouts: map[string]time.Duration{"list": -time.Second}}
+	_, err := spec.Load()
+	assert.EqualError(t, err, "the list timeout cannot be negative")
+
+	spec = PluginSpec{Script: "testdata/external.sh", Limits: ResourceLimits{Memory: "lots"}}
+	_, err = spec.Load()
+	assert.Regexp(t, "^invalid memory limit lots", err)
+}
@@ -0,0 +1,145 @@
+package external
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
+)
+
+// The range of protocol versions that Wash supports. Version 1 is the protocol
+// that predates negotiation; plugins that don't report a version at init are
+// assumed to implement it.
+const (
+	protocolVersion    = 2
+	minProtocolVersion = 1
+)
+
+// Protocol describes the protocol that was negotiated with an external plugin at init.
+type Protocol struct {
+	Version int
+	// Capabilities are the optional features that the plugin requires.
+	Capabilities []string
+}
+
+func (p Protocol) String() string {
+	if len(p.Capabilities) == 0 {
+		return fmt.Sprintf("version %v", p.Version)
+	}
+	return fmt.Sprintf("version %v with capabilities %v", p.Version, strings.Join(p.Capabilities, ", "))
+}
+
+// capabilities returns the optional protocol features that Wash supports. Besides
+// the fixed features, it includes every registered action ("action:<name>"), exec
+// transport ("exec_transport:<name>"), exec option ("exec_option:<name>") and core
+// entry ("core_entry:<type_id>") so that new ones are advertised as they're added.
+func capabilities() []string {
+	caps := []string{
+		// ["read", true]
+		"block_read",
+		// ["write", true]
+		"block_write",
+		// ["exec", {"transport": ..., "options": ...}]
+		"exec_transport",
+		// ["list", [...]], ["read", "..."] and ["schema", {...}]
+		"prefetched_list",
+		"prefetched_read",
+		"prefetched_schema",
+		// "rpc": true in the plugin root
+		"rpc",
+	}
+	for name := range plugin.Actions() {
+		caps = append(caps, "action:"+name)
+	}
+	for _, name := range transport.Names() {
+		caps = append(caps, "exec_transport:"+name)
+	}
+	for _, name := range execOptionNames() {
+		caps = append(caps, "exec_option:"+name)
+	}
+	for typeID := range coreEntries {
+		caps = append(caps, "core_entry:"+strings.Trim(typeID, "_"))
+	}
+	sort.Strings(caps)
+	return caps
+}
+
+// execOptionNames returns the names of the options that are passed to the plugin's
+// exec method.
+func execOptionNames() []string {
+	var names []string
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Anonymous {
+				collect(field.Type)
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			names = append(names, name)
+		}
+	}
+	collect(reflect.TypeOf(serializedExecOptions{}))
+	return names
+}
+
+// protocolOffer returns the JSON that's passed to init so that the plugin can
+// choose a protocol version and learn which capabilities are available.
+func protocolOffer() string {
+	offer := struct {
+		ProtocolVersion    int      `json:"protocol_version"`
+		MinProtocolVersion int      `json:"min_protocol_version"`
+		Capabilities       []string `json:"capabilities"`
+	}{protocolVersion, minProtocolVersion, capabilities()}
+	offerJSON, err := json.Marshal(offer)
+	if err != nil {
+		panic(fmt.Sprintf("could not marshal the protocol offer: %v", err))
+	}
+	return string(offerJSON)
+}
+
+// negotiateProtocol validates the protocol version and capabilities that the plugin
+// reported at init.
+func negotiateProtocol(version int, required []string) (Protocol, error) {
+	if version == 0 {
+		version = minProtocolVersion
+	}
+	if version < minProtocolVersion || version > protocolVersion {
+		return Protocol{}, fmt.Errorf(
+			"the plugin implements protocol version %v, but Wash supports versions %v to %v",
+			version,
+			minProtocolVersion,
+			protocolVersion,
+		)
+	}
+
+	supported := make(map[string]bool)
+	for _, capability := range capabilities() {
+		supported[capability] = true
+	}
+	var unsupported []string
+	for _, capability := range required {
+		if !supported[capability] {
+			unsupported = append(unsupported, capability)
+		}
+	}
+	if len(unsupported) > 0 {
+		return Protocol{}, fmt.Errorf(
+			"the plugin requires capabilities that Wash doesn't support: %v",
+			strings.Join(unsupported, ", "),
+		)
+	}
+
+	return Protocol{Version: version, Capabilities: required}, nil
+}
@@ -0,0 +1,4 @@
+package external
+
+// The memory limit's set with a cgroup, which macOS doesn't have.
+const rlimitsSupported = false
@@ -0,0 +1,4 @@
+package external
+
+// Linux enforces the CPU time limit that ulimit sets, and the memory limit's set with a cgroup.
+const rlimitsSupported = true
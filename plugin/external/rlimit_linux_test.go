@@ -0,0 +1,55 @@
+package external
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCPULimit(t *testing.T) {
+	cmd := NewCommand(context.Background(), "sh", "-c", "while :; do :; done")
+	cmd.SetLimits(commandLimits{cpu: time.Second})
+	inv := &invocationImpl{Command: cmd}
+
+	err := inv.RunAndWait(context.Background())
+	assert.Regexp(t, "^the script exceeded its CPU time limit of 1s\nCOMMAND: ", err)
+}
+
+// skipWithoutMemoryCgroups skips the test if cgroups with the memory controller can't be created,
+// like when the tests aren't run as root.
+func skipWithoutMemoryCgroups(t *testing.T) {
+	cgroup, err := newMemoryCgroup(1024 * 1024)
+	if err != nil {
+		t.Skipf("cannot create a memory cgroup: %v", err)
+	}
+	cgroup.remove()
+}
+
+func TestMemoryLimit(t *testing.T) {
+	skipWithoutMemoryCgroups(t)
+	// tail buffers /dev/zero until it finds a newline, which it never does. The background sleep
+	// is killed with the process group.
+	cmd := NewCommand(context.Background(), "sh", "-c", "sleep 10 & tail /dev/zero")
+	cmd.SetLimits(commandLimits{memory: 32000000})
+	inv := &invocationImpl{Command: cmd}
+
+	start := time.Now()
+	err := inv.RunAndWait(context.Background())
+	assert.Regexp(t, "^the script exceeded its memory limit of 32 MB\nCOMMAND: ", err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestMemoryLimit_GoRuntimeStarts(t *testing.T) {
+	skipWithoutMemoryCgroups(t)
+	// The test binary's a Go program. Go reserves far more virtual memory than the limit, so it
+	// would fail to start if virtual memory was limited.
+	cmd := NewCommand(context.Background(), os.Args[0], "-test.run=^$")
+	cmd.SetLimits(commandLimits{memory: 64000000})
+	inv := &invocationImpl{Command: cmd}
+
+	assert.NoError(t, inv.RunAndWait(context.Background()))
+	assert.NoError(t, cmd.LimitError())
+}
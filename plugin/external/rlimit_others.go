@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package external
+
+// The memory and CPU time limits are only enforced on Linux.
+const rlimitsSupported = false
@@ -0,0 +1,260 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+)
+
+// Entry is an entry in the plugin's hierarchy. All entries must embed EntryBase, and must be
+// pointers to structs. Entries are serialized as their state between invocations of the plugin,
+// so they should keep whatever they need to implement their methods in exported fields.
+type Entry interface {
+	// eb => entryBase
+	eb() *EntryBase
+}
+
+// Root is the plugin root. Its Init method is called when Wash loads the plugin; the root's
+// state is saved afterwards, so config that's needed later should be kept in exported fields.
+type Root interface {
+	Parent
+	Init(ctx context.Context, cfg map[string]interface{}) error
+}
+
+// Parent is an entry with children. ChildTypes returns an example of each type of entry that
+// List can return. They're used to generate the plugin's schema, and to restore entries from
+// their state.
+type Parent interface {
+	Entry
+	ChildTypes() []Entry
+	List(ctx context.Context) ([]Entry, error)
+}
+
+// Readable is an entry with data that can be read.
+type Readable interface {
+	Entry
+	Read(ctx context.Context) ([]byte, error)
+}
+
+// BlockReadable is an entry with data that can be read in blocks. A BlockReadable entry must
+// set its size attribute.
+type BlockReadable interface {
+	Entry
+	Read(ctx context.Context, size int64, offset int64) ([]byte, error)
+}
+
+// Writable is an entry that new data can be written to.
+type Writable interface {
+	Entry
+	Write(ctx context.Context, data []byte) error
+}
+
+// BlockWritable is an entry with data that can be written in blocks. WriteAt writes data at
+// offset, and Truncate changes the size of the entry's data. Commit is called once all of the
+// writes are done, e.g. when a file's closed. Each call is a separate invocation of the plugin,
+// so WriteAt and Truncate should either apply their changes immediately or save them somewhere
+// that Commit can apply them from. A BlockWritable entry must set its size attribute.
+type BlockWritable interface {
+	Entry
+	WriteAt(ctx context.Context, data []byte, offset int64) error
+	Truncate(ctx context.Context, size int64) error
+	Commit(ctx context.Context) error
+}
+
+// Streamable is an entry that returns a stream of updates. The stream is closed when Wash
+// no longer needs it.
+type Streamable interface {
+	Entry
+	Stream(ctx context.Context) (io.ReadCloser, error)
+}
+
+// ExecOptions are the options for Exec. Stdout and Stderr are where the command's output
+// should be written. Stdin is nil unless the caller provided input.
+type ExecOptions struct {
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+	Tty     bool
+	Elevate bool
+	Env     map[string]string
+	Dir     string
+	User    string
+	Timeout time.Duration
+}
+
+// Execable is an entry that can run commands. Exec runs cmd and returns its exit code.
+type Execable interface {
+	Entry
+	Exec(ctx context.Context, cmd string, args []string, opts ExecOptions) (int, error)
+}
+
+// MetadataProvider is an entry that has more metadata than its partial metadata.
+type MetadataProvider interface {
+	Entry
+	Metadata(ctx context.Context) (map[string]interface{}, error)
+}
+
+// Deletable is an entry that can be deleted. Delete returns true if the entry was deleted,
+// or false if its deletion is still in progress.
+type Deletable interface {
+	Entry
+	Delete(ctx context.Context) (bool, error)
+}
+
+// Signalable is an entry that can be signaled. Signalable entries must implement
+// SchemaProvider to list their supported signals.
+type Signalable interface {
+	Entry
+	Signal(ctx context.Context, signal string) error
+}
+
+// Creatable is a parent that new children can be created in.
+type Creatable interface {
+	Parent
+	Create(ctx context.Context, name string, isParent bool) (Entry, error)
+}
+
+// Renamable is an entry that can be renamed or moved to newParent.
+type Renamable interface {
+	Entry
+	Rename(ctx context.Context, newParent Parent, newName string) (Entry, error)
+}
+
+// Op is a cached method.
+type Op int
+
+// The methods whose results Wash caches.
+const (
+	ListOp Op = iota
+	ReadOp
+	MetadataOp
+)
+
+// EntryBase implements Entry, and holds the information about an entry that isn't part of
+// its state. Use NewEntry to create it.
+type EntryBase struct {
+	name               string
+	attributes         Attributes
+	partialMetadata    interface{}
+	ttls               map[Op]time.Duration
+	slashReplacer      rune
+	inaccessibleReason string
+}
+
+// NewEntry creates a new EntryBase with the given name.
+func NewEntry(name string) EntryBase {
+	return EntryBase{name: name}
+}
+
+func (e *EntryBase) eb() *EntryBase {
+	return e
+}
+
+// Name returns the entry's name.
+func (e *EntryBase) Name() string {
+	return e.name
+}
+
+// Attributes returns the entry's attributes so that they can be set.
+func (e *EntryBase) Attributes() *Attributes {
+	return &e.attributes
+}
+
+// SetPartialMetadata sets the entry's partial metadata. It must marshal to a JSON object.
+func (e *EntryBase) SetPartialMetadata(obj interface{}) *EntryBase {
+	e.partialMetadata = obj
+	return e
+}
+
+// SetTTLOf sets how long Wash caches the result of op. The TTL is rounded down to seconds.
+func (e *EntryBase) SetTTLOf(op Op, ttl time.Duration) *EntryBase {
+	if e.ttls == nil {
+		e.ttls = make(map[Op]time.Duration)
+	}
+	e.ttls[op] = ttl
+	return e
+}
+
+// SetSlashReplacer overrides the character that replaces "/" in the entry's name.
+func (e *EntryBase) SetSlashReplacer(char rune) *EntryBase {
+	e.slashReplacer = char
+	return e
+}
+
+// MarkInaccessible omits the entry from its parent's children, logging reason instead.
+func (e *EntryBase) MarkInaccessible(reason string) *EntryBase {
+	e.inaccessibleReason = reason
+	return e
+}
+
+// Attributes are an entry's filesystem attributes. Only the ones that are set are sent to Wash.
+type Attributes struct {
+	atime, mtime, ctime, crtime time.Time
+	mode                        *os.FileMode
+	size                        *uint64
+	loginShell                  string
+}
+
+// SetAtime sets the entry's last access time.
+func (a *Attributes) SetAtime(t time.Time) *Attributes {
+	a.atime = t
+	return a
+}
+
+// SetMtime sets the entry's last modified time.
+func (a *Attributes) SetMtime(t time.Time) *Attributes {
+	a.mtime = t
+	return a
+}
+
+// SetCtime sets the entry's last change time.
+func (a *Attributes) SetCtime(t time.Time) *Attributes {
+	a.ctime = t
+	return a
+}
+
+// SetCrtime sets the entry's creation time.
+func (a *Attributes) SetCrtime(t time.Time) *Attributes {
+	a.crtime = t
+	return a
+}
+
+// SetMode sets the entry's mode.
+func (a *Attributes) SetMode(mode os.FileMode) *Attributes {
+	a.mode = &mode
+	return a
+}
+
+// SetSize sets the entry's size in bytes.
+func (a *Attributes) SetSize(size uint64) *Attributes {
+	a.size = &size
+	return a
+}
+
+// SetLoginShell sets the login shell of an Execable entry, either "posixshell" or "powershell".
+func (a *Attributes) SetLoginShell(shell string) *Attributes {
+	a.loginShell = shell
+	return a
+}
+
+// MarshalJSON marshals the attributes that are set.
+func (a Attributes) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{})
+	for key, t := range map[string]time.Time{"atime": a.atime, "mtime": a.mtime, "ctime": a.ctime, "crtime": a.crtime} {
+		if !t.IsZero() {
+			m[key] = t.Format(time.RFC3339Nano)
+		}
+	}
+	if a.mode != nil {
+		m["mode"] = *a.mode
+	}
+	if a.size != nil {
+		m["size"] = *a.size
+	}
+	if a.loginShell != "" {
+		m["os"] = map[string]string{"login_shell": a.loginShell}
+	}
+	return json.Marshal(m)
+}
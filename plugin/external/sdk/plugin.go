@@ -0,0 +1,487 @@
+/*
+Package sdk turns Go types into a Wash external plugin. It implements the calling conventions
+described in https://puppetlabs.github.io/wash/docs/external_plugins, so a plugin only needs to
+implement the interfaces for the methods that its entries support, then call Run from main:
+
+	type Root struct {
+		sdk.EntryBase
+		Token string
+	}
+
+	func (r *Root) Init(ctx context.Context, cfg map[string]interface{}) error {
+		r.Token, _ = cfg["token"].(string)
+		return nil
+	}
+
+	func (r *Root) ChildTypes() []sdk.Entry {
+		return []sdk.Entry{&Repo{}}
+	}
+
+	func (r *Root) List(ctx context.Context) ([]sdk.Entry, error) {
+		...
+	}
+
+	func main() {
+		sdk.Run(&Root{})
+	}
+
+Entries are restored from their state each time the plugin is invoked. The state is the JSON
+serialization of the entry, so anything that an entry needs to implement its methods must be
+kept in exported fields. The plugin's schema is generated from the types returned by ChildTypes,
+starting from the root.
+
+Errors returned by an entry's methods are printed to stderr, and the plugin exits with a non-zero
+exit code. The package doesn't depend on the rest of Wash.
+*/
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The protocol version that's reported to Wash at init.
+const protocolVersion = 2
+
+// Run invokes the method that Wash passed as arguments to the plugin, then exits. It must be
+// called with a new instance of the plugin's root.
+func Run(root Root) {
+	// Wash sends SIGTERM when the request that invoked the plugin is cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		<-sigCh
+		cancel()
+	}()
+
+	os.Exit(run(ctx, root, os.Args, os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run returns the exit code.
+func run(ctx context.Context, root Root, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	s, err := newSchema(root)
+	if err != nil {
+		fmt.Fprintf(stderr, "invalid plugin: %v\n", err)
+		return 1
+	}
+	p := &pluginRunner{schema: s, stdin: stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) < 2 {
+		fmt.Fprintf(stderr, "usage: %v <method> <path> <state> <args...>\n", args[0])
+		return 1
+	}
+	var exitCode int
+	if method := args[1]; method == "init" {
+		// The root's name must be the script's name without its extension.
+		name := filepath.Base(args[0])
+		root.eb().name = strings.TrimSuffix(name, filepath.Ext(name))
+		err = p.init(ctx, root, args[2:])
+	} else if len(args) < 4 {
+		err = fmt.Errorf("usage: %v %v <path> <state> <args...>", args[0], method)
+	} else {
+		exitCode, err = p.invoke(ctx, method, args[3], args[4:])
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return exitCode
+}
+
+type pluginRunner struct {
+	schema *schema
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// state is the serialized form of an entry.
+type state struct {
+	TypeID string          `json:"type_id"`
+	Name   string          `json:"name"`
+	Value  json.RawMessage `json:"value"`
+}
+
+// restore creates the entry that was serialized as stateJSON.
+func (p *pluginRunner) restore(stateJSON string) (Entry, error) {
+	var st state
+	if err := json.Unmarshal([]byte(stateJSON), &st); err != nil {
+		return nil, fmt.Errorf("could not decode the entry's state: %v", err)
+	}
+	t, ok := p.schema.types[st.TypeID]
+	if !ok {
+		return nil, fmt.Errorf("the entry's type %v isn't in the plugin's schema", st.TypeID)
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(st.Value, v.Interface()); err != nil {
+		return nil, fmt.Errorf("could not restore the %v entry from its state: %v", st.TypeID, err)
+	}
+	entry := v.Interface().(Entry)
+	entry.eb().name = st.Name
+	return entry, nil
+}
+
+// opNames are the names of the cached methods in cache_ttls.
+var opNames = map[Op]string{
+	ListOp:     "list",
+	ReadOp:     "read",
+	MetadataOp: "metadata",
+}
+
+// encodedEntry is an entry JSON object.
+type encodedEntry struct {
+	TypeID             string           `json:"type_id"`
+	Name               string           `json:"name"`
+	Methods            []interface{}    `json:"methods"`
+	Attributes         Attributes       `json:"attributes"`
+	PartialMetadata    interface{}      `json:"partial_metadata,omitempty"`
+	State              string           `json:"state"`
+	CacheTTLs          map[string]int64 `json:"cache_ttls,omitempty"`
+	SlashReplacer      string           `json:"slash_replacer,omitempty"`
+	InaccessibleReason string           `json:"inaccessible_reason,omitempty"`
+}
+
+func (p *pluginRunner) encode(e Entry) (encodedEntry, error) {
+	eb := e.eb()
+	value, err := json.Marshal(e)
+	if err != nil {
+		return encodedEntry{}, fmt.Errorf("could not serialize the state of %v: %v", eb.name, err)
+	}
+	stateJSON, err := json.Marshal(state{TypeID: typeID(e), Name: eb.name, Value: value})
+	if err != nil {
+		return encodedEntry{}, fmt.Errorf("could not serialize the state of %v: %v", eb.name, err)
+	}
+
+	encoded := encodedEntry{
+		TypeID:             typeID(e),
+		Name:               eb.name,
+		Attributes:         eb.attributes,
+		PartialMetadata:    eb.partialMetadata,
+		State:              string(stateJSON),
+		InaccessibleReason: eb.inaccessibleReason,
+	}
+	if eb.slashReplacer != 0 {
+		encoded.SlashReplacer = string(eb.slashReplacer)
+	}
+	for op, ttl := range eb.ttls {
+		if encoded.CacheTTLs == nil {
+			encoded.CacheTTLs = make(map[string]int64)
+		}
+		encoded.CacheTTLs[opNames[op]] = int64(ttl / time.Second)
+	}
+
+	// The root implements schema, so every entry must.
+	encoded.Methods = []interface{}{"schema"}
+	for _, action := range actionsOf(e) {
+		if _, ok := e.(BlockReadable); ok && action == "read" {
+			encoded.Methods = append(encoded.Methods, []interface{}{"read", true})
+		} else if _, ok := e.(BlockWritable); ok && action == "write" {
+			encoded.Methods = append(encoded.Methods, []interface{}{"write", true})
+		} else {
+			encoded.Methods = append(encoded.Methods, action)
+		}
+	}
+	if _, ok := e.(MetadataProvider); ok {
+		encoded.Methods = append(encoded.Methods, "metadata")
+	}
+	return encoded, nil
+}
+
+// encodeChild encodes an entry that was returned by parent, which must be one of its ChildTypes.
+func (p *pluginRunner) encodeChild(parent Entry, child Entry) (encodedEntry, error) {
+	if child == nil || reflect.ValueOf(child).IsNil() {
+		return encodedEntry{}, fmt.Errorf("%v returned a nil entry", parent.eb().name)
+	}
+	if !p.schema.isChildType(parent, child) {
+		return encodedEntry{}, fmt.Errorf(
+			"%v returned %v, but its type %v isn't one of its ChildTypes",
+			parent.eb().name,
+			child.eb().name,
+			typeID(child),
+		)
+	}
+	return p.encode(child)
+}
+
+func (p *pluginRunner) print(v interface{}) error {
+	return json.NewEncoder(p.stdout).Encode(v)
+}
+
+func (p *pluginRunner) init(ctx context.Context, root Root, args []string) error {
+	cfg := make(map[string]interface{})
+	if len(args) > 0 {
+		if err := json.Unmarshal([]byte(args[0]), &cfg); err != nil {
+			return fmt.Errorf("could not decode the plugin config: %v", err)
+		}
+	}
+	if err := root.Init(ctx, cfg); err != nil {
+		return err
+	}
+
+	encoded, err := p.encode(root)
+	if err != nil {
+		return err
+	}
+	// Prefetch the schema so that Wash doesn't need to invoke the plugin to get it.
+	encoded.Methods[0] = []interface{}{"schema", p.schema.graph}
+	return p.print(struct {
+		encodedEntry
+		ProtocolVersion int `json:"protocol_version"`
+	}{encoded, protocolVersion})
+}
+
+// invoke invokes method on the entry serialized as stateJSON, and returns the exit code.
+func (p *pluginRunner) invoke(ctx context.Context, method string, stateJSON string, args []string) (int, error) {
+	entry, err := p.restore(stateJSON)
+	if err != nil {
+		return 1, err
+	}
+	unsupported := fmt.Errorf("%v does not support %v", entry.eb().name, method)
+
+	switch method {
+	case "schema":
+		return 0, p.print(p.schema.graph)
+	case "list":
+		parent, ok := entry.(Parent)
+		if !ok {
+			return 1, unsupported
+		}
+		children, err := parent.List(ctx)
+		if err != nil {
+			return 1, err
+		}
+		encoded := make([]encodedEntry, 0, len(children))
+		for _, child := range children {
+			encodedChild, err := p.encodeChild(entry, child)
+			if err != nil {
+				return 1, err
+			}
+			encoded = append(encoded, encodedChild)
+		}
+		return 0, p.print(encoded)
+	case "read":
+		var data []byte
+		if len(args) == 2 {
+			e, ok := entry.(BlockReadable)
+			if !ok {
+				return 1, unsupported
+			}
+			size, sizeErr := strconv.ParseInt(args[0], 10, 64)
+			offset, offsetErr := strconv.ParseInt(args[1], 10, 64)
+			if sizeErr != nil || offsetErr != nil {
+				return 1, fmt.Errorf("invalid size %q or offset %q", args[0], args[1])
+			}
+			data, err = e.Read(ctx, size, offset)
+		} else {
+			e, ok := entry.(Readable)
+			if !ok {
+				return 1, unsupported
+			}
+			data, err = e.Read(ctx)
+		}
+		if err != nil {
+			return 1, err
+		}
+		_, err = p.stdout.Write(data)
+		return 0, err
+	case "write":
+		if len(args) == 2 {
+			e, ok := entry.(BlockWritable)
+			if !ok {
+				return 1, unsupported
+			}
+			offset, offsetErr := strconv.ParseInt(args[0], 10, 64)
+			size, sizeErr := strconv.ParseInt(args[1], 10, 64)
+			if offsetErr != nil || sizeErr != nil || size < 0 {
+				return 1, fmt.Errorf("invalid offset %q or size %q", args[0], args[1])
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(p.stdin, data); err != nil {
+				return 1, fmt.Errorf("could not read the data to write: %v", err)
+			}
+			return 0, e.WriteAt(ctx, data, offset)
+		}
+		e, ok := entry.(Writable)
+		if !ok {
+			return 1, unsupported
+		}
+		data, err := ioutil.ReadAll(p.stdin)
+		if err != nil {
+			return 1, fmt.Errorf("could not read the data to write: %v", err)
+		}
+		return 0, e.Write(ctx, data)
+	case "truncate":
+		e, ok := entry.(BlockWritable)
+		if !ok || len(args) != 1 {
+			return 1, unsupported
+		}
+		size, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || size < 0 {
+			return 1, fmt.Errorf("invalid size %q", args[0])
+		}
+		return 0, e.Truncate(ctx, size)
+	case "commit":
+		e, ok := entry.(BlockWritable)
+		if !ok {
+			return 1, unsupported
+		}
+		return 0, e.Commit(ctx)
+	case "stream":
+		e, ok := entry.(Streamable)
+		if !ok {
+			return 1, unsupported
+		}
+		return 0, p.stream(ctx, e)
+	case "exec":
+		e, ok := entry.(Execable)
+		if !ok {
+			return 1, unsupported
+		}
+		return p.exec(ctx, e, args)
+	case "metadata":
+		e, ok := entry.(MetadataProvider)
+		if !ok {
+			return 1, unsupported
+		}
+		metadata, err := e.Metadata(ctx)
+		if err != nil {
+			return 1, err
+		}
+		return 0, p.print(metadata)
+	case "delete":
+		e, ok := entry.(Deletable)
+		if !ok {
+			return 1, unsupported
+		}
+		deleted, err := e.Delete(ctx)
+		if err != nil {
+			return 1, err
+		}
+		return 0, p.print(deleted)
+	case "signal":
+		e, ok := entry.(Signalable)
+		if !ok {
+			return 1, unsupported
+		}
+		if len(args) != 1 {
+			return 1, fmt.Errorf("signal expects a signal")
+		}
+		return 0, e.Signal(ctx, args[0])
+	case "create":
+		e, ok := entry.(Creatable)
+		if !ok {
+			return 1, unsupported
+		}
+		if len(args) != 2 {
+			return 1, fmt.Errorf("create expects a name and whether the new entry's a parent")
+		}
+		isParent, err := strconv.ParseBool(args[1])
+		if err != nil {
+			return 1, fmt.Errorf("invalid value %q for whether the new entry's a parent", args[1])
+		}
+		child, err := e.Create(ctx, args[0], isParent)
+		if err != nil {
+			return 1, err
+		}
+		encoded, err := p.encodeChild(entry, child)
+		if err != nil {
+			return 1, err
+		}
+		return 0, p.print(encoded)
+	case "rename":
+		e, ok := entry.(Renamable)
+		if !ok {
+			return 1, unsupported
+		}
+		if len(args) != 3 {
+			return 1, fmt.Errorf("rename expects the new parent's path and state, and the new name")
+		}
+		newParent, err := p.restore(args[1])
+		if err != nil {
+			return 1, err
+		}
+		parent, ok := newParent.(Parent)
+		if !ok {
+			return 1, fmt.Errorf("%v is not a parent", newParent.eb().name)
+		}
+		renamed, err := e.Rename(ctx, parent, args[2])
+		if err != nil {
+			return 1, err
+		}
+		encoded, err := p.encodeChild(parent, renamed)
+		if err != nil {
+			return 1, err
+		}
+		return 0, p.print(encoded)
+	default:
+		return 1, fmt.Errorf("unknown method %v", method)
+	}
+}
+
+func (p *pluginRunner) stream(ctx context.Context, e Streamable) error {
+	rdr, err := e.Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer rdr.Close()
+
+	// The header tells Wash that the stream's ready.
+	if _, err := io.WriteString(p.stdout, "200\n"); err != nil {
+		return err
+	}
+	go func() {
+		// Stop copying once Wash is done with the stream.
+		<-ctx.Done()
+		rdr.Close()
+	}()
+	if _, err := io.Copy(p.stdout, rdr); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+func (p *pluginRunner) exec(ctx context.Context, e Execable, args []string) (int, error) {
+	if len(args) < 2 {
+		return 1, fmt.Errorf("exec expects options and a command")
+	}
+	var decodedOpts struct {
+		Tty     bool              `json:"tty"`
+		Elevate bool              `json:"elevate"`
+		Env     map[string]string `json:"env"`
+		Dir     string            `json:"dir"`
+		User    string            `json:"user"`
+		Stdin   bool              `json:"stdin"`
+		Timeout float64           `json:"timeout"`
+	}
+	if err := json.Unmarshal([]byte(args[0]), &decodedOpts); err != nil {
+		return 1, fmt.Errorf("could not decode the exec options: %v", err)
+	}
+
+	opts := ExecOptions{
+		Stdout:  p.stdout,
+		Stderr:  p.stderr,
+		Tty:     decodedOpts.Tty,
+		Elevate: decodedOpts.Elevate,
+		Env:     decodedOpts.Env,
+		Dir:     decodedOpts.Dir,
+		User:    decodedOpts.User,
+		Timeout: time.Duration(decodedOpts.Timeout * float64(time.Second)),
+	}
+	if decodedOpts.Stdin {
+		opts.Stdin = p.stdin
+	}
+	return e.Exec(ctx, args[1], args[2:], opts)
+}
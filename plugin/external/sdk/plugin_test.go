@@ -0,0 +1,363 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testRoot struct {
+	EntryBase
+	Greeting string
+}
+
+func (r *testRoot) Init(ctx context.Context, cfg map[string]interface{}) error {
+	r.Greeting, _ = cfg["greeting"].(string)
+	if r.Greeting == "" {
+		return fmt.Errorf("greeting is required")
+	}
+	return nil
+}
+
+func (r *testRoot) Schema() EntrySchema {
+	return EntrySchema{Label: "root", Description: "A test plugin."}
+}
+
+func (r *testRoot) ChildTypes() []Entry {
+	return []Entry{&testFile{}, &testVM{}, &testLog{}}
+}
+
+func (r *testRoot) List(ctx context.Context) ([]Entry, error) {
+	file := &testFile{EntryBase: NewEntry("greeting"), Content: r.Greeting}
+	file.Attributes().SetSize(uint64(len(r.Greeting)))
+	file.SetTTLOf(ReadOp, 30*time.Second)
+	vm := &testVM{EntryBase: NewEntry("vm/1")}
+	vm.SetSlashReplacer('#').SetPartialMetadata(map[string]string{"state": "running"})
+	return []Entry{file, vm}, nil
+}
+
+func (r *testRoot) Create(ctx context.Context, name string, isParent bool) (Entry, error) {
+	if isParent {
+		return &testRoot{EntryBase: NewEntry(name)}, nil
+	}
+	return &testFile{EntryBase: NewEntry(name)}, nil
+}
+
+type testFile struct {
+	EntryBase
+	Content string
+}
+
+func (f *testFile) Read(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	return []byte(f.Content[offset : offset+size]), nil
+}
+
+func (f *testFile) Write(ctx context.Context, data []byte) error {
+	if string(data) != "new content" {
+		return fmt.Errorf("unexpected data %q", data)
+	}
+	return nil
+}
+
+type testLog struct {
+	EntryBase
+}
+
+func (l *testLog) WriteAt(ctx context.Context, data []byte, offset int64) error {
+	if string(data) != "more" || offset != 5 {
+		return fmt.Errorf("unexpected write of %q at %v", data, offset)
+	}
+	return nil
+}
+
+func (l *testLog) Truncate(ctx context.Context, size int64) error {
+	if size != 0 {
+		return fmt.Errorf("unexpected truncate to %v", size)
+	}
+	return nil
+}
+
+func (l *testLog) Commit(ctx context.Context) error {
+	return fmt.Errorf("nothing to commit")
+}
+
+type testVM struct {
+	EntryBase
+}
+
+func (vm *testVM) Schema() EntrySchema {
+	return EntrySchema{Signals: []SignalSchema{{Name: "start", Description: "Starts the VM"}}}
+}
+
+func (vm *testVM) Exec(ctx context.Context, cmd string, args []string, opts ExecOptions) (int, error) {
+	input := ""
+	if opts.Stdin != nil {
+		data, _ := ioutil.ReadAll(opts.Stdin)
+		input = string(data)
+	}
+	fmt.Fprintf(opts.Stdout, "%v %v %v %v", cmd, strings.Join(args, " "), opts.Env["FOO"], input)
+	fmt.Fprint(opts.Stderr, opts.Timeout)
+	return 3, nil
+}
+
+func (vm *testVM) Metadata(ctx context.Context) (map[string]interface{}, error) {
+	return map[string]interface{}{"name": vm.Name()}, nil
+}
+
+func (vm *testVM) Signal(ctx context.Context, signal string) error {
+	return fmt.Errorf("%v could not %v", vm.Name(), signal)
+}
+
+func (vm *testVM) Stream(ctx context.Context) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader("log line\n")), nil
+}
+
+type SDKTestSuite struct {
+	suite.Suite
+}
+
+// run runs the plugin with the given arguments, returning its exit code, stdout and stderr.
+func (suite *SDKTestSuite) run(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"/plugins/test.go"}, args...)
+	exitCode := run(context.Background(), &testRoot{}, args, strings.NewReader(stdin), &stdout, &stderr)
+	return exitCode, stdout.String(), stderr.String()
+}
+
+func (suite *SDKTestSuite) state(e Entry) string {
+	encoded, err := (&pluginRunner{schema: suite.mustSchema()}).encode(e)
+	suite.Require().NoError(err)
+	return encoded.State
+}
+
+func (suite *SDKTestSuite) TestSchema() {
+	s, err := newSchema(&testRoot{})
+	if suite.NoError(err) {
+		rootID := "github.com/puppetlabs/wash/plugin/external/sdk/testRoot"
+		fileID := "github.com/puppetlabs/wash/plugin/external/sdk/testFile"
+		vmID := "github.com/puppetlabs/wash/plugin/external/sdk/testVM"
+		logID := "github.com/puppetlabs/wash/plugin/external/sdk/testLog"
+		suite.Equal(schemaNode{
+			Label:       "root",
+			Description: "A test plugin.",
+			Methods:     []string{"list", "create"},
+			Children:    []string{fileID, vmID, logID},
+		}, s.graph[rootID])
+		suite.Equal(schemaNode{Label: "testfile", Methods: []string{"read", "write"}}, s.graph[fileID])
+		suite.Equal([]string{"stream", "exec", "signal"}, s.graph[vmID].Methods)
+		suite.Equal([]string{"write"}, s.graph[logID].Methods)
+	}
+}
+
+func (suite *SDKTestSuite) TestSchemaErrors() {
+	s := &schema{graph: make(map[string]schemaNode), types: make(map[string]reflect.Type)}
+	suite.Regexp("must include its supported signals", s.add(&signalableWithoutSchema{}))
+	suite.Regexp("ChildTypes must return", s.add(&parentWithoutChildTypes{}))
+}
+
+type signalableWithoutSchema struct {
+	EntryBase
+}
+
+func (e *signalableWithoutSchema) Signal(ctx context.Context, signal string) error {
+	return nil
+}
+
+type parentWithoutChildTypes struct {
+	EntryBase
+}
+
+func (p *parentWithoutChildTypes) ChildTypes() []Entry {
+	return nil
+}
+
+func (p *parentWithoutChildTypes) List(ctx context.Context) ([]Entry, error) {
+	return nil, nil
+}
+
+func (suite *SDKTestSuite) TestInit() {
+	exitCode, stdout, stderr := suite.run("", "init", `{"greeting":"hello"}`, `{"protocol_version":2}`)
+	suite.Equal(0, exitCode, stderr)
+
+	var root map[string]interface{}
+	if suite.NoError(json.Unmarshal([]byte(stdout), &root)) {
+		suite.Equal("test", root["name"])
+		suite.Equal(float64(2), root["protocol_version"])
+		methods := root["methods"].([]interface{})
+		suite.Equal("schema", methods[0].([]interface{})[0])
+		suite.Equal([]interface{}{"list", "create"}, methods[1:])
+
+		// The root's state includes its config.
+		entry, err := (&pluginRunner{schema: suite.mustSchema()}).restore(root["state"].(string))
+		if suite.NoError(err) {
+			suite.Equal("hello", entry.(*testRoot).Greeting)
+			suite.Equal("test", entry.eb().Name())
+		}
+	}
+
+	exitCode, _, stderr = suite.run("", "init", `{}`)
+	suite.Equal(1, exitCode)
+	suite.Equal("greeting is required\n", stderr)
+}
+
+func (suite *SDKTestSuite) mustSchema() *schema {
+	s, err := newSchema(&testRoot{})
+	suite.Require().NoError(err)
+	return s
+}
+
+func (suite *SDKTestSuite) TestList() {
+	root := &testRoot{EntryBase: NewEntry("test"), Greeting: "hello"}
+	exitCode, stdout, stderr := suite.run("", "list", "/test", suite.state(root))
+	suite.Equal(0, exitCode, stderr)
+
+	var entries []map[string]interface{}
+	if suite.NoError(json.Unmarshal([]byte(stdout), &entries)) && suite.Len(entries, 2) {
+		file := entries[0]
+		suite.Equal("greeting", file["name"])
+		suite.Equal([]interface{}{"schema", []interface{}{"read", true}, "write"}, file["methods"])
+		suite.Equal(map[string]interface{}{"size": float64(5)}, file["attributes"])
+		suite.Equal(map[string]interface{}{"read": float64(30)}, file["cache_ttls"])
+
+		vm := entries[1]
+		suite.Equal("vm/1", vm["name"])
+		suite.Equal("#", vm["slash_replacer"])
+		suite.Equal(map[string]interface{}{"state": "running"}, vm["partial_metadata"])
+		suite.Equal([]interface{}{"schema", "stream", "exec", "signal", "metadata"}, vm["methods"])
+	}
+}
+
+func (suite *SDKTestSuite) TestBlockRead() {
+	file := &testFile{EntryBase: NewEntry("greeting"), Content: "hello"}
+	exitCode, stdout, stderr := suite.run("", "read", "/test/greeting", suite.state(file), "3", "1")
+	suite.Equal(0, exitCode, stderr)
+	suite.Equal("ell", stdout)
+
+	exitCode, _, stderr = suite.run("", "read", "/test/greeting", suite.state(file))
+	suite.Equal(1, exitCode)
+	suite.Equal("greeting does not support read\n", stderr)
+}
+
+func (suite *SDKTestSuite) TestWrite() {
+	file := &testFile{EntryBase: NewEntry("greeting")}
+	exitCode, _, stderr := suite.run("new content", "write", "/test/greeting", suite.state(file))
+	suite.Equal(0, exitCode, stderr)
+
+	exitCode, _, stderr = suite.run("bad content", "write", "/test/greeting", suite.state(file))
+	suite.Equal(1, exitCode)
+	suite.Equal("unexpected data \"bad content\"\n", stderr)
+}
+
+func (suite *SDKTestSuite) TestBlockWrite() {
+	log := &testLog{EntryBase: NewEntry("log")}
+	encoded, err := (&pluginRunner{schema: suite.mustSchema()}).encode(log)
+	if suite.NoError(err) {
+		suite.Equal([]interface{}{"schema", []interface{}{"write", true}}, encoded.Methods)
+	}
+
+	exitCode, _, stderr := suite.run("more and more", "write", "/test/log", suite.state(log), "5", "4")
+	suite.Equal(0, exitCode, stderr)
+
+	exitCode, _, stderr = suite.run("mo", "write", "/test/log", suite.state(log), "5", "4")
+	suite.Equal(1, exitCode)
+	suite.Equal("could not read the data to write: unexpected EOF\n", stderr)
+
+	// An empty write isn't a truncation
+	exitCode, _, stderr = suite.run("", "write", "/test/log", suite.state(log), "0", "0")
+	suite.Equal(1, exitCode)
+	suite.Equal("unexpected write of \"\" at 0\n", stderr)
+
+	exitCode, _, stderr = suite.run("", "truncate", "/test/log", suite.state(log), "0")
+	suite.Equal(0, exitCode, stderr)
+
+	exitCode, _, stderr = suite.run("", "truncate", "/test/log", suite.state(log), "-1")
+	suite.Equal(1, exitCode)
+	suite.Equal("invalid size \"-1\"\n", stderr)
+
+	exitCode, _, stderr = suite.run("", "commit", "/test/log", suite.state(log))
+	suite.Equal(1, exitCode)
+	suite.Equal("nothing to commit\n", stderr)
+
+	// Other entries don't support truncate or commit
+	file := &testFile{EntryBase: NewEntry("greeting")}
+	exitCode, _, stderr = suite.run("", "truncate", "/test/greeting", suite.state(file), "0")
+	suite.Equal(1, exitCode)
+	suite.Equal("greeting does not support truncate\n", stderr)
+
+	// Block-writable entries don't support the default signature.
+	exitCode, _, stderr = suite.run("more", "write", "/test/log", suite.state(log))
+	suite.Equal(1, exitCode)
+	suite.Equal("log does not support write\n", stderr)
+}
+
+func (suite *SDKTestSuite) TestExec() {
+	vm := &testVM{EntryBase: NewEntry("vm")}
+	opts := `{"env":{"FOO":"bar"},"stdin":true,"timeout":1.5}`
+	exitCode, stdout, stderr := suite.run("input", "exec", "/test/vm", suite.state(vm), opts, "echo", "a", "b")
+	suite.Equal(3, exitCode)
+	suite.Equal("echo a b bar input", stdout)
+	suite.Equal("1.5s", stderr)
+
+	// Stdin isn't passed unless Wash has input for the command.
+	exitCode, stdout, _ = suite.run("input", "exec", "/test/vm", suite.state(vm), `{}`, "echo")
+	suite.Equal(3, exitCode)
+	suite.Equal("echo   ", stdout)
+}
+
+func (suite *SDKTestSuite) TestStream() {
+	vm := &testVM{EntryBase: NewEntry("vm")}
+	exitCode, stdout, stderr := suite.run("", "stream", "/test/vm", suite.state(vm))
+	suite.Equal(0, exitCode, stderr)
+	suite.Equal("200\nlog line\n", stdout)
+}
+
+func (suite *SDKTestSuite) TestMetadataAndSignal() {
+	vm := &testVM{EntryBase: NewEntry("vm")}
+	exitCode, stdout, stderr := suite.run("", "metadata", "/test/vm", suite.state(vm))
+	suite.Equal(0, exitCode, stderr)
+	suite.JSONEq(`{"name":"vm"}`, stdout)
+
+	exitCode, _, stderr = suite.run("", "signal", "/test/vm", suite.state(vm), "start")
+	suite.Equal(1, exitCode)
+	suite.Equal("vm could not start\n", stderr)
+}
+
+func (suite *SDKTestSuite) TestCreate() {
+	root := &testRoot{EntryBase: NewEntry("test")}
+	exitCode, stdout, stderr := suite.run("", "create", "/test", suite.state(root), "new", "false")
+	suite.Equal(0, exitCode, stderr)
+	suite.Contains(stdout, `"name":"new"`)
+
+	// The root isn't one of its own child types.
+	exitCode, _, stderr = suite.run("", "create", "/test", suite.state(root), "new", "true")
+	suite.Equal(1, exitCode)
+	suite.Contains(stderr, "isn't one of its ChildTypes")
+}
+
+func (suite *SDKTestSuite) TestInvalidInvocations() {
+	exitCode, _, stderr := suite.run("", "list")
+	suite.Equal(1, exitCode)
+	suite.Contains(stderr, "usage")
+
+	exitCode, _, stderr = suite.run("", "list", "/test", `{"type_id":"unknown"}`)
+	suite.Equal(1, exitCode)
+	suite.Contains(stderr, "the entry's type unknown isn't in the plugin's schema")
+
+	vm := &testVM{EntryBase: NewEntry("vm")}
+	exitCode, _, stderr = suite.run("", "list", "/test/vm", suite.state(vm))
+	suite.Equal(1, exitCode)
+	suite.Equal("vm does not support list\n", stderr)
+}
+
+func TestSDK(t *testing.T) {
+	suite.Run(t, new(SDKTestSuite))
+}
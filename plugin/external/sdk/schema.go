@@ -0,0 +1,164 @@
+package sdk
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EntrySchema describes a type of entry.
+type EntrySchema struct {
+	// Label is shown by stree. It defaults to the type's name in lower case.
+	Label string
+	// Description is shown by docs. Plugin roots should describe how to get the plugin working.
+	Description string
+	// Singleton is true if the entry's parent only has one of it.
+	Singleton bool
+	// Signals are the entry's supported signals and signal groups.
+	Signals []SignalSchema
+}
+
+// SignalSchema describes a signal, or a group of signals if Regex is set.
+type SignalSchema struct {
+	Name        string `json:"name"`
+	Regex       string `json:"regex,omitempty"`
+	Description string `json:"description"`
+}
+
+// SchemaProvider is an entry that describes its type. Schema is called on the examples
+// returned by ChildTypes, so it shouldn't depend on the entry's state.
+type SchemaProvider interface {
+	Entry
+	Schema() EntrySchema
+}
+
+// schemaNode is the serialized form of an entry's schema.
+type schemaNode struct {
+	Label       string         `json:"label"`
+	Description string         `json:"description,omitempty"`
+	Singleton   bool           `json:"singleton"`
+	Methods     []string       `json:"methods"`
+	Children    []string       `json:"children,omitempty"`
+	Signals     []SignalSchema `json:"signals,omitempty"`
+}
+
+// schema is the plugin's schema graph, and the types that are used to restore entries.
+type schema struct {
+	graph map[string]schemaNode
+	types map[string]reflect.Type
+}
+
+// typeID returns the unique identifier of the entry's type.
+func typeID(e Entry) string {
+	t := reflect.TypeOf(e)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath() + "/" + t.Name()
+}
+
+// actionsOf returns the Wash actions that the entry supports.
+func actionsOf(e Entry) []string {
+	var actions []string
+	if _, ok := e.(Parent); ok {
+		actions = append(actions, "list")
+	}
+	switch e.(type) {
+	case Readable, BlockReadable:
+		actions = append(actions, "read")
+	}
+	switch e.(type) {
+	case Writable, BlockWritable:
+		actions = append(actions, "write")
+	}
+	if _, ok := e.(Streamable); ok {
+		actions = append(actions, "stream")
+	}
+	if _, ok := e.(Execable); ok {
+		actions = append(actions, "exec")
+	}
+	if _, ok := e.(Deletable); ok {
+		actions = append(actions, "delete")
+	}
+	if _, ok := e.(Signalable); ok {
+		actions = append(actions, "signal")
+	}
+	if _, ok := e.(Creatable); ok {
+		actions = append(actions, "create")
+	}
+	if _, ok := e.(Renamable); ok {
+		actions = append(actions, "rename")
+	}
+	return actions
+}
+
+// newSchema walks the types reachable from root via ChildTypes.
+func newSchema(root Root) (*schema, error) {
+	s := &schema{
+		graph: make(map[string]schemaNode),
+		types: make(map[string]reflect.Type),
+	}
+	if err := s.add(root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *schema) add(e Entry) error {
+	t := reflect.TypeOf(e)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("entries must be pointers to structs, not %v", t)
+	}
+	id := typeID(e)
+	if _, ok := s.types[id]; ok {
+		return nil
+	}
+	s.types[id] = t.Elem()
+
+	node := schemaNode{
+		Label:   strings.ToLower(t.Elem().Name()),
+		Methods: actionsOf(e),
+	}
+	if provider, ok := e.(SchemaProvider); ok {
+		entrySchema := provider.Schema()
+		if entrySchema.Label != "" {
+			node.Label = entrySchema.Label
+		}
+		node.Description = entrySchema.Description
+		node.Singleton = entrySchema.Singleton
+		node.Signals = entrySchema.Signals
+	}
+	if _, ok := e.(Signalable); ok && len(node.Signals) == 0 {
+		return fmt.Errorf("%v is signalable, so its Schema must include its supported signals", id)
+	}
+
+	var children []Entry
+	if parent, ok := e.(Parent); ok {
+		children = parent.ChildTypes()
+		if len(children) == 0 {
+			return fmt.Errorf("%v is a parent, so ChildTypes must return its children's types", id)
+		}
+	}
+	for _, child := range children {
+		node.Children = append(node.Children, typeID(child))
+	}
+	s.graph[id] = node
+
+	for _, child := range children {
+		if err := s.add(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isChildType returns true if child is one of parent's ChildTypes.
+func (s *schema) isChildType(parent Entry, child Entry) bool {
+	childID := typeID(child)
+	for _, id := range s.graph[typeID(parent)].Children {
+		if id == childID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package external
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/plugin/external/sdk"
+	"github.com/stretchr/testify/suite"
+)
+
+// Setting sdkPluginEnv makes the test binary run as a plugin that's written with the SDK.
+const sdkPluginEnv = "WASH_TEST_SDK_PLUGIN"
+
+type sdkTestRoot struct {
+	sdk.EntryBase
+	Greeting string
+}
+
+func (r *sdkTestRoot) Init(ctx context.Context, cfg map[string]interface{}) error {
+	r.Greeting = fmt.Sprintf("%v", cfg["greeting"])
+	return nil
+}
+
+func (r *sdkTestRoot) ChildTypes() []sdk.Entry {
+	return []sdk.Entry{&sdkTestFile{}}
+}
+
+func (r *sdkTestRoot) List(ctx context.Context) ([]sdk.Entry, error) {
+	file := &sdkTestFile{EntryBase: sdk.NewEntry("greeting"), Content: r.Greeting}
+	file.Attributes().SetSize(uint64(len(r.Greeting)))
+	return []sdk.Entry{file}, nil
+}
+
+type sdkTestFile struct {
+	sdk.EntryBase
+	Content string
+}
+
+func (f *sdkTestFile) Read(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	return []byte(f.Content[offset : offset+size]), nil
+}
+
+func (f *sdkTestFile) Metadata(ctx context.Context) (map[string]interface{}, error) {
+	return map[string]interface{}{"content": f.Content}, nil
+}
+
+// SDKPluginTestSuite checks that plugins written with the SDK follow the external plugin protocol.
+type SDKPluginTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SDKPluginTestSuite) SetupTest() {
+	if err := os.Setenv(sdkPluginEnv, "1"); err != nil {
+		suite.FailNow(err.Error())
+	}
+}
+
+func (suite *SDKPluginTestSuite) TearDownTest() {
+	os.Unsetenv(sdkPluginEnv)
+}
+
+func (suite *SDKPluginTestSuite) TestPlugin() {
+	ctx := context.Background()
+	root := &pluginRoot{pluginEntry: pluginEntry{
+		EntryBase: plugin.NewEntry("external"),
+		script:    externalPluginScriptImpl{path: os.Args[0]},
+	}}
+	if !suite.NoError(root.Init(map[string]interface{}{"greeting": "hello"})) {
+		return
+	}
+	root.SetTestID("/external")
+	suite.Equal(Protocol{Version: 2}, root.Protocol())
+	suite.True(root.schemaKnown)
+
+	entries, err := root.List(ctx)
+	if !suite.NoError(err) || !suite.Len(entries, 1) {
+		return
+	}
+	file := entries[0].(*pluginEntry)
+	file.SetTestID("/external/greeting")
+	suite.Equal("greeting", plugin.Name(file))
+	suite.Equal("github.com/puppetlabs/wash/plugin/external/sdkTestFile", file.RawTypeID())
+	suite.Equal(plugin.BlockReadableSignature, file.MethodSignature("read"))
+
+	data, err := file.BlockRead(ctx, 3, 1)
+	if suite.NoError(err) {
+		suite.Equal("ell", string(data))
+	}
+	metadata, err := file.Metadata(ctx)
+	if suite.NoError(err) {
+		suite.Equal(plugin.JSONObject{"content": "hello"}, metadata)
+	}
+}
+
+func TestSDKPlugin(t *testing.T) {
+	suite.Run(t, new(SDKPluginTestSuite))
+}
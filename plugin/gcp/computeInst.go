@@ -58,9 +58,9 @@ func (c *computeInstance) List(ctx context.Context) ([]plugin.Entry, error) {
 	return []plugin.Entry{
 		newComputeInstanceConsoleOutput(c.instance, c.service),
 		metadataJSONFile,
-		// Include a view of the remote filesystem using volume.FS. Use a small maxdepth because
+		// Include a view of the remote filesystem using volume.SFTP. Use a small maxdepth because
 		// VMs can have lots of files and SSH is fast.
-		volume.NewFS(ctx, "fs", c, 3),
+		volume.NewSFTP(ctx, "fs", c, 3),
 	}, nil
 }
 
@@ -83,7 +83,7 @@ func (c *computeInstance) ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
 		(&computeInstanceConsoleOutput{}).Schema(),
 		(&plugin.MetadataJSONFile{}).Schema(),
-		(&volume.FS{}).Schema(),
+		(&volume.SFTP{}).Schema(),
 	}
 }
 
@@ -104,20 +104,30 @@ func (c *computeInstance) Signal(ctx context.Context, signal string) error {
 
 func (c *computeInstance) Exec(ctx context.Context, cmd string, args []string,
 	opts plugin.ExecOptions) (plugin.ExecCommand, error) {
-	conf, err := gceSSHFiles()
+	identity, err := c.SSHIdentity(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return transport.ExecSSH(ctx, identity, append([]string{cmd}, args...), opts)
+}
+
+// SSHIdentity returns the identity used to connect to the instance via SSH, adding the user's
+// public key to the instance if needed. It's used by Exec and by the instance's SFTP volume.
+func (c *computeInstance) SSHIdentity(ctx context.Context) (transport.Identity, error) {
+	conf, err := gceSSHFiles()
+	if err != nil {
+		return transport.Identity{}, err
+	}
 
 	// Extract username and key from public key file name.
 	user, key, err := parseUserAndKey(conf.publicKey)
 	if err != nil {
-		return nil, err
+		return transport.Identity{}, err
 	}
 
 	keyAdded, err := c.addPublicKey(ctx, user, key)
 	if err != nil {
-		return nil, err
+		return transport.Identity{}, err
 	}
 
 	// TODO: Get host keys for the instance.
@@ -129,7 +139,7 @@ func (c *computeInstance) Exec(ctx context.Context, cmd string, args []string,
 
 	hostname := getExternalIP(c.instance)
 	if hostname == "" {
-		return nil, fmt.Errorf("%v does not have an external IP address", c.Name())
+		return transport.Identity{}, fmt.Errorf("%v does not have an external IP address", c.Name())
 	}
 
 	identity := transport.Identity{
@@ -143,7 +153,7 @@ func (c *computeInstance) Exec(ctx context.Context, cmd string, args []string,
 		// It may take some time for the new key to be added to the instance. Retry for up to 15s.
 		identity.Retries = 30
 	}
-	return transport.ExecSSH(ctx, identity, append([]string{cmd}, args...), opts)
+	return identity, nil
 }
 
 // Based on https://github.com/google-cloud-sdk/google-cloud-sdk/blob/v255.0.0/lib/googlecloudsdk/command_lib/compute/ssh_utils.py#L106
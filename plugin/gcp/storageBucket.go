@@ -73,6 +73,15 @@ func (s *storageBucket) List(ctx context.Context) ([]plugin.Entry, error) {
 	return listBucket(ctx, bucket, "")
 }
 
+// ListPage lists a single page of storage objects as dirs and files.
+func (s *storageBucket) ListPage(ctx context.Context, token string) ([]plugin.Entry, string, error) {
+	return listBucketPage(ctx, s.Bucket(s.Name()), "", token)
+}
+
+func (s *storageBucket) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	return createObject(ctx, s.Bucket(s.Name()), "", name, isParent)
+}
+
 func (s *storageBucket) Delete(ctx context.Context) (bool, error) {
 	// GCP only deletes empty buckets, so we'll need to delete all of its
 	// objects before deleting the bucket.
@@ -97,19 +106,39 @@ func (s *storageBucket) ChildSchemas() []*plugin.EntrySchema {
 
 const delimiter = "/"
 
+// listPageSize is the maximum number of objects returned by listBucketPage.
+const listPageSize = 1000
+
 func listBucket(ctx context.Context, bucket *storage.BucketHandle, prefix string) ([]plugin.Entry, error) {
 	var entries []plugin.Entry
-	// Get objects directly under this prefix.
-	it := bucket.Objects(ctx, &storage.Query{Delimiter: delimiter, Prefix: prefix})
+	token := ""
 	for {
-		objAttrs, err := it.Next()
-		if err == iterator.Done {
-			break
-		}
+		page, nextToken, err := listBucketPage(ctx, bucket, prefix, token)
 		if err != nil {
 			return nil, err
 		}
+		entries = append(entries, page...)
+		if nextToken == "" {
+			return entries, nil
+		}
+		token = nextToken
+	}
+}
+
+// listBucketPage lists the page of objects under the prefix that's identified by
+// the given page token. It returns the page's entries along with the next page's
+// token, which is empty if there are no more pages.
+func listBucketPage(ctx context.Context, bucket *storage.BucketHandle, prefix string, token string) ([]plugin.Entry, string, error) {
+	// Get objects directly under this prefix.
+	it := bucket.Objects(ctx, &storage.Query{Delimiter: delimiter, Prefix: prefix})
+	var page []*storage.ObjectAttrs
+	nextToken, err := iterator.NewPager(it, listPageSize, token).NextPage(&page)
+	if err != nil {
+		return nil, "", err
+	}
 
+	var entries []plugin.Entry
+	for _, objAttrs := range page {
 		// https://godoc.org/cloud.google.com/go/storage#Query notes that providing a delimiter returns
 		// results in a directory-like fashion. Results will contain objects whose names, aside from
 		// the prefix, do not contain delimiter. Objects whose names, aside from the prefix, contain
@@ -129,7 +158,60 @@ func listBucket(ctx context.Context, bucket *storage.BucketHandle, prefix string
 			entries = append(entries, newStorageObject(name, bucket.Object(objAttrs.Name), objAttrs))
 		}
 	}
-	return entries, nil
+	return entries, nextToken, nil
+}
+
+// createObject creates an empty object named name under the given prefix. If
+// isParent is true, then the object's name ends with the delimiter so that it's
+// represented as a storageObjectPrefix.
+func createObject(ctx context.Context, bucket *storage.BucketHandle, prefix string, name string, isParent bool) (plugin.Entry, error) {
+	objName := prefix + name
+	if isParent {
+		objName += delimiter
+	}
+	obj := bucket.Object(objName)
+	wr := obj.NewWriter(ctx)
+	// When Close fails we can assume the object creation failed.
+	if err := wr.Close(); err != nil {
+		return nil, err
+	}
+	if isParent {
+		return newStorageObjectPrefix(bucket, name, objName, wr.Attrs()), nil
+	}
+	return newStorageObject(name, obj, wr.Attrs()), nil
+}
+
+// renameDestination returns the bucket and prefix of a rename's new parent. The
+// new parent must be a storage bucket or a storage object prefix.
+func renameDestination(newParent plugin.Parent) (*storage.BucketHandle, string, error) {
+	switch p := newParent.(type) {
+	case *storageBucket:
+		return p.Bucket(p.Name()), "", nil
+	case *storageObjectPrefix:
+		return p.bucket, p.prefix, nil
+	default:
+		return nil, "", fmt.Errorf("%v is not a storage bucket or a storage object prefix", plugin.ID(newParent))
+	}
+}
+
+// copyObjects copies all objects that start with srcPrefix so that they start
+// with dstPrefix instead.
+func copyObjects(ctx context.Context, src *storage.BucketHandle, srcPrefix string, dst *storage.BucketHandle, dstPrefix string) error {
+	it := src.Objects(ctx, &storage.Query{Prefix: srcPrefix})
+	for {
+		objAttrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		dstName := dstPrefix + strings.TrimPrefix(objAttrs.Name, srcPrefix)
+		if _, err := dst.Object(dstName).CopierFrom(src.Object(objAttrs.Name)).Run(ctx); err != nil {
+			return fmt.Errorf("failed to copy the %v object to %v: %v", objAttrs.Name, dstName, err)
+		}
+	}
+	return nil
 }
 
 func deleteObjects(ctx context.Context, bucket *storage.BucketHandle, prefix string) error {
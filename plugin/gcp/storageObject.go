@@ -2,6 +2,7 @@ package gcp
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
 
 	"cloud.google.com/go/storage"
@@ -56,6 +57,24 @@ func (s *storageObject) Write(ctx context.Context, p []byte) error {
 	return wr.Close()
 }
 
+// Rename copies the object to its new name then deletes the original, since
+// Storage does not support renaming objects.
+func (s *storageObject) Rename(ctx context.Context, newParent plugin.Parent, newName string) (plugin.Entry, error) {
+	bucket, prefix, err := renameDestination(newParent)
+	if err != nil {
+		return nil, err
+	}
+	dst := bucket.Object(prefix + newName)
+	attrs, err := dst.CopierFrom(s.ObjectHandle).Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ObjectHandle.Delete(ctx); err != nil {
+		return nil, fmt.Errorf("copied the object to %v, but failed to delete the original: %v", dst.ObjectName(), err)
+	}
+	return newStorageObject(newName, dst, attrs), nil
+}
+
 func (s *storageObject) Delete(ctx context.Context) (bool, error) {
 	err := s.ObjectHandle.Delete(ctx)
 	return true, err
@@ -2,8 +2,10 @@ package gcp
 
 import (
 	"context"
+	"fmt"
 
 	"cloud.google.com/go/storage"
+	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
 )
 
@@ -38,6 +40,38 @@ func (s *storageObjectPrefix) List(ctx context.Context) ([]plugin.Entry, error)
 	return listBucket(ctx, s.bucket, s.prefix)
 }
 
+// ListPage lists a single page of storage objects under this prefix as dirs and files.
+func (s *storageObjectPrefix) ListPage(ctx context.Context, token string) ([]plugin.Entry, string, error) {
+	return listBucketPage(ctx, s.bucket, s.prefix, token)
+}
+
+// Create a storage object (or a prefix if isParent is true) under this prefix.
+func (s *storageObjectPrefix) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	return createObject(ctx, s.bucket, s.prefix, name, isParent)
+}
+
+// Rename copies all storage objects under this prefix to the new prefix then
+// deletes the originals, since Storage does not support renaming objects.
+func (s *storageObjectPrefix) Rename(ctx context.Context, newParent plugin.Parent, newName string) (plugin.Entry, error) {
+	bucket, prefix, err := renameDestination(newParent)
+	if err != nil {
+		return nil, err
+	}
+	newPrefix := prefix + newName + delimiter
+	if err := copyObjects(ctx, s.bucket, s.prefix, bucket, newPrefix); err != nil {
+		return nil, err
+	}
+	if err := deleteObjects(ctx, s.bucket, s.prefix); err != nil {
+		return nil, fmt.Errorf("copied the objects to %v, but failed to delete the originals: %v", newPrefix, err)
+	}
+	attrs, err := bucket.Object(newPrefix).Attrs(ctx)
+	if err != nil {
+		// Don't treat this as an error. Not all prefixes have attributes.
+		activity.Record(ctx, "Could not get attributes of %v: %v", newPrefix, err)
+	}
+	return newStorageObjectPrefix(bucket, newName, newPrefix, attrs), nil
+}
+
 func (s *storageObjectPrefix) Delete(ctx context.Context) (bool, error) {
 	err := deleteObjects(ctx, s.bucket, s.prefix)
 	return true, err
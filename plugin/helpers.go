@@ -1,8 +1,10 @@
 package plugin
 
 import (
+	"context"
 	"time"
 
+	"github.com/kballard/go-shellquote"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -12,3 +14,45 @@ func TrackTime(start time.Time, name string) {
 	elapsed := time.Since(start)
 	log.Infof("%s took %s", name, elapsed)
 }
+
+// PosixCommand returns a command that runs cmd on a POSIX system with opts'
+// Env, Dir and User. It's useful for executors whose API can only run a
+// command. Note that User is implemented with sudo, so the executor's user
+// must be able to sudo as that user.
+func PosixCommand(cmd []string, opts ExecOptions) []string {
+	if opts.Dir != "" {
+		script := "cd " + shellquote.Join(opts.Dir) + ` && exec "$@"`
+		cmd = append([]string{"sh", "-c", script, "sh"}, cmd...)
+	}
+	if len(opts.Env) > 0 {
+		cmd = append(append([]string{"env"}, opts.Environ()...), cmd...)
+	}
+	if opts.User != "" {
+		cmd = append([]string{"sudo", "-u", opts.User, "--"}, cmd...)
+	}
+	return cmd
+}
+
+// DetachedContext returns a context with all of ctx's values that's never
+// cancelled and has no deadline. Use it for work that's shared by several
+// callers, like a background refresh, so that it isn't cancelled with the
+// caller that started it.
+func DetachedContext(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
@@ -0,0 +1,25 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPosixCommand(t *testing.T) {
+	cmd := []string{"echo", "hello world"}
+	assert.Equal(t, cmd, PosixCommand(cmd, ExecOptions{}))
+
+	opts := ExecOptions{Dir: "/my dir"}
+	assert.Equal(t, []string{"sh", "-c", `cd '/my dir' && exec "$@"`, "sh", "echo", "hello world"}, PosixCommand(cmd, opts))
+
+	opts = ExecOptions{Env: map[string]string{"B": "2", "A": "1"}}
+	assert.Equal(t, []string{"env", "A=1", "B=2", "echo", "hello world"}, PosixCommand(cmd, opts))
+
+	opts = ExecOptions{User: "bob"}
+	assert.Equal(t, []string{"sudo", "-u", "bob", "--", "echo", "hello world"}, PosixCommand(cmd, opts))
+
+	opts = ExecOptions{Dir: "/tmp", Env: map[string]string{"A": "1"}, User: "bob"}
+	expected := []string{"sudo", "-u", "bob", "--", "env", "A=1", "sh", "-c", `cd /tmp && exec "$@"`, "sh", "echo", "hello world"}
+	assert.Equal(t, expected, PosixCommand(cmd, opts))
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/mattn/go-isatty"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 var isInteractive bool = (isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())) &&
@@ -41,3 +42,20 @@ func Prompt(msg string) (string, error) {
 	_, err := fmt.Scanln(&v)
 	return v, err
 }
+
+// PromptPassword is like Prompt, but it doesn't echo the input. Use it to ask for passwords and
+// other secrets.
+func PromptPassword(msg string) (string, error) {
+	if !IsInteractive() {
+		return "", fmt.Errorf("not an interactive session")
+	}
+
+	promptMux.Lock()
+	defer promptMux.Unlock()
+
+	fmt.Fprintf(os.Stderr, "%s: ", msg)
+	v, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+	// The user's newline isn't echoed either.
+	fmt.Fprintln(os.Stderr)
+	return string(v), err
+}
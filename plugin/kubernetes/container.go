@@ -3,14 +3,11 @@ package kubernetes
 import (
 	"context"
 
-	"github.com/pkg/errors"
 	"github.com/puppetlabs/wash/plugin"
 	"github.com/puppetlabs/wash/volume"
 	corev1 "k8s.io/api/core/v1"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/remotecommand"
-	k8exec "k8s.io/client-go/util/exec"
 )
 
 type container struct {
@@ -75,32 +72,5 @@ func (c *container) List(ctx context.Context) ([]plugin.Entry, error) {
 }
 
 func (c *container) Exec(ctx context.Context, cmd string, args []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
-	execCmd := plugin.NewExecCommand(ctx)
-	executor, err := c.newExecutor(ctx, cmd, args, remotecommand.StreamOptions{
-		Stdout: execCmd.Stdout(),
-		Stderr: execCmd.Stderr(),
-		Stdin:  opts.Stdin,
-		Tty:    opts.Tty,
-	})
-	if err != nil {
-		return nil, errors.Wrap(err, "kubernetes.container.Exec request")
-	}
-
-	errHandler := func(err error) {
-		if err == nil {
-			execCmd.SetExitCode(0)
-		} else if exerr, ok := err.(k8exec.ExitError); ok {
-			execCmd.SetExitCode(exerr.ExitStatus())
-			err = nil
-		} else {
-			// Set the exit code error so that callers don't block
-			// when trying to retrieve the command's exit code
-			execCmd.SetExitCodeErr(err)
-		}
-		execCmd.CloseStreamsWithError(err)
-	}
-
-	cleanup := executor.AsyncStream(errHandler)
-	execCmd.SetStopFunc(cleanup)
-	return execCmd, nil
+	return c.exec(ctx, append([]string{cmd}, args...), opts)
 }
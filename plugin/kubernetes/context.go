@@ -2,9 +2,11 @@ package kubernetes
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8s "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -59,6 +61,20 @@ func (c *k8context) List(ctx context.Context) ([]plugin.Entry, error) {
 	return namespaces, nil
 }
 
+// Create creates a new namespace. Equivalent to 'kubectl create namespace <name>'
+func (c *k8context) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	if !isParent {
+		return nil, fmt.Errorf("%v can only contain namespaces, which are directories", c)
+	}
+	meta := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	ns, err := c.client.CoreV1().Namespaces().Create(ctx, meta, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Created namespace %v", ns.Name)
+	return newNamespace(ns.Name, ns, c.client, c.config), nil
+}
+
 const contextDescription = `
 This is a Kubernetes context.
 `
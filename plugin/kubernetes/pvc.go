@@ -3,7 +3,9 @@ package kubernetes
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"strings"
 
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/plugin"
@@ -13,7 +15,6 @@ import (
 	k8s "k8s.io/client-go/kubernetes"
 	typedv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/remotecommand"
 )
 
 type pvc struct {
@@ -62,6 +63,10 @@ func (v *pvc) List(ctx context.Context) ([]plugin.Entry, error) {
 	return volume.List(ctx, v)
 }
 
+func (v *pvc) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	return volume.Create(ctx, v, name, isParent)
+}
+
 func (v *pvc) Delete(ctx context.Context) (bool, error) {
 	err := v.pvci.Delete(ctx, v.Name(), metav1.DeleteOptions{})
 	return true, err
@@ -71,6 +76,7 @@ type mountInfo struct {
 	pod       *corev1.Pod
 	container *corev1.Container
 	path      string
+	readOnly  bool
 }
 
 // TODO: return read-write mount if available (fallback to read-only) that mounts the volume
@@ -106,6 +112,7 @@ func (v *pvc) getMountInfo(pod *corev1.Pod, volumeName string) mountInfo {
 					pod:       pod,
 					container: &container,
 					path:      mount.MountPath,
+					readOnly:  mount.ReadOnly,
 				}
 			}
 		}
@@ -119,8 +126,8 @@ func (v *pvc) getMountInfo(pod *corev1.Pod, volumeName string) mountInfo {
 type containerCb = func(c *containerBase, mountpoint string, cleanup func()) (interface{}, error)
 
 // Execution containerCb in a container that has the current PVC mounted. Creates one if one is
-// not currently running.
-func (v *pvc) inContainer(ctx context.Context, fn containerCb) (interface{}, error) {
+// not currently running. If writable is true, then the PVC must be mounted read-write.
+func (v *pvc) inContainer(ctx context.Context, writable bool, fn containerCb) (interface{}, error) {
 	mountingPod, volumeName, err := v.getFirstMountingPod(ctx)
 	if err != nil {
 		return nil, err
@@ -131,7 +138,7 @@ func (v *pvc) inContainer(ctx context.Context, fn containerCb) (interface{}, err
 	var cleanup func()
 	if mountingPod == nil {
 		mountpoint = "/mnt"
-		tempPod, err := createContainer(ctx, v.podi, v.Name(), mountpoint)
+		tempPod, err := createContainer(ctx, v.podi, v.Name(), mountpoint, !writable)
 		if err != nil {
 			return nil, err
 		}
@@ -144,6 +151,9 @@ func (v *pvc) inContainer(ctx context.Context, fn containerCb) (interface{}, err
 		}
 	} else {
 		mount := v.getMountInfo(mountingPod, volumeName)
+		if writable && mount.readOnly {
+			return nil, fmt.Errorf("%v is mounted read-only by pod %v", v.Name(), mount.pod.Name)
+		}
 		execContainer.pod = mount.pod
 		execContainer.container = mount.container
 		mountpoint = mount.path
@@ -157,22 +167,43 @@ func (v *pvc) inContainer(ctx context.Context, fn containerCb) (interface{}, err
 type cmdBuilder func(string) []string
 
 func (v *pvc) exec(ctx context.Context, buildCmd cmdBuilder) ([]byte, error) {
-	obj, err := v.inContainer(ctx, func(c *containerBase, mountpoint string, cleanup func()) (interface{}, error) {
+	return v.execIn(ctx, false, nil, buildCmd)
+}
+
+// execIn is exec with the PVC mounted read-write if writable is true. If stdin is not nil,
+// then its content is sent to the command's stdin.
+func (v *pvc) execIn(ctx context.Context, writable bool, stdin io.Reader, buildCmd cmdBuilder) ([]byte, error) {
+	obj, err := v.inContainer(ctx, writable, func(c *containerBase, mountpoint string, cleanup func()) (interface{}, error) {
 		defer cleanup()
 
 		cmd := buildCmd(mountpoint)
 		activity.Record(ctx, "Executing in %v: %v", c, cmd)
 
-		var stdout, stderr bytes.Buffer
-		streamOpts := remotecommand.StreamOptions{Stdout: &stdout, Stderr: &stderr}
-		executor, err := c.newExecutor(ctx, cmd[0], cmd[1:], streamOpts)
+		execCmd, err := c.exec(ctx, cmd, plugin.ExecOptions{Stdin: stdin})
 		if err != nil {
 			return []byte{}, err
 		}
 
-		err = executor.Stream()
+		var stdout, stderr bytes.Buffer
+		for chunk := range execCmd.OutputCh() {
+			switch {
+			case chunk.Err != nil:
+				err = chunk.Err
+			case chunk.StreamID == plugin.Stdout:
+				stdout.WriteString(chunk.Data)
+			case chunk.StreamID == plugin.Stderr:
+				stderr.WriteString(chunk.Data)
+			}
+		}
 		activity.Record(ctx, "stdout: %v", stdout.String())
 		activity.Record(ctx, "stderr: %v", stderr.String())
+		if err != nil {
+			return stdout.Bytes(), err
+		}
+		exitCode, err := execCmd.ExitCode()
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("command terminated with non-zero exit code %v: %v", exitCode, strings.TrimSpace(stderr.String()))
+		}
 		return stdout.Bytes(), err
 	})
 	return obj.([]byte), err
@@ -204,24 +235,34 @@ func (v *pvc) VolumeRead(ctx context.Context, path string) ([]byte, error) {
 }
 
 func (v *pvc) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
-	obj, err := v.inContainer(ctx, func(c *containerBase, mountpoint string, cleanup func()) (interface{}, error) {
+	obj, err := v.inContainer(ctx, false, func(c *containerBase, mountpoint string, cleanup func()) (interface{}, error) {
 		cmd := []string{"tail", "-f", mountpoint + path}
 		activity.Record(ctx, "Streaming from %v: %v", c, cmd)
 
-		stdoutR, stdoutW := io.Pipe()
-		streamOpts := remotecommand.StreamOptions{Stdout: stdoutW, Tty: true}
-		executor, err := c.newExecutor(ctx, cmd[0], cmd[1:], streamOpts)
+		// Use a TTY so that tail's stopped with Ctrl-C when the stream's closed.
+		execCtx, cancel := context.WithCancel(ctx)
+		execCmd, err := c.exec(execCtx, cmd, plugin.ExecOptions{Tty: true})
 		if err != nil {
-			stdoutR.Close()
-			stdoutW.Close()
+			cancel()
 			cleanup()
 			return nil, err
 		}
 
-		cleanupExec := executor.AsyncStream(func(error) {})
+		stdoutR, stdoutW := io.Pipe()
+		go func() {
+			var err error
+			for chunk := range execCmd.OutputCh() {
+				if chunk.Err != nil {
+					err = chunk.Err
+				} else if err == nil {
+					_, err = stdoutW.Write([]byte(chunk.Data))
+				}
+			}
+			stdoutW.CloseWithError(err)
+		}()
 		return plugin.CleanupReader{ReadCloser: stdoutR, Cleanup: func() {
-			// Cleanup execution and cleanup the container on completion.
-			cleanupExec()
+			// Stop the command and cleanup the container on completion.
+			cancel()
 			cleanup()
 		}}, nil
 	})
@@ -229,7 +270,7 @@ func (v *pvc) VolumeStream(ctx context.Context, path string) (io.ReadCloser, err
 }
 
 func (v *pvc) VolumeDelete(ctx context.Context, path string) (bool, error) {
-	_, err := v.exec(ctx, func(base string) []string {
+	_, err := v.execIn(ctx, true, nil, func(base string) []string {
 		return []string{"rm", "-rf", base + path}
 	})
 	if err != nil {
@@ -238,10 +279,33 @@ func (v *pvc) VolumeDelete(ctx context.Context, path string) (bool, error) {
 	return true, nil
 }
 
+func (v *pvc) VolumeWrite(ctx context.Context, path string, b []byte) error {
+	_, err := v.execIn(ctx, true, bytes.NewReader(b), func(base string) []string {
+		return []string{"sh", "-c", `cat > "$1"`, "sh", base + path}
+	})
+	return err
+}
+
+func (v *pvc) VolumeCreate(ctx context.Context, path string) error {
+	_, err := v.execIn(ctx, true, nil, func(base string) []string {
+		return []string{"touch", base + path}
+	})
+	return err
+}
+
+func (v *pvc) VolumeMkdir(ctx context.Context, path string) error {
+	_, err := v.execIn(ctx, true, nil, func(base string) []string {
+		return []string{"mkdir", base + path}
+	})
+	return err
+}
+
 const pvcDescription = `
 This is a Kubernetes persistent volume claim. We create a temporary Kubernetes
 pod whenever Wash invokes a currently uncached List/Read/Stream action on it or
 one of its children. For List, we run 'find -exec stat' on the pod and parse its
 output. For Read, we run 'cat' and return its output. For Stream, we run 'tail -f'
-and stream its output.
+and stream its output. For Write, we run 'cat' and send the new content to its
+stdin. For Create, we run 'touch' or 'mkdir'. Write and Create fail if the claim
+is only mounted read-only.
 `
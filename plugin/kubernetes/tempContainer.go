@@ -21,7 +21,7 @@ type tempContainer struct {
 }
 
 // Create a container that mounts a pvc to a default mountpoint and waits for 7 days.
-func createContainer(ctx context.Context, podi typedv1.PodInterface, volumeClaim, mountpoint string) (c tempContainer, err error) {
+func createContainer(ctx context.Context, podi typedv1.PodInterface, volumeClaim, mountpoint string, readOnly bool) (c tempContainer, err error) {
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: "wash",
@@ -42,7 +42,7 @@ func createContainer(ctx context.Context, podi typedv1.PodInterface, volumeClaim
 						{
 							Name:      volumeClaim,
 							MountPath: mountpoint,
-							ReadOnly:  true,
+							ReadOnly:  readOnly,
 						},
 					},
 				},
@@ -54,7 +54,7 @@ func createContainer(ctx context.Context, podi typedv1.PodInterface, volumeClaim
 					VolumeSource: corev1.VolumeSource{
 						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
 							ClaimName: volumeClaim,
-							ReadOnly:  true,
+							ReadOnly:  readOnly,
 						},
 					},
 				},
@@ -117,6 +117,19 @@ func List(ctx context.Context, p Parent) (*EntryMap, error) {
 	return cachedList(ctx, p)
 }
 
+// ListPages lists the parent's children, invoking onPage on each page of children
+// as soon as it's listed. This lets callers start processing a PagedParent's children
+// before the entire listing is finished. If p is not a PagedParent, or if p's children
+// were already listed, then onPage is invoked once with all of p's children. ListPages
+// stops listing if onPage returns an error, and returns that error.
+//
+// Concurrent ListPages calls on the same parent share one listing. Note that ListPages
+// caches the entire listing once it is finished, so subsequent List calls will use its
+// result. Listings that are too large to keep in memory aren't cached.
+func ListPages(ctx context.Context, p Parent, onPage func(*EntryMap) error) error {
+	return cachedListPages(ctx, p, onPage)
+}
+
 // Read reads up to size bits of the entry's content starting at the given offset.
 // It will panic if the entry does not support the read action. Callers can use
 // len(data) to check the amount of data that was actually read.
@@ -196,9 +209,53 @@ func Metadata(ctx context.Context, e Entry) (JSONObject, error) {
 	return cachedMetadata(ctx, e)
 }
 
-// Exec execs the command on the given entry.
+// Exec execs the command on the given entry. The exec is audited once the
+// command's exit code is available or ctx ends. If opts.Timeout is set, then
+// the command is stopped once it elapses.
 func Exec(ctx context.Context, e Execable, cmd string, args []string, opts ExecOptions) (ExecCommand, error) {
-	return e.Exec(ctx, cmd, args, opts)
+	record := startAuditRecord(ctx, e, ExecAction(), map[string]interface{}{"command": cmd, "args": args})
+	if err := checkPolicy(ctx, e, ExecAction()); err != nil {
+		finishAuditRecord(record, nil, err)
+		return nil, err
+	}
+	execCtx := ctx
+	var cancel context.CancelFunc
+	if opts.Timeout > 0 {
+		execCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+	}
+	command, err := e.Exec(execCtx, cmd, args, opts)
+	if err != nil {
+		if cancel != nil {
+			cancel()
+		}
+		finishAuditRecord(record, nil, err)
+		return nil, err
+	}
+	if cancel != nil {
+		command = &timedExecCommand{ExecCommand: command, ctx: execCtx, cancel: cancel, timeout: opts.Timeout}
+	}
+	// Use ctx rather than execCtx so that a timeout's recorded with the
+	// timedExecCommand's error.
+	return newAuditedExecCommand(ctx, command, record), nil
+}
+
+// timedExecCommand releases the exec's timeout once its exit code's retrieved.
+// It also replaces the exit code's context error with a more helpful message if
+// the command timed out.
+type timedExecCommand struct {
+	ExecCommand
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+}
+
+func (cmd *timedExecCommand) ExitCode() (int, error) {
+	exitCode, err := cmd.ExecCommand.ExitCode()
+	if err != nil && cmd.ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("the command timed out after %v", cmd.timeout)
+	}
+	cmd.cancel()
+	return exitCode, err
 }
 
 // Stream streams the entry's content for updates.
@@ -207,12 +264,59 @@ func Stream(ctx context.Context, s Streamable) (io.ReadCloser, error) {
 }
 
 // Write sends the supplied buffer to the entry.
-func Write(ctx context.Context, a Writable, b []byte) error {
+func Write(ctx context.Context, a Writable, b []byte) (err error) {
+	record := startAuditRecord(ctx, a, WriteAction(), map[string]interface{}{"size": len(b)})
+	defer func() { finishAuditRecord(record, nil, err) }()
+
+	if err = checkPolicy(ctx, a, WriteAction()); err != nil {
+		return err
+	}
+	if IsDryRun(ctx) {
+		return planDryRun(ctx, record, func() ([]string, error) {
+			if planner, ok := a.(WritePlanner); ok {
+				return planner.PlanWrite(ctx, b)
+			}
+			return []string{fmt.Sprintf("write %v bytes to %v", len(b), a.eb().id)}, nil
+		})
+	}
 	return a.Write(ctx, b)
 }
 
+// IsBlockWritable returns true if the entry's data is written in blocks with
+// OpenWriter rather than all at once with Write.
+func IsBlockWritable(e Entry) bool {
+	return WriteAction().signature(e) == BlockWritableSignature
+}
+
+// OpenWriter opens a writer for the entry's data. The write is audited once the
+// writer's closed. During a dry run, nothing's written. Instead, closing the
+// writer adds what it would've written to the plan.
+func OpenWriter(ctx context.Context, w BlockWritable) (BlockWriter, error) {
+	record := startAuditRecord(ctx, w, WriteAction(), map[string]interface{}{"size": int64(0)})
+	if err := checkPolicy(ctx, w, WriteAction()); err != nil {
+		finishAuditRecord(record, nil, err)
+		return nil, err
+	}
+	if IsDryRun(ctx) {
+		return &auditedBlockWriter{ctx: ctx, record: record, id: w.eb().id}, nil
+	}
+	writer, err := w.OpenWriter(ctx)
+	if err != nil {
+		finishAuditRecord(record, nil, err)
+		return nil, err
+	}
+	return &auditedBlockWriter{BlockWriter: writer, ctx: ctx, record: record, id: w.eb().id}, nil
+}
+
 // Signal signals the entry with the specified signal
-func Signal(ctx context.Context, s Signalable, signal string) error {
+func Signal(ctx context.Context, s Signalable, signal string) (err error) {
+	record := startAuditRecord(ctx, s, SignalAction(), map[string]interface{}{"signal": signal})
+	defer func() { finishAuditRecord(record, nil, err) }()
+
+	if err = checkPolicy(ctx, s, SignalAction()); err != nil {
+		return err
+	}
+
 	// Signals are case-insensitive
 	signal = strings.ToLower(signal)
 
@@ -249,6 +353,15 @@ func Signal(ctx context.Context, s Signalable, signal string) error {
 		}
 	}
 
+	if IsDryRun(ctx) {
+		return planDryRun(ctx, record, func() ([]string, error) {
+			if planner, ok := s.(SignalPlanner); ok {
+				return planner.PlanSignal(ctx, signal)
+			}
+			return []string{fmt.Sprintf("send the %v signal to %v", signal, s.eb().id)}, nil
+		})
+	}
+
 	// Go ahead and send the signal
 	err = s.Signal(ctx, signal)
 	if err != nil {
@@ -263,6 +376,28 @@ func Signal(ctx context.Context, s Signalable, signal string) error {
 
 // Delete deletes the given entry.
 func Delete(ctx context.Context, d Deletable) (deleted bool, err error) {
+	record := startAuditRecord(ctx, d, DeleteAction(), nil)
+	defer func() {
+		var result interface{}
+		if err == nil && !record.DryRun {
+			result = map[string]interface{}{"deleted": deleted}
+		}
+		finishAuditRecord(record, result, err)
+	}()
+
+	if err = checkPolicy(ctx, d, DeleteAction()); err != nil {
+		return
+	}
+	if IsDryRun(ctx) {
+		err = planDryRun(ctx, record, func() ([]string, error) {
+			if planner, ok := d.(DeletePlanner); ok {
+				return planner.PlanDelete(ctx)
+			}
+			return []string{"delete " + d.eb().id}, nil
+		})
+		return
+	}
+
 	deleted, err = d.Delete(ctx)
 	if err != nil {
 		return
@@ -290,3 +425,85 @@ func Delete(ctx context.Context, d Deletable) (deleted bool, err error) {
 
 	return
 }
+
+func validateNewName(name string) error {
+	if name == "" {
+		return InvalidInputErr{"the new entry's name must be provided"}
+	}
+	if strings.Contains(name, "/") {
+		return InvalidInputErr{fmt.Sprintf("the new entry's name %v cannot contain a '/'", name)}
+	}
+	return nil
+}
+
+// Create creates a new child of the given parent. If isParent is true, then the
+// child will be a parent.
+func Create(ctx context.Context, c Creatable, name string, isParent bool) (entry Entry, err error) {
+	record := startAuditRecord(ctx, c, CreateAction(), map[string]interface{}{"name": name, "is_parent": isParent})
+	defer func() {
+		var result interface{}
+		if err == nil {
+			result = map[string]interface{}{"path": entry.eb().id}
+		}
+		finishAuditRecord(record, result, err)
+	}()
+
+	if err := validateNewName(name); err != nil {
+		return nil, err
+	}
+	if err := checkPolicy(ctx, c, CreateAction()); err != nil {
+		return nil, err
+	}
+
+	entry, err = c.Create(context.WithValue(ctx, parentID, c.eb().id), name, isParent)
+	if err != nil {
+		return nil, err
+	}
+	if isParent && !ListAction().IsSupportedOn(entry) {
+		return nil, fmt.Errorf("%v was created, but it is not a parent", name)
+	}
+	setChildID(c.eb().id, entry)
+	passAlongWrappedTypes(c, entry)
+
+	// The entry was created, so clear any stale cached data for it and clear the
+	// parent's cached list result so that the new entry's included.
+	ClearCacheFor(entry.eb().id, true)
+	return entry, nil
+}
+
+// Rename renames the given entry to newName and moves it into newParent.
+func Rename(ctx context.Context, r Renamable, newParent Parent, newName string) (entry Entry, err error) {
+	oldID := r.eb().id
+	newParentID := newParent.eb().id
+	record := startAuditRecord(ctx, r, RenameAction(), map[string]interface{}{"new_path": newParentID + "/" + newName})
+	defer func() { finishAuditRecord(record, nil, err) }()
+
+	if err := validateNewName(newName); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(newParentID+"/", oldID+"/") {
+		return nil, InvalidInputErr{fmt.Sprintf("cannot move %v into itself", oldID)}
+	}
+	if err := checkPolicy(ctx, r, RenameAction()); err != nil {
+		return nil, err
+	}
+	// Renaming creates the entry in newParent, so it's also subject to newParent's
+	// create policy.
+	if err := checkPolicy(ctx, newParent, CreateAction()); err != nil {
+		return nil, err
+	}
+
+	entry, err = r.Rename(context.WithValue(ctx, parentID, newParentID), newParent, newName)
+	if err != nil {
+		return nil, err
+	}
+	setChildID(newParentID, entry)
+	passAlongWrappedTypes(newParent, entry)
+
+	// The entry was renamed, so clear the cached data for both its old and new
+	// IDs. This also clears the old and new parents' cached list results so that
+	// the renamed entry's removed from the old parent and included in the new one.
+	ClearCacheFor(oldID, true)
+	ClearCacheFor(entry.eb().id, true)
+	return entry, nil
+}
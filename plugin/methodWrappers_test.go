@@ -88,6 +88,23 @@ func (m *methodWrappersTestsMockEntry) Write(ctx context.Context, p []byte) erro
 	return args.Error(0)
 }
 
+// methodWrappersTestsMockExecable's commands exit immediately with 0 unless
+// they have a timeout, in which case they never exit.
+type methodWrappersTestsMockExecable struct {
+	*methodWrappersTestsMockEntry
+}
+
+func (m *methodWrappersTestsMockExecable) Exec(ctx context.Context, cmd string, args []string, opts ExecOptions) (ExecCommand, error) {
+	if err := m.Called(ctx, cmd, args, opts).Error(0); err != nil {
+		return nil, err
+	}
+	execCmd := NewExecCommand(ctx)
+	if opts.Timeout == 0 {
+		execCmd.SetExitCode(0)
+	}
+	return execCmd, nil
+}
+
 func newMethodWrappersTestsMockEntry(name string) *methodWrappersTestsMockEntry {
 	e := &methodWrappersTestsMockEntry{
 		EntryBase: NewEntry(name),
@@ -296,6 +313,61 @@ func (suite *MethodWrappersTestSuite) TestWrite() {
 	writable.AssertExpectations(suite.T())
 }
 
+func (suite *MethodWrappersTestSuite) TestOpenWriter() {
+	ctx := context.Background()
+	writable := &methodWrappersTestsMockBlockWritableEntry{newMethodWrappersTestsMockEntry("/mock")}
+	suite.True(IsBlockWritable(writable))
+	suite.False(IsBlockWritable(newMethodWrappersTestsMockEntry("/mock")))
+
+	writer := &methodWrappersTestsMockBlockWriter{}
+	writable.On("OpenWriter", ctx).Return(writer, nil).Once()
+	writer.On("Truncate", ctx, int64(0)).Return(nil).Once()
+	writer.On("WriteAt", ctx, []byte("some"), int64(0)).Return(nil).Once()
+	writer.On("WriteAt", ctx, []byte("thing"), int64(4)).Return(nil).Once()
+	writer.On("Close", ctx).Return(nil).Once()
+
+	w, err := OpenWriter(ctx, writable)
+	if suite.NoError(err) {
+		suite.NoError(w.Truncate(ctx, 0))
+		suite.NoError(w.WriteAt(ctx, []byte("some"), 0))
+		suite.NoError(w.WriteAt(ctx, []byte("thing"), 4))
+		suite.NoError(w.Close(ctx))
+	}
+	writable.AssertExpectations(suite.T())
+	writer.AssertExpectations(suite.T())
+}
+
+func (suite *MethodWrappersTestSuite) TestExec_Timeout() {
+	e := &methodWrappersTestsMockExecable{newMethodWrappersTestsMockEntry("foo")}
+	var execCtx context.Context
+	e.On("Exec", mock.Anything, "sleep", []string{"10"}, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		execCtx = args.Get(0).(context.Context)
+	})
+
+	cmd, err := Exec(context.Background(), e, "sleep", []string{"10"}, ExecOptions{Timeout: 10 * time.Millisecond})
+	if suite.NoError(err) {
+		// The command never exits, so the exit code's only available once the
+		// timeout elapses.
+		_, err = cmd.ExitCode()
+		suite.EqualError(err, "the command timed out after 10ms")
+		suite.Equal(context.DeadlineExceeded, execCtx.Err())
+	}
+}
+
+func (suite *MethodWrappersTestSuite) TestExec_NoTimeout() {
+	e := &methodWrappersTestsMockExecable{newMethodWrappersTestsMockEntry("foo")}
+	ctx := context.Background()
+	e.On("Exec", ctx, "echo", []string{"hello"}, ExecOptions{}).Return(nil).Once()
+
+	cmd, err := Exec(ctx, e, "echo", []string{"hello"}, ExecOptions{})
+	if suite.NoError(err) {
+		exitCode, err := cmd.ExitCode()
+		suite.NoError(err)
+		suite.Equal(0, exitCode)
+		e.AssertExpectations(suite.T())
+	}
+}
+
 func (suite *MethodWrappersTestSuite) TestSignal_ReturnsSignalError() {
 	ctx := context.Background()
 	e := newMethodWrappersTestsMockEntry("foo")
@@ -443,6 +515,178 @@ func (suite *MethodWrappersTestSuite) TestDelete_DeletedEntry_NoCachedListResult
 	}
 }
 
+type methodWrappersTestsMockBlockWritableEntry struct {
+	*methodWrappersTestsMockEntry
+}
+
+func (m *methodWrappersTestsMockBlockWritableEntry) OpenWriter(ctx context.Context) (BlockWriter, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(BlockWriter), args.Error(1)
+}
+
+type methodWrappersTestsMockBlockWriter struct {
+	mock.Mock
+}
+
+func (m *methodWrappersTestsMockBlockWriter) WriteAt(ctx context.Context, p []byte, offset int64) error {
+	args := m.Called(ctx, p, offset)
+	return args.Error(0)
+}
+
+func (m *methodWrappersTestsMockBlockWriter) Truncate(ctx context.Context, size int64) error {
+	args := m.Called(ctx, size)
+	return args.Error(0)
+}
+
+func (m *methodWrappersTestsMockBlockWriter) Close(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+type methodWrappersTestsMockCreatableEntry struct {
+	*methodWrappersTestsMockEntry
+}
+
+func (m *methodWrappersTestsMockCreatableEntry) ChildSchemas() []*EntrySchema {
+	return nil
+}
+
+func (m *methodWrappersTestsMockCreatableEntry) Create(ctx context.Context, name string, isParent bool) (Entry, error) {
+	args := m.Called(ctx, name, isParent)
+	return args.Get(0).(Entry), args.Error(1)
+}
+
+func newMethodWrappersTestsMockCreatableEntry(name string) *methodWrappersTestsMockCreatableEntry {
+	return &methodWrappersTestsMockCreatableEntry{newMethodWrappersTestsMockEntry(name)}
+}
+
+func (suite *MethodWrappersTestSuite) TestCreate_InvalidName() {
+	e := newMethodWrappersTestsMockCreatableEntry("foo")
+
+	_, err := Create(context.Background(), e, "", false)
+	suite.True(IsInvalidInputErr(err))
+	suite.Regexp("name.*must.*provided", err)
+
+	_, err = Create(context.Background(), e, "bar/baz", false)
+	suite.True(IsInvalidInputErr(err))
+	suite.Regexp("bar/baz.*cannot.*contain", err)
+}
+
+func (suite *MethodWrappersTestSuite) TestCreate_ReturnsCreateError() {
+	e := newMethodWrappersTestsMockCreatableEntry("foo")
+
+	expectedErr := fmt.Errorf("an error")
+	e.On("Create", mock.Anything, "bar", false).Return((*methodWrappersTestsMockCreatableEntry)(nil), expectedErr)
+
+	_, err := Create(context.Background(), e, "bar", false)
+	suite.Equal(expectedErr, err)
+}
+
+func (suite *MethodWrappersTestSuite) TestCreate_CreatedNonParent_ReturnsError() {
+	e := newMethodWrappersTestsMockCreatableEntry("foo")
+	e.SetTestID("/foo")
+
+	child := &mockNonReadable{EntryBase: NewEntry("bar")}
+	e.On("Create", mock.Anything, "bar", true).Return(child, nil)
+
+	_, err := Create(context.Background(), e, "bar", true)
+	suite.Regexp("bar.*created.*not.*parent", err)
+}
+
+func (suite *MethodWrappersTestSuite) TestCreate_CreatesEntryAndUpdatesCache() {
+	e := newMethodWrappersTestsMockCreatableEntry("foo")
+	e.SetTestID("/foo")
+
+	child := newMethodWrappersTestsMockCreatableEntry("bar")
+	child.SetTestID("")
+	e.On("Create", mock.Anything, "bar", true).Return(child, nil)
+
+	suite.cache.On("Delete", allOpKeysIncludingChildrenRegex("/foo/bar")).Return([]string{})
+	suite.cache.On("Delete", opKeyRegex("List", "/foo")).Return([]string{})
+
+	created, err := Create(context.Background(), e, "bar", true)
+	if suite.NoError(err) {
+		suite.Equal(child, created)
+		suite.Equal("/foo/bar", ID(created))
+		e.AssertExpectations(suite.T())
+		suite.cache.AssertExpectations(suite.T())
+	}
+}
+
+type methodWrappersTestsMockRenamableEntry struct {
+	*methodWrappersTestsMockEntry
+}
+
+func (m *methodWrappersTestsMockRenamableEntry) Rename(ctx context.Context, newParent Parent, newName string) (Entry, error) {
+	args := m.Called(ctx, newParent, newName)
+	return args.Get(0).(Entry), args.Error(1)
+}
+
+func newMethodWrappersTestsMockRenamableEntry(name string) *methodWrappersTestsMockRenamableEntry {
+	return &methodWrappersTestsMockRenamableEntry{newMethodWrappersTestsMockEntry(name)}
+}
+
+func (suite *MethodWrappersTestSuite) TestRename_InvalidName() {
+	e := newMethodWrappersTestsMockRenamableEntry("foo")
+	e.SetTestID("/foo")
+	p := newMethodWrappersTestsMockCreatableEntry("bar")
+	p.SetTestID("/bar")
+
+	_, err := Rename(context.Background(), e, p, "")
+	suite.True(IsInvalidInputErr(err))
+
+	_, err = Rename(context.Background(), e, p, "baz/qux")
+	suite.True(IsInvalidInputErr(err))
+}
+
+func (suite *MethodWrappersTestSuite) TestRename_IntoItself() {
+	e := newMethodWrappersTestsMockRenamableEntry("foo")
+	e.SetTestID("/foo")
+	p := newMethodWrappersTestsMockCreatableEntry("bar")
+	p.SetTestID("/foo/bar")
+
+	_, err := Rename(context.Background(), e, p, "baz")
+	suite.True(IsInvalidInputErr(err))
+	suite.Regexp("into itself", err)
+}
+
+func (suite *MethodWrappersTestSuite) TestRename_ReturnsRenameError() {
+	e := newMethodWrappersTestsMockRenamableEntry("foo")
+	e.SetTestID("/foo")
+	p := newMethodWrappersTestsMockCreatableEntry("bar")
+	p.SetTestID("/bar")
+
+	expectedErr := fmt.Errorf("an error")
+	e.On("Rename", mock.Anything, p, "baz").Return((*methodWrappersTestsMockRenamableEntry)(nil), expectedErr)
+
+	_, err := Rename(context.Background(), e, p, "baz")
+	suite.Equal(expectedErr, err)
+}
+
+func (suite *MethodWrappersTestSuite) TestRename_RenamesEntryAndUpdatesCache() {
+	e := newMethodWrappersTestsMockRenamableEntry("foo")
+	e.SetTestID("/a/foo")
+	p := newMethodWrappersTestsMockCreatableEntry("bar")
+	p.SetTestID("/b/bar")
+
+	renamed := newMethodWrappersTestsMockRenamableEntry("baz")
+	renamed.SetTestID("")
+	e.On("Rename", mock.Anything, p, "baz").Return(renamed, nil)
+
+	suite.cache.On("Delete", allOpKeysIncludingChildrenRegex("/a/foo")).Return([]string{})
+	suite.cache.On("Delete", opKeyRegex("List", "/a")).Return([]string{})
+	suite.cache.On("Delete", allOpKeysIncludingChildrenRegex("/b/bar/baz")).Return([]string{})
+	suite.cache.On("Delete", opKeyRegex("List", "/b/bar")).Return([]string{})
+
+	entry, err := Rename(context.Background(), e, p, "baz")
+	if suite.NoError(err) {
+		suite.Equal(renamed, entry)
+		suite.Equal("/b/bar/baz", ID(entry))
+		e.AssertExpectations(suite.T())
+		suite.cache.AssertExpectations(suite.T())
+	}
+}
+
 func TestMethodWrappers(t *testing.T) {
 	suite.Run(t, new(MethodWrappersTestSuite))
 }
@@ -0,0 +1,292 @@
+package plugin
+
+import (
+	"context"
+	"regexp"
+	"sync"
+)
+
+// maxCachedPagedListSize is the most children that a paged listing caches. Larger
+// listings aren't cached, so they're re-listed by each ListPages call. That way,
+// listing an S3 bucket with millions of keys doesn't keep them all in memory.
+var maxCachedPagedListSize = 10000
+
+// pagedListings contains the in-progress paged listings, keyed by their parent's
+// ID. Concurrent ListPages calls on the same parent share its listing.
+var pagedListings = struct {
+	mux sync.Mutex
+	mp  map[string]*pagedListing
+}{mp: make(map[string]*pagedListing)}
+
+// pagedListing lists a PagedParent's children one page at a time on behalf of all of
+// its readers. Whichever reader needs the next page lists it, so the listing continues
+// if the reader that started it stops. Pages are dropped once every reader has read
+// them if the listing's too large to cache.
+type pagedListing struct {
+	p   PagedParent
+	mux sync.Mutex
+	// pages[i] is page number base+i.
+	pages []*EntryMap
+	base  int
+	// readers contains each reader's next page number.
+	readers map[*int]struct{}
+	// entries contains all of the listed children. It's nil once there are too many of
+	// them to cache.
+	entries *EntryMap
+	// cnames contains the listed children's cnames so that duplicates are detected
+	// across pages.
+	cnames    map[string]cnameOwner
+	nextToken string
+	listing   bool
+	done      bool
+	err       error
+	// invalidated is set if the parent's cache is cleared while it's being listed,
+	// in which case the result isn't cached.
+	invalidated bool
+	// changed is closed when a page is listed, or when the listing finishes or is
+	// given up by the reader that's listing the next page.
+	changed chan struct{}
+}
+
+// cnameOwner identifies the child that has a cname.
+type cnameOwner struct {
+	name          string
+	slashReplacer rune
+}
+
+// joinPagedListing returns p's in-progress listing, or starts a new one. It returns
+// nil if p's children are already cached.
+func joinPagedListing(p PagedParent) (*pagedListing, *int) {
+	id := p.eb().id
+	pagedListings.mux.Lock()
+	defer pagedListings.mux.Unlock()
+
+	l := pagedListings.mp[id]
+	if l != nil {
+		l.mux.Lock()
+		if l.base == 0 && !l.done {
+			next := new(int)
+			l.readers[next] = struct{}{}
+			l.mux.Unlock()
+			return l, next
+		}
+		// The listing's finished, or its first pages were dropped, so it can't be
+		// read from the start.
+		l.mux.Unlock()
+	}
+
+	// A listing's cached before it finishes, so checking the cache here means that
+	// a finished listing's result is never missed.
+	if cached, err := cache.Get(defaultOpCodeToNameMap[ListOp], id); cached != nil || err != nil {
+		return nil, nil
+	}
+	l = &pagedListing{
+		p:       p,
+		readers: make(map[*int]struct{}),
+		entries: newEntryMap(),
+		cnames:  make(map[string]cnameOwner),
+		changed: make(chan struct{}),
+	}
+	next := new(int)
+	l.readers[next] = struct{}{}
+	pagedListings.mp[id] = l
+	return l, next
+}
+
+// unshare removes l from pagedListings so that subsequent ListPages calls start a new
+// listing.
+func (l *pagedListing) unshare() {
+	pagedListings.mux.Lock()
+	defer pagedListings.mux.Unlock()
+	if pagedListings.mp[l.p.eb().id] == l {
+		delete(pagedListings.mp, l.p.eb().id)
+	}
+}
+
+// invalidatePagedListings stops sharing the in-progress listings whose cache keys match
+// rx, and stops them from being cached.
+func invalidatePagedListings(rx *regexp.Regexp) {
+	listOpName := defaultOpCodeToNameMap[ListOp]
+	pagedListings.mux.Lock()
+	defer pagedListings.mux.Unlock()
+	for id, l := range pagedListings.mp {
+		if rx.MatchString(listOpName + "::" + id) {
+			l.mux.Lock()
+			l.invalidated = true
+			l.mux.Unlock()
+			delete(pagedListings.mp, id)
+		}
+	}
+}
+
+// read invokes onPage on each of the listing's pages, starting at the page that the
+// reader reads next.
+func (l *pagedListing) read(ctx context.Context, next *int, onPage func(*EntryMap) error) error {
+	defer l.leave(next)
+	for {
+		l.mux.Lock()
+		if *next < l.base+len(l.pages) {
+			page := l.pages[*next-l.base]
+			*next++
+			l.dropReadPages()
+			l.mux.Unlock()
+			if err := onPage(page); err != nil {
+				return err
+			}
+			continue
+		}
+		if l.done {
+			err := l.err
+			l.mux.Unlock()
+			return err
+		}
+		if !l.listing {
+			l.listing = true
+			token := l.nextToken
+			l.mux.Unlock()
+			if err := l.listPage(ctx, token); err != nil {
+				return err
+			}
+			continue
+		}
+		changed := l.changed
+		l.mux.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// leave stops tracking the reader so that the pages it hasn't read can be dropped.
+// The listing's abandoned once it has no readers.
+func (l *pagedListing) leave(next *int) {
+	pagedListings.mux.Lock()
+	defer pagedListings.mux.Unlock()
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	delete(l.readers, next)
+	if len(l.readers) == 0 {
+		if pagedListings.mp[l.p.eb().id] == l {
+			delete(pagedListings.mp, l.p.eb().id)
+		}
+		return
+	}
+	l.dropReadPages()
+}
+
+// dropReadPages drops the pages that every reader has read if the listing's too large
+// to cache. l.mux must be held.
+func (l *pagedListing) dropReadPages() {
+	if l.entries != nil {
+		return
+	}
+	minNext := l.base + len(l.pages)
+	for next := range l.readers {
+		if *next < minNext {
+			minNext = *next
+		}
+	}
+	if minNext == l.base {
+		return
+	}
+	l.pages = append([]*EntryMap(nil), l.pages[minNext-l.base:]...)
+	l.base = minNext
+	// Subsequent ListPages calls can't read the dropped pages, so they'll need
+	// to start a new listing. pagedListings.mux is locked before l.mux, so it's
+	// unshared in the background.
+	go l.unshare()
+}
+
+// listPage lists the page identified by token. If listing the page fails because ctx
+// was cancelled, then another reader lists it instead. Otherwise, the listing finishes
+// with the error.
+func (l *pagedListing) listPage(ctx context.Context, token string) error {
+	ctx = context.WithValue(ctx, parentID, l.p.eb().id)
+	entries, nextToken, err := l.p.ListPage(ctx, token)
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	defer l.notify()
+	l.listing = false
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		l.finish(err)
+		return nil
+	}
+
+	page := newEntryMap()
+	for _, entry := range entries {
+		if err := l.addChild(page, entry); err != nil {
+			l.finish(err)
+			return nil
+		}
+	}
+	l.pages = append(l.pages, page)
+	if l.entries != nil && l.entries.Len() > maxCachedPagedListSize {
+		l.entries = nil
+		l.dropReadPages()
+	}
+
+	l.nextToken = nextToken
+	if nextToken == "" {
+		if l.entries != nil && !l.invalidated {
+			// Cache the entire listing so that subsequent operations (like finding
+			// one of p's children) do not have to re-list p.
+			entries := l.entries
+			entries.parent, _ = l.p.(PersistentParent)
+			if _, err := cachedDefaultOp(ctx, ListOp, l.p, func(context.Context) (interface{}, error) {
+				return entries, nil
+			}); err != nil {
+				l.finish(err)
+				return nil
+			}
+		}
+		l.finish(nil)
+	}
+	return nil
+}
+
+// addChild adds the listed child to page. Like the addChild function, it skips
+// inaccessible children and returns a DuplicateCNameErr if a child with the same
+// cname was already listed. l.mux must be held.
+func (l *pagedListing) addChild(page *EntryMap, entry Entry) error {
+	cname := CName(entry)
+	if owner, ok := l.cnames[cname]; ok {
+		return DuplicateCNameErr{
+			ParentID:                 l.p.eb().id,
+			FirstChildName:           owner.name,
+			FirstChildSlashReplacer:  owner.slashReplacer,
+			SecondChildName:          entry.eb().name,
+			SecondChildSlashReplacer: entry.eb().slashReplacer,
+			CName:                    cname,
+		}
+	}
+	added, err := addChild(l.p, page, entry)
+	if err != nil || !added {
+		return err
+	}
+
+	l.cnames[cname] = cnameOwner{name: entry.eb().name, slashReplacer: entry.eb().slashReplacer}
+	if l.entries != nil {
+		l.entries.mp[cname] = entry
+	}
+	return nil
+}
+
+// finish finishes the listing with err. l.mux must be held.
+func (l *pagedListing) finish(err error) {
+	l.done = true
+	l.err = err
+	go l.unshare()
+}
+
+// notify wakes up the readers that are waiting for the listing to change. l.mux must
+// be held.
+func (l *pagedListing) notify() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
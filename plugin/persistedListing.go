@@ -0,0 +1,157 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+func init() {
+	// Register listings so that a disk-backed cache can persist them.
+	gob.Register(&EntryMap{})
+}
+
+// PersistedEntry is the persisted form of a PersistentParent's child.
+type PersistedEntry struct {
+	Name            string
+	SlashReplacer   rune
+	Attributes      EntryAttributes
+	PartialMetadata JSONObject
+	// State is the state that the parent's PersistChild method returned.
+	State []byte
+}
+
+// DecodePartialMetadata decodes the child's partial metadata into v. It's useful
+// when the partial metadata is the object that the child was created from.
+func (e PersistedEntry) DecodePartialMetadata(v interface{}) error {
+	data, err := json.Marshal(e.PartialMetadata)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// persistedChild is the gob-encoded form of a PersistedEntry. EntryAttributes'
+// fields are unexported, so the attributes and partial metadata are encoded as
+// JSON.
+type persistedChild struct {
+	Name            string
+	SlashReplacer   rune
+	Attributes      []byte
+	PartialMetadata []byte
+	State           []byte
+}
+
+// GobEncode encodes the listing's children so that a disk-backed cache can persist
+// it. It returns an error if the listing's parent isn't a PersistentParent.
+func (m *EntryMap) GobEncode() ([]byte, error) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+
+	children := m.persisted
+	if !m.loaded {
+		if m.parent == nil {
+			return nil, fmt.Errorf("the listing's parent does not implement PersistentParent")
+		}
+		children = make([]persistedChild, 0, len(m.mp))
+		for _, entry := range m.mp {
+			child, err := persistChild(m.parent, entry)
+			if err != nil {
+				return nil, fmt.Errorf("could not persist %v: %v", entry.eb().id, err)
+			}
+			children = append(children, child)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(children); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func persistChild(p PersistentParent, entry Entry) (persistedChild, error) {
+	state, err := p.PersistChild(entry)
+	if err != nil {
+		return persistedChild{}, err
+	}
+	child := persistedChild{
+		Name:          entry.eb().name,
+		SlashReplacer: entry.eb().slashReplacer,
+		State:         state,
+	}
+	if child.Attributes, err = json.Marshal(entry.eb().attributes); err != nil {
+		return persistedChild{}, err
+	}
+	if child.PartialMetadata, err = json.Marshal(entry.eb().specifiedPartialMetadata); err != nil {
+		return persistedChild{}, err
+	}
+	return child, nil
+}
+
+// GobDecode decodes a persisted listing. Its children are recreated by restore.
+func (m *EntryMap) GobDecode(data []byte) error {
+	var children []persistedChild
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&children); err != nil {
+		return err
+	}
+	m.mp = make(map[string]Entry)
+	m.persisted = children
+	m.loaded = true
+	return nil
+}
+
+// restore recreates the children of a listing that was loaded from a persisted cache
+// with p's RestoreChild method. It does nothing if the listing wasn't loaded from a
+// persisted cache.
+func (m *EntryMap) restore(ctx context.Context, p Parent) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if !m.loaded {
+		return m.restoreErr
+	}
+	m.loaded = false
+	m.persisted, m.restoreErr = nil, m.restoreChildren(ctx, p)
+	return m.restoreErr
+}
+
+// restoreChildren restores m.persisted. m.mux must be locked.
+func (m *EntryMap) restoreChildren(ctx context.Context, p Parent) error {
+	pp, ok := p.(PersistentParent)
+	if !ok {
+		return fmt.Errorf("the parent does not implement PersistentParent")
+	}
+	ctx = context.WithValue(ctx, parentID, p.eb().id)
+	for _, child := range m.persisted {
+		if err := restoreChild(ctx, pp, m, child); err != nil {
+			m.mp = make(map[string]Entry)
+			return fmt.Errorf("could not restore %v: %v", child.Name, err)
+		}
+	}
+	m.parent = pp
+	return nil
+}
+
+// restoreChild restores the persisted child, then adds it to entries. entries must
+// be locked.
+func restoreChild(ctx context.Context, p PersistentParent, entries *EntryMap, child persistedChild) error {
+	persisted := PersistedEntry{
+		Name:          child.Name,
+		SlashReplacer: child.SlashReplacer,
+		State:         child.State,
+	}
+	if err := json.Unmarshal(child.Attributes, &persisted.Attributes); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(child.PartialMetadata, &persisted.PartialMetadata); err != nil {
+		return err
+	}
+	entry, err := p.RestoreChild(ctx, persisted)
+	if err != nil {
+		return err
+	}
+	_, err = addChild(p, entries, entry)
+	return err
+}
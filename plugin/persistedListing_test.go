@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/stretchr/testify/suite"
+)
+
+type persistentMockParent struct {
+	mockParent
+	listed     int
+	restoreErr error
+}
+
+func (p *persistentMockParent) List(ctx context.Context) ([]Entry, error) {
+	p.listed++
+	return p.mockParent.List(ctx)
+}
+
+func (p *persistentMockParent) PersistChild(child Entry) ([]byte, error) {
+	return []byte("state of " + Name(child)), nil
+}
+
+func (p *persistentMockParent) RestoreChild(ctx context.Context, child PersistedEntry) (Entry, error) {
+	if p.restoreErr != nil {
+		return nil, p.restoreErr
+	}
+	if string(child.State) != "state of "+child.Name {
+		return nil, fmt.Errorf("unexpected state %v", string(child.State))
+	}
+	entry := newMockEntry(child.Name)
+	entry.SetAttributes(child.Attributes)
+	entry.SetPartialMetadata(child.PartialMetadata)
+	entry.SetSlashReplacer(child.SlashReplacer)
+	return entry, nil
+}
+
+type PersistedListingTestSuite struct {
+	suite.Suite
+	path string
+}
+
+func (suite *PersistedListingTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "wash_persisted_listing")
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	suite.path = filepath.Join(dir, "cache")
+}
+
+func (suite *PersistedListingTestSuite) TearDownTest() {
+	if cache != nil {
+		UnsetTestCache()
+	}
+	os.RemoveAll(filepath.Dir(suite.path))
+}
+
+// listAndRestart lists p with a new disk cache, then saves it so that the next disk cache
+// loads the listing.
+func (suite *PersistedListingTestSuite) listAndRestart(p Parent) {
+	if cache != nil {
+		suite.NoError(cache.(*datastore.DiskCache).Close())
+		UnsetTestCache()
+	}
+	diskCache, err := datastore.NewDiskCache(suite.path, 0)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	SetTestCache(diskCache)
+	_, err = List(context.Background(), p)
+	suite.NoError(err)
+	suite.NoError(diskCache.Close())
+	UnsetTestCache()
+
+	if diskCache, err = datastore.NewDiskCache(suite.path, 0); err != nil {
+		suite.FailNow(err.Error())
+	}
+	SetTestCache(diskCache)
+}
+
+func (suite *PersistedListingTestSuite) newParent() *persistentMockParent {
+	child := newMockEntry("foo/bar")
+	child.SetSlashReplacer(':')
+	child.Attributes().SetSize(3).SetMtime(time.Unix(1000, 0))
+	child.SetPartialMetadata(map[string]interface{}{"key": "value"})
+
+	p := &persistentMockParent{mockParent: mockParent{NewEntry("root"), []Entry{child}}}
+	p.SetTestID("/root")
+	return p
+}
+
+func (suite *PersistedListingTestSuite) TestRestoresPersistedListing() {
+	p := suite.newParent()
+	suite.listAndRestart(p)
+
+	entries, err := List(context.Background(), p)
+	if !suite.NoError(err) {
+		return
+	}
+	suite.Equal(1, p.listed)
+	child, ok := entries.Load("foo:bar")
+	if suite.True(ok) {
+		suite.Equal("foo/bar", Name(child))
+		suite.Equal("/root/foo:bar", ID(child))
+		suite.Equal(uint64(3), child.eb().attributes.Size())
+		suite.True(time.Unix(1000, 0).Equal(child.eb().attributes.Mtime()))
+		suite.Equal(JSONObject{"key": "value"}, child.eb().specifiedPartialMetadata)
+	}
+
+	// The restored listing's persisted again.
+	suite.listAndRestart(p)
+	_, err = List(context.Background(), p)
+	suite.NoError(err)
+	suite.Equal(1, p.listed)
+}
+
+func (suite *PersistedListingTestSuite) TestRelistsIfRestoreFails() {
+	p := suite.newParent()
+	suite.listAndRestart(p)
+
+	p.restoreErr = fmt.Errorf("failed")
+	entries, err := List(context.Background(), p)
+	if suite.NoError(err) {
+		suite.Equal(2, p.listed)
+		suite.Equal(1, entries.Len())
+	}
+}
+
+func (suite *PersistedListingTestSuite) TestDoesNotPersistOtherListings() {
+	p := &mockParent{NewEntry("root"), []Entry{newMockEntry("foo")}}
+	p.SetTestID("/root")
+	suite.listAndRestart(p)
+
+	items := cache.(*datastore.DiskCache).Items(opKeyRegex("List", "/root"))
+	suite.Empty(items)
+}
+
+func TestPersistedListing(t *testing.T) {
+	suite.Run(t, new(PersistedListingTestSuite))
+}
@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gobwas/glob"
+	"github.com/puppetlabs/wash/activity"
+)
+
+// Policy controls which of the mutating actions (exec, write, signal, delete,
+// create, and rename) can be invoked on which entries. It's enforced by the
+// plugin.<Method> wrappers, so it applies to every Wash interface.
+type Policy struct {
+	// ReadOnly denies all of the mutating actions regardless of the rules.
+	ReadOnly bool `mapstructure:"read-only"`
+	// Rules are checked in order. The first rule that matches the entry and
+	// action decides whether the action's allowed. If none of the rules match,
+	// then the action's allowed.
+	Rules []PolicyRule `mapstructure:"rules"`
+}
+
+// PolicyRule allows or denies actions on the entries that it matches. A rule
+// matches an entry if the entry matches all of its specified Path, TypeID, and
+// Plugin fields. A rule without any of them matches every entry.
+type PolicyRule struct {
+	// Effect is either "allow" or "deny".
+	Effect string `mapstructure:"effect"`
+	// Actions are the names of the actions that the rule applies to. An empty
+	// list applies to all of the mutating actions.
+	Actions []string `mapstructure:"actions"`
+	// Path is a glob that's matched against the entry's path, e.g.
+	// "/docker/containers/*". A '*' does not match a '/' but a '**' does. Note
+	// that create's matched against the parent's path.
+	Path string `mapstructure:"path"`
+	// TypeID is the entry's type ID, e.g. "docker::container".
+	TypeID string `mapstructure:"type_id"`
+	// Plugin is the name of the entry's plugin.
+	Plugin string `mapstructure:"plugin"`
+
+	pathGlob glob.Glob
+}
+
+const (
+	allowEffect = "allow"
+	denyEffect  = "deny"
+)
+
+var mutatingActions = map[string]bool{
+	execAction.Name:   true,
+	writeAction.Name:  true,
+	signalAction.Name: true,
+	deleteAction.Name: true,
+	createAction.Name: true,
+	renameAction.Name: true,
+}
+
+var policy Policy
+var policyMux sync.RWMutex
+
+// SetPolicy sets the policy that's enforced on the mutating actions. It returns
+// an error if any of p's rules are invalid, in which case the current policy's
+// unchanged.
+func SetPolicy(p Policy) error {
+	rules := make([]PolicyRule, len(p.Rules))
+	for i, rule := range p.Rules {
+		if rule.Effect != allowEffect && rule.Effect != denyEffect {
+			return fmt.Errorf("policy rule %v: effect must be %v or %v, got %q", i+1, allowEffect, denyEffect, rule.Effect)
+		}
+		for _, action := range rule.Actions {
+			if !mutatingActions[action] {
+				return fmt.Errorf("policy rule %v: %q is not one of the exec, write, signal, delete, create, or rename actions", i+1, action)
+			}
+		}
+		if rule.Path != "" {
+			g, err := glob.Compile(rule.Path, '/')
+			if err != nil {
+				return fmt.Errorf("policy rule %v: invalid path %q: %v", i+1, rule.Path, err)
+			}
+			rule.pathGlob = g
+		}
+		rules[i] = rule
+	}
+	p.Rules = rules
+
+	policyMux.Lock()
+	defer policyMux.Unlock()
+	policy = p
+	return nil
+}
+
+// PolicyDeniedErr indicates that the policy denied an action.
+type PolicyDeniedErr struct {
+	Action string
+	Path   string
+	reason string
+}
+
+func (e PolicyDeniedErr) Error() string {
+	return fmt.Sprintf("the %v action on %v was denied by %v", e.Action, e.Path, e.reason)
+}
+
+// IsPolicyDeniedErr returns true if err is a PolicyDeniedErr error object
+func IsPolicyDeniedErr(err error) bool {
+	_, ok := err.(PolicyDeniedErr)
+	return ok
+}
+
+// checkPolicy returns a PolicyDeniedErr if the policy denies the action on e.
+// Denials are recorded to ctx's activity journal.
+func checkPolicy(ctx context.Context, e Entry, a Action) error {
+	policyMux.RLock()
+	defer policyMux.RUnlock()
+
+	reason := ""
+	if policy.ReadOnly {
+		reason = "read-only mode"
+	} else {
+		for i, rule := range policy.Rules {
+			if rule.matches(e, a) {
+				if rule.Effect == denyEffect {
+					reason = fmt.Sprintf("policy rule %v", i+1)
+				}
+				break
+			}
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+
+	err := PolicyDeniedErr{Action: a.Name, Path: e.eb().id, reason: reason}
+	activity.Warnf(ctx, "Policy: %v", err)
+	return err
+}
+
+func (r PolicyRule) matches(e Entry, a Action) bool {
+	if len(r.Actions) > 0 {
+		found := false
+		for _, action := range r.Actions {
+			if action == a.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.pathGlob != nil && !r.pathGlob.Match(e.eb().id) {
+		return false
+	}
+	if r.TypeID != "" && r.TypeID != TypeID(e) {
+		return false
+	}
+	if r.Plugin != "" && r.Plugin != pluginName(e) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PolicyTestSuite struct {
+	suite.Suite
+}
+
+func (suite *PolicyTestSuite) TearDownTest() {
+	suite.NoError(SetPolicy(Policy{}))
+}
+
+func (suite *PolicyTestSuite) newEntry(id string) *methodWrappersTestsMockEntry {
+	e := newMethodWrappersTestsMockEntry("foo")
+	e.SetTestID(id)
+	return e
+}
+
+func (suite *PolicyTestSuite) TestSetPolicy_ValidatesRules() {
+	suite.Regexp("effect must be allow or deny", SetPolicy(Policy{Rules: []PolicyRule{{Effect: "maybe"}}}))
+	suite.Regexp(`"read" is not one of`, SetPolicy(Policy{Rules: []PolicyRule{{Effect: "deny", Actions: []string{"read"}}}}))
+	suite.Regexp("invalid path", SetPolicy(Policy{Rules: []PolicyRule{{Effect: "deny", Path: "/foo/["}}}))
+}
+
+func (suite *PolicyTestSuite) TestCheckPolicy_AllowsByDefault() {
+	suite.NoError(checkPolicy(context.Background(), suite.newEntry("/docker/containers/foo"), DeleteAction()))
+}
+
+func (suite *PolicyTestSuite) TestCheckPolicy_ReadOnlyDeniesEverything() {
+	suite.NoError(SetPolicy(Policy{
+		ReadOnly: true,
+		Rules:    []PolicyRule{{Effect: "allow"}},
+	}))
+	e := suite.newEntry("/docker/containers/foo")
+	for _, action := range []Action{ExecAction(), WriteAction(), SignalAction(), DeleteAction(), CreateAction(), RenameAction()} {
+		err := checkPolicy(context.Background(), e, action)
+		if suite.True(IsPolicyDeniedErr(err), action.Name) {
+			suite.Regexp("denied by read-only mode", err)
+		}
+	}
+}
+
+func (suite *PolicyTestSuite) TestCheckPolicy_FirstMatchingRuleWins() {
+	suite.NoError(SetPolicy(Policy{
+		Rules: []PolicyRule{
+			{Effect: "allow", Actions: []string{"signal"}, Path: "/docker/containers/*"},
+			{Effect: "deny", Path: "/docker/**"},
+		},
+	}))
+	ctx := context.Background()
+
+	container := suite.newEntry("/docker/containers/foo")
+	suite.NoError(checkPolicy(ctx, container, SignalAction()))
+	err := checkPolicy(ctx, container, DeleteAction())
+	if suite.True(IsPolicyDeniedErr(err)) {
+		suite.EqualError(err, "the delete action on /docker/containers/foo was denied by policy rule 2")
+	}
+
+	// '*' doesn't match a '/'
+	suite.Error(checkPolicy(ctx, suite.newEntry("/docker/containers/foo/fs"), SignalAction()))
+	suite.NoError(checkPolicy(ctx, suite.newEntry("/aws/foo"), DeleteAction()))
+}
+
+func (suite *PolicyTestSuite) TestCheckPolicy_MatchesPluginAndTypeID() {
+	e := suite.newEntry("/docker/containers/foo")
+	suite.NoError(SetPolicy(Policy{
+		Rules: []PolicyRule{
+			{Effect: "deny", Actions: []string{"exec"}, Plugin: "docker"},
+			{Effect: "deny", Actions: []string{"delete"}, TypeID: TypeID(e)},
+			{Effect: "deny", Actions: []string{"signal"}, TypeID: "docker::other"},
+		},
+	}))
+	ctx := context.Background()
+
+	suite.Error(checkPolicy(ctx, e, ExecAction()))
+	suite.NoError(checkPolicy(ctx, suite.newEntry("/aws/foo"), ExecAction()))
+	suite.Error(checkPolicy(ctx, e, DeleteAction()))
+	suite.NoError(checkPolicy(ctx, e, SignalAction()))
+}
+
+func (suite *PolicyTestSuite) TestDelete_DeniedByPolicy() {
+	suite.NoError(SetPolicy(Policy{ReadOnly: true}))
+	e := suite.newEntry("/docker/containers/foo")
+
+	deleted, err := Delete(context.Background(), e)
+	suite.False(deleted)
+	suite.True(IsPolicyDeniedErr(err))
+	// The entry's Delete method shouldn't have been called
+	e.AssertNotCalled(suite.T(), "Delete")
+}
+
+func (suite *PolicyTestSuite) TestSignal_DeniedByPolicy() {
+	suite.NoError(SetPolicy(Policy{ReadOnly: true}))
+	e := suite.newEntry("/docker/containers/foo")
+
+	err := Signal(context.Background(), e, "start")
+	suite.True(IsPolicyDeniedErr(err))
+	e.AssertNotCalled(suite.T(), "Signal")
+}
+
+func (suite *PolicyTestSuite) TestRename_DeniedByNewParentsCreatePolicy() {
+	suite.NoError(SetPolicy(Policy{
+		Rules: []PolicyRule{{Effect: "deny", Actions: []string{"create"}, Path: "/b"}},
+	}))
+	e := newMethodWrappersTestsMockRenamableEntry("foo")
+	e.SetTestID("/a/foo")
+	p := newMethodWrappersTestsMockCreatableEntry("b")
+	p.SetTestID("/b")
+
+	_, err := Rename(context.Background(), e, p, "foo")
+	if suite.True(IsPolicyDeniedErr(err)) {
+		suite.Regexp("create", err)
+	}
+	e.AssertNotCalled(suite.T(), "Rename")
+}
+
+func TestPolicy(t *testing.T) {
+	suite.Run(t, new(PolicyTestSuite))
+}
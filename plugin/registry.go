@@ -3,16 +3,26 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"io"
 	"regexp"
+	"sort"
 	"sync"
+
+	log "github.com/sirupsen/logrus"
 )
 
+// PluginLoader returns the currently configured plugins and their config,
+// keyed by plugin name. The registry uses it to reload plugins, so it should
+// re-read Wash's config and return new (uninitialized) plugin roots.
+type PluginLoader func() (map[string]Root, map[string]map[string]interface{}, error)
+
 // Registry represents the plugin registry. It is also Wash's root.
 type Registry struct {
 	EntryBase
 	mux         sync.Mutex
 	plugins     map[string]Root
 	pluginRoots []Entry
+	loader      PluginLoader
 }
 
 // NewRegistry creates a new plugin registry object
@@ -27,10 +37,22 @@ func NewRegistry() *Registry {
 	return r
 }
 
-// Plugins returns a map of the currently registered plugins. It should not be called
-// while registering plugins.
+// Plugins returns a map of the currently registered plugins.
 func (r *Registry) Plugins() map[string]Root {
-	return r.plugins
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	plugins := make(map[string]Root, len(r.plugins))
+	for name, root := range r.plugins {
+		plugins[name] = root
+	}
+	return plugins
+}
+
+// SetPluginLoader sets the loader that's used by ReloadPlugins.
+func (r *Registry) SetPluginLoader(loader PluginLoader) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.loader = loader
 }
 
 var pluginNameRegex = regexp.MustCompile("^[0-9a-zA-Z_-]+$")
@@ -38,15 +60,90 @@ var pluginNameRegex = regexp.MustCompile("^[0-9a-zA-Z_-]+$")
 // RegisterPlugin initializes the given plugin and adds it to the registry if
 // initialization was successful.
 func (r *Registry) RegisterPlugin(root Root, config map[string]interface{}) error {
+	return r.initPlugin(root, config, false)
+}
+
+// ReloadPlugin initializes the given plugin, then replaces the registered plugin
+// of the same name with it and clears that plugin's cached results. The replaced
+// plugin's closed if it implements io.Closer. The other plugins are left untouched.
+// The plugin's registered if it wasn't already. Like RegisterPlugin, a plugin that
+// fails to initialize is replaced with a stub root.
+func (r *Registry) ReloadPlugin(root Root, config map[string]interface{}) error {
+	err := r.initPlugin(root, config, true)
+	// A core plugin's root is only named once it's initialized. Check the name
+	// so that a failed Init doesn't clear the entire cache.
+	if name := root.eb().name; name != "" {
+		ClearCacheFor("/"+name, true)
+	}
+	return err
+}
+
+// UnregisterPlugin removes the named plugin from the registry and clears its
+// cached results. The plugin's closed if it implements io.Closer.
+func (r *Registry) UnregisterPlugin(name string) error {
+	r.mux.Lock()
+	root, ok := r.plugins[name]
+	if !ok {
+		r.mux.Unlock()
+		return UnknownPluginErr{Name: name}
+	}
+	delete(r.plugins, name)
+	for i, root := range r.pluginRoots {
+		if root.eb().name == name {
+			r.pluginRoots = append(r.pluginRoots[:i:i], r.pluginRoots[i+1:]...)
+			break
+		}
+	}
+	r.mux.Unlock()
+
+	closePlugin(root)
+	ClearCacheFor("/"+name, true)
+	return nil
+}
+
+// ClosePlugins closes the registered plugins that implement io.Closer. Call it
+// when Wash shuts down.
+func (r *Registry) ClosePlugins() {
+	var wg sync.WaitGroup
+	for _, root := range r.Plugins() {
+		wg.Add(1)
+		go func(root Root) {
+			defer wg.Done()
+			closePlugin(root)
+		}(root)
+	}
+	wg.Wait()
+}
+
+func closePlugin(root Root) {
+	if closer, ok := root.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Warnf("Failed to close the %v plugin: %v", root.eb().name, err)
+		}
+	}
+}
+
+func (r *Registry) initPlugin(root Root, config map[string]interface{}, replace bool) error {
+	var replaced Root
+	defer func() {
+		// Close the replaced plugin after it's unregistered so that it's no longer
+		// used.
+		if replaced != nil && replaced != root {
+			closePlugin(replaced)
+		}
+	}()
+
 	registerPlugin := func(initSucceeded bool) {
 		r.mux.Lock()
+		defer r.mux.Unlock()
+		oldRoot, registered := r.plugins[root.eb().name]
 		if initSucceeded {
 			if !pluginNameRegex.MatchString(root.eb().name) {
 				msg := fmt.Sprintf("r.RegisterPlugin: invalid plugin name %v. The plugin name must consist of alphanumeric characters, or a hyphen", root.eb().name)
 				panic(msg)
 			}
 
-			if _, ok := r.plugins[root.eb().name]; ok {
+			if registered && !replace {
 				msg := fmt.Sprintf("r.RegisterPlugin: the %v plugin's already been registered", root.eb().name)
 				panic(msg)
 			}
@@ -57,9 +154,21 @@ func (r *Registry) RegisterPlugin(root Root, config map[string]interface{}) erro
 			}
 		}
 
+		if registered {
+			replaced = oldRoot
+			for i, pluginRoot := range r.pluginRoots {
+				if pluginRoot.eb().name == root.eb().name {
+					// Copy pluginRoots so that it's safe to modify while
+					// previous List results are in use.
+					r.pluginRoots = append([]Entry{}, r.pluginRoots...)
+					r.pluginRoots[i] = root
+					break
+				}
+			}
+		} else {
+			r.pluginRoots = append(r.pluginRoots, root)
+		}
 		r.plugins[root.eb().name] = root
-		r.pluginRoots = append(r.pluginRoots, root)
-		r.mux.Unlock()
 	}
 
 	if err := root.Init(config); err != nil {
@@ -80,6 +189,91 @@ func (r *Registry) RegisterPlugin(root Root, config map[string]interface{}) erro
 	return nil
 }
 
+// UnknownPluginErr represents an attempt to reload or unregister a plugin that
+// isn't registered or configured.
+type UnknownPluginErr struct {
+	Name string
+}
+
+func (e UnknownPluginErr) Error() string {
+	return fmt.Sprintf("the %v plugin isn't registered or configured", e.Name)
+}
+
+// PluginReloadResult describes the outcome of reloading a plugin.
+type PluginReloadResult struct {
+	Name string `json:"name"`
+	// Unregistered is true if the plugin was unregistered because it's no
+	// longer configured.
+	Unregistered bool `json:"unregistered,omitempty"`
+	// Error is set if the plugin failed to initialize.
+	Error string `json:"error,omitempty"`
+}
+
+// ReloadPlugins loads the configured plugins with the registry's PluginLoader,
+// then reloads the named plugin. If name is empty, then it reloads all of them.
+// Configured plugins that aren't registered are registered, and registered plugins
+// that are no longer configured are unregistered. The returned results are sorted
+// by plugin name.
+func (r *Registry) ReloadPlugins(name string) ([]PluginReloadResult, error) {
+	r.mux.Lock()
+	loader := r.loader
+	r.mux.Unlock()
+	if loader == nil {
+		return nil, fmt.Errorf("plugins can't be reloaded without a plugin loader")
+	}
+
+	roots, config, err := loader()
+	if err != nil {
+		return nil, fmt.Errorf("could not load the plugins: %w", err)
+	}
+
+	registered := r.Plugins()
+	var names []string
+	if name == "" {
+		for name := range roots {
+			names = append(names, name)
+		}
+		for name := range registered {
+			if _, ok := roots[name]; !ok {
+				names = append(names, name)
+			}
+		}
+	} else {
+		_, isConfigured := roots[name]
+		_, isRegistered := registered[name]
+		if !isConfigured && !isRegistered {
+			return nil, UnknownPluginErr{Name: name}
+		}
+		names = []string{name}
+	}
+	sort.Strings(names)
+
+	// Initialize the plugins concurrently because some of them (like the core
+	// plugins) make network requests.
+	results := make([]PluginReloadResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			result := PluginReloadResult{Name: name}
+			if root, ok := roots[name]; ok {
+				if err := r.ReloadPlugin(root, config[name]); err != nil {
+					result.Error = err.Error()
+				}
+			} else {
+				result.Unregistered = true
+				if err := r.UnregisterPlugin(name); err != nil {
+					result.Error = err.Error()
+				}
+			}
+			results[i] = result
+		}(i, name)
+	}
+	wg.Wait()
+	return results, nil
+}
+
 // ChildSchemas only makes sense for core plugin roots
 func (r *Registry) ChildSchemas() []*EntrySchema {
 	return nil
@@ -92,6 +286,8 @@ func (r *Registry) Schema() *EntrySchema {
 
 // List all of Wash's loaded plugins
 func (r *Registry) List(ctx context.Context) ([]Entry, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
 	return r.pluginRoots, nil
 }
 
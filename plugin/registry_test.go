@@ -3,8 +3,11 @@ package plugin
 import (
 	"context"
 	"errors"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/puppetlabs/wash/datastore"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
@@ -13,6 +16,14 @@ type RegistryTestSuite struct {
 	suite.Suite
 }
 
+func (suite *RegistryTestSuite) SetupTest() {
+	SetTestCache(datastore.NewMemCache())
+}
+
+func (suite *RegistryTestSuite) TearDownTest() {
+	UnsetTestCache()
+}
+
 func (suite *RegistryTestSuite) TestEmptyRegistry() {
 	reg := NewRegistry()
 	suite.Empty(reg.Plugins())
@@ -139,6 +150,190 @@ func (suite *RegistryTestSuite) TestRegisterPluginPluginRootImplementsDelete() {
 	suite.Panics(panicFunc, "r.RegisterPlugin: the mine plugin's root implements delete")
 }
 
+func (suite *RegistryTestSuite) TestReloadPlugin() {
+	reg := NewRegistry()
+	m1 := &mockRoot{EntryBase: NewEntry("mine")}
+	m1.On("Init", map[string]interface{}(nil)).Return(nil)
+	other := &mockRoot{EntryBase: NewEntry("other")}
+	other.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.RegisterPlugin(m1, nil))
+	suite.NoError(reg.RegisterPlugin(other, nil))
+
+	_, err := cache.GetOrUpdate("List", "/mine", time.Minute, false, func() (interface{}, error) { return "mine", nil })
+	suite.NoError(err)
+	_, err = cache.GetOrUpdate("List", "/other", time.Minute, false, func() (interface{}, error) { return "other", nil })
+	suite.NoError(err)
+
+	m2 := &mockRoot{EntryBase: NewEntry("mine")}
+	cfg := map[string]interface{}{"key": "value"}
+	m2.On("Init", cfg).Return(nil)
+	suite.NoError(reg.ReloadPlugin(m2, cfg))
+	m2.AssertExpectations(suite.T())
+
+	entries, err := reg.List(context.Background())
+	suite.NoError(err)
+	suite.Equal([]Entry{m2, other}, entries)
+	suite.Equal(map[string]Root{"mine": m2, "other": other}, reg.Plugins())
+	suite.Len(cache.Items(regexp.MustCompile("^List::/mine$")), 0)
+	suite.Len(cache.Items(regexp.MustCompile("^List::/other$")), 1)
+}
+
+type mockClosableRoot struct {
+	mockRoot
+}
+
+func (m *mockClosableRoot) Close() error {
+	return m.Called().Error(0)
+}
+
+func (suite *RegistryTestSuite) TestReloadPluginClosesReplacedPlugin() {
+	reg := NewRegistry()
+	m1 := &mockClosableRoot{mockRoot{EntryBase: NewEntry("mine")}}
+	m1.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.RegisterPlugin(m1, nil))
+	m1.AssertNotCalled(suite.T(), "Close")
+
+	m2 := &mockClosableRoot{mockRoot{EntryBase: NewEntry("mine")}}
+	m2.On("Init", map[string]interface{}(nil)).Return(nil)
+	m1.On("Close").Return(nil).Once()
+	suite.NoError(reg.ReloadPlugin(m2, nil))
+	m1.AssertExpectations(suite.T())
+	m2.AssertNotCalled(suite.T(), "Close")
+
+	// The replaced plugin's also closed if the new plugin fails to initialize
+	m3 := &mockClosableRoot{mockRoot{EntryBase: NewEntry("mine")}}
+	m3.On("Init", map[string]interface{}(nil)).Return(errors.New("failed"))
+	m2.On("Close").Return(errors.New("failed to close")).Once()
+	suite.EqualError(reg.ReloadPlugin(m3, nil), "failed")
+	m2.AssertExpectations(suite.T())
+	m3.AssertNotCalled(suite.T(), "Close")
+}
+
+func (suite *RegistryTestSuite) TestReloadPluginInitError() {
+	reg := NewRegistry()
+	m1 := &mockRoot{EntryBase: NewEntry("mine")}
+	m1.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.RegisterPlugin(m1, nil))
+
+	m2 := &mockRoot{EntryBase: NewEntry("mine")}
+	m2.On("Init", map[string]interface{}(nil)).Return(errors.New("failed"))
+	suite.EqualError(reg.ReloadPlugin(m2, nil), "failed")
+	_, ok := reg.Plugins()["mine"].(*stubRoot)
+	suite.True(ok, "expected a stub plugin root to be registered")
+	entries, err := reg.List(context.Background())
+	suite.NoError(err)
+	suite.Len(entries, 1)
+}
+
+func (suite *RegistryTestSuite) TestUnregisterPlugin() {
+	reg := NewRegistry()
+	m := &mockRoot{EntryBase: NewEntry("mine")}
+	m.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.RegisterPlugin(m, nil))
+
+	suite.NoError(reg.UnregisterPlugin("mine"))
+	suite.Empty(reg.Plugins())
+	entries, err := reg.List(context.Background())
+	suite.NoError(err)
+	suite.Empty(entries)
+
+	suite.Equal(UnknownPluginErr{Name: "mine"}, reg.UnregisterPlugin("mine"))
+}
+
+func (suite *RegistryTestSuite) TestUnregisterPluginClosesPlugin() {
+	reg := NewRegistry()
+	m := &mockClosableRoot{mockRoot{EntryBase: NewEntry("mine")}}
+	m.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.RegisterPlugin(m, nil))
+
+	m.On("Close").Return(nil).Once()
+	suite.NoError(reg.UnregisterPlugin("mine"))
+	m.AssertExpectations(suite.T())
+}
+
+func (suite *RegistryTestSuite) TestClosePlugins() {
+	reg := NewRegistry()
+	closable := &mockClosableRoot{mockRoot{EntryBase: NewEntry("closable")}}
+	closable.On("Init", map[string]interface{}(nil)).Return(nil)
+	other := &mockRoot{EntryBase: NewEntry("other")}
+	other.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.RegisterPlugin(closable, nil))
+	suite.NoError(reg.RegisterPlugin(other, nil))
+
+	closable.On("Close").Return(nil).Once()
+	reg.ClosePlugins()
+	closable.AssertExpectations(suite.T())
+}
+
+func (suite *RegistryTestSuite) TestReloadPlugins_NoLoader() {
+	reg := NewRegistry()
+	_, err := reg.ReloadPlugins("")
+	suite.EqualError(err, "plugins can't be reloaded without a plugin loader")
+}
+
+func (suite *RegistryTestSuite) TestReloadPlugins_LoaderError() {
+	reg := NewRegistry()
+	reg.SetPluginLoader(func() (map[string]Root, map[string]map[string]interface{}, error) {
+		return nil, nil, errors.New("invalid config")
+	})
+	_, err := reg.ReloadPlugins("")
+	suite.EqualError(err, "could not load the plugins: invalid config")
+}
+
+func (suite *RegistryTestSuite) newReloadRegistry() (*Registry, *mockRoot, *mockRoot) {
+	reg := NewRegistry()
+	m1 := &mockRoot{EntryBase: NewEntry("mine")}
+	m1.On("Init", map[string]interface{}(nil)).Return(nil)
+	removed := &mockRoot{EntryBase: NewEntry("removed")}
+	removed.On("Init", map[string]interface{}(nil)).Return(nil)
+	suite.NoError(reg.RegisterPlugin(m1, nil))
+	suite.NoError(reg.RegisterPlugin(removed, nil))
+
+	m2 := &mockRoot{EntryBase: NewEntry("mine")}
+	m2.On("Init", map[string]interface{}{"key": "value"}).Return(nil)
+	added := &mockRoot{EntryBase: NewEntry("added")}
+	added.On("Init", map[string]interface{}(nil)).Return(errors.New("failed"))
+	reg.SetPluginLoader(func() (map[string]Root, map[string]map[string]interface{}, error) {
+		return map[string]Root{"mine": m2, "added": added}, map[string]map[string]interface{}{"mine": {"key": "value"}}, nil
+	})
+	return reg, m2, removed
+}
+
+func (suite *RegistryTestSuite) TestReloadPlugins_All() {
+	reg, m2, _ := suite.newReloadRegistry()
+
+	results, err := reg.ReloadPlugins("")
+	suite.NoError(err)
+	suite.Equal([]PluginReloadResult{
+		{Name: "added", Error: "failed"},
+		{Name: "mine"},
+		{Name: "removed", Unregistered: true},
+	}, results)
+
+	plugins := reg.Plugins()
+	suite.Len(plugins, 2)
+	suite.Equal(m2, plugins["mine"])
+	_, ok := plugins["added"].(*stubRoot)
+	suite.True(ok, "expected a stub plugin root to be registered")
+}
+
+func (suite *RegistryTestSuite) TestReloadPlugins_Named() {
+	reg, m2, removed := suite.newReloadRegistry()
+
+	results, err := reg.ReloadPlugins("mine")
+	suite.NoError(err)
+	suite.Equal([]PluginReloadResult{{Name: "mine"}}, results)
+	suite.Equal(map[string]Root{"mine": m2, "removed": removed}, reg.Plugins())
+
+	results, err = reg.ReloadPlugins("removed")
+	suite.NoError(err)
+	suite.Equal([]PluginReloadResult{{Name: "removed", Unregistered: true}}, results)
+	suite.Equal(map[string]Root{"mine": m2}, reg.Plugins())
+
+	_, err = reg.ReloadPlugins("unknown")
+	suite.Equal(UnknownPluginErr{Name: "unknown"}, err)
+}
+
 func TestRegistry(t *testing.T) {
 	suite.Run(t, new(RegistryTestSuite))
 }
@@ -114,3 +114,50 @@ func (m *MockBlockReadWrite) Write(ctx context.Context, p []byte) error {
 
 var _ = plugin.BlockReadable(&MockBlockReadWrite{})
 var _ = plugin.Writable(&MockBlockReadWrite{})
+
+// MockBlockReadBlockWrite mocks block read and block write operations.
+type MockBlockReadBlockWrite struct {
+	MockBase
+}
+
+// NewMockBlockReadBlockWrite creates a new "mock" entry for block reads and writes.
+func NewMockBlockReadBlockWrite() *MockBlockReadBlockWrite {
+	m := &MockBlockReadBlockWrite{MockBase{EntryBase: plugin.NewEntry("mockbrbw")}}
+	m.SetTestID("/mockbrbw")
+	return m
+}
+
+func (m *MockBlockReadBlockWrite) Read(ctx context.Context, size, off int64) ([]byte, error) {
+	args := m.Called(ctx, size, off)
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (m *MockBlockReadBlockWrite) OpenWriter(ctx context.Context) (plugin.BlockWriter, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(plugin.BlockWriter), args.Error(1)
+}
+
+var _ = plugin.BlockReadable(&MockBlockReadBlockWrite{})
+var _ = plugin.BlockWritable(&MockBlockReadBlockWrite{})
+
+// MockBlockWriter mocks a block writer.
+type MockBlockWriter struct {
+	mock.Mock
+}
+
+func (m *MockBlockWriter) WriteAt(ctx context.Context, p []byte, offset int64) error {
+	args := m.Called(ctx, p, offset)
+	return args.Error(0)
+}
+
+func (m *MockBlockWriter) Truncate(ctx context.Context, size int64) error {
+	args := m.Called(ctx, size)
+	return args.Error(0)
+}
+
+func (m *MockBlockWriter) Close(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+var _ = plugin.BlockWriter(&MockBlockWriter{})
@@ -37,6 +37,7 @@ package plugin
 import (
 	"context"
 	"io"
+	"sort"
 	"time"
 
 	"github.com/emirpasic/gods/maps/linkedhashmap"
@@ -90,11 +91,41 @@ type Parent interface {
 	List(context.Context) ([]Entry, error)
 }
 
+// PagedParent is a Parent that can list its children one page at a time. Parents
+// with lots of children (e.g. an S3 bucket with millions of keys) should implement
+// it so that Wash can start processing their children before the entire listing
+// is finished. ListPage should return the page of children identified by the given
+// token along with the next page's token. The first page's token is the empty string.
+// An empty next page token means that there are no more pages.
+//
+// NOTE: List should still return all of the parent's children. It is used when Wash
+// needs the entire listing at once.
+type PagedParent interface {
+	Parent
+	ListPage(ctx context.Context, token string) (entries []Entry, nextToken string, err error)
+}
+
+// PersistentParent is a Parent whose cached listings can be persisted across restarts
+// by a disk-backed cache (see CacheOptions), so that a restarted server doesn't have
+// to re-list it. Each child's name, attributes, partial metadata and slash replacer
+// are persisted along with the state that PersistChild returns. PersistChild should
+// return an error if the child can't be persisted, in which case the listing's only
+// cached in memory. RestoreChild recreates a child from its persisted form.
+type PersistentParent interface {
+	Parent
+	PersistChild(child Entry) (state []byte, err error)
+	RestoreChild(ctx context.Context, child PersistedEntry) (Entry, error)
+}
+
 // SchemaMap represents a map of <type> => <JSON schema>.
 type SchemaMap = map[interface{}]*JSONSchema
 
 // Root represents the plugin root. The Init function is passed a config map representing
 // plugin-specific configuration.
+//
+// A root that holds resources outside of Wash's cache, like a running process, can implement
+// io.Closer to release them. Close is called when the plugin is reloaded or unregistered, and
+// when the Wash server shuts down.
 type Root interface {
 	Parent
 	Init(map[string]interface{}) error
@@ -118,7 +149,6 @@ type HasWrappedTypes interface {
 }
 
 // ExecOptions is a struct we can add new features to that must be serializable to JSON.
-// Examples of potential features: user, privileged, map of environment variables, timeout.
 type ExecOptions struct {
 	// Stdin can be used to pass a stream of input to write to stdin when executing the command.
 	// It is not included in ExecOption's JSON serialization.
@@ -141,6 +171,51 @@ type ExecOptions struct {
 
 	// Elevate execution to run as a privileged user if not already running as a privileged user.
 	Elevate bool `json:"elevate"`
+
+	// Env contains environment variables to set for the command. They're added to the
+	// executor's default environment.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Dir is the command's working directory. If it's empty, then the executor's default
+	// working directory is used.
+	Dir string `json:"dir,omitempty"`
+
+	// User is the user that runs the command. If it's empty, then the executor's default
+	// user is used.
+	User string `json:"user,omitempty"`
+
+	// TerminalSizes receives the size of the client's terminal whenever it changes, starting
+	// with its initial size. It's only relevant if Tty is true, and it's closed once the
+	// client's finished. It is not included in ExecOption's JSON serialization.
+	//
+	// NOTE TO PLUGIN AUTHORS: If your executor can resize its TTY, then resize it to each
+	// received size so that interactive programs (e.g. shells and editors) render correctly.
+	// Otherwise, ignore TerminalSizes. Only the latest size is kept until it's received, so
+	// ignoring it won't block the client.
+	TerminalSizes <-chan TerminalSize `json:"-"`
+
+	// Timeout is how long the command's allowed to run. If it's zero, then the command can
+	// run indefinitely. It is not included in ExecOption's JSON serialization.
+	//
+	// NOTE TO PLUGIN AUTHORS: plugin.Exec enforces the timeout by cancelling the Exec context once
+	// it elapses, so your executor only needs to stop the command when that context is cancelled.
+	Timeout time.Duration `json:"-"`
+}
+
+// TerminalSize is the size of a terminal, in characters.
+type TerminalSize struct {
+	Rows uint16 `json:"rows"`
+	Cols uint16 `json:"cols"`
+}
+
+// Environ returns opts.Env as a sorted list of "key=value" strings.
+func (opts ExecOptions) Environ() []string {
+	env := make([]string, 0, len(opts.Env))
+	for k, v := range opts.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
 }
 
 // ExecPacketType identifies the packet type.
@@ -214,6 +289,29 @@ type Writable interface {
 	Write(context.Context, []byte) error
 }
 
+// BlockWritable is an entry with data that can be written in blocks, so that
+// writing it doesn't require holding all of its data in memory. Like a
+// BlockReadable entry, it must set its Size attribute.
+//
+// If an entry is both Writable and BlockWritable, then the filesystem writes it
+// in blocks.
+type BlockWritable interface {
+	Entry
+	OpenWriter(context.Context) (BlockWriter, error)
+}
+
+// BlockWriter writes an entry's data in blocks. WriteAt writes p at offset, and
+// Truncate changes the size of the entry's data. Close commits the writes. If a
+// WriteAt or Truncate call failed, then Close should discard the writes and
+// return an error. A BlockWriter should return an error from WriteAt and
+// Truncate if it can't support the requested change (e.g. a write that isn't
+// sequential).
+type BlockWriter interface {
+	WriteAt(ctx context.Context, p []byte, offset int64) error
+	Truncate(ctx context.Context, size int64) error
+	Close(ctx context.Context) error
+}
+
 // Deletable is an entry that can be deleted. Entries that implement Delete
 // should ensure that it and all its children are removed. If the entry has
 // any dependencies that need to be deleted, then Delete should return an
@@ -238,6 +336,58 @@ type Signalable interface {
 	Signal(context.Context, string) error
 }
 
+// DeletePlanner is a Deletable entry that can describe what Delete would do
+// without doing it, e.g. which objects would be removed. During a dry run (see
+// WithDryRun), plugin.Delete calls PlanDelete instead of Delete. PlanDelete
+// must not change anything. Each of its returned steps should be a short,
+// human-readable description of a single change.
+//
+// Deletable entries that aren't DeletePlanners are never deleted during a dry
+// run. Instead, their plan is a single step that deletes the entry.
+type DeletePlanner interface {
+	Deletable
+	PlanDelete(context.Context) ([]string, error)
+}
+
+// SignalPlanner is a Signalable entry that can describe what Signal would do
+// without doing it. It's the Signalable equivalent of a DeletePlanner.
+type SignalPlanner interface {
+	Signalable
+	PlanSignal(context.Context, string) ([]string, error)
+}
+
+// WritePlanner is a Writable entry that can describe what Write would do
+// without doing it. It's the Writable equivalent of a DeletePlanner.
+type WritePlanner interface {
+	Writable
+	PlanWrite(context.Context, []byte) ([]string, error)
+}
+
+// Creatable is a parent that new children can be created in. Create should
+// create a new child with the given name and return it. If isParent is true,
+// then the new child should be a parent (e.g. a directory, a namespace).
+// Otherwise, it should be a non-parent entry (e.g. an empty file). Create
+// should return an error if the entry doesn't support creating the requested
+// kind of child.
+//
+// NOTE: You can assume that name is non-empty and does not contain a '/'.
+type Creatable interface {
+	Parent
+	Create(ctx context.Context, name string, isParent bool) (Entry, error)
+}
+
+// Renamable is an entry that can be renamed or moved. Rename should rename the
+// entry to newName and move it into newParent, then return the renamed entry.
+// newParent can be the entry's current parent. Rename should return an error
+// if newParent is not a valid destination for the entry (e.g. if it belongs to
+// a different plugin).
+//
+// NOTE: You can assume that newName is non-empty and does not contain a '/'.
+type Renamable interface {
+	Entry
+	Rename(ctx context.Context, newParent Parent, newName string) (Entry, error)
+}
+
 // This interface exists to break the circular dependency between plugin and external.
 // The external plugin implementation is in its own module so it can use other modules
 // that implement new features and have dependencies on this module.
@@ -0,0 +1,162 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// DockerTarget identifies a container to execute commands on via Docker's exec API.
+type DockerTarget struct {
+	// Container is the container's name or ID.
+	Container string `json:"container"`
+	// Host is the Docker daemon's address, e.g. unix:///var/run/docker.sock. It defaults
+	// to the DOCKER_HOST environment variable, or the local daemon if that's not set.
+	Host string `json:"host"`
+}
+
+// Exec executes cmd on the target container. It makes DockerTarget an Executor.
+func (t DockerTarget) Exec(ctx context.Context, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	cli, err := dockerClient(t.Host)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the Docker client: %v", err)
+	}
+	return ExecDocker(ctx, cli, t.Container, cmd, opts)
+}
+
+// newDockerExecutor implements the docker transport. Its options are a DockerTarget.
+func newDockerExecutor(options json.RawMessage) (Executor, error) {
+	var target DockerTarget
+	if err := decodeOptions("docker", options, &target); err != nil {
+		return nil, err
+	}
+	if target.Container == "" {
+		return nil, fmt.Errorf("the docker transport's container option must be provided")
+	}
+	return target, nil
+}
+
+// Cache Docker clients by host so that their connections are re-used.
+var dockerClients = make(map[string]*client.Client)
+var dockerClientsMux sync.Mutex
+
+func dockerClient(host string) (*client.Client, error) {
+	dockerClientsMux.Lock()
+	defer dockerClientsMux.Unlock()
+	if cli, ok := dockerClients[host]; ok {
+		return cli, nil
+	}
+
+	clientOpts := []client.Opt{client.FromEnv, client.WithAPIVersionNegotiation()}
+	if host != "" {
+		clientOpts = append(clientOpts, client.WithHost(host))
+	}
+	cli, err := client.NewClientWithOpts(clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	dockerClients[host] = cli
+	return cli, nil
+}
+
+// ExecDocker executes cmd on the container via Docker's exec API. container can be the
+// container's name or ID. opts.Elevate is ignored because the command runs as the
+// container's default user (usually root) unless opts.User is set.
+func ExecDocker(ctx context.Context, cli *client.Client, container string, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	cfg := types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          opts.Tty,
+		Env:          opts.Environ(),
+		WorkingDir:   opts.Dir,
+		User:         opts.User,
+	}
+	if opts.Stdin != nil || opts.Tty {
+		cfg.AttachStdin = true
+	}
+	created, err := cli.ContainerExecCreate(ctx, container, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cli.ContainerExecAttach(ctx, created.ID, types.ExecStartCheck{})
+	if err != nil {
+		return nil, err
+	}
+
+	// If stdin is supplied, asynchronously copy it to container exec input.
+	if opts.Stdin != nil {
+		go func() {
+			_, writeErr := io.Copy(resp.Conn, opts.Stdin)
+			// If using a Tty, wait until done reading in case we need to send Ctrl-C; when a Tty is
+			// allocated commands expect user input and will respond to control signals. Otherwise close
+			// input now to ensure commands that depend on EOF execute correctly.
+			if !opts.Tty {
+				respErr := resp.CloseWrite()
+				activity.Record(ctx, "Closed execution input stream for %v: %v, %v", container, writeErr, respErr)
+			}
+		}()
+	}
+
+	// Resize the TTY whenever the client's terminal is resized.
+	if opts.Tty && opts.TerminalSizes != nil {
+		go func() {
+			for size := range opts.TerminalSizes {
+				resizeOpts := types.ResizeOptions{Height: uint(size.Rows), Width: uint(size.Cols)}
+				if err := cli.ContainerExecResize(ctx, created.ID, resizeOpts); err != nil {
+					activity.Record(ctx, "Failed to resize the TTY for %v: %v", container, err)
+				}
+			}
+		}()
+	}
+
+	execCmd := plugin.NewExecCommand(ctx)
+	execCmd.SetStopFunc(func() {
+		// Close the response on cancellation. Copying will block until there's more to read from the
+		// exec output. For an action with no more output it may never return.
+		if opts.Tty {
+			// If resp.Conn is still open, send Ctrl-C over resp.Conn before closing it.
+			_, err := resp.Conn.Write([]byte{0x03})
+			activity.Record(ctx, "Sent ETX on context termination: %v", err)
+		}
+		resp.Close()
+	})
+	// Asynchronously copy container exec output, then fetch the exit code once
+	// the copy's finished.
+	go func() {
+		var err error
+		if opts.Tty {
+			// A TTY's output isn't multiplexed because stdout and stderr are
+			// both written to the TTY.
+			_, err = io.Copy(execCmd.Stdout(), resp.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(execCmd.Stdout(), execCmd.Stderr(), resp.Reader)
+		}
+		activity.Record(ctx, "Exec on %v complete: %v", container, err)
+		execCmd.CloseStreamsWithError(err)
+		resp.Close()
+
+		// Command's finished. Now send the exit code.
+		resp, err := cli.ContainerExecInspect(ctx, created.ID)
+		if err != nil {
+			execCmd.SetExitCodeErr(err)
+			return
+		}
+		if resp.Running {
+			execCmd.SetExitCodeErr(fmt.Errorf("the command was marked as 'Running' even though the output streams reached EOF"))
+			return
+		}
+		activity.Record(ctx, "Exec on %v exited %v", container, resp.ExitCode)
+		execCmd.SetExitCode(resp.ExitCode)
+	}()
+	return execCmd, nil
+}
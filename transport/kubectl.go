@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	corev1 "k8s.io/api/core/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	k8exec "k8s.io/client-go/util/exec"
+)
+
+// KubectlTarget identifies a pod's container to execute commands on via Kubernetes'
+// exec API. Like kubectl, it connects to the cluster using a kubeconfig file.
+type KubectlTarget struct {
+	Pod string `json:"pod"`
+	// Container defaults to the pod's only container.
+	Container string `json:"container"`
+	// Namespace defaults to the context's namespace.
+	Namespace string `json:"namespace"`
+	// Context defaults to the kubeconfig's current context.
+	Context string `json:"context"`
+	// Kubeconfig defaults to the KUBECONFIG environment variable, or ~/.kube/config if
+	// that's not set.
+	Kubeconfig string `json:"kubeconfig"`
+}
+
+// Exec executes cmd on the target container. It makes KubectlTarget an Executor.
+func (t KubectlTarget) Exec(ctx context.Context, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	return ExecKubectl(ctx, t, cmd, opts)
+}
+
+// newKubectlExecutor implements the kubectl transport. Its options are a KubectlTarget.
+func newKubectlExecutor(options json.RawMessage) (Executor, error) {
+	var target KubectlTarget
+	if err := decodeOptions("kubectl", options, &target); err != nil {
+		return nil, err
+	}
+	if target.Pod == "" {
+		return nil, fmt.Errorf("the kubectl transport's pod option must be provided")
+	}
+	return target, nil
+}
+
+// ExecKubectl executes cmd on the target container via Kubernetes' exec API, like
+// 'kubectl exec' does. See ExecKubernetes for how opts are handled.
+func ExecKubectl(ctx context.Context, target KubectlTarget, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = target.Kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: target.Context})
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load the Kubernetes config: %v", err)
+	}
+	namespace := target.Namespace
+	if namespace == "" {
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			return nil, fmt.Errorf("Failed to load the Kubernetes config: %v", err)
+		}
+	}
+	client, err := k8s.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create the Kubernetes client: %v", err)
+	}
+	return ExecKubernetes(ctx, client, config, namespace, target.Pod, target.Container, cmd, opts)
+}
+
+// ExecKubernetes executes cmd on the pod's container via Kubernetes' exec API. container
+// can be empty if the pod only has one container. The exec API only takes a command, so
+// opts' environment and working directory are set up with a POSIX shell (see
+// plugin.PosixCommand). Running a command as a different user (opts.User) isn't supported,
+// and opts.Elevate is ignored because the command runs as the container's user.
+func ExecKubernetes(ctx context.Context, client k8s.Interface, config *rest.Config, namespace, pod, container string, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("a command must be provided")
+	}
+	if opts.User != "" {
+		return nil, fmt.Errorf("running a command as a different user is not supported over the Kubernetes exec API")
+	}
+
+	execRequest := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(pod).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   plugin.PosixCommand(cmd, opts),
+			Stdin:     opts.Stdin != nil || opts.Tty,
+			Stdout:    true,
+			// A TTY's output isn't multiplexed because stdout and stderr are both
+			// written to the TTY.
+			Stderr: !opts.Tty,
+			TTY:    opts.Tty,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", execRequest.URL())
+	if err != nil {
+		return nil, err
+	}
+
+	execCmd := plugin.NewExecCommand(ctx)
+	streamOpts := remotecommand.StreamOptions{
+		Stdout: execCmd.Stdout(),
+		Stdin:  opts.Stdin,
+		Tty:    opts.Tty,
+	}
+	if !opts.Tty {
+		streamOpts.Stderr = execCmd.Stderr()
+	}
+	if opts.Tty && opts.TerminalSizes != nil {
+		streamOpts.TerminalSizeQueue = kubernetesTerminalSizeQueue(opts.TerminalSizes)
+	}
+
+	// Track when Stream finishes because the calling context may cancel even though the
+	// command's completed and cause us to invoke the stop func.
+	done := make(chan struct{})
+	if opts.Tty {
+		// Create an input stream that allows us to send Ctrl-C to end execution; when a
+		// Tty is allocated commands expect user input and will respond to control signals.
+		r, w := io.Pipe()
+		if streamOpts.Stdin != nil {
+			streamOpts.Stdin = io.MultiReader(streamOpts.Stdin, r)
+		} else {
+			streamOpts.Stdin = r
+		}
+		execCmd.SetStopFunc(func() {
+			select {
+			case <-done:
+				// The command's finished, so there's nothing to stop.
+			default:
+				_, err := w.Write([]byte{0x03})
+				activity.Record(ctx, "Sent ETX on context termination: %v", err)
+			}
+			w.Close()
+		})
+	}
+
+	name := namespace + "/" + pod
+	if container != "" {
+		name += "/" + container
+	}
+	go func() {
+		err := executor.Stream(streamOpts)
+		close(done)
+		activity.Record(ctx, "Exec on %v complete: %v", name, err)
+		if exitErr, ok := err.(k8exec.ExitError); ok {
+			execCmd.CloseStreamsWithError(nil)
+			execCmd.SetExitCode(exitErr.ExitStatus())
+			return
+		}
+		execCmd.CloseStreamsWithError(err)
+		if err != nil {
+			// Set the exit code error so that callers don't block when trying to
+			// retrieve the command's exit code.
+			execCmd.SetExitCodeErr(err)
+			return
+		}
+		execCmd.SetExitCode(0)
+	}()
+	return execCmd, nil
+}
+
+// kubernetesTerminalSizeQueue adapts ExecOptions#TerminalSizes to a
+// remotecommand.TerminalSizeQueue.
+type kubernetesTerminalSizeQueue <-chan plugin.TerminalSize
+
+func (q kubernetesTerminalSizeQueue) Next() *remotecommand.TerminalSize {
+	size, ok := <-q
+	if !ok {
+		return nil
+	}
+	return &remotecommand.TerminalSize{Width: size.Cols, Height: size.Rows}
+}
@@ -0,0 +1,58 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// SFTPUnavailableError is returned by SFTPClient if the target's SSH server doesn't support
+// the SFTP subsystem.
+type SFTPUnavailableError struct {
+	Err error
+}
+
+func (e SFTPUnavailableError) Error() string {
+	return fmt.Sprintf("Failed to start SFTP: %s", e.Err)
+}
+
+// SFTPClient returns an SFTP client for the identified target. The client uses the target's
+// cached SSH connection, and is cached itself. It returns an SFTPUnavailableError if the
+// target doesn't support the SFTP subsystem. The client stays open for at least
+// SFTPClientTTL after it's returned, so callers can re-use it for that long.
+//
+// SFTP runs as the user that's logged in, so unlike ExecSSH there's no way to elevate.
+func SFTPClient(ctx context.Context, id Identity) (*sftp.Client, error) {
+	conf, err := getConnInfo(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get connection info: %s", err)
+	}
+
+	// Keep the SSH connection cached for longer than the SFTP client so that it isn't closed
+	// while the client's still using it.
+	connection, err := sshConnectWithTTL(ctx, conf, id.Retries, 2*expires)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect: %s", err)
+	}
+
+	// This is a single-use cache, so pass in an empty category.
+	obj, err := connectionCache.GetOrUpdate("", "sftp:"+conf.connID(), expires, true, func() (interface{}, error) {
+		client, err := sftp.NewClient(connection)
+		if err != nil {
+			return nil, SFTPUnavailableError{Err: err}
+		}
+		return client, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*sftp.Client), nil
+}
+
+// SFTPClientTTL returns how long a client that's returned by SFTPClient stays cached (and
+// open) after it's returned.
+func SFTPClientTTL() time.Duration {
+	return expires
+}
@@ -2,17 +2,20 @@ package transport
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/avast/retry-go"
 	"github.com/kballard/go-shellquote"
 	"github.com/kevinburke/ssh_config"
+	"github.com/pkg/sftp"
 	"github.com/puppetlabs/wash/activity"
 	"github.com/puppetlabs/wash/datastore"
 	"github.com/puppetlabs/wash/plugin"
@@ -21,30 +24,175 @@ import (
 	"golang.org/x/crypto/ssh/knownhosts"
 )
 
-// Cache SSH connections for better performance. Re-using SSH connections can significantly speed
-// up repeated SSH operations.
+// Cache SSH connections and SFTP clients for better performance. Re-using SSH connections can
+// significantly speed up repeated SSH operations.
 var connectionCache = datastore.NewMemCache().WithEvicted(closeConnection)
 var expires = 15 * time.Second
 
 func closeConnection(id string, obj interface{}) {
-	if client, ok := obj.(*ssh.Client); ok {
+	switch client := obj.(type) {
+	case *ssh.Client:
+		client.Close()
+	case *sftp.Client:
 		client.Close()
 	}
 }
 
-func newAgent(path string) (ssh.AuthMethod, error) {
+// These are overridden by tests.
+var isInteractive = plugin.IsInteractive
+var prompt = plugin.Prompt
+var promptPassword = plugin.PromptPassword
+
+func newAgent(path string) (func() ([]ssh.Signer, error), error) {
 	sshAgent, err := net.Dial("unix", path)
 
 	if err != nil {
 		return nil, err
 	}
-	return ssh.PublicKeysCallback(agent.NewClient(sshAgent).Signers), nil
+	return agent.NewClient(sshAgent).Signers, nil
+}
+
+// loadIdentity returns a signer for the private key in identityFile. If there's a certificate for
+// the key in identityFile-cert.pub, as created by `ssh-keygen -s`, a signer for the certificate is
+// returned first.
+func loadIdentity(identityFile string) ([]ssh.Signer, error) {
+	key, err := ioutil.ReadFile(identityFile)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read private key: %v", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse private key: %v", err)
+	}
+
+	certFile := identityFile + "-cert.pub"
+	certBytes, err := ioutil.ReadFile(certFile)
+	if os.IsNotExist(err) {
+		return []ssh.Signer{signer}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Unable to read certificate: %v", err)
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(certBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse certificate %v: %v", certFile, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a certificate", certFile)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to use certificate %v: %v", certFile, err)
+	}
+	return []ssh.Signer{certSigner, signer}, nil
+}
+
+// authMethods returns the ways to authenticate to the host described by conf. Only the first
+// method of each type is tried, so all public keys are offered by a single method. Methods that
+// prompt for input are only included if Wash is running interactively.
+func authMethods(ctx context.Context, conf sshConfig) []ssh.AuthMethod {
+	var authmethod []ssh.AuthMethod
+	if conf.password != "" {
+		authmethod = append(authmethod, ssh.Password(conf.password))
+	}
+
+	var signers []ssh.Signer
+	for _, identityFile := range conf.identityFiles {
+		if identity, err := loadIdentity(identityFile); err != nil {
+			activity.Record(ctx, "%v", err)
+		} else {
+			signers = append(signers, identity...)
+		}
+	}
+
+	agentPath := os.Getenv("SSH_AUTH_SOCK")
+	var agentSigners func() ([]ssh.Signer, error)
+	if agentPath == "" {
+		// If an SSH agent isn't configured, we should still try to connect without it
+		activity.Record(ctx, "SSH_AUTH_SOCK unconfigured, skipping SSH agent configuration")
+	} else {
+		var err error
+		if agentSigners, err = newAgent(agentPath); err != nil {
+			activity.Warnf(ctx, "Failed to connect to SSH agent at %v: %v", agentPath, err)
+		} else {
+			activity.Record(ctx, "Adding SSH agent at %v", agentPath)
+		}
+	}
+
+	// Offer the agent's keys last in case one of the identity files works.
+	authmethod = append(authmethod, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		if agentSigners == nil {
+			return signers, nil
+		}
+		keys, err := agentSigners()
+		if err != nil {
+			activity.Warnf(ctx, "Failed to get keys from SSH agent at %v: %v", agentPath, err)
+			return signers, nil
+		}
+		return append(signers[:len(signers):len(signers)], keys...), nil
+	}))
+
+	if conf.password == "" && isInteractive() {
+		authmethod = append(authmethod, ssh.PasswordCallback(func() (string, error) {
+			return promptPassword(fmt.Sprintf("(%v@%v) Password", conf.user, conf.host))
+		}))
+	}
+	if conf.password != "" || isInteractive() {
+		authmethod = append(authmethod, ssh.KeyboardInteractive(keyboardInteractive(conf)))
+	}
+	return authmethod
+}
+
+// keyboardInteractive answers the host's keyboard-interactive questions, such as for a password
+// or a one-time password. If the host asks for a single secret and a password was configured, the
+// first answer is the password. Other questions are answered by prompting the user.
+func keyboardInteractive(conf sshConfig) ssh.KeyboardInteractiveChallenge {
+	usedPassword := false
+	return func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+		answers := make([]string, len(questions))
+		for i, question := range questions {
+			if conf.password != "" && !usedPassword && len(questions) == 1 && !echos[i] {
+				usedPassword = true
+				answers[i] = conf.password
+				continue
+			}
+			if !isInteractive() {
+				return nil, fmt.Errorf("%v@%v asked %q, but prompting for it requires an interactive session", conf.user, conf.host, question)
+			}
+
+			msg := fmt.Sprintf("(%v@%v) %v", conf.user, conf.host, strings.TrimRight(question, ": "))
+			if i == 0 && instruction != "" {
+				msg = instruction + "\n" + msg
+			}
+			var err error
+			if echos[i] {
+				answers[i], err = prompt(msg)
+			} else {
+				answers[i], err = promptPassword(msg)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return answers, nil
+	}
 }
 
 type sshConfig struct {
 	host, port, user, password string
 	identityFiles              []string
 	hostKeyCallback            ssh.HostKeyCallback
+	// jump is the host to connect through, if any. It may have its own jump host.
+	jump *sshConfig
+}
+
+// connID identifies the connection described by conf, including the jump hosts it goes through.
+func (conf sshConfig) connID() string {
+	connID := conf.user + "@" + conf.host + ":" + conf.port
+	if conf.jump != nil {
+		connID = conf.jump.connID() + "," + connID
+	}
+	return connID
 }
 
 func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
@@ -90,8 +238,38 @@ func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
 	}
 	// We later provide a fallback to ssh-agent if none of the provided identity files work.
 
-	// Implement permissive and accept-new host key checking. Also account for HostKeyAlias.
-	// Defaults to accepting new hosts.
+	// Find the jump hosts. Each one is looked up in SSH config like any other host, but their own
+	// ProxyJump settings are ignored. They use the requested known hosts and identity files so
+	// that bastions sharing a key pair with the target work without extra configuration.
+	proxyJump := id.ProxyJump
+	if proxyJump == "" {
+		if proxyJump, err = ssh_config.GetStrict(id.Host, "ProxyJump"); err != nil {
+			return
+		}
+	}
+	if proxyJump != "" && proxyJump != "none" {
+		var jumps []Identity
+		if jumps, err = parseProxyJump(proxyJump); err != nil {
+			return
+		}
+		for _, jumpID := range jumps {
+			jumpID.ProxyJump = "none"
+			jumpID.KnownHosts = id.KnownHosts
+			if len(conf.identityFiles) > 0 {
+				jumpID.IdentityFile = conf.identityFiles[0]
+			}
+			var jump sshConfig
+			if jump, err = getConnInfo(ctx, jumpID); err != nil {
+				err = fmt.Errorf("jump host %v: %v", jumpID.Host, err)
+				return
+			}
+			jump.jump = conf.jump
+			conf.jump = &jump
+		}
+	}
+
+	// Implement permissive, strict and accept-new host key checking. Also account for HostKeyAlias.
+	// Defaults to asking whether to accept new hosts, or accepting them if Wash isn't interactive.
 	var hostKeyChecking string
 	if hostKeyChecking, err = ssh_config.GetStrict(id.Host, "StrictHostKeyChecking"); err != nil {
 		return
@@ -122,7 +300,10 @@ func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
 		err = fmt.Errorf("Loading SSH known hosts file: %v", err)
 		return
 	}
-	conf.hostKeyCallback = acceptNewCallback(ctx, conf.hostKeyCallback, id.KnownHosts)
+	if hostKeyChecking != "yes" {
+		ask := hostKeyChecking != "accept-new"
+		conf.hostKeyCallback = acceptNewCallback(ctx, conf.hostKeyCallback, id.KnownHosts, ask)
+	}
 
 	// Lookup host key alias for use in key checking. This should be the last wrapped so that
 	// other callbacks use the alias.
@@ -137,55 +318,84 @@ func getConnInfo(ctx context.Context, id Identity) (conf sshConfig, err error) {
 	return
 }
 
-func sshConnect(ctx context.Context, conf sshConfig, retries uint) (*ssh.Client, error) {
-	connID := conf.user + "@" + conf.host + ":" + conf.port
-	// This is a single-use cache, so pass in an empty category.
-	obj, err := connectionCache.GetOrUpdate("", connID, expires, true, func() (interface{}, error) {
-		agentPath := os.Getenv("SSH_AUTH_SOCK")
-		var agent ssh.AuthMethod
-		if agentPath == "" {
-			// If an SSH agent isn't configured, we should still try to connect without it
-			activity.Record(ctx, "SSH_AUTH_SOCK unconfigured, skipping SSH agent configuration")
-		} else {
-			var err error
-			if agent, err = newAgent(agentPath); err != nil {
-				activity.Warnf(ctx, "Failed to connect to SSH agent at %v: %v", agentPath, err)
-			}
-		}
-
-		var authmethod []ssh.AuthMethod
-		if conf.password != "" {
-			authmethod = append(authmethod, ssh.Password(conf.password))
+// parseProxyJump parses a ProxyJump setting, which is a comma-separated list of [user@]host[:port]
+// jump hosts. Each may also be given as an ssh:// URI.
+func parseProxyJump(proxyJump string) ([]Identity, error) {
+	var jumps []Identity
+	for _, spec := range strings.Split(proxyJump, ",") {
+		var jump Identity
+		hostport := strings.TrimPrefix(strings.TrimSpace(spec), "ssh://")
+		if i := strings.LastIndex(hostport, "@"); i >= 0 {
+			jump.User, hostport = hostport[:i], hostport[i+1:]
 		}
-		for _, identityFile := range conf.identityFiles {
-			if key, err := ioutil.ReadFile(identityFile); err != nil {
-				activity.Record(ctx, "Unable to read private key: %v", err)
-			} else {
-				if signer, err := ssh.ParsePrivateKey(key); err != nil {
-					activity.Record(ctx, "Unable to parse private key: %v", err)
-				} else {
-					authmethod = append(authmethod, ssh.PublicKeys(signer))
-				}
+		jump.Host = hostport
+		if host, port, err := net.SplitHostPort(hostport); err == nil {
+			p, err := strconv.ParseUint(port, 10, 16)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port in jump host %v", spec)
 			}
+			jump.Host, jump.Port = host, uint(p)
+		}
+		jump.Host = strings.TrimSuffix(strings.TrimPrefix(jump.Host, "["), "]")
+		if jump.Host == "" {
+			return nil, fmt.Errorf("invalid jump host %q", spec)
 		}
+		jumps = append(jumps, jump)
+	}
+	return jumps, nil
+}
+
+func sshConnect(ctx context.Context, conf sshConfig, retries uint) (*ssh.Client, error) {
+	return sshConnectWithTTL(ctx, conf, retries, expires)
+}
 
-		// Append agent now so that it comes last in case we find another method to try.
-		if agent != nil {
-			activity.Record(ctx, "Adding SSH agent at %v", os.Getenv("SSH_AUTH_SOCK"))
-			authmethod = append(authmethod, agent)
+func sshConnectWithTTL(ctx context.Context, conf sshConfig, retries uint, ttl time.Duration) (*ssh.Client, error) {
+	// Connect to the jump host first, even if our own connection's cached, so that its cached
+	// connection is kept alive for as long as ours. It's given a longer TTL so that it isn't
+	// evicted (and closed) while our connection still depends on it. Cache hits only ever
+	// extend a connection's expiration, so connecting to the jump host directly with a
+	// shorter TTL doesn't shorten it.
+	var jumpClient *ssh.Client
+	if conf.jump != nil {
+		var err error
+		if jumpClient, err = sshConnectWithTTL(ctx, *conf.jump, retries, ttl+expires); err != nil {
+			return nil, fmt.Errorf("jump host %v: %v", conf.jump.host, err)
 		}
+	}
 
+	// This is a single-use cache, so pass in an empty category.
+	obj, err := connectionCache.GetOrUpdate("", conf.connID(), ttl, false, func() (interface{}, error) {
 		sshConfig := &ssh.ClientConfig{
 			User:            conf.user,
-			Auth:            authmethod,
+			Auth:            authMethods(ctx, conf),
 			HostKeyCallback: conf.hostKeyCallback,
 		}
 
+		addr := net.JoinHostPort(conf.host, conf.port)
+		dial := func() (*ssh.Client, error) {
+			return ssh.Dial("tcp", addr, sshConfig)
+		}
+		if jumpClient != nil {
+			// Tunnel the connection through the jump host.
+			dial = func() (*ssh.Client, error) {
+				conn, err := jumpClient.Dial("tcp", addr)
+				if err != nil {
+					return nil, err
+				}
+				c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+				if err != nil {
+					conn.Close()
+					return nil, err
+				}
+				return ssh.NewClient(c, chans, reqs), nil
+			}
+		}
+
 		// Try until we've retried desired number of times or connection is established.
 		var cli *ssh.Client
 		err := retry.Do(
 			func() (err error) {
-				cli, err = ssh.Dial("tcp", conf.host+":"+conf.port, sshConfig)
+				cli, err = dial()
 				return
 			},
 			retry.Attempts(retries+1),
@@ -193,6 +403,7 @@ func sshConnect(ctx context.Context, conf sshConfig, retries uint) (*ssh.Client,
 		)
 		return cli, err
 	})
+	connectionCache.Extend("", conf.connID(), ttl)
 
 	if err != nil {
 		return nil, err
@@ -209,16 +420,42 @@ type Identity struct {
 	IdentityFile string `json:"identity_file"`
 	KnownHosts   string `json:"known_hosts"`
 	HostKeyAlias string `json:"host_key_alias"`
+	// ProxyJump is a comma-separated list of [user@]host[:port] jump hosts to connect through, in
+	// order. It overrides ProxyJump in SSH config; set it to "none" to connect directly.
+	ProxyJump string `json:"proxy_jump"`
 	// Retries can be set to a non-zero value to retry every 500ms for that many times.
 	Retries uint `json:"retries"`
 	Port    uint `json:"port"`
 }
 
+// Exec executes cmd on the identified target via SSH. It makes Identity an Executor.
+func (id Identity) Exec(ctx context.Context, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	return ExecSSH(ctx, id, cmd, opts)
+}
+
+// newSSHExecutor implements the ssh transport. Its options are an Identity.
+func newSSHExecutor(options json.RawMessage) (Executor, error) {
+	var id Identity
+	if err := decodeOptions("ssh", options, &id); err != nil {
+		return nil, err
+	}
+	if id.Host == "" {
+		return nil, fmt.Errorf("the ssh transport's host option must be provided")
+	}
+	return id, nil
+}
+
 // ExecSSH executes against a target via SSH. It will look up port, user, and other configuration
 // by exact hostname match from default SSH config files. Identity can be used to override the
 // user configured in SSH config. If opts.Elevate is true, will attempt to `sudo` as root.
+// opts.Env, opts.Dir and opts.User are implemented by wrapping the command with
+// plugin.PosixCommand, so they assume a POSIX target.
 //
-// If present, a local SSH agent will be used for authentication.
+// Authentication tries the password, then the identity files (including any certificates in
+// <identity file>-cert.pub) and a local SSH agent's keys if present. When Wash is running
+// interactively, it also prompts for passwords and keyboard-interactive answers such as
+// one-time passwords, and asks before adding new hosts to the known hosts file unless
+// StrictHostKeyChecking is accept-new. StrictHostKeyChecking=yes rejects unknown hosts.
 //
 // Lots of SSH configuration is currently omitted, such as global known hosts files, finding known
 // hosts from the config, identity file from config... pretty much everything but port and user
@@ -227,6 +464,9 @@ type Identity struct {
 // The known hosts file will be ignored if StrictHostKeyChecking=no, such as in
 //   Host *.compute.amazonaws.com
 //     StrictHostKeyChecking no
+//
+// Jump hosts are read from Identity.ProxyJump or ProxyJump in SSH config. Connections to them are
+// cached and re-used like any other connection. ProxyCommand is not supported.
 func ExecSSH(ctx context.Context, id Identity, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
 	// find port, username, etc from .ssh/config
 	conf, err := getConnInfo(ctx, id)
@@ -249,14 +489,30 @@ func ExecSSH(ctx context.Context, id Identity, cmd []string, opts plugin.ExecOpt
 	if opts.Tty {
 		// sshd only processes signal codes if a TTY has been allocated. So set one up when requested.
 		modes := ssh.TerminalModes{ssh.ECHO: 0, ssh.TTY_OP_ISPEED: 14400, ssh.TTY_OP_OSPEED: 14400}
+		if opts.TerminalSizes != nil {
+			// The client's terminal is attached, so echo its input like a normal terminal.
+			modes[ssh.ECHO] = 1
+		}
 		if err := session.RequestPty("xterm", 40, 80, modes); err != nil {
 			return nil, fmt.Errorf("Unable to setup a TTY: %v", err)
 		}
+
+		// Resize the TTY whenever the client's terminal is resized.
+		if opts.TerminalSizes != nil {
+			go func() {
+				for size := range opts.TerminalSizes {
+					if err := session.WindowChange(int(size.Rows), int(size.Cols)); err != nil {
+						activity.Record(ctx, "Failed to resize the TTY for %v: %v", id.Host, err)
+					}
+				}
+			}()
+		}
 	}
 
 	execCmd := plugin.NewExecCommand(ctx)
 	session.Stdin, session.Stdout, session.Stderr = opts.Stdin, execCmd.Stdout(), execCmd.Stderr()
 
+	cmd = plugin.PosixCommand(cmd, opts)
 	if opts.Elevate {
 		cmd = append([]string{"sudo"}, cmd...)
 	}
@@ -302,12 +558,19 @@ func hostAliasCallback(cb ssh.HostKeyCallback, hostKeyAlias string) ssh.HostKeyC
 	}
 }
 
-func acceptNewCallback(ctx context.Context, cb ssh.HostKeyCallback, knownHosts string) ssh.HostKeyCallback {
+// acceptNewCallback adds new hosts to known hosts. If ask is true and Wash is running
+// interactively, the user's asked to confirm the host's key first.
+func acceptNewCallback(ctx context.Context, cb ssh.HostKeyCallback, knownHosts string, ask bool) ssh.HostKeyCallback {
 	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
 		err := cb(hostname, remote, key)
 		if err != nil {
 			// If the error occurred because no entry was found, add it to known hosts and succeed.
 			if kerr, ok := err.(*knownhosts.KeyError); ok && len(kerr.Want) == 0 {
+				if ask && isInteractive() {
+					if err := confirmHostKey(hostname, key); err != nil {
+						return err
+					}
+				}
 				line := knownhosts.Line([]string{hostname}, key)
 				if err := appendToKnownHosts(ctx, knownHosts, line); err != nil {
 					activity.Warnf(ctx, "Unable to update %v with new host %v: %v", knownHosts, hostname, err)
@@ -319,6 +582,21 @@ func acceptNewCallback(ctx context.Context, cb ssh.HostKeyCallback, knownHosts s
 	}
 }
 
+func confirmHostKey(hostname string, key ssh.PublicKey) error {
+	msg := fmt.Sprintf(
+		"The authenticity of host %v can't be established.\n%v key fingerprint is %v.\nAre you sure you want to continue connecting (yes/no)",
+		hostname, key.Type(), ssh.FingerprintSHA256(key),
+	)
+	answer, err := prompt(msg)
+	if err != nil {
+		return fmt.Errorf("could not confirm the host key for %v: %v", hostname, err)
+	}
+	if answer := strings.ToLower(strings.TrimSpace(answer)); answer != "yes" && answer != "y" {
+		return fmt.Errorf("the host key for %v was rejected", hostname)
+	}
+	return nil
+}
+
 func appendToKnownHosts(ctx context.Context, knownHosts, line string) error {
 	f, err := os.OpenFile(knownHosts, os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
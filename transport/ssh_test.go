@@ -8,9 +8,12 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
+	"regexp"
 	"strconv"
 	"testing"
+	"time"
 
 	gssh "github.com/gliderlabs/ssh"
 	"github.com/puppetlabs/wash/plugin"
@@ -49,6 +52,11 @@ func (suite *SSHTestSuite) PasswordHandler(ctx gssh.Context, password string) bo
 	return suite.m.Called(ctx, password).Bool(0)
 }
 
+// Must be mocked if keyboard-interactive authentication is reached.
+func (suite *SSHTestSuite) KeyboardInteractiveHandler(ctx gssh.Context, challenger ssh.KeyboardInteractiveChallenge) bool {
+	return suite.m.Called(ctx, challenger).Bool(0)
+}
+
 // Must be mocked.
 func (suite *SSHTestSuite) PublicKeyHandler(ctx gssh.Context, key gssh.PublicKey) bool {
 	return suite.m.Called(ctx, key).Bool(0)
@@ -120,12 +128,32 @@ func (suite *SSHTestSuite) SetupSuite() {
 	suite.s.AddHostKey(signer)
 	suite.s.PasswordHandler = suite.PasswordHandler
 	suite.s.PublicKeyHandler = suite.PublicKeyHandler
-	go func() { fmt.Println(suite.s.ListenAndServe()) }()
+	suite.s.KeyboardInteractiveHandler = suite.KeyboardInteractiveHandler
+	// Allow the server to be used as a jump host.
+	suite.s.LocalPortForwardingCallback = func(gssh.Context, string, uint32) bool { return true }
+	suite.s.ChannelHandlers = map[string]gssh.ChannelHandler{
+		"session":      gssh.DefaultSessionHandler,
+		"direct-tcpip": gssh.DirectTCPIPHandler,
+	}
+	// Listen before the tests start so that they don't race with the server.
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		suite.T().Fatal(err)
+	}
+	go func() { fmt.Println(suite.s.Serve(listener)) }()
 }
 
 func (suite *SSHTestSuite) SetupTest() {
 	// Reset mocks before every test
 	suite.m = mock.Mock{}
+
+	// Don't prompt unless a test asks for it.
+	isInteractive = func() bool { return false }
+	prompt = func(msg string) (string, error) {
+		suite.Fail("unexpected prompt", msg)
+		return "", fmt.Errorf("unexpected prompt")
+	}
+	promptPassword = prompt
 }
 
 func (suite *SSHTestSuite) TearDownTest() {
@@ -146,6 +174,8 @@ func (suite *SSHTestSuite) TearDownSuite() {
 	if err := os.Remove(suite.identityFile); err != nil {
 		suite.T().Log(err)
 	}
+
+	isInteractive, prompt, promptPassword = plugin.IsInteractive, plugin.Prompt, plugin.PromptPassword
 }
 
 func (suite *SSHTestSuite) Identity() Identity {
@@ -242,6 +272,256 @@ func (suite *SSHTestSuite) TestExec_HostKeyConflict() {
 	suite.EqualError(err, "Failed to connect: All attempts fail:\n#1: ssh: handshake failed: knownhosts: key mismatch")
 }
 
+func (suite *SSHTestSuite) TestExec_Certificate() {
+	// Sign the identity file's key with a CA.
+	keyBytes, err := ioutil.ReadFile(suite.identityFile)
+	if !suite.NoError(err) {
+		return
+	}
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	if !suite.NoError(err) {
+		return
+	}
+	ca, err := generateSigner()
+	if !suite.NoError(err) {
+		return
+	}
+	cert := &ssh.Certificate{
+		Key:             signer.PublicKey(),
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"root"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if !suite.NoError(cert.SignCert(rand.Reader, ca)) {
+		return
+	}
+	certFile := suite.identityFile + "-cert.pub"
+	if !suite.NoError(ioutil.WriteFile(certFile, ssh.MarshalAuthorizedKey(cert), 0644)) {
+		return
+	}
+	defer os.Remove(certFile)
+
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	var offered ssh.PublicKey
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		offered = args.Get(1).(gssh.PublicKey)
+	}).Return(true)
+
+	cmd, err := ExecSSH(context.Background(), suite.Identity(), []string{"echo", "hello"}, plugin.ExecOptions{})
+	if suite.NoError(err) {
+		<-cmd.OutputCh()
+		exit, err := cmd.ExitCode()
+		suite.NoError(err)
+		suite.Zero(exit)
+		if offeredCert, ok := offered.(*ssh.Certificate); suite.True(ok, "expected a certificate, got %T", offered) {
+			suite.Equal(ca.PublicKey().Marshal(), offeredCert.SignatureKey.Marshal())
+		}
+	}
+}
+
+func (suite *SSHTestSuite) TestExec_PasswordPrompt() {
+	isInteractive = func() bool { return true }
+	var prompts []string
+	promptPassword = func(msg string) (string, error) {
+		prompts = append(prompts, msg)
+		return "password", nil
+	}
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(false)
+	suite.m.On("PasswordHandler", mock.Anything, "password").Return(true)
+
+	cmd, err := ExecSSH(context.Background(), suite.Identity(), []string{}, plugin.ExecOptions{})
+	if suite.NoError(err) {
+		<-cmd.OutputCh()
+		exit, err := cmd.ExitCode()
+		suite.NoError(err)
+		suite.Zero(exit)
+		suite.Equal([]string{"(root@localhost) Password"}, prompts)
+	}
+}
+
+func (suite *SSHTestSuite) TestExec_KeyboardInteractive() {
+	isInteractive = func() bool { return true }
+	var prompts []string
+	prompt = func(msg string) (string, error) {
+		prompts = append(prompts, msg)
+		return "123456", nil
+	}
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(false)
+	suite.m.On("PasswordHandler", mock.Anything, "password").Return(false)
+	var answers []string
+	suite.m.On("KeyboardInteractiveHandler", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		challenger := args.Get(1).(ssh.KeyboardInteractiveChallenge)
+		// Ask for the password, then a one-time password.
+		password, err := challenger("", "", []string{"Password: "}, []bool{false})
+		suite.NoError(err)
+		otp, err := challenger("", "Two-factor authentication", []string{"Verification code: "}, []bool{true})
+		suite.NoError(err)
+		answers = append(password, otp...)
+	}).Return(true)
+
+	identity := suite.Identity()
+	identity.Password = "password"
+	cmd, err := ExecSSH(context.Background(), identity, []string{}, plugin.ExecOptions{})
+	if suite.NoError(err) {
+		<-cmd.OutputCh()
+		exit, err := cmd.ExitCode()
+		suite.NoError(err)
+		suite.Zero(exit)
+		suite.Equal([]string{"password", "123456"}, answers)
+		suite.Equal([]string{"Two-factor authentication\n(root@localhost) Verification code"}, prompts)
+	}
+}
+
+func (suite *SSHTestSuite) TestExec_KeyboardInteractiveRequiresInteractiveSession() {
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(false)
+	suite.m.On("PasswordHandler", mock.Anything, "password").Return(false)
+	suite.m.On("KeyboardInteractiveHandler", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		_, _ = args.Get(1).(ssh.KeyboardInteractiveChallenge)("", "", []string{"Verification code: "}, []bool{true})
+	}).Return(false)
+
+	identity := suite.Identity()
+	identity.Password = "password"
+	_, err := ExecSSH(context.Background(), identity, []string{}, plugin.ExecOptions{})
+	suite.EqualError(err, "Failed to connect: All attempts fail:\n#1: ssh: handshake failed: root@localhost asked \"Verification code: \", but prompting for it requires an interactive session")
+}
+
+func (suite *SSHTestSuite) TestExec_NewHostKeyPrompt() {
+	knownHosts, err := ioutil.TempFile("", "wash_ssh_knownhosts")
+	if !suite.NoError(err) {
+		return
+	}
+	suite.NoError(knownHosts.Close())
+	defer os.Remove(knownHosts.Name())
+
+	isInteractive = func() bool { return true }
+	answer := "no"
+	var prompts []string
+	prompt = func(msg string) (string, error) {
+		prompts = append(prompts, msg)
+		return answer, nil
+	}
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {})
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(true)
+
+	identity := suite.Identity()
+	identity.KnownHosts = knownHosts.Name()
+	_, err = ExecSSH(context.Background(), identity, []string{}, plugin.ExecOptions{})
+	suite.EqualError(err, "Failed to connect: All attempts fail:\n#1: ssh: handshake failed: the host key for localhost:2222 was rejected")
+	if suite.Len(prompts, 1) {
+		suite.Contains(prompts[0], "The authenticity of host localhost:2222 can't be established.")
+	}
+	content, err := ioutil.ReadFile(knownHosts.Name())
+	if suite.NoError(err) {
+		suite.Empty(content)
+	}
+
+	// Failed connections are cached, so clear the cache before trying again.
+	connectionCache.Flush()
+	answer = "yes"
+	cmd, err := ExecSSH(context.Background(), identity, []string{}, plugin.ExecOptions{})
+	if suite.NoError(err) {
+		<-cmd.OutputCh()
+		_, err := cmd.ExitCode()
+		suite.NoError(err)
+	}
+	content, err = ioutil.ReadFile(knownHosts.Name())
+	if suite.NoError(err) {
+		suite.Contains(string(content), "[localhost]:2222 ssh-rsa ")
+	}
+}
+
+func (suite *SSHTestSuite) TestExec_ProxyJump() {
+	var user string
+	suite.m.On("Handler", mock.Anything).Run(func(args mock.Arguments) {
+		user = args.Get(0).(gssh.Session).User()
+	})
+	var authenticated []string
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		authenticated = append(authenticated, args.Get(0).(gssh.Context).User())
+	}).Return(true)
+
+	// The test server is its own jump host.
+	identity := suite.Identity()
+	identity.ProxyJump = "jumper@" + host + ":" + strconv.Itoa(port)
+	for i := 0; i < 2; i++ {
+		cmd, err := ExecSSH(context.Background(), identity, []string{"echo", "hello"}, plugin.ExecOptions{})
+		if suite.NoError(err) {
+			<-cmd.OutputCh()
+			exit, err := cmd.ExitCode()
+			suite.NoError(err)
+			suite.Zero(exit)
+			suite.Equal("root", user)
+		}
+	}
+	// The connections to the jump host and the target are re-used.
+	suite.Equal([]string{"jumper", "root"}, authenticated)
+}
+
+func (suite *SSHTestSuite) TestExec_ProxyJumpIsNotExpiredByDirectConnections() {
+	suite.m.On("Handler", mock.Anything).Return()
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(true)
+
+	jumpExpires := func() time.Time {
+		for _, item := range connectionCache.Items(regexp.MustCompile("")) {
+			if item.Key == "jumper@"+host+":"+strconv.Itoa(port) {
+				return item.Expires
+			}
+		}
+		suite.Fail("the jump host's connection is not cached")
+		return time.Time{}
+	}
+	exec := func(identity Identity) {
+		cmd, err := ExecSSH(context.Background(), identity, []string{"echo", "hello"}, plugin.ExecOptions{})
+		if suite.NoError(err) {
+			<-cmd.OutputCh()
+			_, err := cmd.ExitCode()
+			suite.NoError(err)
+		}
+	}
+
+	identity := suite.Identity()
+	identity.ProxyJump = "jumper@" + host + ":" + strconv.Itoa(port)
+	exec(identity)
+	expires := jumpExpires()
+
+	// Connecting to the jump host directly uses a shorter TTL, which mustn't shorten
+	// the TTL of the connection that the target's tunneled through.
+	identity = suite.Identity()
+	identity.User = "jumper"
+	exec(identity)
+	suite.False(jumpExpires().Before(expires))
+}
+
+func (suite *SSHTestSuite) TestExec_ProxyJumpFailure() {
+	suite.m.On("PublicKeyHandler", mock.Anything, mock.Anything).Return(false)
+
+	identity := suite.Identity()
+	identity.ProxyJump = "jumper@" + host + ":" + strconv.Itoa(port)
+	_, err := ExecSSH(context.Background(), identity, []string{"echo", "hello"}, plugin.ExecOptions{})
+	// The attempted authentication methods are listed in random order, so only check the prefix.
+	if suite.Error(err) {
+		suite.Regexp("^Failed to connect: jump host localhost: All attempts fail:\n#1: ssh: handshake failed: ssh: unable to authenticate", err.Error())
+	}
+}
+
+func (suite *SSHTestSuite) TestParseProxyJump() {
+	jumps, err := parseProxyJump("bastion, admin@gateway:2200,ssh://other@[::1]:22")
+	if suite.NoError(err) {
+		suite.Equal([]Identity{
+			{Host: "bastion"},
+			{Host: "gateway", User: "admin", Port: 2200},
+			{Host: "::1", User: "other", Port: 22},
+		}, jumps)
+	}
+
+	_, err = parseProxyJump("bastion:ssh")
+	suite.EqualError(err, "invalid port in jump host bastion:ssh")
+	_, err = parseProxyJump("bastion,")
+	suite.EqualError(err, `invalid jump host ""`)
+}
+
 func (suite *SSHTestSuite) TestExec_ContextCancelled() {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
@@ -0,0 +1,98 @@
+// Package transport implements ways of executing commands on a target, such as
+// SSH, WinRM, and Docker's and Kubernetes' exec APIs. Plugins can use them to implement
+// plugin.Execable, and external plugins can request them by name via the
+// ["exec", {"transport": <name>, "options": {...}}] method tuple.
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// Executor executes commands on a specific target. cmd includes the command's
+// name followed by its arguments.
+type Executor interface {
+	Exec(ctx context.Context, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error)
+}
+
+// Transport creates executors from its options. The options are JSON because
+// they're usually provided by an external plugin. NewExecutor should return an
+// error if the options are invalid.
+type Transport interface {
+	NewExecutor(options json.RawMessage) (Executor, error)
+}
+
+// TransportFunc adapts an ordinary function to a Transport.
+type TransportFunc func(options json.RawMessage) (Executor, error)
+
+// NewExecutor calls f(options).
+func (f TransportFunc) NewExecutor(options json.RawMessage) (Executor, error) {
+	return f(options)
+}
+
+var transports = map[string]Transport{
+	"ssh":     TransportFunc(newSSHExecutor),
+	"winrm":   TransportFunc(newWinRMExecutor),
+	"docker":  TransportFunc(newDockerExecutor),
+	"kubectl": TransportFunc(newKubectlExecutor),
+}
+var transportsMux sync.RWMutex
+
+// Register makes a transport available by the provided name. It panics if
+// the name's already registered or if t is nil.
+func Register(name string, t Transport) {
+	if t == nil {
+		panic("transport.Register: the transport cannot be nil")
+	}
+
+	transportsMux.Lock()
+	defer transportsMux.Unlock()
+	if _, ok := transports[name]; ok {
+		panic(fmt.Sprintf("transport.Register: the %v transport is already registered", name))
+	}
+	transports[name] = t
+}
+
+// Lookup returns the transport with the given name. It returns an error
+// listing the registered transports if there isn't one.
+func Lookup(name string) (Transport, error) {
+	transportsMux.RLock()
+	defer transportsMux.RUnlock()
+	if t, ok := transports[name]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("unsupported transport %v requested, supported transports are %v", name, strings.Join(names(), ", "))
+}
+
+// Names returns the names of the registered transports in sorted order.
+func Names() []string {
+	transportsMux.RLock()
+	defer transportsMux.RUnlock()
+	return names()
+}
+
+func names() []string {
+	names := make([]string, 0, len(transports))
+	for name := range transports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// decodeOptions decodes a transport's JSON options into v.
+func decodeOptions(transport string, options json.RawMessage, v interface{}) error {
+	if len(options) == 0 {
+		return fmt.Errorf("the %v transport's options must be provided", transport)
+	}
+	if err := json.Unmarshal(options, v); err != nil {
+		return fmt.Errorf("could not decode the %v transport's options: %v", transport, err)
+	}
+	return nil
+}
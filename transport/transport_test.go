@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TransportTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TransportTestSuite) TestLookup() {
+	t, err := Lookup("ssh")
+	if suite.NoError(err) {
+		executor, err := t.NewExecutor(json.RawMessage(`{"host": "example.com", "port": 2222}`))
+		if suite.NoError(err) {
+			suite.Equal(Identity{Host: "example.com", Port: 2222}, executor)
+		}
+	}
+
+	_, err = Lookup("foo")
+	suite.EqualError(err, "unsupported transport foo requested, supported transports are docker, kubectl, ssh, winrm")
+}
+
+func (suite *TransportTestSuite) TestNewExecutor_ValidatesOptions() {
+	cases := []struct {
+		transport, options, err string
+	}{
+		{"ssh", ``, "the ssh transport's options must be provided"},
+		{"ssh", `[]`, "could not decode the ssh transport's options: json: cannot unmarshal array into Go value of type transport.Identity"},
+		{"ssh", `{}`, "the ssh transport's host option must be provided"},
+		{"winrm", `{"user": "Administrator"}`, "the winrm transport's host option must be provided"},
+		{"winrm", `{"host": "example.com"}`, "the winrm transport's user option must be provided"},
+		{"docker", `{"host": "unix:///var/run/docker.sock"}`, "the docker transport's container option must be provided"},
+		{"kubectl", `{"container": "web"}`, "the kubectl transport's pod option must be provided"},
+	}
+	for _, c := range cases {
+		t, err := Lookup(c.transport)
+		if suite.NoError(err) {
+			_, err = t.NewExecutor(json.RawMessage(c.options))
+			suite.EqualError(err, c.err, "%v transport with options %v", c.transport, c.options)
+		}
+	}
+
+	t, err := Lookup("docker")
+	if suite.NoError(err) {
+		executor, err := t.NewExecutor(json.RawMessage(`{"container": "web"}`))
+		if suite.NoError(err) {
+			suite.Equal(DockerTarget{Container: "web"}, executor)
+		}
+	}
+}
+
+func (suite *TransportTestSuite) TestRegister() {
+	executor := DockerTarget{Container: "web"}
+	Register("test", TransportFunc(func(json.RawMessage) (Executor, error) {
+		return executor, nil
+	}))
+	defer func() {
+		transportsMux.Lock()
+		delete(transports, "test")
+		transportsMux.Unlock()
+	}()
+
+	suite.Equal([]string{"docker", "kubectl", "ssh", "test", "winrm"}, Names())
+	t, err := Lookup("test")
+	if suite.NoError(err) {
+		actual, err := t.NewExecutor(nil)
+		suite.NoError(err)
+		suite.Equal(executor, actual)
+	}
+
+	suite.Panics(func() {
+		Register("ssh", TransportFunc(func(json.RawMessage) (Executor, error) { return nil, nil }))
+	})
+	suite.Panics(func() { Register("other", nil) })
+}
+
+func TestTransport(t *testing.T) {
+	suite.Run(t, new(TransportTestSuite))
+}
+
+var _ = Executor(Identity{})
+var _ = Executor(WinRMTarget{})
+var _ = Executor(DockerTarget{})
+var _ = Executor(KubectlTarget{})
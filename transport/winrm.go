@@ -0,0 +1,369 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/Azure/go-ntlmssp"
+	"github.com/masterzen/winrm"
+	"github.com/masterzen/winrm/soap"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// WinRMTarget identifies how to connect to a Windows target via WinRM.
+type WinRMTarget struct {
+	Host     string `json:"host"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// HTTP connects over plain HTTP instead of HTTPS. Insecure skips verifying the
+	// target's certificate when connecting over HTTPS.
+	HTTP     bool `json:"http"`
+	Insecure bool `json:"insecure"`
+	// Port defaults to 5986, or 5985 if HTTP is true.
+	Port uint `json:"port"`
+}
+
+// Exec executes cmd on the target via WinRM. It makes WinRMTarget an Executor.
+func (t WinRMTarget) Exec(ctx context.Context, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	return ExecWinRM(ctx, t, cmd, opts)
+}
+
+// newWinRMExecutor implements the winrm transport. Its options are a WinRMTarget.
+func newWinRMExecutor(options json.RawMessage) (Executor, error) {
+	var target WinRMTarget
+	if err := decodeOptions("winrm", options, &target); err != nil {
+		return nil, err
+	}
+	if target.Host == "" {
+		return nil, fmt.Errorf("the winrm transport's host option must be provided")
+	}
+	if target.User == "" {
+		return nil, fmt.Errorf("the winrm transport's user option must be provided")
+	}
+	return target, nil
+}
+
+func (t WinRMTarget) endpoint() string {
+	scheme, port := "https", t.Port
+	if t.HTTP {
+		scheme = "http"
+	}
+	if port == 0 {
+		port = 5986
+		if t.HTTP {
+			port = 5985
+		}
+	}
+	return scheme + "://" + net.JoinHostPort(t.Host, strconv.Itoa(int(port))) + "/wsman"
+}
+
+// ExecWinRM executes a command on the target via WinRM. It authenticates with NTLM. It
+// only falls back to HTTP basic auth if the target doesn't support NTLM and the connection
+// is over HTTPS, so the password's never sent in cleartext.
+//
+// The command's run by PowerShell. If cmd is a single string, then it's treated as a
+// PowerShell expression (like volume.FS' PowerShell commands). Otherwise, cmd[0] is
+// invoked with the remaining arguments. Entries that use WinRM should therefore set
+// their os.login_shell attribute to powershell.
+//
+// WinRM doesn't support TTYs, so opts.Tty is ignored; the command's terminated when ctx
+// is cancelled. opts.Elevate is also ignored because WinRM sessions run with the user's
+// full privileges. Running a command as a different user (opts.User) isn't supported.
+func ExecWinRM(ctx context.Context, target WinRMTarget, cmd []string, opts plugin.ExecOptions) (plugin.ExecCommand, error) {
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("a command must be provided")
+	}
+	if opts.User != "" {
+		return nil, fmt.Errorf("running a command as a different user is not supported over WinRM")
+	}
+
+	c := newWinRMClient(target)
+	shellID, err := c.createShell(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create a WinRM shell on %v: %v", target.Host, err)
+	}
+	activity.Record(ctx, "Created WinRM shell %v on %v", shellID, target.Host)
+	deleteShell := func() {
+		// Use a new context because ctx may have been cancelled.
+		err := c.deleteShell(context.Background(), shellID)
+		activity.Record(ctx, "Deleted WinRM shell %v on %v: %v", shellID, target.Host, err)
+	}
+
+	commandID, err := c.runCommand(ctx, shellID, powershellCommand(cmd, opts))
+	if err != nil {
+		deleteShell()
+		return nil, fmt.Errorf("Failed to run the command on %v: %v", target.Host, err)
+	}
+
+	// Send stdin in chunks so that large inputs don't exceed WinRM's maximum envelope size.
+	if opts.Stdin != nil {
+		go func() {
+			buf := make([]byte, winrmStdinChunkSize)
+			for {
+				n, readErr := opts.Stdin.Read(buf)
+				eof := readErr != nil
+				if n > 0 || eof {
+					if err := c.send(ctx, shellID, commandID, buf[:n], eof); err != nil {
+						activity.Record(ctx, "Failed to send stdin to %v: %v", target.Host, err)
+						return
+					}
+				}
+				if eof {
+					if readErr != io.EOF {
+						activity.Record(ctx, "Failed to read stdin for %v: %v", target.Host, readErr)
+					}
+					return
+				}
+			}
+		}()
+	}
+
+	execCmd := plugin.NewExecCommand(ctx)
+	done := make(chan struct{})
+	execCmd.SetStopFunc(func() {
+		select {
+		case <-done:
+			// The command's finished, so there's nothing to stop.
+		default:
+			err := c.terminate(context.Background(), shellID, commandID)
+			activity.Record(ctx, "Terminated the command on %v on context termination: %v", target.Host, err)
+		}
+	})
+
+	// Receive the command's output until it's finished. The shell's deleted before the
+	// result's set so that it's cleaned up by the time callers see the exit code.
+	go func() {
+		for {
+			finished, exitCode, err := c.receive(ctx, shellID, commandID, execCmd.Stdout(), execCmd.Stderr())
+			if err != nil {
+				close(done)
+				deleteShell()
+				execCmd.CloseStreamsWithError(err)
+				execCmd.SetExitCodeErr(err)
+				return
+			}
+			if finished {
+				activity.Record(ctx, "Exec on %v exited %v", target.Host, exitCode)
+				close(done)
+				deleteShell()
+				execCmd.CloseStreamsWithError(nil)
+				execCmd.SetExitCode(exitCode)
+				return
+			}
+		}
+	}()
+	return execCmd, nil
+}
+
+// powershellCommand returns a command line that runs cmd with PowerShell in opts'
+// environment and working directory. The script's encoded so that it doesn't need
+// to be escaped for cmd.exe.
+func powershellCommand(cmd []string, opts plugin.ExecOptions) string {
+	var script strings.Builder
+	// Propagate failures as a non-zero exit code. $? is false if the script's last
+	// statement failed, and $LASTEXITCODE is the exit code of the last native command.
+	script.WriteString("$LASTEXITCODE = 0\n")
+	names := make([]string, 0, len(opts.Env))
+	for name := range opts.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		script.WriteString("[Environment]::SetEnvironmentVariable(" + powershellQuote(name) + ", " + powershellQuote(opts.Env[name]) + ")\n")
+	}
+	if opts.Dir != "" {
+		script.WriteString("Set-Location -LiteralPath " + powershellQuote(opts.Dir) + " -ErrorAction Stop\n")
+	}
+	if len(cmd) > 1 {
+		quoted := make([]string, len(cmd))
+		for i, arg := range cmd {
+			quoted[i] = powershellQuote(arg)
+		}
+		script.WriteString("& " + strings.Join(quoted, " "))
+	} else {
+		script.WriteString(cmd[0])
+	}
+	script.WriteString("\nif (!$?) { if ($LASTEXITCODE) { exit $LASTEXITCODE }; exit 1 }\nexit $LASTEXITCODE")
+
+	encoded := make([]byte, 0, script.Len()*2)
+	for _, r := range utf16.Encode([]rune(script.String())) {
+		encoded = append(encoded, 0, 0)
+		binary.LittleEndian.PutUint16(encoded[len(encoded)-2:], r)
+	}
+	return "powershell -NoProfile -NonInteractive -EncodedCommand " + base64.StdEncoding.EncodeToString(encoded)
+}
+
+func powershellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+const (
+	winrmStdinChunkSize   = 32 * 1024
+	winrmOperationTimeout = 60 * time.Second
+)
+
+// winrmClient sends WS-Management requests to a WinRM endpoint. The requests are
+// built and their responses are parsed by the winrm package. The client authenticates
+// with NTLM via ntlmssp.
+type winrmClient struct {
+	target WinRMTarget
+	url    string
+	params *winrm.Parameters
+	http   *http.Client
+}
+
+func newWinRMClient(target WinRMTarget) *winrmClient {
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if target.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &winrmClient{
+		target: target,
+		url:    target.endpoint(),
+		params: winrm.NewParameters(fmt.Sprintf("PT%vS", int(winrmOperationTimeout.Seconds())), "en-US", 153600),
+		http: &http.Client{
+			// The negotiator converts the request's basic auth credentials to NTLM if the
+			// target supports it. Otherwise, it retries the request with basic auth, which
+			// noCleartextPasswords refuses to send over HTTP.
+			Transport: ntlmssp.Negotiator{RoundTripper: noCleartextPasswords{transport}},
+			// Receive requests wait up to the operation timeout for output, so allow
+			// for that plus some slack.
+			Timeout: winrmOperationTimeout + 30*time.Second,
+		},
+	}
+}
+
+// noCleartextPasswords refuses to send HTTP basic auth credentials over plain HTTP.
+type noCleartextPasswords struct {
+	http.RoundTripper
+}
+
+func (rt noCleartextPasswords) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, _, ok := req.BasicAuth(); ok && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("the host doesn't support NTLM authentication, and basic authentication is only supported over HTTPS")
+	}
+	return rt.RoundTripper.RoundTrip(req)
+}
+
+// winrmFault is a SOAP fault returned by the WinRM endpoint.
+type winrmFault struct {
+	Subcode string `xml:"Body>Fault>Code>Subcode>Value"`
+	Reason  string `xml:"Body>Fault>Reason>Text"`
+}
+
+func (f winrmFault) Error() string {
+	return strings.TrimSpace(f.Reason)
+}
+
+// timedOut returns true if the fault's because no output was available within
+// the operation timeout.
+func (f winrmFault) timedOut() bool {
+	return strings.HasSuffix(f.Subcode, ":TimedOut")
+}
+
+// post sends the request, then returns the response.
+func (c *winrmClient) post(ctx context.Context, request *soap.SoapMessage) (string, error) {
+	defer request.Free()
+	req, err := http.NewRequest(http.MethodPost, c.url, strings.NewReader(request.String()))
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	req.SetBasicAuth(c.target.User, c.target.Password)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return string(body), nil
+	case http.StatusUnauthorized:
+		return "", fmt.Errorf("authentication failed for %v", c.target.User)
+	default:
+		var fault winrmFault
+		if xml.Unmarshal(body, &fault) == nil && fault.Reason != "" {
+			return "", fault
+		}
+		return "", fmt.Errorf("request failed with status %v", resp.Status)
+	}
+}
+
+// createShell creates a shell, then returns its ID.
+func (c *winrmClient) createShell(ctx context.Context) (string, error) {
+	resp, err := c.post(ctx, winrm.NewOpenShellRequest(c.url, c.params))
+	if err != nil {
+		return "", err
+	}
+	shellID, err := winrm.ParseOpenShellResponse(resp)
+	if err == nil && shellID == "" {
+		err = fmt.Errorf("the response did not include the shell's ID")
+	}
+	return shellID, err
+}
+
+func (c *winrmClient) deleteShell(ctx context.Context, shellID string) error {
+	_, err := c.post(ctx, winrm.NewDeleteShellRequest(c.url, shellID, c.params))
+	return err
+}
+
+// runCommand starts the command line in the shell, then returns the command's ID.
+func (c *winrmClient) runCommand(ctx context.Context, shellID string, commandLine string) (string, error) {
+	resp, err := c.post(ctx, winrm.NewExecuteCommandRequest(c.url, shellID, commandLine, nil, c.params))
+	if err != nil {
+		return "", err
+	}
+	commandID, err := winrm.ParseExecuteCommandResponse(resp)
+	if err == nil && commandID == "" {
+		err = fmt.Errorf("the response did not include the command's ID")
+	}
+	return commandID, err
+}
+
+// send sends data to the command's stdin. If eof is true, then the command's stdin
+// is closed after data's sent.
+func (c *winrmClient) send(ctx context.Context, shellID, commandID string, data []byte, eof bool) error {
+	_, err := c.post(ctx, winrm.NewSendInputRequest(c.url, shellID, commandID, data, eof, c.params))
+	return err
+}
+
+// receive writes the command's next batch of output to stdout and stderr. It returns
+// true and the command's exit code once the command's finished. The batch is empty if
+// no output was available within the operation timeout.
+func (c *winrmClient) receive(ctx context.Context, shellID, commandID string, stdout, stderr io.Writer) (bool, int, error) {
+	resp, err := c.post(ctx, winrm.NewGetOutputRequest(c.url, shellID, commandID, "stdout stderr", c.params))
+	if err != nil {
+		if fault, ok := err.(winrmFault); ok && fault.timedOut() {
+			return false, 0, nil
+		}
+		return false, 0, err
+	}
+	return winrm.ParseSlurpOutputErrResponse(resp, stdout, stderr)
+}
+
+// terminate sends the terminate signal to the command.
+func (c *winrmClient) terminate(ctx context.Context, shellID, commandID string) error {
+	_, err := c.post(ctx, winrm.NewSignalRequest(c.url, shellID, commandID, c.params))
+	return err
+}
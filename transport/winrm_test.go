@@ -0,0 +1,276 @@
+package transport
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/suite"
+)
+
+type WinRMTestSuite struct {
+	suite.Suite
+}
+
+// decodePowershellCommand returns the script that's run by a command line returned by
+// powershellCommand.
+func decodePowershellCommand(commandLine string) (string, error) {
+	const prefix = "powershell -NoProfile -NonInteractive -EncodedCommand "
+	if !strings.HasPrefix(commandLine, prefix) {
+		return "", fmt.Errorf("%v is not an encoded PowerShell command", commandLine)
+	}
+	encoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(commandLine, prefix))
+	if err != nil {
+		return "", err
+	}
+	runes := make([]uint16, len(encoded)/2)
+	for i := range runes {
+		runes[i] = binary.LittleEndian.Uint16(encoded[2*i:])
+	}
+	return string(utf16.Decode(runes)), nil
+}
+
+func (suite *WinRMTestSuite) TestPowershellCommand() {
+	script, err := decodePowershellCommand(powershellCommand([]string{"Get-Content 'C:\\ü.txt'"}, plugin.ExecOptions{}))
+	if suite.NoError(err) {
+		suite.Contains(script, "\nGet-Content 'C:\\ü.txt'\n")
+	}
+
+	script, err = decodePowershellCommand(powershellCommand([]string{"echo", "it's", "a test"}, plugin.ExecOptions{}))
+	if suite.NoError(err) {
+		suite.Contains(script, "\n& 'echo' 'it''s' 'a test'\n")
+	}
+
+	opts := plugin.ExecOptions{Env: map[string]string{"FOO": "it's", "BAR": "baz"}, Dir: `C:\Program Files`}
+	script, err = decodePowershellCommand(powershellCommand([]string{"dir"}, opts))
+	if suite.NoError(err) {
+		suite.Contains(script, "\n[Environment]::SetEnvironmentVariable('BAR', 'baz')\n"+
+			"[Environment]::SetEnvironmentVariable('FOO', 'it''s')\n"+
+			"Set-Location -LiteralPath 'C:\\Program Files' -ErrorAction Stop\n"+
+			"dir\n")
+	}
+}
+
+func (suite *WinRMTestSuite) TestEndpoint() {
+	suite.Equal("https://example.com:5986/wsman", WinRMTarget{Host: "example.com"}.endpoint())
+	suite.Equal("http://example.com:5985/wsman", WinRMTarget{Host: "example.com", HTTP: true}.endpoint())
+	suite.Equal("https://[::1]:443/wsman", WinRMTarget{Host: "::1", Port: 443}.endpoint())
+}
+
+func (suite *WinRMTestSuite) TestExecWinRM() {
+	server := newFakeWinRMServer(true)
+	defer server.Close()
+	target := server.target()
+
+	opts := plugin.ExecOptions{
+		Env:   map[string]string{"FOO": "bar & baz"},
+		Dir:   `C:\Users`,
+		Stdin: strings.NewReader("some input"),
+	}
+	cmd, err := ExecWinRM(context.Background(), target, []string{"Get-Content 'C:\\foo.txt'"}, opts)
+	if !suite.NoError(err) {
+		return
+	}
+
+	var stdout, stderr strings.Builder
+	for chunk := range cmd.OutputCh() {
+		if suite.NoError(chunk.Err) {
+			switch chunk.StreamID {
+			case plugin.Stdout:
+				stdout.WriteString(chunk.Data)
+			case plugin.Stderr:
+				stderr.WriteString(chunk.Data)
+			}
+		}
+	}
+	exitCode, err := cmd.ExitCode()
+	if suite.NoError(err) {
+		suite.Equal(3, exitCode)
+	}
+	suite.Equal("hello world", stdout.String())
+	suite.Equal("oops", stderr.String())
+
+	server.mux.Lock()
+	defer server.mux.Unlock()
+	script, err := decodePowershellCommand(server.command)
+	if suite.NoError(err) {
+		suite.Contains(script, "\n[Environment]::SetEnvironmentVariable('FOO', 'bar & baz')\n")
+		suite.Contains(script, "\nSet-Location -LiteralPath 'C:\\Users' -ErrorAction Stop\n")
+		suite.Contains(script, "\nGet-Content 'C:\\foo.txt'\n")
+	}
+	suite.Equal("some input", server.stdin.String())
+	suite.True(server.stdinClosed)
+	suite.True(server.deleted)
+}
+
+func (suite *WinRMTestSuite) TestExecWinRM_AuthenticationFailure() {
+	server := newFakeWinRMServer(true)
+	defer server.Close()
+	target := server.target()
+	target.Password = "wrong"
+
+	_, err := ExecWinRM(context.Background(), target, []string{"dir"}, plugin.ExecOptions{})
+	suite.EqualError(err, "Failed to create a WinRM shell on 127.0.0.1: authentication failed for Administrator")
+}
+
+func (suite *WinRMTestSuite) TestExecWinRM_RefusesBasicAuthOverHTTP() {
+	server := newFakeWinRMServer(false)
+	defer server.Close()
+	target := server.target()
+
+	_, err := ExecWinRM(context.Background(), target, []string{"dir"}, plugin.ExecOptions{})
+	if suite.Error(err) {
+		suite.Contains(err.Error(), "basic authentication is only supported over HTTPS")
+	}
+	server.mux.Lock()
+	defer server.mux.Unlock()
+	suite.False(server.sawPassword)
+}
+
+func (suite *WinRMTestSuite) TestExecWinRM_UserIsNotSupported() {
+	_, err := ExecWinRM(context.Background(), WinRMTarget{Host: "example.com"}, []string{"dir"}, plugin.ExecOptions{User: "other"})
+	suite.EqualError(err, "running a command as a different user is not supported over WinRM")
+}
+
+func TestWinRM(t *testing.T) {
+	suite.Run(t, new(WinRMTestSuite))
+}
+
+// fakeWinRMServer implements enough of WinRM to run a single command. The command
+// writes "hello world" to stdout and "oops" to stderr, then exits 3. It only supports
+// HTTP basic auth.
+type fakeWinRMServer struct {
+	*httptest.Server
+	mux         sync.Mutex
+	receives    int
+	sawPassword bool
+	command     string
+	stdin       strings.Builder
+	stdinClosed bool
+	deleted     bool
+}
+
+type fakeWinRMRequest struct {
+	Header struct {
+		Action string `xml:"Action"`
+	} `xml:"Header"`
+	Body struct {
+		Command string `xml:"CommandLine>Command"`
+		Stream  struct {
+			End  bool   `xml:"End,attr"`
+			Data string `xml:",chardata"`
+		} `xml:"Send>Stream"`
+	} `xml:"Body"`
+}
+
+const fakeWinRMEnvelope = `<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:w="http://schemas.dmtf.org/wbem/wsman/1/wsman.xsd" xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell"><s:Body>%v</s:Body></s:Envelope>`
+
+const (
+	fakeWinRMShellURI      = "http://schemas.microsoft.com/wbem/wsman/1/windows/shell"
+	fakeWinRMCreateAction  = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Create"
+	fakeWinRMDeleteAction  = "http://schemas.xmlsoap.org/ws/2004/09/transfer/Delete"
+	fakeWinRMCommandAction = fakeWinRMShellURI + "/Command"
+	fakeWinRMSendAction    = fakeWinRMShellURI + "/Send"
+	fakeWinRMReceiveAction = fakeWinRMShellURI + "/Receive"
+)
+
+func newFakeWinRMServer(https bool) *fakeWinRMServer {
+	server := &fakeWinRMServer{}
+	if https {
+		server.Server = httptest.NewTLSServer(http.HandlerFunc(server.handle))
+	} else {
+		server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	}
+	return server
+}
+
+func (s *fakeWinRMServer) target() WinRMTarget {
+	u, _ := url.Parse(s.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return WinRMTarget{
+		Host:     u.Hostname(),
+		Port:     uint(port),
+		User:     "Administrator",
+		Password: "password",
+		HTTP:     u.Scheme == "http",
+		Insecure: true,
+	}
+}
+
+func (s *fakeWinRMServer) handle(w http.ResponseWriter, r *http.Request) {
+	user, password, ok := r.BasicAuth()
+	if ok {
+		s.mux.Lock()
+		s.sawPassword = true
+		s.mux.Unlock()
+	}
+	if !ok || user != "Administrator" || password != "password" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="WSMAN"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req fakeWinRMRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	var body string
+	switch req.Header.Action {
+	case fakeWinRMCreateAction:
+		body = `<x:ResourceCreated xmlns:x="http://schemas.xmlsoap.org/ws/2004/09/transfer"><a:ReferenceParameters xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing"><w:SelectorSet><w:Selector Name="ShellId">shell-1</w:Selector></w:SelectorSet></a:ReferenceParameters></x:ResourceCreated>`
+	case fakeWinRMCommandAction:
+		s.command = req.Body.Command
+		body = `<rsp:CommandResponse><rsp:CommandId>command-1</rsp:CommandId></rsp:CommandResponse>`
+	case fakeWinRMSendAction:
+		data, _ := base64.StdEncoding.DecodeString(req.Body.Stream.Data)
+		s.stdin.Write(data)
+		s.stdinClosed = req.Body.Stream.End
+	case fakeWinRMReceiveAction:
+		s.receives++
+		switch s.receives {
+		case 1:
+			// No output was available within the operation timeout.
+			w.WriteHeader(http.StatusInternalServerError)
+			body = `<s:Fault><s:Code><s:Value>s:Receiver</s:Value><s:Subcode><s:Value>w:TimedOut</s:Value></s:Subcode></s:Code><s:Reason><s:Text xml:lang="en-US">The WS-Management service cannot complete the operation within the time specified in OperationTimeout.</s:Text></s:Reason></s:Fault>`
+		case 2:
+			body = `<rsp:ReceiveResponse>` +
+				`<rsp:Stream Name="stdout" CommandId="command-1">` + base64.StdEncoding.EncodeToString([]byte("hello ")) + `</rsp:Stream>` +
+				`<rsp:Stream Name="stderr" CommandId="command-1">` + base64.StdEncoding.EncodeToString([]byte("oops")) + `</rsp:Stream>` +
+				`<rsp:CommandState CommandId="command-1" State="` + fakeWinRMShellURI + `/CommandState/Running"/>` +
+				`</rsp:ReceiveResponse>`
+		default:
+			if !s.stdinClosed {
+				// The command's still reading its input.
+				body = `<rsp:ReceiveResponse><rsp:CommandState CommandId="command-1" State="` + fakeWinRMShellURI + `/CommandState/Running"/></rsp:ReceiveResponse>`
+				break
+			}
+			body = `<rsp:ReceiveResponse>` +
+				`<rsp:Stream Name="stdout" CommandId="command-1">` + base64.StdEncoding.EncodeToString([]byte("world")) + `</rsp:Stream>` +
+				`<rsp:Stream Name="stdout" CommandId="command-1" End="true"></rsp:Stream>` +
+				`<rsp:CommandState CommandId="command-1" State="` + fakeWinRMShellURI + `/CommandState/Done"><rsp:ExitCode>3</rsp:ExitCode></rsp:CommandState>` +
+				`</rsp:ReceiveResponse>`
+		}
+	case fakeWinRMDeleteAction:
+		s.deleted = true
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/soap+xml;charset=UTF-8")
+	fmt.Fprintf(w, fakeWinRMEnvelope, body)
+}
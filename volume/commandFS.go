@@ -0,0 +1,287 @@
+package volume
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	pathpkg "path"
+	"strconv"
+	"strings"
+
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+)
+
+// Commands are the commands that a CommandFS runs on its executor. Each command is a list of
+// arguments, where "{path}" is replaced with the path of the volume node that the command
+// operates on and "{maxdepth}" is replaced with the volume's maxdepth. Paths start at "/",
+// the volume's root. List and Read are required; the volume doesn't support streaming if
+// Stream is empty. Write, Create, Mkdir and Delete are only used by a WritableCommandFS,
+// which requires all of them.
+//
+// {path} is substituted as-is, so it's always a single argument. The only exception is the
+// script of a command that's run by a shell's -c option (like `sh -c`), where {path} is quoted
+// so that the shell doesn't interpret it. Don't quote {path} in the script yourself.
+type Commands struct {
+	// List outputs a line for each node in {path}, up to {maxdepth} levels deep, in the format
+	// of `stat -c '%s %X %Y %Z %f %n'`: its size, atime, mtime and ctime as seconds since the
+	// epoch, its mode in hex and its path.
+	List []string `json:"list"`
+	// Read outputs the content of the file at {path}.
+	Read []string `json:"read"`
+	// Stream outputs updates to the file at {path} until it's stopped.
+	Stream []string `json:"stream"`
+	// Delete deletes the file or directory at {path}.
+	Delete []string `json:"delete"`
+	// Write replaces the content of the file at {path} with its stdin.
+	Write []string `json:"write"`
+	// Create creates an empty file at {path}.
+	Create []string `json:"create"`
+	// Mkdir creates a directory at {path}.
+	Mkdir []string `json:"mkdir"`
+}
+
+func (c Commands) hasWriteCommands() bool {
+	return len(c.Delete) > 0 || len(c.Write) > 0 || len(c.Create) > 0 || len(c.Mkdir) > 0
+}
+
+// commandFS implements the volume operations of CommandFS and WritableCommandFS.
+type commandFS struct {
+	plugin.EntryBase
+	executor plugin.Execable
+	commands Commands
+	maxdepth int
+}
+
+// CommandFS presents a read-only volume on an Execable resource that's accessed by running the
+// supplied commands. Unlike FS, it isn't tied to a shell, so it can represent things like
+// archives or object stores that a resource's CLI tools can access. Use WritableCommandFS for
+// a volume that can be modified.
+type CommandFS struct {
+	commandFS
+}
+
+// NewCommandFS creates a new CommandFS entry with the given name, using the supplied executor to
+// run the commands that satisfy volume operations. The commands can't include the ones that
+// modify the volume.
+func NewCommandFS(ctx context.Context, name string, executor plugin.Execable, commands Commands, maxdepth int) (*CommandFS, error) {
+	if len(commands.List) == 0 || len(commands.Read) == 0 {
+		return nil, fmt.Errorf("the list and read commands are required")
+	}
+	if commands.hasWriteCommands() {
+		return nil, fmt.Errorf("the write, create, mkdir and delete commands are only supported by a writable volume")
+	}
+
+	fs := &CommandFS{}
+	fs.init(name, executor, commands, maxdepth)
+	if _, err := plugin.List(ctx, fs); err != nil {
+		fs.MarkInaccessible(ctx, err)
+	}
+	return fs, nil
+}
+
+func (d *commandFS) init(name string, executor plugin.Execable, commands Commands, maxdepth int) {
+	d.EntryBase = plugin.NewEntry(name)
+	d.executor = executor
+	d.commands = commands
+	d.maxdepth = maxdepth
+	d.SetTTLOf(plugin.ListOp, ListTTL)
+}
+
+// VolumeReadOnly marks the CommandFS as ReadOnly.
+func (d *CommandFS) VolumeReadOnly() {}
+
+// ChildSchemas returns the CommandFS entry's child schema
+func (d *CommandFS) ChildSchemas() []*plugin.EntrySchema {
+	return ReadOnlyChildSchemas()
+}
+
+// Schema returns the CommandFS entry's schema
+func (d *CommandFS) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(d, "volume").
+		SetDescription(commandFSDescription)
+}
+
+// List creates a hierarchy of the volume from the output of the list command.
+func (d *CommandFS) List(ctx context.Context) ([]plugin.Entry, error) {
+	return List(ctx, d)
+}
+
+// WritableCommandFS is a CommandFS whose files can be written, and that supports creating and
+// deleting files and directories.
+type WritableCommandFS struct {
+	commandFS
+}
+
+// NewWritableCommandFS creates a new WritableCommandFS entry with the given name, using the
+// supplied executor to run the commands that satisfy volume operations.
+func NewWritableCommandFS(ctx context.Context, name string, executor plugin.Execable, commands Commands, maxdepth int) (*WritableCommandFS, error) {
+	if len(commands.List) == 0 || len(commands.Read) == 0 ||
+		len(commands.Write) == 0 || len(commands.Create) == 0 || len(commands.Mkdir) == 0 || len(commands.Delete) == 0 {
+		return nil, fmt.Errorf("the list, read, write, create, mkdir and delete commands are required")
+	}
+
+	fs := &WritableCommandFS{}
+	fs.init(name, executor, commands, maxdepth)
+	if _, err := plugin.List(ctx, fs); err != nil {
+		fs.MarkInaccessible(ctx, err)
+	}
+	return fs, nil
+}
+
+// ChildSchemas returns the WritableCommandFS entry's child schema
+func (d *WritableCommandFS) ChildSchemas() []*plugin.EntrySchema {
+	return ChildSchemas()
+}
+
+// Schema returns the WritableCommandFS entry's schema
+func (d *WritableCommandFS) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(d, "volume").
+		SetDescription(writableCommandFSDescription)
+}
+
+// List creates a hierarchy of the volume from the output of the list command.
+func (d *WritableCommandFS) List(ctx context.Context) ([]plugin.Entry, error) {
+	return List(ctx, d)
+}
+
+// Create creates a file (or a directory if isParent is true) in the root directory.
+func (d *WritableCommandFS) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	return Create(ctx, d, name, isParent)
+}
+
+// shells are the shells whose -c option runs a script in which {path} is quoted.
+var shells = map[string]bool{"sh": true, "bash": true, "dash": true, "ash": true, "ksh": true, "zsh": true}
+
+// isShellScript returns true if command runs its third argument as a shell script.
+func isShellScript(command []string) bool {
+	return len(command) >= 3 && shells[pathpkg.Base(command[0])] && command[1] == "-c"
+}
+
+// shellQuote quotes s so that a POSIX shell treats it as a single word.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// command returns the command with its placeholders replaced. It returns an error if the
+// volume doesn't support the operation.
+func (d *commandFS) command(op string, command []string, path string) ([]string, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("the %v volume does not support %v", d.Name(), op)
+	}
+	// List uses "" to mean root. Translate for executing on the target.
+	if path == RootPath {
+		path = "/"
+	}
+	maxdepth := strconv.Itoa(d.maxdepth)
+	replacer := strings.NewReplacer("{path}", path, "{maxdepth}", maxdepth)
+	cmdline := make([]string, len(command))
+	for i, arg := range command {
+		cmdline[i] = replacer.Replace(arg)
+	}
+	if isShellScript(command) {
+		cmdline[2] = strings.NewReplacer("{path}", shellQuote(path), "{maxdepth}", maxdepth).Replace(command[2])
+	}
+	return cmdline, nil
+}
+
+// run runs the operation's command, returning its stdout.
+func (d *commandFS) run(ctx context.Context, op string, command []string, path string, stdin io.Reader) (*bytes.Buffer, error) {
+	cmdline, err := d.command(op, command, path)
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Running %v on %v", cmdline, plugin.ID(d.executor))
+
+	// Skip tty because it would mangle the output.
+	buf, err := exec(ctx, d.executor, cmdline, false, stdin)
+	if err != nil {
+		activity.Record(ctx, "Exec error running %+v to %v %v: %v", cmdline, op, path, err)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// VolumeList satisfies the Interface required by List to enumerate files.
+func (d *commandFS) VolumeList(ctx context.Context, path string) (DirMap, error) {
+	buf, err := d.run(ctx, "list", d.commands.List, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	// ParseStatPOSIX assumes that the output's no deeper than maxdepth, which the plugin's
+	// command might not respect.
+	maxdepth := d.maxdepth + numPathSegments(path)
+	for _, line := range strings.Split(buf.String(), "\n") {
+		segments := strings.SplitN(strings.TrimSpace(line), " ", 6)
+		if len(segments) == 6 && numPathSegments(segments[5]) > maxdepth {
+			return nil, fmt.Errorf("the list command returned %v, which is more than %v levels below %v", segments[5], d.maxdepth, path)
+		}
+	}
+	return ParseStatPOSIX(buf, RootPath, path, d.maxdepth)
+}
+
+// VolumeRead satisfies the Interface required by List to read file contents.
+func (d *commandFS) VolumeRead(ctx context.Context, path string) ([]byte, error) {
+	buf, err := d.run(ctx, "read", d.commands.Read, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// VolumeStream satisfies the Interface required by List to stream file contents.
+func (d *commandFS) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
+	cmdline, err := d.command("stream", d.commands.Stream, path)
+	if err != nil {
+		return nil, err
+	}
+	activity.Record(ctx, "Streaming %v on %v", path, plugin.ID(d.executor))
+	return ExecStream(ctx, d.executor, cmdline, false)
+}
+
+// VolumeDelete satisfies the Interface required by Delete to delete volume nodes.
+func (d *commandFS) VolumeDelete(ctx context.Context, path string) (bool, error) {
+	if _, err := d.run(ctx, "delete", d.commands.Delete, path, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// VolumeWrite satisfies the Interface required by Write to write file contents. The
+// content's streamed to the command's stdin.
+func (d *commandFS) VolumeWrite(ctx context.Context, path string, b []byte) error {
+	_, err := d.run(ctx, "write", d.commands.Write, path, bytes.NewReader(b))
+	return err
+}
+
+// VolumeCreate satisfies the Interface required by Create to create files.
+func (d *commandFS) VolumeCreate(ctx context.Context, path string) error {
+	_, err := d.run(ctx, "create", d.commands.Create, path, nil)
+	return err
+}
+
+// VolumeMkdir satisfies the Interface required by Create to create directories.
+func (d *commandFS) VolumeMkdir(ctx context.Context, path string) error {
+	_, err := d.run(ctx, "mkdir", d.commands.Mkdir, path, nil)
+	return err
+}
+
+const commandFSDescription = `
+This represents a read-only volume that Wash accesses by exec'ing commands on
+its parent. The commands are chosen by the plugin. Wash execs the list command
+whenever it invokes a List action on a directory, and the read command whenever
+it invokes a Read action on a file. Stream is supported if the plugin provided
+a stream command.
+`
+
+const writableCommandFSDescription = `
+This represents a volume that Wash accesses by exec'ing commands on its parent.
+The commands are chosen by the plugin. Wash execs the list command whenever it
+invokes a List action on a directory, and the read command whenever it invokes
+a Read action on a file. Likewise, the write, delete, create and mkdir commands
+are exec'd for the Write, Delete and Create actions. Stream is supported if the
+plugin provided a stream command.
+`
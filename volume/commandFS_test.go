@@ -0,0 +1,152 @@
+package volume
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type commandFSTestSuite struct {
+	suite.Suite
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+	exec       *mockExecutor
+	commands   Commands
+}
+
+func (suite *commandFSTestSuite) SetupTest() {
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	suite.ctx, suite.cancelFunc = context.WithCancel(ctx)
+
+	suite.exec = &mockExecutor{EntryBase: plugin.NewEntry("instance")}
+	suite.exec.SetTestID("/instance")
+	suite.commands = Commands{
+		List: []string{"archive-ls", "--depth={maxdepth}", "{path}"},
+		Read: []string{"archive-cat", "{path}"},
+	}
+}
+
+func (suite *commandFSTestSuite) TearDownTest() {
+	plugin.UnsetTestCache()
+	suite.cancelFunc()
+}
+
+func (suite *commandFSTestSuite) newFS() *CommandFS {
+	suite.exec.onExec([]string{"archive-ls", "--depth=3", "/"}, mockExecCmd{posixFixtureShort})
+	fs, err := NewCommandFS(suite.ctx, "archive", suite.exec, suite.commands, 3)
+	suite.Require().NoError(err)
+	fs.SetTestID("/instance/archive")
+	return fs
+}
+
+func (suite *commandFSTestSuite) newWritableFS() *WritableCommandFS {
+	suite.commands.Write = []string{"archive-put", "{path}"}
+	suite.commands.Create = []string{"archive-touch", "{path}"}
+	suite.commands.Mkdir = []string{"archive-mkdir", "{path}"}
+	suite.commands.Delete = []string{"archive-rm", "{path}"}
+	suite.exec.onExec([]string{"archive-ls", "--depth=3", "/"}, mockExecCmd{posixFixtureShort})
+	fs, err := NewWritableCommandFS(suite.ctx, "archive", suite.exec, suite.commands, 3)
+	suite.Require().NoError(err)
+	fs.SetTestID("/instance/archive")
+	return fs
+}
+
+func (suite *commandFSTestSuite) TestNewCommandFSRequiresListAndRead() {
+	_, err := NewCommandFS(suite.ctx, "archive", suite.exec, Commands{List: suite.commands.List}, 2)
+	suite.EqualError(err, "the list and read commands are required")
+}
+
+func (suite *commandFSTestSuite) TestNewCommandFSRejectsWriteCommands() {
+	suite.commands.Write = []string{"archive-put", "{path}"}
+	_, err := NewCommandFS(suite.ctx, "archive", suite.exec, suite.commands, 2)
+	suite.EqualError(err, "the write, create, mkdir and delete commands are only supported by a writable volume")
+}
+
+func (suite *commandFSTestSuite) TestNewWritableCommandFSRequiresAllCommands() {
+	suite.commands.Write = []string{"archive-put", "{path}"}
+	_, err := NewWritableCommandFS(suite.ctx, "archive", suite.exec, suite.commands, 2)
+	suite.EqualError(err, "the list, read, write, create, mkdir and delete commands are required")
+}
+
+func (suite *commandFSTestSuite) TestSupportedActions() {
+	fs := suite.newFS()
+	suite.ElementsMatch([]string{"list"}, plugin.SupportedActionsOf(fs))
+	entries, err := fs.List(suite.ctx)
+	if suite.NoError(err) && suite.Len(entries, 1) {
+		suite.ElementsMatch([]string{"list"}, plugin.SupportedActionsOf(entries[0]))
+	}
+
+	plugin.ClearCacheFor("/instance/archive", true)
+	writableFS := suite.newWritableFS()
+	suite.ElementsMatch([]string{"list", "create"}, plugin.SupportedActionsOf(writableFS))
+	entries, err = writableFS.List(suite.ctx)
+	if suite.NoError(err) && suite.Len(entries, 1) {
+		suite.ElementsMatch([]string{"list", "create", "delete"}, plugin.SupportedActionsOf(entries[0]))
+	}
+}
+
+func (suite *commandFSTestSuite) TestShellScriptQuotesPath() {
+	suite.commands.Read = []string{"sh", "-c", "archive-cat {path} | gunzip", "{path}"}
+	fs := suite.newFS()
+
+	suite.exec.onExec([]string{"sh", "-c", `archive-cat '/it'\''s; rm -rf' | gunzip`, "/it's; rm -rf"}, mockExecCmd{"hello"})
+	content, err := fs.VolumeRead(suite.ctx, "/it's; rm -rf")
+	if suite.NoError(err) {
+		suite.Equal([]byte("hello"), content)
+	}
+	suite.exec.AssertExpectations(suite.T())
+}
+
+func (suite *commandFSTestSuite) TestListAndRead() {
+	fs := suite.newFS()
+
+	suite.exec.onExec([]string{"archive-ls", "--depth=3", "/var/log/path"}, mockExecCmd{posixFixtureDeep})
+	entries, err := fs.VolumeList(suite.ctx, "/var/log/path")
+	if suite.NoError(err) {
+		suite.Contains(entries["/var/log/path/has"], "got")
+	}
+
+	// Output that's deeper than maxdepth is an error rather than a panic.
+	suite.exec.onExec([]string{"archive-ls", "--depth=3", "/var"}, mockExecCmd{posixFixture})
+	_, err = fs.VolumeList(suite.ctx, "/var")
+	suite.EqualError(err, "the list command returned /var/log/path/has/got, which is more than 3 levels below /var")
+
+	suite.exec.onExec([]string{"archive-cat", "/var/log/path1/a file"}, mockExecCmd{"hello"})
+	content, err := fs.VolumeRead(suite.ctx, "/var/log/path1/a file")
+	if suite.NoError(err) {
+		suite.Equal([]byte("hello"), content)
+	}
+	suite.exec.AssertExpectations(suite.T())
+}
+
+func (suite *commandFSTestSuite) TestWrite() {
+	fs := suite.newWritableFS()
+
+	var stdin []byte
+	suite.exec.On("Exec", mock.Anything, "archive-put", []string{"/var/log"}, mock.Anything).Return(mockExecCmd{}, nil).Run(func(args mock.Arguments) {
+		stdin, _ = ioutil.ReadAll(args.Get(3).(plugin.ExecOptions).Stdin)
+	})
+	if suite.NoError(fs.VolumeWrite(suite.ctx, "/var/log", []byte("content"))) {
+		suite.Equal("content", string(stdin))
+	}
+	suite.exec.AssertExpectations(suite.T())
+}
+
+func (suite *commandFSTestSuite) TestUnsupportedOperations() {
+	fs := suite.newFS()
+
+	_, err := fs.VolumeDelete(suite.ctx, "/var")
+	suite.EqualError(err, "the archive volume does not support delete")
+	_, err = fs.VolumeStream(suite.ctx, "/var")
+	suite.EqualError(err, "the archive volume does not support stream")
+	suite.EqualError(fs.VolumeMkdir(suite.ctx, "/var/tmp"), "the archive volume does not support mkdir")
+}
+
+func TestCommandFS(t *testing.T) {
+	suite.Run(t, new(commandFSTestSuite))
+}
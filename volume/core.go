@@ -9,6 +9,7 @@ package volume
 import (
 	"context"
 	"io"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -33,6 +34,32 @@ type Interface interface {
 	VolumeStream(ctx context.Context, path string) (io.ReadCloser, error)
 	// Deletes the volume node at the specified path. Mirrors plugin.Deletable#Delete
 	VolumeDelete(ctx context.Context, path string) (bool, error)
+	// Replaces the content of the file at the specified path. Mirrors plugin.Writable#Write
+	VolumeWrite(ctx context.Context, path string, b []byte) error
+	// Creates an empty file at the specified path. The file's parent directory already exists.
+	VolumeCreate(ctx context.Context, path string) error
+	// Creates a directory at the specified path. The directory's parent already exists.
+	VolumeMkdir(ctx context.Context, path string) error
+}
+
+// BlockReadable is implemented by an Interface that can read part of a file. The volume's files
+// are then plugin.BlockReadable, so their content is read as it's needed rather than all-at-once.
+type BlockReadable interface {
+	Interface
+
+	// Reads up to size bytes of the file at the specified path, starting at offset. Mirrors
+	// plugin.BlockReadable#Read
+	VolumeReadBlock(ctx context.Context, path string, size int64, offset int64) ([]byte, error)
+}
+
+// ReadOnly is implemented by an Interface that can't modify the volume. The volume's files and
+// directories then don't support the write, create or delete actions, so VolumeDelete,
+// VolumeWrite, VolumeCreate and VolumeMkdir aren't called.
+type ReadOnly interface {
+	Interface
+
+	// VolumeReadOnly is only used to mark the Interface as read-only.
+	VolumeReadOnly()
 }
 
 // Children represents a directory's children. It is a map of <child_basename> => <child_attributes>.
@@ -53,8 +80,33 @@ type dirMap struct {
 // ChildSchemas returns a volume's child schema
 func ChildSchemas() []*plugin.EntrySchema {
 	return []*plugin.EntrySchema{
-		(&dir{}).Schema(),
-		(&file{}).Schema(),
+		(&dir{readOnlyDir: &readOnlyDir{}}).Schema(),
+		(&file{readOnlyFile: &readOnlyFile{}}).Schema(),
+	}
+}
+
+// ReadOnlyChildSchemas returns the child schema of a volume that implements ReadOnly
+func ReadOnlyChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&readOnlyDir{}).Schema(),
+		(&readOnlyFile{}).Schema(),
+	}
+}
+
+// BlockReadableChildSchemas returns the child schema of a volume that implements BlockReadable
+func BlockReadableChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&blockDir{dir: &dir{readOnlyDir: &readOnlyDir{}}}).Schema(),
+		(&blockFile{file: &file{readOnlyFile: &readOnlyFile{}}}).Schema(),
+	}
+}
+
+// ReadOnlyBlockReadableChildSchemas returns the child schema of a volume that implements both
+// BlockReadable and ReadOnly
+func ReadOnlyBlockReadableChildSchemas() []*plugin.EntrySchema {
+	return []*plugin.EntrySchema{
+		(&readOnlyBlockDir{readOnlyDir: &readOnlyDir{}}).Schema(),
+		(&readOnlyBlockFile{readOnlyFile: &readOnlyFile{}}).Schema(),
 	}
 }
 
@@ -66,7 +118,13 @@ const RootPath = ""
 // Requests are cached against the supplied Interface using the VolumeListCB op.
 func List(ctx context.Context, impl Interface) ([]plugin.Entry, error) {
 	// Start with the implementation as the cache key so we re-use data we get from it for subdirectory queries.
-	return newDir("dummy", plugin.EntryAttributes{}, impl, RootPath).List(ctx)
+	return newReadOnlyDir("dummy", plugin.EntryAttributes{}, impl, RootPath).List(ctx)
+}
+
+// Create creates a new file (or directory if isParent is true) in the root of the volume.
+// The entry implementing volume.Interface should use it to implement plugin.Creatable.
+func Create(ctx context.Context, impl Interface, name string, isParent bool) (plugin.Entry, error) {
+	return newDir("dummy", plugin.EntryAttributes{}, impl, RootPath).Create(ctx, name, isParent)
 }
 
 // ListTTL represents the List op's TTL. The entry implementing volume.Interface should
@@ -80,7 +138,7 @@ func deleteNode(ctx context.Context, impl Interface, path string, dirmap *dirMap
 	if err != nil {
 		return
 	}
-	if !deleted {
+	if !deleted || dirmap == nil {
 		return
 	}
 
@@ -97,3 +155,64 @@ func deleteNode(ctx context.Context, impl Interface, path string, dirmap *dirMap
 	}
 	return
 }
+
+// writeNode writes the file at path, then updates its size and mtime in the dirmap.
+func writeNode(ctx context.Context, impl Interface, path string, b []byte, dirmap *dirMap) error {
+	if err := impl.VolumeWrite(ctx, path, b); err != nil {
+		return err
+	}
+	if dirmap == nil {
+		return nil
+	}
+
+	dirmap.mux.Lock()
+	defer dirmap.mux.Unlock()
+
+	segments := strings.Split(path, "/")
+	parentPath := strings.Join(segments[:len(segments)-1], "/")
+	if parentChildren, ok := dirmap.mp[parentPath]; ok {
+		basename := segments[len(segments)-1]
+		if attr, ok := parentChildren[basename]; ok {
+			attr.SetSize(uint64(len(b))).SetMtime(time.Now())
+			parentChildren[basename] = attr
+		}
+	}
+	return nil
+}
+
+// createNode creates a file (or directory if isDir is true) at path, then adds it to
+// its parent's children in the dirmap. It returns the new node's attributes.
+func createNode(ctx context.Context, impl Interface, path string, isDir bool, dirmap *dirMap) (plugin.EntryAttributes, error) {
+	var attr plugin.EntryAttributes
+	var err error
+	if isDir {
+		err = impl.VolumeMkdir(ctx, path)
+		attr.SetMode(0755 | os.ModeDir)
+	} else {
+		err = impl.VolumeCreate(ctx, path)
+		attr.SetMode(0644).SetSize(0)
+	}
+	if err != nil {
+		return attr, err
+	}
+	now := time.Now()
+	attr.SetCrtime(now).SetMtime(now).SetCtime(now).SetAtime(now)
+	if dirmap == nil {
+		return attr, nil
+	}
+
+	dirmap.mux.Lock()
+	defer dirmap.mux.Unlock()
+
+	segments := strings.Split(path, "/")
+	parentPath := strings.Join(segments[:len(segments)-1], "/")
+	if parentChildren := dirmap.mp[parentPath]; parentChildren != nil {
+		basename := segments[len(segments)-1]
+		parentChildren[basename] = attr
+		if isDir {
+			// The new directory's empty, so there's nothing left to explore.
+			dirmap.mp[path] = Children{}
+		}
+	}
+	return attr, nil
+}
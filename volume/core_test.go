@@ -69,6 +69,107 @@ func (s *coreTestSuite) TestDeleteNode_DeletedNode_UpdatesDirMap() {
 	}
 }
 
+func (s *coreTestSuite) TestWriteNode_ReturnsVolumeWriteError() {
+	ctx := context.Background()
+	mockImpl := &mockDirEntry{EntryBase: plugin.NewEntry("foo")}
+	path := "bar/baz"
+	dirMap := &dirMap{
+		mp: map[string]Children{
+			"bar": map[string]plugin.EntryAttributes{
+				"baz": plugin.EntryAttributes{},
+			},
+		},
+	}
+
+	expectedErr := fmt.Errorf("failed to write")
+	mockImpl.On("VolumeWrite", ctx, path, []byte("hello")).Return(expectedErr)
+
+	err := writeNode(ctx, mockImpl, path, []byte("hello"), dirMap)
+	s.EqualError(err, expectedErr.Error())
+	attr := dirMap.mp["bar"]["baz"]
+	s.False(attr.HasSize())
+}
+
+func (s *coreTestSuite) TestWriteNode_UpdatesDirMap() {
+	ctx := context.Background()
+	mockImpl := &mockDirEntry{EntryBase: plugin.NewEntry("foo")}
+	path := "bar/baz"
+	dirMap := &dirMap{
+		mp: map[string]Children{
+			"bar": map[string]plugin.EntryAttributes{
+				"baz": plugin.EntryAttributes{},
+			},
+		},
+	}
+
+	mockImpl.On("VolumeWrite", ctx, path, []byte("hello")).Return(nil)
+
+	if s.NoError(writeNode(ctx, mockImpl, path, []byte("hello"), dirMap)) {
+		attr := dirMap.mp["bar"]["baz"]
+		s.Equal(uint64(5), attr.Size())
+		s.True(attr.HasMtime())
+	}
+}
+
+func (s *coreTestSuite) TestCreateNode_File_UpdatesDirMap() {
+	ctx := context.Background()
+	mockImpl := &mockDirEntry{EntryBase: plugin.NewEntry("foo")}
+	path := "bar/baz"
+	dirMap := &dirMap{
+		mp: map[string]Children{
+			"bar": map[string]plugin.EntryAttributes{},
+		},
+	}
+
+	mockImpl.On("VolumeCreate", ctx, path).Return(nil)
+
+	attr, err := createNode(ctx, mockImpl, path, false, dirMap)
+	if s.NoError(err) {
+		s.False(attr.Mode().IsDir())
+		s.Equal(attr, dirMap.mp["bar"]["baz"])
+		s.NotContains(dirMap.mp, path)
+	}
+}
+
+func (s *coreTestSuite) TestCreateNode_Dir_UpdatesDirMap() {
+	ctx := context.Background()
+	mockImpl := &mockDirEntry{EntryBase: plugin.NewEntry("foo")}
+	path := "bar/baz"
+	dirMap := &dirMap{
+		mp: map[string]Children{
+			"bar": map[string]plugin.EntryAttributes{},
+		},
+	}
+
+	mockImpl.On("VolumeMkdir", ctx, path).Return(nil)
+
+	attr, err := createNode(ctx, mockImpl, path, true, dirMap)
+	if s.NoError(err) {
+		s.True(attr.Mode().IsDir())
+		s.Equal(attr, dirMap.mp["bar"]["baz"])
+		s.Equal(Children{}, dirMap.mp[path])
+	}
+}
+
+func (s *coreTestSuite) TestCreateNode_ReturnsVolumeMkdirError() {
+	ctx := context.Background()
+	mockImpl := &mockDirEntry{EntryBase: plugin.NewEntry("foo")}
+	path := "bar/baz"
+	dirMap := &dirMap{
+		mp: map[string]Children{
+			"bar": map[string]plugin.EntryAttributes{},
+		},
+	}
+
+	expectedErr := fmt.Errorf("failed to mkdir")
+	mockImpl.On("VolumeMkdir", ctx, path).Return(expectedErr)
+
+	_, err := createNode(ctx, mockImpl, path, true, dirMap)
+	s.EqualError(err, expectedErr.Error())
+	s.NotContains(dirMap.mp["bar"], "baz")
+	s.NotContains(dirMap.mp, path)
+}
+
 func TestCore(t *testing.T) {
 	suite.Run(t, new(coreTestSuite))
 }
@@ -6,17 +6,18 @@ import (
 	"github.com/puppetlabs/wash/plugin"
 )
 
-// dir represents a directory in a volume. It populates a subtree from the Interface as needed.
-type dir struct {
+// readOnlyDir represents a directory in a ReadOnly volume. It populates a subtree from the
+// Interface as needed.
+type readOnlyDir struct {
 	plugin.EntryBase
 	impl   Interface
 	path   string
 	dirmap *dirMap
 }
 
-// newDir creates a dir populated from dirs.
-func newDir(name string, attr plugin.EntryAttributes, impl Interface, path string) *dir {
-	vd := &dir{
+// newReadOnlyDir creates a readOnlyDir populated from dirs.
+func newReadOnlyDir(name string, attr plugin.EntryAttributes, impl Interface, path string) *readOnlyDir {
+	vd := &readOnlyDir{
 		EntryBase: plugin.NewEntry(name),
 	}
 	vd.impl = impl
@@ -25,44 +26,69 @@ func newDir(name string, attr plugin.EntryAttributes, impl Interface, path strin
 	return vd
 }
 
-func (v *dir) ChildSchemas() []*plugin.EntrySchema {
-	return ChildSchemas()
+func (v *readOnlyDir) ChildSchemas() []*plugin.EntrySchema {
+	return ReadOnlyChildSchemas()
 }
 
-func (v *dir) Schema() *plugin.EntrySchema {
+func (v *readOnlyDir) Schema() *plugin.EntrySchema {
 	return plugin.NewEntrySchema(v, "dir").SetDescription(dirDescription)
 }
 
 // Generate children using the provided DirMap. The dir may not have a dirmap
 // stored if it's a source because it should dynamically generate it.
-func (v *dir) generateChildren(dirmap *dirMap) []plugin.Entry {
+func (v *readOnlyDir) generateChildren(dirmap *dirMap) []plugin.Entry {
 	dirmap.mux.RLock()
 	defer dirmap.mux.RUnlock()
 
 	parent := dirmap.mp[v.path]
 	entries := make([]plugin.Entry, 0, len(parent))
 	for name, attr := range parent {
-		subpath := v.path + "/" + name
-		if attr.Mode().IsDir() {
-			newEntry := newDir(name, attr, v.impl, subpath)
-			newEntry.SetTTLOf(plugin.ListOp, ListTTL)
+		entries = append(entries, v.newChild(name, attr, dirmap))
+	}
+	return entries
+}
+
+// newChild creates the child with the given name and attributes. The caller must hold
+// dirmap's lock if dirmap is not nil.
+func (v *readOnlyDir) newChild(name string, attr plugin.EntryAttributes, dirmap *dirMap) plugin.Entry {
+	subpath := v.path + "/" + name
+	_, blockReadable := v.impl.(BlockReadable)
+	_, readOnly := v.impl.(ReadOnly)
+	if attr.Mode().IsDir() {
+		newEntry := newReadOnlyDir(name, attr, v.impl, subpath)
+		newEntry.SetTTLOf(plugin.ListOp, ListTTL)
+		if dirmap != nil {
 			if d, ok := dirmap.mp[subpath]; ok && d != nil {
 				newEntry.dirmap = dirmap
 				newEntry.Prefetched()
 				newEntry.DisableCachingFor(plugin.ListOp)
 			}
-			entries = append(entries, newEntry)
-		} else {
-			newEntry := newFile(name, attr, v.impl, subpath)
-			newEntry.dirmap = dirmap
-			entries = append(entries, newEntry)
 		}
+		switch {
+		case blockReadable && readOnly:
+			return &readOnlyBlockDir{readOnlyDir: newEntry}
+		case blockReadable:
+			return &blockDir{dir: &dir{readOnlyDir: newEntry}}
+		case readOnly:
+			return newEntry
+		}
+		return &dir{readOnlyDir: newEntry}
 	}
-	return entries
+	newEntry := newReadOnlyFile(name, attr, v.impl, subpath)
+	newEntry.dirmap = dirmap
+	switch {
+	case blockReadable && readOnly:
+		return &readOnlyBlockFile{readOnlyFile: newEntry}
+	case blockReadable:
+		return &blockFile{file: &file{readOnlyFile: newEntry}}
+	case readOnly:
+		return newEntry
+	}
+	return &file{readOnlyFile: newEntry}
 }
 
 // List lists the children of the directory.
-func (v *dir) List(ctx context.Context) ([]plugin.Entry, error) {
+func (v *readOnlyDir) List(ctx context.Context) ([]plugin.Entry, error) {
 	if v.dirmap != nil {
 		// Children have been pre-populated by a source parent.
 		return v.generateChildren(v.dirmap), nil
@@ -77,10 +103,71 @@ func (v *dir) List(ctx context.Context) ([]plugin.Entry, error) {
 	return v.generateChildren(&dirMap{mp: dirmap}), nil
 }
 
+// dir represents a directory in a volume. Unlike readOnlyDir, files and directories can be
+// created in it and it can be deleted.
+type dir struct {
+	*readOnlyDir
+}
+
+// newDir creates a dir populated from dirs.
+func newDir(name string, attr plugin.EntryAttributes, impl Interface, path string) *dir {
+	return &dir{readOnlyDir: newReadOnlyDir(name, attr, impl, path)}
+}
+
+func (v *dir) ChildSchemas() []*plugin.EntrySchema {
+	return ChildSchemas()
+}
+
+func (v *dir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(v, "dir").SetDescription(dirDescription)
+}
+
+// Create creates a file (or a directory if isParent is true) in the directory.
+func (v *dir) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	attr, err := createNode(ctx, v.impl, v.path+"/"+name, isParent, v.dirmap)
+	if err != nil {
+		return nil, err
+	}
+	if v.dirmap == nil {
+		return v.newChild(name, attr, nil), nil
+	}
+
+	v.dirmap.mux.RLock()
+	defer v.dirmap.mux.RUnlock()
+	return v.newChild(name, attr, v.dirmap), nil
+}
+
 func (v *dir) Delete(ctx context.Context) (bool, error) {
 	return deleteNode(ctx, v.impl, v.path, v.dirmap)
 }
 
+// blockDir is a directory in a volume that implements BlockReadable. It's only needed so
+// that its schema describes block-readable files.
+type blockDir struct {
+	*dir
+}
+
+func (v *blockDir) ChildSchemas() []*plugin.EntrySchema {
+	return BlockReadableChildSchemas()
+}
+
+func (v *blockDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(v, "dir").SetDescription(dirDescription)
+}
+
+// readOnlyBlockDir is a directory in a ReadOnly volume that implements BlockReadable.
+type readOnlyBlockDir struct {
+	*readOnlyDir
+}
+
+func (v *readOnlyBlockDir) ChildSchemas() []*plugin.EntrySchema {
+	return ReadOnlyBlockReadableChildSchemas()
+}
+
+func (v *readOnlyBlockDir) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(v, "dir").SetDescription(dirDescription)
+}
+
 const dirDescription = `
 This is a directory on a remote volume or a container/VM.
 `
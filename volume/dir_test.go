@@ -37,6 +37,18 @@ func (m *mockDirEntry) VolumeDelete(ctx context.Context, path string) (bool, err
 	return args.Get(0).(bool), args.Error(1)
 }
 
+func (m *mockDirEntry) VolumeWrite(ctx context.Context, path string, b []byte) error {
+	return m.Called(ctx, path, b).Error(0)
+}
+
+func (m *mockDirEntry) VolumeCreate(ctx context.Context, path string) error {
+	return m.Called(ctx, path).Error(0)
+}
+
+func (m *mockDirEntry) VolumeMkdir(ctx context.Context, path string) error {
+	return m.Called(ctx, path).Error(0)
+}
+
 func (m *mockDirEntry) Schema() *plugin.EntrySchema {
 	return nil
 }
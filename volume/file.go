@@ -8,17 +8,17 @@ import (
 	"github.com/puppetlabs/wash/plugin"
 )
 
-// file represents a file in a volume that has content we can access.
-type file struct {
+// readOnlyFile represents a file in a ReadOnly volume that has content we can access.
+type readOnlyFile struct {
 	plugin.EntryBase
 	impl   Interface
 	path   string
 	dirmap *dirMap
 }
 
-// newFile creates a VolumeFile.
-func newFile(name string, attr plugin.EntryAttributes, impl Interface, path string) *file {
-	vf := &file{
+// newReadOnlyFile creates a readOnlyFile.
+func newReadOnlyFile(name string, attr plugin.EntryAttributes, impl Interface, path string) *readOnlyFile {
+	vf := &readOnlyFile{
 		EntryBase: plugin.NewEntry(name),
 	}
 	vf.impl = impl
@@ -29,23 +29,71 @@ func newFile(name string, attr plugin.EntryAttributes, impl Interface, path stri
 	return vf
 }
 
-func (v *file) Schema() *plugin.EntrySchema {
+func (v *readOnlyFile) Schema() *plugin.EntrySchema {
 	return plugin.NewEntrySchema(v, "file").SetDescription(fileDescription)
 }
 
 // Read reads the content of the file
-func (v *file) Read(ctx context.Context) ([]byte, error) {
+func (v *readOnlyFile) Read(ctx context.Context) ([]byte, error) {
 	return v.impl.VolumeRead(ctx, v.path)
 }
 
-func (v *file) Stream(ctx context.Context) (io.ReadCloser, error) {
+func (v *readOnlyFile) Stream(ctx context.Context) (io.ReadCloser, error) {
 	return v.impl.VolumeStream(ctx, v.path)
 }
 
+// file represents a file in a volume that has content we can access. Unlike readOnlyFile, it
+// can be written and deleted.
+type file struct {
+	*readOnlyFile
+}
+
+// newFile creates a VolumeFile.
+func newFile(name string, attr plugin.EntryAttributes, impl Interface, path string) *file {
+	return &file{readOnlyFile: newReadOnlyFile(name, attr, impl, path)}
+}
+
+func (v *file) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(v, "file").SetDescription(fileDescription)
+}
+
+// Write replaces the content of the file
+func (v *file) Write(ctx context.Context, b []byte) error {
+	return writeNode(ctx, v.impl, v.path, b, v.dirmap)
+}
+
 func (v *file) Delete(ctx context.Context) (bool, error) {
 	return deleteNode(ctx, v.impl, v.path, v.dirmap)
 }
 
+// blockFile is a file in a volume that implements BlockReadable. Its content is read in blocks.
+type blockFile struct {
+	*file
+}
+
+func (v *blockFile) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(v, "file").SetDescription(fileDescription)
+}
+
+// Read reads up to size bytes of the file's content, starting at offset
+func (v *blockFile) Read(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	return v.impl.(BlockReadable).VolumeReadBlock(ctx, v.path, size, offset)
+}
+
+// readOnlyBlockFile is a file in a ReadOnly volume that implements BlockReadable.
+type readOnlyBlockFile struct {
+	*readOnlyFile
+}
+
+func (v *readOnlyBlockFile) Schema() *plugin.EntrySchema {
+	return plugin.NewEntrySchema(v, "file").SetDescription(fileDescription)
+}
+
+// Read reads up to size bytes of the file's content, starting at offset
+func (v *readOnlyBlockFile) Read(ctx context.Context, size int64, offset int64) ([]byte, error) {
+	return v.impl.(BlockReadable).VolumeReadBlock(ctx, v.path, size, offset)
+}
+
 const fileDescription = `
 This is a file on a remote volume or a container/VM.
 `
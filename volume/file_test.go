@@ -41,6 +41,22 @@ func (m *mockFileEntry) VolumeDelete(context.Context, string) (bool, error) {
 	return true, nil
 }
 
+func (m *mockFileEntry) VolumeWrite(_ context.Context, _ string, b []byte) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.content = string(b)
+	return nil
+}
+
+func (m *mockFileEntry) VolumeCreate(context.Context, string) error {
+	return nil
+}
+
+func (m *mockFileEntry) VolumeMkdir(context.Context, string) error {
+	return nil
+}
+
 func (m *mockFileEntry) Schema() *plugin.EntrySchema {
 	return nil
 }
@@ -70,6 +86,10 @@ func TestVolumeFile(t *testing.T) {
 			assert.Equal(t, "hello", string(buf))
 		}
 	}
+
+	if assert.NoError(t, vf.Write(context.Background(), []byte("goodbye"))) {
+		assert.Equal(t, "goodbye", impl.content)
+	}
 }
 
 func TestVolumeFileErr(t *testing.T) {
@@ -83,4 +103,7 @@ func TestVolumeFileErr(t *testing.T) {
 	rdr, err := plugin.Stream(context.Background(), vf)
 	assert.Nil(t, rdr)
 	assert.Equal(t, errors.New("fail"), err)
+
+	err = vf.Write(context.Background(), []byte("goodbye"))
+	assert.Equal(t, errors.New("fail"), err)
 }
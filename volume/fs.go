@@ -53,6 +53,11 @@ func (d *FS) List(ctx context.Context) ([]plugin.Entry, error) {
 	return List(ctx, d)
 }
 
+// Create creates a file (or a directory if isParent is true) in the root directory.
+func (d *FS) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	return Create(ctx, d, name, isParent)
+}
+
 type nonZeroError struct {
 	cmdline  []string
 	stderr   string
@@ -63,9 +68,9 @@ func (e nonZeroError) Error() string {
 	return fmt.Sprintf("Exec exited non-zero [%v] running %v:\n%v", e.exitcode, strings.Join(e.cmdline, " "), e.stderr)
 }
 
-func exec(ctx context.Context, executor plugin.Execable, cmdline []string, tty bool) (*bytes.Buffer, error) {
+func exec(ctx context.Context, executor plugin.Execable, cmdline []string, tty bool, stdin io.Reader) (*bytes.Buffer, error) {
 	// Use Elevate because it's common to login to systems as a non-root user and sudo.
-	opts := plugin.ExecOptions{Elevate: true, Tty: tty}
+	opts := plugin.ExecOptions{Elevate: true, Tty: tty, Stdin: stdin}
 	cmd, err := plugin.Exec(ctx, executor, cmdline[0], cmdline[1:], opts)
 	if err != nil {
 		return nil, err
@@ -101,6 +106,60 @@ func exec(ctx context.Context, executor plugin.Execable, cmdline []string, tty b
 	return &stdout, nil
 }
 
+// ExecOutput runs cmdline on the executor and returns its stdout. It returns an error that
+// includes the command's stderr if the command exits non-zero.
+func ExecOutput(ctx context.Context, executor plugin.Execable, cmdline []string) ([]byte, error) {
+	// Don't use Tty when outputting content because it may convert LF to CRLF.
+	buf, err := exec(ctx, executor, cmdline, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExecStream runs cmdline on the executor, returning a reader for its output. The command's
+// stopped when the context's cancelled or the reader's closed. Use tty for commands that
+// don't exit on their own (like 'tail -f') so that they're stopped with Ctrl-C.
+func ExecStream(ctx context.Context, executor plugin.Execable, cmdline []string, tty bool) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	execOpts := plugin.ExecOptions{Elevate: true, Tty: tty}
+	cmd, err := plugin.Exec(ctx, executor, cmdline[0], cmdline[1:], execOpts)
+	if err != nil {
+		cancel()
+		activity.Record(ctx, "Exec error running %+v: %v", cmdline, err)
+		return nil, err
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		// Exec uses context; if it's canceled, the OutputCh will close. Close the writer.
+		var errs []error
+		for chunk := range cmd.OutputCh() {
+			if chunk.Err != nil {
+				activity.Record(ctx, "Error on exec: %v", chunk.Err)
+				errs = append(errs, chunk.Err)
+				continue
+			}
+
+			activity.Record(ctx, "%v: %v", chunk.StreamID, chunk.Data)
+			if len(errs) == 0 {
+				if _, err := w.Write([]byte(chunk.Data)); err != nil {
+					activity.Record(ctx, "Error copying exec result: %v", err)
+					errs = append(errs, err)
+				}
+			}
+		}
+
+		if len(errs) > 0 {
+			err = w.CloseWithError(fmt.Errorf("Multiple errors from exec output: %v", errs))
+		} else {
+			err = w.Close()
+		}
+		activity.Record(ctx, "Closing write pipe: %v", err)
+	}()
+	return plugin.CleanupReader{ReadCloser: r, Cleanup: cancel}, nil
+}
+
 // VolumeList satisfies the Interface required by List to enumerate files.
 func (d *FS) VolumeList(ctx context.Context, path string) (DirMap, error) {
 	cmdline := d.selectShellCommand(StatCmdPOSIX(path, d.maxdepth), StatCmdPowershell(path, d.maxdepth))
@@ -109,7 +168,7 @@ func (d *FS) VolumeList(ctx context.Context, path string) (DirMap, error) {
 	// Use Tty if running Wash interactively so we get a reflection of the system consistent with
 	// being logged in as a user. `ls` will report different file types based on whether you're using
 	// it interactively, see character device vs named pipe on /dev/stderr as an example.
-	buf, err := exec(ctx, d.executor, cmdline, plugin.IsInteractive())
+	buf, err := exec(ctx, d.executor, cmdline, plugin.IsInteractive(), nil)
 	if nzerr, ok := err.(nonZeroError); ok {
 		// Some messages are considered normal, such as when stat fails because a file no longer exists
 		// as part of `find ... -exec stat`. We ignore these errors, but if we see any other errors
@@ -149,7 +208,7 @@ func (d *FS) VolumeRead(ctx context.Context, path string) ([]byte, error) {
 	command := d.selectShellCommand([]string{"cat", path}, []string{"Get-Content '" + path + "'"})
 
 	// Don't use Tty when outputting file content because it may convert LF to CRLF.
-	buf, err := exec(ctx, d.executor, command, false)
+	buf, err := exec(ctx, d.executor, command, false, nil)
 	if err != nil {
 		activity.Record(ctx, "Exec error running %+v in VolumeOpen: %v", command, err)
 		return nil, err
@@ -165,41 +224,7 @@ func (d *FS) VolumeStream(ctx context.Context, path string) (io.ReadCloser, erro
 		[]string{"Get-Content -Wait -Tail 10 '" + path + "'"},
 	)
 
-	execOpts := plugin.ExecOptions{Elevate: true, Tty: true}
-	cmd, err := plugin.Exec(ctx, d.executor, command[0], command[1:], execOpts)
-	if err != nil {
-		activity.Record(ctx, "Exec error in VolumeRead: %v", err)
-		return nil, err
-	}
-
-	r, w := io.Pipe()
-	go func() {
-		// Exec uses context; if it's canceled, the OutputCh will close. Close the writer.
-		var errs []error
-		for chunk := range cmd.OutputCh() {
-			if chunk.Err != nil {
-				activity.Record(ctx, "Error on exec: %v", chunk.Err)
-				errs = append(errs, chunk.Err)
-				continue
-			}
-
-			activity.Record(ctx, "%v: %v", chunk.StreamID, chunk.Data)
-			if len(errs) == 0 {
-				if _, err := w.Write([]byte(chunk.Data)); err != nil {
-					activity.Record(ctx, "Error copying exec result: %v", err)
-					errs = append(errs, err)
-				}
-			}
-		}
-
-		if len(errs) > 0 {
-			err = w.CloseWithError(fmt.Errorf("Multiple errors from exec output: %v", errs))
-		} else {
-			err = w.Close()
-		}
-		activity.Record(ctx, "Closing write pipe: %v", err)
-	}()
-	return r, nil
+	return ExecStream(ctx, d.executor, command, true)
 }
 
 // VolumeDelete satisfies the Interface required by Delete to delete volume nodes.
@@ -211,7 +236,7 @@ func (d *FS) VolumeDelete(ctx context.Context, path string) (bool, error) {
 	)
 
 	// Skip tty because we don't need it, we ignore the output.
-	_, err := exec(ctx, d.executor, command, false)
+	_, err := exec(ctx, d.executor, command, false, nil)
 	if err != nil {
 		activity.Record(ctx, "Exec error running 'rm -rf %v' in VolumeDelete: %v", path, err)
 		return false, err
@@ -219,12 +244,72 @@ func (d *FS) VolumeDelete(ctx context.Context, path string) (bool, error) {
 	return true, nil
 }
 
+// VolumeWrite satisfies the Interface required by Write to write file contents. The
+// content's streamed to the command's stdin.
+func (d *FS) VolumeWrite(ctx context.Context, path string, b []byte) error {
+	activity.Record(ctx, "Writing %v bytes to %v on %v", len(b), path, plugin.ID(d.executor))
+	command := d.selectShellCommand(
+		[]string{"sh", "-c", `cat > "$1"`, "sh", path},
+		[]string{"$f = [IO.File]::Create('" + path + "'); try { [Console]::OpenStandardInput().CopyTo($f) } finally { $f.Close() }"},
+	)
+
+	// Skip tty because it would mangle the content.
+	_, err := exec(ctx, d.executor, command, false, bytes.NewReader(b))
+	if err != nil {
+		activity.Record(ctx, "Exec error running %+v in VolumeWrite: %v", command, err)
+		return err
+	}
+	return nil
+}
+
+// VolumeCreate satisfies the Interface required by Create to create files.
+func (d *FS) VolumeCreate(ctx context.Context, path string) error {
+	activity.Record(ctx, "Creating %v on %v", path, plugin.ID(d.executor))
+	command := d.selectShellCommand(
+		[]string{"touch", path},
+		[]string{"New-Item -ItemType File -Path '" + path + "'"},
+	)
+
+	_, err := exec(ctx, d.executor, command, false, nil)
+	if err != nil {
+		activity.Record(ctx, "Exec error running %+v in VolumeCreate: %v", command, err)
+		return err
+	}
+	return nil
+}
+
+// VolumeMkdir satisfies the Interface required by Create to create directories.
+func (d *FS) VolumeMkdir(ctx context.Context, path string) error {
+	activity.Record(ctx, "Creating directory %v on %v", path, plugin.ID(d.executor))
+	command := d.selectShellCommand(
+		[]string{"mkdir", path},
+		[]string{"New-Item -ItemType Directory -Path '" + path + "'"},
+	)
+
+	_, err := exec(ctx, d.executor, command, false, nil)
+	if err != nil {
+		activity.Record(ctx, "Exec error running %+v in VolumeMkdir: %v", command, err)
+		return err
+	}
+	return nil
+}
+
 // Selects between a posix and powershell command based on the entry's login shell.
 // Note that powershell commands are often a single string because they represent a PowerShell
 // expression, and it's easier to pass that as a string than try to correctly escape it as
 // multiple tokens.
 func (d *FS) selectShellCommand(posix []string, power []string) []string {
-	switch d.loginShell() {
+	return SelectShellCommand(d.executor, posix, power)
+}
+
+func (d *FS) loginShell() plugin.Shell {
+	return LoginShell(d.executor)
+}
+
+// SelectShellCommand selects between a posix and powershell command based on the executor's
+// login shell.
+func SelectShellCommand(executor plugin.Entry, posix []string, power []string) []string {
+	switch LoginShell(executor) {
 	case plugin.POSIXShell:
 		return posix
 	case plugin.PowerShell:
@@ -234,8 +319,10 @@ func (d *FS) selectShellCommand(posix []string, power []string) []string {
 	}
 }
 
-func (d *FS) loginShell() plugin.Shell {
-	attr := plugin.Attributes(d.executor)
+// LoginShell returns the executor's login shell from its os.login_shell attribute. It
+// defaults to a POSIX shell if the attribute isn't set.
+func LoginShell(executor plugin.Entry) plugin.Shell {
+	attr := plugin.Attributes(executor)
 	if shell := attr.OS().LoginShell; attr.HasOS() && shell != plugin.UnknownShell {
 		return shell
 	}
@@ -247,10 +334,14 @@ const fsDescription = `
 This represents the root directory of a container/VM. It lets you navigate
 and interact with that container/VM's filesystem as if you were logged into
 it. Thus, you're able to do things like 'cat'/'tail' that container/VM's files
-(or even multiple files spread out across multiple containers/VMs).
+(or even multiple files spread out across multiple containers/VMs), or edit
+them in place.
 
 Note that Wash will exec a command on the container/VM whenever it invokes a
 List/Read/Stream action on a directory/file, and the action's result is not
 currently cached. For List, that command is 'find -exec stat'. For Read, that
-command is 'cat'. For Stream, that command is 'tail -f'.
+command is 'cat'. For Stream, that command is 'tail -f'. Wash also execs a
+command whenever it invokes a Write/Create action. For Write, that command is
+'cat' with the new content streamed to its stdin. For Create, that command is
+'touch' for files and 'mkdir' for directories.
 `
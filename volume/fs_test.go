@@ -3,6 +3,7 @@ package volume
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"sort"
 	"strings"
 	"testing"
@@ -77,6 +78,8 @@ type fsTestSuite struct {
 	outputDepth               int
 	shortFixture, deepFixture string
 	readCmdFn, deleteCmdFn    func(path string) (command []string)
+	writeCmdFn                func(path string) (command []string)
+	createCmdFn, mkdirCmdFn   func(path string) (command []string)
 }
 
 func (suite *fsTestSuite) SetupTest() {
@@ -261,6 +264,68 @@ func (suite *fsTestSuite) TestVolumeDelete() {
 	exec.AssertExpectations(suite.T())
 }
 
+func (suite *fsTestSuite) TestVolumeWrite() {
+	exec := suite.createExec()
+	exec.onExec(suite.statCmd("/", suite.outputDepth), suite.createResult(suite.outputFixture))
+	fs := NewFS(suite.ctx, "fs", exec, suite.outputDepth)
+
+	entry := suite.find(fs, "var/log/path1/a file")
+	cmd := suite.writeCmdFn("/var/log/path1/a file")
+	var stdin []byte
+	exec.On("Exec", mock.Anything, cmd[0], cmd[1:], mock.Anything).Return(suite.createResult(""), nil).Run(func(args mock.Arguments) {
+		opts := args.Get(3).(plugin.ExecOptions)
+		suite.False(opts.Tty)
+		stdin, _ = ioutil.ReadAll(opts.Stdin)
+	})
+
+	suite.NoError(plugin.Write(suite.ctx, entry.(plugin.Writable), []byte("hello")))
+	suite.Equal([]byte("hello"), stdin)
+	exec.AssertExpectations(suite.T())
+
+	// The file's size was updated.
+	entry = suite.find(fs, "var/log/path1/a file")
+	attr := plugin.Attributes(entry)
+	suite.Equal(uint64(5), attr.Size())
+}
+
+func (suite *fsTestSuite) TestVolumeCreate() {
+	exec := suite.createExec()
+	exec.onExec(suite.statCmd("/", suite.outputDepth), suite.createResult(suite.outputFixture))
+	fs := NewFS(suite.ctx, "fs", exec, suite.outputDepth)
+
+	parent := suite.find(fs, "var/log/path1")
+	exec.onExec(suite.createCmdFn("/var/log/path1/new file"), suite.createResult(""))
+	entry, err := plugin.Create(suite.ctx, parent.(plugin.Creatable), "new file", false)
+	if suite.NoError(err) {
+		suite.Equal("new file", plugin.Name(entry))
+		_, ok := entry.(plugin.Writable)
+		suite.True(ok)
+	}
+	exec.AssertExpectations(suite.T())
+
+	// The new file's included when listing its parent.
+	suite.find(fs, "var/log/path1/new file")
+}
+
+func (suite *fsTestSuite) TestVolumeMkdir() {
+	exec := suite.createExec()
+	exec.onExec(suite.statCmd("/", suite.outputDepth), suite.createResult(suite.outputFixture))
+	fs := NewFS(suite.ctx, "fs", exec, suite.outputDepth)
+
+	parent := suite.find(fs, "var/log/path1")
+	exec.onExec(suite.mkdirCmdFn("/var/log/path1/new dir"), suite.createResult(""))
+	entry, err := plugin.Create(suite.ctx, parent.(plugin.Creatable), "new dir", true)
+	if suite.NoError(err) {
+		suite.Equal("new dir", plugin.Name(entry))
+		// The new directory's empty, so listing it doesn't exec anything.
+		entries, err := plugin.List(suite.ctx, entry.(plugin.Parent))
+		if suite.NoError(err) {
+			suite.Equal(0, entries.Len())
+		}
+	}
+	exec.AssertExpectations(suite.T())
+}
+
 func TestPOSIXFS(t *testing.T) {
 	suite.Run(t, &fsTestSuite{
 		loginShell:    plugin.POSIXShell,
@@ -271,6 +336,9 @@ func TestPOSIXFS(t *testing.T) {
 		deepFixture:   posixFixtureDeep,
 		readCmdFn:     func(path string) []string { return []string{"cat", path} },
 		deleteCmdFn:   func(path string) []string { return []string{"rm", "-rf", path} },
+		writeCmdFn:    func(path string) []string { return []string{"sh", "-c", `cat > "$1"`, "sh", path} },
+		createCmdFn:   func(path string) []string { return []string{"touch", path} },
+		mkdirCmdFn:    func(path string) []string { return []string{"mkdir", path} },
 	})
 }
 
@@ -284,6 +352,11 @@ func TestPowershellFS(t *testing.T) {
 		deepFixture:   powershellFixtureDeep,
 		readCmdFn:     func(path string) []string { return []string{"Get-Content '" + path + "'"} },
 		deleteCmdFn:   func(path string) []string { return []string{"Remove-Item -Recurse -Force '" + path + "'"} },
+		writeCmdFn: func(path string) []string {
+			return []string{"$f = [IO.File]::Create('" + path + "'); try { [Console]::OpenStandardInput().CopyTo($f) } finally { $f.Close() }"}
+		},
+		createCmdFn: func(path string) []string { return []string{"New-Item -ItemType File -Path '" + path + "'"} },
+		mkdirCmdFn:  func(path string) []string { return []string{"New-Item -ItemType Directory -Path '" + path + "'"} },
 	})
 }
 
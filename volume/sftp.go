@@ -0,0 +1,584 @@
+package volume
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/puppetlabs/wash/activity"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
+)
+
+// SSHTarget is an Execable resource whose commands are executed via transport.ExecSSH.
+type SSHTarget interface {
+	plugin.Execable
+	// SSHIdentity returns the identity that's used to connect to the resource.
+	SSHIdentity(ctx context.Context) (transport.Identity, error)
+}
+
+// Overridden by tests.
+var sftpClient = transport.SFTPClient
+var sftpClientTTL = transport.SFTPClientTTL
+
+// How long a file that's opened for block reads is kept open after its last read.
+const sftpFileIdleTimeout = 5 * time.Second
+
+// The number of directories that are read at once when listing. The SFTP server handles
+// requests concurrently, so this hides some of the latency.
+const sftpListConcurrency = 16
+
+// SFTP presents a view of the filesystem of an SSHTarget using SFTP. It re-uses the target's
+// SSH connection, and reads files in blocks. If the target doesn't support SFTP, or SFTP's
+// denied permission, it falls back to running commands like FS.
+type SFTP struct {
+	plugin.EntryBase
+	target   SSHTarget
+	fs       *FS
+	maxdepth int
+
+	// The target's client is cached so that each operation doesn't look up the target's
+	// identity, which can be expensive (e.g. it may add a key to a cloud VM's metadata). A
+	// nil client means that the target doesn't support SFTP.
+	clientMux     sync.Mutex
+	cachedClient  *sftp.Client
+	clientExpires time.Time
+
+	// Files are kept open between block reads so that reading a file sequentially doesn't
+	// open and close it for each block.
+	filesMux sync.Mutex
+	files    map[string]*sftpFile
+}
+
+// NewSFTP creates a new SFTP entry with the given name, using SFTP to connect to the supplied
+// target to satisfy volume operations.
+func NewSFTP(ctx context.Context, name string, target SSHTarget, maxdepth int) *SFTP {
+	d := &SFTP{
+		EntryBase: plugin.NewEntry(name),
+	}
+	d.target = target
+	// The fallback isn't an entry in its own right, so it doesn't need an EntryBase.
+	d.fs = &FS{executor: target, maxdepth: maxdepth}
+	d.maxdepth = maxdepth
+	d.SetTTLOf(plugin.ListOp, ListTTL)
+
+	if _, err := plugin.List(ctx, d); err != nil {
+		d.MarkInaccessible(ctx, err)
+	}
+
+	return d
+}
+
+// ChildSchemas returns the SFTP entry's child schema
+func (d *SFTP) ChildSchemas() []*plugin.EntrySchema {
+	return BlockReadableChildSchemas()
+}
+
+// Schema returns the SFTP entry's schema
+func (d *SFTP) Schema() *plugin.EntrySchema {
+	return plugin.
+		NewEntrySchema(d, "fs").
+		SetDescription(sftpDescription).
+		IsSingleton()
+}
+
+// List creates a hierarchy of the filesystem of the target.
+func (d *SFTP) List(ctx context.Context) ([]plugin.Entry, error) {
+	return List(ctx, d)
+}
+
+// Create creates a file (or a directory if isParent is true) in the root directory.
+func (d *SFTP) Create(ctx context.Context, name string, isParent bool) (plugin.Entry, error) {
+	return Create(ctx, d, name, isParent)
+}
+
+// client returns the target's SFTP client. It returns a nil client if the target doesn't
+// support SFTP, in which case the caller should fall back to d.fs. Other errors, like failing
+// to connect, are returned because running commands would fail for the same reason. The
+// result's cached for as long as transport.SFTPClient keeps the client open.
+func (d *SFTP) client(ctx context.Context) (*sftp.Client, error) {
+	d.clientMux.Lock()
+	defer d.clientMux.Unlock()
+	if time.Now().Before(d.clientExpires) {
+		return d.cachedClient, nil
+	}
+
+	start := time.Now()
+	id, err := d.target.SSHIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client, err := sftpClient(ctx, id)
+	if err != nil {
+		if _, ok := err.(transport.SFTPUnavailableError); !ok {
+			return nil, err
+		}
+		activity.Record(ctx, "SFTP is unavailable on %v, falling back to exec: %v", plugin.ID(d.target), err)
+		client = nil
+	}
+	d.cachedClient, d.clientExpires = client, start.Add(sftpClientTTL())
+	return client, nil
+}
+
+// sftpFile is a file that's kept open for block reads. It's closed once it's been idle for
+// sftpFileIdleTimeout, or once it's released after it's been forgotten.
+type sftpFile struct {
+	*sftp.File
+	client    *sftp.Client
+	refs      int
+	forgotten bool
+	timer     *time.Timer
+	// Concurrent reads share the file's offset, so they're serialized.
+	readMux sync.Mutex
+}
+
+// ReadAt reads len(p) bytes at offset. It returns io.EOF if the end of the file is reached
+// first.
+func (f *sftpFile) ReadAt(p []byte, offset int64) (int, error) {
+	f.readMux.Lock()
+	defer f.readMux.Unlock()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(f.File, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// openForBlockReads returns the file at path, opened with client. The file must be released
+// with releaseFile once it's been read.
+func (d *SFTP) openForBlockReads(client *sftp.Client, path string) (*sftpFile, error) {
+	d.filesMux.Lock()
+	defer d.filesMux.Unlock()
+	if f, ok := d.files[path]; ok {
+		if f.client == client {
+			f.refs++
+			return f, nil
+		}
+		// The file was opened with an expired client.
+		d.forgetFile(path, f)
+	}
+
+	file, err := client.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &sftpFile{File: file, client: client, refs: 1}
+	f.timer = time.AfterFunc(sftpFileIdleTimeout, func() {
+		d.filesMux.Lock()
+		defer d.filesMux.Unlock()
+		if f.refs == 0 && d.files[path] == f {
+			d.forgetFile(path, f)
+		}
+	})
+	if d.files == nil {
+		d.files = make(map[string]*sftpFile)
+	}
+	d.files[path] = f
+	return f, nil
+}
+
+func (d *SFTP) releaseFile(f *sftpFile) {
+	d.filesMux.Lock()
+	defer d.filesMux.Unlock()
+	f.refs--
+	if f.refs > 0 {
+		return
+	}
+	if f.forgotten {
+		f.Close()
+	} else {
+		f.timer.Reset(sftpFileIdleTimeout)
+	}
+}
+
+// forgetFile stops re-using f for block reads of path. It's closed once it's no longer being
+// read. d.filesMux must be held.
+func (d *SFTP) forgetFile(path string, f *sftpFile) {
+	delete(d.files, path)
+	f.forgotten = true
+	f.timer.Stop()
+	if f.refs == 0 {
+		f.Close()
+	}
+}
+
+// forgetFiles stops re-using the files at path and its descendants for block reads, so that
+// subsequent reads see their changes.
+func (d *SFTP) forgetFiles(path string) {
+	d.filesMux.Lock()
+	defer d.filesMux.Unlock()
+	for p, f := range d.files {
+		if p == path || strings.HasPrefix(p, path+"/") {
+			d.forgetFile(p, f)
+		}
+	}
+}
+
+// fallback returns true if the operation should be retried by running commands. That's the case
+// if SFTP was denied permission because the commands are elevated.
+func (d *SFTP) fallback(ctx context.Context, err error) bool {
+	if os.IsPermission(err) {
+		activity.Record(ctx, "SFTP on %v was denied permission, falling back to exec: %v", plugin.ID(d.target), err)
+		return true
+	}
+	if statusErr, ok := err.(*sftp.StatusError); ok && statusErr.FxCode() == sftp.ErrSSHFxPermissionDenied {
+		activity.Record(ctx, "SFTP on %v was denied permission, falling back to exec: %v", plugin.ID(d.target), err)
+		return true
+	}
+	return false
+}
+
+// sftpPath translates a volume path to the path on the target.
+func sftpPath(path string) string {
+	// List uses "" to mean root.
+	if path == RootPath {
+		return "/"
+	}
+	return path
+}
+
+// VolumeList satisfies the Interface required by List to enumerate files.
+func (d *SFTP) VolumeList(ctx context.Context, path string) (DirMap, error) {
+	client, err := d.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return d.fs.VolumeList(ctx, path)
+	}
+
+	activity.Record(ctx, "Listing %v on %v via SFTP", path, plugin.ID(d.target))
+	dirmap, err := listSFTP(ctx, client, path, d.maxdepth)
+	if err != nil && d.fallback(ctx, err) {
+		return d.fs.VolumeList(ctx, path)
+	}
+	return dirmap, err
+}
+
+// listSFTP reads the directory at path and its descendants up to maxdepth. Like StatCmdPOSIX,
+// symbolic links are represented by their targets. Descendants that can't be read are left
+// unexplored so that the error is reported when they're listed.
+func listSFTP(ctx context.Context, client *sftp.Client, path string, maxdepth int) (DirMap, error) {
+	maxdepth += numPathSegments(path)
+	dirmap := DirMap{RootPath: make(Children)}
+	var mux sync.Mutex
+	var wg sync.WaitGroup
+	var rootErr error
+	sem := make(chan struct{}, sftpListConcurrency)
+
+	var readDir func(dirpath string)
+	readDir = func(dirpath string) {
+		defer wg.Done()
+		sem <- struct{}{}
+		infos, err := readDirSFTP(ctx, client, dirpath)
+		<-sem
+
+		mux.Lock()
+		defer mux.Unlock()
+		if err != nil {
+			if dirpath == path {
+				rootErr = err
+			} else {
+				activity.Record(ctx, "Unable to read %v via SFTP: %v", dirpath, err)
+				dirmap[dirpath] = nil
+			}
+			return
+		}
+		for _, info := range infos {
+			subpath := dirpath + "/" + info.Name()
+			addAttributesForPath(dirmap, fileInfoAttributes(info), RootPath, subpath, maxdepth)
+			if info.IsDir() && numPathSegments(subpath) < maxdepth {
+				wg.Add(1)
+				go readDir(subpath)
+			}
+		}
+	}
+
+	if path != RootPath {
+		// Ensure the start of the search is in the dirmap even if it's empty.
+		makeChildren(dirmap, path)
+	}
+	wg.Add(1)
+	readDir(path)
+	wg.Wait()
+	if rootErr != nil {
+		return nil, rootErr
+	}
+	return dirmap, nil
+}
+
+// readDirSFTP reads the directory at path, resolving any symbolic links in it.
+func readDirSFTP(ctx context.Context, client *sftp.Client, path string) ([]os.FileInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	infos, err := client.ReadDir(sftpPath(path))
+	if err != nil {
+		return nil, err
+	}
+	resolved := infos[:0]
+	for _, info := range infos {
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := client.Stat(path + "/" + info.Name())
+			if err != nil {
+				// Dangling links are skipped, similar to `find -L ... -exec stat -L`.
+				activity.Record(ctx, "Unable to resolve %v/%v via SFTP: %v", path, info.Name(), err)
+				continue
+			}
+			info = namedFileInfo{FileInfo: target, name: info.Name()}
+		}
+		resolved = append(resolved, info)
+	}
+	return resolved, nil
+}
+
+// namedFileInfo gives a resolved symbolic link the link's name.
+type namedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (info namedFileInfo) Name() string {
+	return info.name
+}
+
+func fileInfoAttributes(info os.FileInfo) plugin.EntryAttributes {
+	var attr plugin.EntryAttributes
+	attr.
+		SetMode(info.Mode()).
+		SetSize(uint64(info.Size())).
+		SetMtime(info.ModTime())
+	if stat, ok := info.Sys().(*sftp.FileStat); ok {
+		attr.SetAtime(time.Unix(int64(stat.Atime), 0))
+	}
+	return attr
+}
+
+// VolumeRead satisfies the Interface required by List to read file contents.
+func (d *SFTP) VolumeRead(ctx context.Context, path string) ([]byte, error) {
+	client, err := d.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return d.fs.VolumeRead(ctx, path)
+	}
+
+	activity.Record(ctx, "Reading %v on %v via SFTP", path, plugin.ID(d.target))
+	f, err := client.Open(path)
+	if err != nil {
+		if d.fallback(ctx, err) {
+			return d.fs.VolumeRead(ctx, path)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// VolumeReadBlock satisfies the BlockReadable interface required to read part of a file's
+// contents. If SFTP's unavailable, a command that outputs the requested block is run.
+func (d *SFTP) VolumeReadBlock(ctx context.Context, path string, size int64, offset int64) ([]byte, error) {
+	client, err := d.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return d.readBlockViaExec(ctx, path, size, offset)
+	}
+
+	activity.Record(ctx, "Reading %v bytes at offset %v of %v on %v via SFTP", size, offset, path, plugin.ID(d.target))
+	f, err := d.openForBlockReads(client, path)
+	if err != nil {
+		if d.fallback(ctx, err) {
+			return d.readBlockViaExec(ctx, path, size, offset)
+		}
+		return nil, err
+	}
+	defer d.releaseFile(f)
+
+	data := make([]byte, size)
+	n, err := f.ReadAt(data, offset)
+	if err == io.EOF {
+		// The end of the file was reached before size bytes were read.
+		err = nil
+	}
+	return data[:n], err
+}
+
+// readBlockViaExec runs a command that only outputs the requested block so that reading a file
+// in blocks doesn't read all of it for each block.
+func (d *SFTP) readBlockViaExec(ctx context.Context, path string, size int64, offset int64) ([]byte, error) {
+	activity.Record(ctx, "Reading %v bytes at offset %v of %v on %v", size, offset, path, plugin.ID(d.target))
+	command := d.fs.selectShellCommand(
+		// Pass the path as an argument so that the shell doesn't interpret it.
+		[]string{"sh", "-c", `tail -c +"$1" "$2" | head -c "$3"`, "sh", strconv.FormatInt(offset+1, 10), path, strconv.FormatInt(size, 10)},
+		[]string{readBlockCmdPowershell(path, size, offset)},
+	)
+
+	// Don't use Tty when outputting file content because it may convert LF to CRLF.
+	buf, err := exec(ctx, d.fs.executor, command, false, nil)
+	if err != nil {
+		activity.Record(ctx, "Exec error running %+v in VolumeReadBlock: %v", command, err)
+		return nil, err
+	}
+	if d.fs.loginShell() == plugin.PowerShell {
+		// PowerShell's output is text, so the block's base64-encoded.
+		return base64.StdEncoding.DecodeString(strings.TrimSpace(buf.String()))
+	}
+	return buf.Bytes(), nil
+}
+
+func readBlockCmdPowershell(path string, size int64, offset int64) string {
+	return fmt.Sprintf(
+		"$f = [System.IO.File]::OpenRead('%v'); try { [void]$f.Seek(%v, 'Begin'); $b = New-Object byte[] %v; $n = $f.Read($b, 0, %v); [Convert]::ToBase64String($b, 0, $n) } finally { $f.Close() }",
+		strings.Replace(path, "'", "''", -1), offset, size, size,
+	)
+}
+
+// VolumeStream satisfies the Interface required by List to stream file contents. SFTP can't
+// follow a file, so it always runs `tail -f`.
+func (d *SFTP) VolumeStream(ctx context.Context, path string) (io.ReadCloser, error) {
+	return d.fs.VolumeStream(ctx, path)
+}
+
+// VolumeDelete satisfies the Interface required by Delete to delete volume nodes. Directories
+// are deleted recursively.
+func (d *SFTP) VolumeDelete(ctx context.Context, path string) (bool, error) {
+	client, err := d.client(ctx)
+	if err != nil {
+		return false, err
+	}
+	if client == nil {
+		return d.fs.VolumeDelete(ctx, path)
+	}
+
+	activity.Record(ctx, "Deleting %v on %v via SFTP", path, plugin.ID(d.target))
+	d.forgetFiles(path)
+	if err := removeAllSFTP(client, path); err != nil {
+		if d.fallback(ctx, err) {
+			return d.fs.VolumeDelete(ctx, path)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// removeAllSFTP removes path and, if it's a directory, everything in it.
+func removeAllSFTP(client *sftp.Client, path string) error {
+	info, err := client.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return client.Remove(path)
+	}
+
+	children, err := client.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := removeAllSFTP(client, path+"/"+child.Name()); err != nil {
+			return err
+		}
+	}
+	return client.RemoveDirectory(path)
+}
+
+// VolumeWrite satisfies the Interface required by Write to write file contents.
+func (d *SFTP) VolumeWrite(ctx context.Context, path string, b []byte) error {
+	client, err := d.client(ctx)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return d.fs.VolumeWrite(ctx, path, b)
+	}
+
+	activity.Record(ctx, "Writing %v bytes to %v on %v via SFTP", len(b), path, plugin.ID(d.target))
+	d.forgetFiles(path)
+	f, err := client.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		if d.fallback(ctx, err) {
+			return d.fs.VolumeWrite(ctx, path, b)
+		}
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// VolumeCreate satisfies the Interface required by Create to create files.
+func (d *SFTP) VolumeCreate(ctx context.Context, path string) error {
+	client, err := d.client(ctx)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return d.fs.VolumeCreate(ctx, path)
+	}
+
+	activity.Record(ctx, "Creating %v on %v via SFTP", path, plugin.ID(d.target))
+	// Like touch, leave an existing file's content alone.
+	f, err := client.OpenFile(path, os.O_WRONLY|os.O_CREATE)
+	if err != nil {
+		if d.fallback(ctx, err) {
+			return d.fs.VolumeCreate(ctx, path)
+		}
+		return err
+	}
+	return f.Close()
+}
+
+// VolumeMkdir satisfies the Interface required by Create to create directories.
+func (d *SFTP) VolumeMkdir(ctx context.Context, path string) error {
+	client, err := d.client(ctx)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return d.fs.VolumeMkdir(ctx, path)
+	}
+
+	activity.Record(ctx, "Creating directory %v on %v via SFTP", path, plugin.ID(d.target))
+	if err := client.Mkdir(path); err != nil {
+		if d.fallback(ctx, err) {
+			return d.fs.VolumeMkdir(ctx, path)
+		}
+		return err
+	}
+	return nil
+}
+
+const sftpDescription = `
+This represents the root directory of a VM. It lets you navigate and interact
+with that VM's filesystem as if you were logged into it. Thus, you're able to
+do things like 'cat'/'tail' that VM's files (or even multiple files spread out
+across multiple VMs), or edit them in place.
+
+Wash uses SFTP over the VM's SSH connection to list, read, write, create and
+delete files. Files are read in blocks as they're needed. SFTP runs as the
+user that's logged in, so if it's denied permission (or the VM doesn't support
+SFTP) then Wash falls back to exec'ing commands with sudo like it does for
+containers. Stream always execs 'tail -f'.
+`
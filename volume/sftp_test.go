@@ -0,0 +1,352 @@
+package volume
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/puppetlabs/wash/datastore"
+	"github.com/puppetlabs/wash/plugin"
+	"github.com/puppetlabs/wash/transport"
+	"github.com/stretchr/testify/suite"
+)
+
+type sftpTestSuite struct {
+	suite.Suite
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+	dir        string
+	client     *sftp.Client
+	server     *sftp.Server
+}
+
+func (suite *sftpTestSuite) SetupTest() {
+	ctx := plugin.SetTestCache(datastore.NewMemCache())
+	suite.ctx, suite.cancelFunc = context.WithCancel(ctx)
+
+	var err error
+	suite.dir, err = ioutil.TempDir("", "wash_sftp")
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	// Resolve symlinks in the temp dir's path (such as on macOS) so that paths are predictable.
+	if suite.dir, err = filepath.EvalSymlinks(suite.dir); err != nil {
+		suite.FailNow(err.Error())
+	}
+
+	// Serve the local filesystem over a pair of pipes.
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	suite.server, err = sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverR, serverW})
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	go func() { _ = suite.server.Serve() }()
+	suite.client, err = sftp.NewClientPipe(clientR, clientW)
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+	sftpClient = func(ctx context.Context, id transport.Identity) (*sftp.Client, error) {
+		suite.Equal("example.com", id.Host)
+		return suite.client, nil
+	}
+}
+
+func (suite *sftpTestSuite) TearDownTest() {
+	sftpClient = transport.SFTPClient
+	// Close the server first so that the client sees EOF.
+	suite.server.Close()
+	suite.client.Close()
+	os.RemoveAll(suite.dir)
+	plugin.UnsetTestCache()
+	suite.cancelFunc()
+}
+
+func (suite *sftpTestSuite) createTarget() *mockSSHTarget {
+	target := &mockSSHTarget{mockExecutor: mockExecutor{EntryBase: plugin.NewEntry("instance")}}
+	// Used when recording activity.
+	target.SetTestID("/instance")
+	return target
+}
+
+func (suite *sftpTestSuite) newSFTP(target SSHTarget) *SFTP {
+	d := &SFTP{EntryBase: plugin.NewEntry("fs")}
+	d.target = target
+	d.fs = &FS{executor: target, maxdepth: 2}
+	d.maxdepth = 2
+	return d
+}
+
+func (suite *sftpTestSuite) writeFile(path, content string) {
+	path = filepath.Join(suite.dir, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		suite.FailNow(err.Error())
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		suite.FailNow(err.Error())
+	}
+}
+
+func (suite *sftpTestSuite) TestVolumeList() {
+	suite.writeFile("a/b/c/file", "hello")
+	suite.writeFile("a/top", "top")
+	suite.NoError(os.Symlink(filepath.Join(suite.dir, "a"), filepath.Join(suite.dir, "link")))
+	suite.NoError(os.Symlink(filepath.Join(suite.dir, "missing"), filepath.Join(suite.dir, "dangling")))
+
+	dirmap, err := suite.newSFTP(suite.createTarget()).VolumeList(suite.ctx, suite.dir)
+	if !suite.NoError(err) {
+		return
+	}
+
+	root := dirmap[suite.dir]
+	suite.Len(root, 2)
+	a := root["a"]
+	suite.True(a.Mode().IsDir())
+	link := root["link"]
+	suite.True(link.Mode().IsDir(), "symlinks should be represented by their targets")
+
+	children := dirmap[suite.dir+"/a"]
+	suite.Len(children, 2)
+	top := children["top"]
+	suite.Equal(uint64(3), top.Size())
+	suite.Equal(os.FileMode(0644), top.Mode())
+
+	// Directories at maxdepth are unexplored.
+	b, ok := dirmap[suite.dir+"/a/b"]
+	suite.True(ok)
+	suite.Nil(b)
+	_, ok = dirmap[suite.dir+"/a/b/c"]
+	suite.False(ok)
+
+	// The parents of the starting directory are included.
+	_, ok = dirmap[RootPath]
+	suite.True(ok)
+}
+
+func (suite *sftpTestSuite) TestVolumeList_Missing() {
+	_, err := suite.newSFTP(suite.createTarget()).VolumeList(suite.ctx, suite.dir+"/missing")
+	suite.True(os.IsNotExist(err))
+}
+
+func (suite *sftpTestSuite) TestVolumeRead() {
+	suite.writeFile("file", "hello world")
+	d := suite.newSFTP(suite.createTarget())
+
+	content, err := d.VolumeRead(suite.ctx, suite.dir+"/file")
+	if suite.NoError(err) {
+		suite.Equal("hello world", string(content))
+	}
+
+	block, err := d.VolumeReadBlock(suite.ctx, suite.dir+"/file", 5, 6)
+	if suite.NoError(err) {
+		suite.Equal("world", string(block))
+	}
+	block, err = d.VolumeReadBlock(suite.ctx, suite.dir+"/file", 10, 6)
+	if suite.NoError(err) {
+		suite.Equal("world", string(block))
+	}
+	block, err = d.VolumeReadBlock(suite.ctx, suite.dir+"/file", 10, 20)
+	if suite.NoError(err) {
+		suite.Empty(block)
+	}
+}
+
+func (suite *sftpTestSuite) TestVolumeReadBlock_ReusesClientAndFile() {
+	calls := 0
+	sftpClient = func(ctx context.Context, id transport.Identity) (*sftp.Client, error) {
+		calls++
+		return suite.client, nil
+	}
+	suite.writeFile("file", "hello world")
+	d := suite.newSFTP(suite.createTarget())
+	path := suite.dir + "/file"
+
+	for offset, expected := range map[int64]string{0: "hello", 6: "world", 2: "llo w"} {
+		block, err := d.VolumeReadBlock(suite.ctx, path, 5, offset)
+		if suite.NoError(err) {
+			suite.Equal(expected, string(block))
+		}
+	}
+	suite.Equal(1, calls)
+	f := d.files[path]
+	if suite.NotNil(f) {
+		suite.Equal(0, f.refs)
+	}
+
+	// Writes are seen by subsequent reads.
+	suite.NoError(d.VolumeWrite(suite.ctx, path, []byte("goodbye world")))
+	suite.NotContains(d.files, path)
+	block, err := d.VolumeReadBlock(suite.ctx, path, 7, 0)
+	if suite.NoError(err) {
+		suite.Equal("goodbye", string(block))
+	}
+	suite.NotEqual(f, d.files[path])
+	suite.Equal(1, calls)
+
+	// The client's fetched again once it expires.
+	d.clientExpires = time.Time{}
+	_, err = d.VolumeReadBlock(suite.ctx, path, 7, 0)
+	suite.NoError(err)
+	suite.Equal(2, calls)
+}
+
+func (suite *sftpTestSuite) TestVolumeWriteCreateMkdirDelete() {
+	d := suite.newSFTP(suite.createTarget())
+
+	if suite.NoError(d.VolumeMkdir(suite.ctx, suite.dir+"/dir")) {
+		info, err := os.Stat(suite.dir + "/dir")
+		if suite.NoError(err) {
+			suite.True(info.IsDir())
+		}
+	}
+
+	if suite.NoError(d.VolumeCreate(suite.ctx, suite.dir+"/dir/file")) {
+		content, err := ioutil.ReadFile(suite.dir + "/dir/file")
+		if suite.NoError(err) {
+			suite.Empty(content)
+		}
+	}
+
+	if suite.NoError(d.VolumeWrite(suite.ctx, suite.dir+"/dir/file", []byte("some content"))) {
+		suite.NoError(d.VolumeWrite(suite.ctx, suite.dir+"/dir/file", []byte("new")))
+		content, err := ioutil.ReadFile(suite.dir + "/dir/file")
+		if suite.NoError(err) {
+			suite.Equal("new", string(content))
+		}
+	}
+
+	// Create leaves existing content alone, like touch.
+	if suite.NoError(d.VolumeCreate(suite.ctx, suite.dir+"/dir/file")) {
+		content, err := ioutil.ReadFile(suite.dir + "/dir/file")
+		if suite.NoError(err) {
+			suite.Equal("new", string(content))
+		}
+	}
+
+	deleted, err := d.VolumeDelete(suite.ctx, suite.dir+"/dir")
+	if suite.NoError(err) {
+		suite.True(deleted)
+		_, err := os.Stat(suite.dir + "/dir")
+		suite.True(os.IsNotExist(err))
+	}
+}
+
+func (suite *sftpTestSuite) TestFallbackToExec() {
+	sftpClient = func(ctx context.Context, id transport.Identity) (*sftp.Client, error) {
+		return nil, transport.SFTPUnavailableError{Err: fmt.Errorf("subsystem request failed")}
+	}
+	target := suite.createTarget()
+	target.onExec([]string{"cat", "/file"}, mockExecCmd{"hello world"})
+	d := suite.newSFTP(target)
+
+	content, err := d.VolumeRead(suite.ctx, "/file")
+	if suite.NoError(err) {
+		suite.Equal("hello world", string(content))
+	}
+
+	// Only the block's read.
+	target.onExec([]string{"sh", "-c", `tail -c +"$1" "$2" | head -c "$3"`, "sh", "7", "/file", "5"}, mockExecCmd{"world"})
+	block, err := d.VolumeReadBlock(suite.ctx, "/file", 5, 6)
+	if suite.NoError(err) {
+		suite.Equal("world", string(block))
+	}
+	target.AssertExpectations(suite.T())
+}
+
+func (suite *sftpTestSuite) TestNoFallbackToExecOnConnectionError() {
+	sftpClient = func(ctx context.Context, id transport.Identity) (*sftp.Client, error) {
+		return nil, fmt.Errorf("Failed to connect: unable to authenticate")
+	}
+	target := suite.createTarget()
+	d := suite.newSFTP(target)
+
+	_, err := d.VolumeRead(suite.ctx, "/file")
+	suite.EqualError(err, "Failed to connect: unable to authenticate")
+	_, err = d.VolumeReadBlock(suite.ctx, "/file", 5, 6)
+	suite.EqualError(err, "Failed to connect: unable to authenticate")
+	target.AssertNotCalled(suite.T(), "Exec")
+}
+
+func (suite *sftpTestSuite) TestFallbackToExecOnPermissionDenied() {
+	if os.Geteuid() == 0 {
+		suite.T().Skip("root is never denied permission")
+	}
+	suite.writeFile("secret", "hidden")
+	suite.NoError(os.Chmod(suite.dir+"/secret", 0))
+
+	target := suite.createTarget()
+	target.onExec([]string{"cat", suite.dir + "/secret"}, mockExecCmd{"hidden"})
+	content, err := suite.newSFTP(target).VolumeRead(suite.ctx, suite.dir+"/secret")
+	if suite.NoError(err) {
+		suite.Equal("hidden", string(content))
+	}
+	target.AssertExpectations(suite.T())
+}
+
+func (suite *sftpTestSuite) TestEntries() {
+	suite.writeFile("dir/file", "hello world")
+	target := suite.createTarget()
+	d := NewSFTP(suite.ctx, "fs", target, 1)
+
+	var entry plugin.Entry = d
+	for _, name := range append(splitPath(suite.dir), "dir", "file") {
+		entries, err := plugin.List(suite.ctx, entry.(plugin.Parent))
+		if !suite.NoError(err) {
+			return
+		}
+		var ok bool
+		if entry, ok = entries.Load(name); !suite.True(ok, "%v not found", name) {
+			return
+		}
+	}
+
+	// Files are read in blocks.
+	f, ok := entry.(plugin.BlockReadable)
+	if suite.True(ok) {
+		block, err := f.Read(suite.ctx, 5, 6)
+		if suite.NoError(err) {
+			suite.Equal("world", string(block))
+		}
+	}
+	attr := plugin.Attributes(entry)
+	suite.Equal(uint64(11), attr.Size())
+
+	// The schema describes block-readable files.
+	suite.IsType(&blockFile{}, entry)
+	schemas := d.ChildSchemas()
+	if suite.Len(schemas, 2) {
+		suite.Equal("dir", schemas[0].Label)
+		suite.Equal("file", schemas[1].Label)
+		suite.Contains(schemas[1].Actions, plugin.ReadAction().Name)
+	}
+}
+
+func splitPath(path string) []string {
+	var segments []string
+	for path != "/" {
+		segments = append([]string{filepath.Base(path)}, segments...)
+		path = filepath.Dir(path)
+	}
+	return segments
+}
+
+func TestSFTP(t *testing.T) {
+	suite.Run(t, new(sftpTestSuite))
+}
+
+type mockSSHTarget struct {
+	mockExecutor
+}
+
+func (m *mockSSHTarget) SSHIdentity(ctx context.Context) (transport.Identity, error) {
+	return transport.Identity{Host: "example.com"}, nil
+}